@@ -0,0 +1,170 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fabricates/traefik7/pkg/parser"
+)
+
+// convertOptions controls the output of the convert subcommand
+type convertOptions struct {
+	stdout   bool   // Print to stdout instead of writing files
+	annotate bool   // Annotate generated YAML with provenance comments
+	outDir   string // Output directory, a new timestamped directory when empty
+	generate parser.GenerateOptions
+}
+
+// generateFlags are the flags that control how the model is translated, shared
+// by the subcommands that generate Traefik configuration
+type generateFlags struct {
+	weights  string
+	disabled string
+	traefik  string
+	certDirs inputList
+}
+
+// register adds the generation flags to a flag set
+func (g *generateFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&g.weights, "weights", "services", "How to express member weights: services (a weighted service over child services, any Traefik version) or servers (a weight on each server URL, for Traefik versions that support it)")
+	flags.StringVar(&g.disabled, "disabled", "drop", "What to do with disabled servers, members and vservers: drop them, or comment them out in the generated files")
+	flags.StringVar(&g.traefik, "traefik-version", "", "Target Traefik version, e.g. 2.11 or 3.5; load balancing methods use the strategies it supports (default: any version, round robin only)")
+	flags.Var(&g.certDirs, "cert-dir", "Directory holding the certificate and key files of SSL certKeys, by their path below /nsconfig/ssl (repeatable, searched in order)")
+}
+
+// options converts the flag values to generation options
+func (g *generateFlags) options() (parser.GenerateOptions, error) {
+	weights, err := parser.ParseWeightMode(g.weights)
+	if err != nil {
+		return parser.GenerateOptions{}, err
+	}
+	disabled, err := parser.ParseDisabledMode(g.disabled)
+	if err != nil {
+		return parser.GenerateOptions{}, err
+	}
+	traefik, err := parser.ParseTraefikVersion(g.traefik)
+	if err != nil {
+		return parser.GenerateOptions{}, err
+	}
+	return parser.GenerateOptions{Weights: weights, Disabled: disabled, Traefik: traefik, CertDirs: g.certDirs}, nil
+}
+
+// runConvert implements the convert subcommand
+func runConvert(args []string) int {
+	flags := flag.NewFlagSet("convert", flag.ExitOnError)
+	setUsage(flags, "convert [flags] [input ...]",
+		"Generates traefik-services.yaml and mapping.yaml from Citrix or F5 configurations.\n"+
+			"Diagnostics are printed to stderr so that -o output stays valid YAML.")
+
+	var input inputFlags
+	input.register(flags)
+	var opts convertOptions
+	flags.BoolVar(&opts.stdout, "o", false, "Print the generated configuration to stdout instead of writing files")
+	flags.BoolVar(&opts.annotate, "annotate", false, "Annotate generated services, server URLs and mappings with their source file, line and command")
+	flags.StringVar(&opts.outDir, "out", "", "Directory for the generated files (default: a new directory named after the current time, yyyymmddhhMM)")
+	var generate generateFlags
+	generate.register(flags)
+	flags.Parse(args)
+
+	var err error
+	if opts.generate, err = generate.options(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 2
+	}
+
+	return convertCommand(flags, &input, opts)
+}
+
+// convertCommand parses the inputs and writes the generated Traefik services and mappings
+func convertCommand(flags *flag.FlagSet, input *inputFlags, opts convertOptions) int {
+	// Parse the L7 settings
+	config, err := input.load(flags.Args())
+	if err != nil {
+		return loadError(flags, err)
+	}
+
+	// Warnings go to stderr so that -o output stays valid YAML
+	diagnostics := append(append(parser.Diagnostics{}, config.Diagnostics...), parser.Verify(config)...)
+	parser.WriteDiagnostics(os.Stderr, append(diagnostics, parser.CheckCertificateFiles(config, opts.generate.CertDirs)...))
+
+	// Generate Traefik configuration
+	traefikConfig := parser.GenerateTraefikConfigWithOptions(config, opts.generate)
+
+	// Generate mapping configuration
+	mappingConfig := parser.GenerateMappingConfigFromTraefik(config, traefikConfig, opts.generate)
+	writeOptions := parser.WriteOptions{Provenance: opts.annotate}
+
+	// If output mode is enabled, print to stdout
+	if opts.stdout {
+		fmt.Println("# Traefik Services Configuration")
+		err = parser.WriteTraefikConfigWithOptions(os.Stdout, traefikConfig, writeOptions)
+		if err != nil {
+			fmt.Printf("Error writing Traefik config to stdout: %v\n", err)
+			return 1
+		}
+
+		fmt.Println()
+		fmt.Println("# Mapping Configuration")
+		err = parser.WriteMappingConfigWithOptions(os.Stdout, mappingConfig, writeOptions)
+		if err != nil {
+			fmt.Printf("Error writing mapping config to stdout: %v\n", err)
+			return 1
+		}
+		parser.WriteSkipSummary(os.Stderr, config.Diagnostics)
+		return 0
+	}
+
+	// Create the output directory, timestamped unless -out is given
+	outputDir := opts.outDir
+	if outputDir == "" {
+		timestamp := time.Now().Format("200601021504") // yyyymmddhhMM format
+		outputDir = filepath.Join(".", timestamp)
+	}
+	err = os.MkdirAll(outputDir, 0755)
+	if err != nil {
+		fmt.Printf("Error creating output directory %s: %v\n", outputDir, err)
+		return 1
+	}
+
+	// Write Traefik configuration to file
+	traefikPath := filepath.Join(outputDir, "traefik-services.yaml")
+	err = writeFile(traefikPath, func(f *os.File) error {
+		return parser.WriteTraefikConfigWithOptions(f, traefikConfig, writeOptions)
+	})
+	if err != nil {
+		fmt.Printf("Error writing Traefik config: %v\n", err)
+		return 1
+	}
+
+	// Write mapping configuration to file
+	mappingPath := filepath.Join(outputDir, "mapping.yaml")
+	err = writeFile(mappingPath, func(f *os.File) error {
+		return parser.WriteMappingConfigWithOptions(f, mappingConfig, writeOptions)
+	})
+	if err != nil {
+		fmt.Printf("Error writing mapping config: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Successfully generated files in directory: %s\n", outputDir)
+	fmt.Printf("  - %s\n", traefikPath)
+	fmt.Printf("  - %s\n", mappingPath)
+	parser.WriteSkipSummary(os.Stdout, config.Diagnostics)
+	return 0
+}
+
+// writeFile creates a file and fills it with write, reporting close errors
+func writeFile(path string, write func(f *os.File) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/fabricates/traefik7/pkg/parser"
+)
+
+// runDetect implements the detect subcommand: it explains which format each
+// input is detected as and why. It returns the process exit code, 1 when an
+// input could not be read or matched no format.
+func runDetect(args []string) int {
+	flags := flag.NewFlagSet("detect", flag.ExitOnError)
+	setUsage(flags, "detect [input ...]",
+		"Prints the detected format of each input with its confidence and the evidence\n"+
+			"for every format that matched. Reads stdin when no input is given.")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		if stdinIsTerminal() {
+			fmt.Printf("Error: %v\n\n", errNoInput)
+			flags.Usage()
+			return 2
+		}
+		result, err := parser.DetectFormat(os.Stdin)
+		if err != nil {
+			fmt.Printf("Error reading stdin: %v\n", err)
+			return 1
+		}
+		parser.WriteDetection(os.Stdout, "<stdin>", result)
+		if result.Type == parser.ConfigTypeUnknown {
+			return 1
+		}
+		return 0
+	}
+
+	files, err := expandInputs(flags.Args(), false)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	status := 0
+	for _, file := range files {
+		result, err := parser.DetectFormatFile(file.path)
+		if err != nil {
+			fmt.Printf("Error reading %s: %v\n", file.path, err)
+			status = 1
+			continue
+		}
+		parser.WriteDetection(os.Stdout, file.path, result)
+		if result.Type == parser.ConfigTypeUnknown {
+			status = 1
+		}
+	}
+
+	return status
+}
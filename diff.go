@@ -0,0 +1,321 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/fabricates/traefik7/pkg/parser"
+)
+
+// runDiff implements the diff subcommand
+func runDiff(args []string) int {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	setUsage(flags, "diff [flags] <old> <new>",
+		"Converts two load balancer configurations (files or directories) and prints the Traefik\n"+
+			"routers, middlewares, services, servers transports, TLS certificates and options and IP:port mappings that were\n"+
+			"added, removed or changed.\n"+
+			"Exits with status 1 when the generated configurations differ.")
+
+	formatName := flags.String("format", "auto", "Input format: auto, citrix or f5 (auto detects the format of each input)")
+	lenient := flags.Bool("lenient", false, "Skip malformed commands with an error diagnostic instead of aborting")
+	var generate generateFlags
+	generate.register(flags)
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		fmt.Println("Error: diff needs exactly two inputs")
+		fmt.Println()
+		flags.Usage()
+		return 2
+	}
+
+	format, err := parser.ParseFormat(*formatName)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 2
+	}
+	opts := loadOptions{lenient: *lenient, format: format}
+	generateOptions, err := generate.options()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 2
+	}
+
+	var services [2]parser.TraefikConfig
+	var mappings [2]parser.MappingConfig
+	for i, input := range flags.Args() {
+		config, err := loadConfig([]string{input}, false, opts)
+		if err != nil {
+			printParseError(fmt.Sprintf("Error parsing %s", input), err)
+			return 1
+		}
+		parser.WriteDiagnostics(os.Stderr, config.Diagnostics)
+		services[i] = parser.GenerateTraefikConfigWithOptions(config, generateOptions)
+		mappings[i] = parser.GenerateMappingConfigFromTraefik(config, services[i], generateOptions)
+	}
+
+	fmt.Printf("--- %s\n+++ %s\n", flags.Arg(0), flags.Arg(1))
+	changes := diffRouters(os.Stdout, services[0], services[1])
+	changes += diffMiddlewares(os.Stdout, services[0], services[1])
+	changes += diffServices(os.Stdout, services[0], services[1])
+	changes += diffServersTransports(os.Stdout, services[0], services[1])
+	changes += diffCertificates(os.Stdout, services[0], services[1])
+	changes += diffTLSOptions(os.Stdout, services[0], services[1])
+	changes += diffMappings(os.Stdout, mappings[0], mappings[1])
+
+	if changes == 0 {
+		fmt.Println("No differences in the generated configuration")
+		return 0
+	}
+	fmt.Printf("%d difference(s)\n", changes)
+	return 1
+}
+
+// diffServices prints added, removed and changed Traefik services and returns the number of differences
+func diffServices(w io.Writer, old, new parser.TraefikConfig) int {
+	var lines []string
+
+	for _, name := range unionKeys(old.HTTP.Services, new.HTTP.Services) {
+		oldService, inOld := old.HTTP.Services[name]
+		newService, inNew := new.HTTP.Services[name]
+		switch {
+		case !inOld:
+			lines = append(lines, fmt.Sprintf("+ %s (%d server(s))", name, len(newService.Backends())))
+		case !inNew:
+			lines = append(lines, fmt.Sprintf("- %s (%d server(s))", name, len(oldService.Backends())))
+		default:
+			oldURLs := serverURLs(oldService)
+			newURLs := serverURLs(newService)
+			var changed []string
+			for _, url := range unionKeys(oldURLs, newURLs) {
+				switch {
+				case !oldURLs[url]:
+					changed = append(changed, "+ "+url)
+				case !newURLs[url]:
+					changed = append(changed, "- "+url)
+				}
+			}
+			if oldStrategy, newStrategy := oldService.LoadBalancer.Strategy, newService.LoadBalancer.Strategy; oldStrategy != newStrategy {
+				changed = append(changed, fmt.Sprintf("~ strategy: %q -> %q", oldStrategy, newStrategy))
+			}
+			oldCheck := oldService.LoadBalancer.HealthCheck.String()
+			newCheck := newService.LoadBalancer.HealthCheck.String()
+			if oldCheck != newCheck {
+				changed = append(changed, fmt.Sprintf("~ healthCheck: %s -> %s", oldCheck, newCheck))
+			}
+			oldSticky := oldService.StickySessions().String()
+			newSticky := newService.StickySessions().String()
+			if oldSticky != newSticky {
+				changed = append(changed, fmt.Sprintf("~ sticky: %s -> %s", oldSticky, newSticky))
+			}
+			oldTransport, newTransport := oldService.LoadBalancer.ServersTransport, newService.LoadBalancer.ServersTransport
+			if oldTransport != newTransport {
+				changed = append(changed, fmt.Sprintf("~ serversTransport: %q -> %q", oldTransport, newTransport))
+			}
+			if len(changed) > 0 {
+				lines = append(lines, fmt.Sprintf("~ %s", name))
+				for _, change := range changed {
+					lines = append(lines, "    "+change)
+				}
+			}
+		}
+	}
+
+	return writeSection(w, "Services", lines)
+}
+
+// diffRouters prints added, removed and changed Traefik routers and returns the number of differences
+func diffRouters(w io.Writer, old, new parser.TraefikConfig) int {
+	var lines []string
+	for _, name := range unionKeys(old.HTTP.Routers, new.HTTP.Routers) {
+		oldRouter, inOld := old.HTTP.Routers[name]
+		newRouter, inNew := new.HTTP.Routers[name]
+		switch {
+		case !inOld:
+			lines = append(lines, fmt.Sprintf("+ %s (%s)", name, newRouter))
+		case !inNew:
+			lines = append(lines, fmt.Sprintf("- %s (%s)", name, oldRouter))
+		case oldRouter.String() != newRouter.String():
+			lines = append(lines, fmt.Sprintf("~ %s", name), fmt.Sprintf("    ~ %s -> %s", oldRouter, newRouter))
+		}
+	}
+
+	return writeSection(w, "Routers", lines)
+}
+
+// diffMiddlewares prints added, removed and changed Traefik middlewares and returns the number of differences
+func diffMiddlewares(w io.Writer, old, new parser.TraefikConfig) int {
+	var lines []string
+	for _, name := range unionKeys(old.HTTP.Middlewares, new.HTTP.Middlewares) {
+		oldMiddleware, inOld := old.HTTP.Middlewares[name]
+		newMiddleware, inNew := new.HTTP.Middlewares[name]
+		switch {
+		case !inOld:
+			lines = append(lines, fmt.Sprintf("+ %s (%s)", name, newMiddleware))
+		case !inNew:
+			lines = append(lines, fmt.Sprintf("- %s (%s)", name, oldMiddleware))
+		case oldMiddleware.String() != newMiddleware.String():
+			lines = append(lines, fmt.Sprintf("~ %s", name), fmt.Sprintf("    ~ %s -> %s", oldMiddleware, newMiddleware))
+		}
+	}
+
+	return writeSection(w, "Middlewares", lines)
+}
+
+// diffServersTransports prints added, removed and changed servers transports and returns the number of differences
+func diffServersTransports(w io.Writer, old, new parser.TraefikConfig) int {
+	var lines []string
+	for _, name := range unionKeys(old.HTTP.ServersTransports, new.HTTP.ServersTransports) {
+		oldTransport, inOld := old.HTTP.ServersTransports[name]
+		newTransport, inNew := new.HTTP.ServersTransports[name]
+		switch {
+		case !inOld:
+			lines = append(lines, fmt.Sprintf("+ %s (%s)", name, newTransport))
+		case !inNew:
+			lines = append(lines, fmt.Sprintf("- %s (%s)", name, oldTransport))
+		case oldTransport.String() != newTransport.String():
+			lines = append(lines, fmt.Sprintf("~ %s", name), fmt.Sprintf("    ~ %s -> %s", oldTransport, newTransport))
+		}
+	}
+
+	return writeSection(w, "Servers transports", lines)
+}
+
+// diffCertificates prints a changed default certificate and added, removed
+// and changed TLS certificates, keyed by certFile, and returns the number of
+// differences
+func diffCertificates(w io.Writer, old, new parser.TraefikConfig) int {
+	oldCertificates := old.TLS.CertificatesByFile()
+	newCertificates := new.TLS.CertificatesByFile()
+
+	var lines []string
+	oldDefault, newDefault := old.TLS.DefaultCertificate(), new.TLS.DefaultCertificate()
+	switch {
+	case oldDefault == nil && newDefault == nil:
+	case oldDefault == nil:
+		lines = append(lines, fmt.Sprintf("+ default certificate (%s)", newDefault))
+	case newDefault == nil:
+		lines = append(lines, fmt.Sprintf("- default certificate (%s)", oldDefault))
+	case oldDefault.String() != newDefault.String():
+		lines = append(lines, "~ default certificate", fmt.Sprintf("    ~ %s -> %s", oldDefault, newDefault))
+	}
+	for _, file := range unionKeys(oldCertificates, newCertificates) {
+		oldCertificate, inOld := oldCertificates[file]
+		newCertificate, inNew := newCertificates[file]
+		switch {
+		case !inOld:
+			lines = append(lines, fmt.Sprintf("+ %s (%s)", file, newCertificate))
+		case !inNew:
+			lines = append(lines, fmt.Sprintf("- %s (%s)", file, oldCertificate))
+		case oldCertificate.String() != newCertificate.String():
+			lines = append(lines, fmt.Sprintf("~ %s", file), fmt.Sprintf("    ~ %s -> %s", oldCertificate, newCertificate))
+		}
+	}
+
+	return writeSection(w, "TLS certificates", lines)
+}
+
+// diffTLSOptions prints added, removed and changed TLS options and returns the number of differences
+func diffTLSOptions(w io.Writer, old, new parser.TraefikConfig) int {
+	var oldOptions, newOptions map[string]parser.TraefikTLSOptions
+	if old.TLS != nil {
+		oldOptions = old.TLS.Options
+	}
+	if new.TLS != nil {
+		newOptions = new.TLS.Options
+	}
+
+	var lines []string
+	for _, name := range unionKeys(oldOptions, newOptions) {
+		oldOption, inOld := oldOptions[name]
+		newOption, inNew := newOptions[name]
+		switch {
+		case !inOld:
+			lines = append(lines, fmt.Sprintf("+ %s (%s)", name, newOption))
+		case !inNew:
+			lines = append(lines, fmt.Sprintf("- %s (%s)", name, oldOption))
+		case oldOption.String() != newOption.String():
+			lines = append(lines, fmt.Sprintf("~ %s", name), fmt.Sprintf("    ~ %s -> %s", oldOption, newOption))
+		}
+	}
+
+	return writeSection(w, "TLS options", lines)
+}
+
+// diffMappings prints added, removed and changed IP:port mappings and returns the number of differences
+func diffMappings(w io.Writer, old, new parser.MappingConfig) int {
+	oldValues := mappingValues(old)
+	newValues := mappingValues(new)
+
+	var lines []string
+	for _, key := range unionKeys(oldValues, newValues) {
+		oldValue, inOld := oldValues[key]
+		newValue, inNew := newValues[key]
+		switch {
+		case !inOld:
+			lines = append(lines, fmt.Sprintf("+ %q: %q", key, newValue))
+		case !inNew:
+			lines = append(lines, fmt.Sprintf("- %q: %q", key, oldValue))
+		case oldValue != newValue:
+			lines = append(lines, fmt.Sprintf("~ %q: %q -> %q", key, oldValue, newValue))
+		}
+	}
+
+	return writeSection(w, "Mappings", lines)
+}
+
+// writeSection prints a titled list of differences and returns how many top-level entries it has
+func writeSection(w io.Writer, title string, lines []string) int {
+	if len(lines) == 0 {
+		return 0
+	}
+
+	fmt.Fprintf(w, "%s:\n", title)
+	count := 0
+	for _, line := range lines {
+		if line[0] != ' ' {
+			count++
+		}
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+	return count
+}
+
+// serverURLs returns the set of server URLs of a service, with their weights
+func serverURLs(service parser.TraefikService) map[string]bool {
+	urls := make(map[string]bool)
+	for _, backend := range service.Backends() {
+		urls[backend] = true
+	}
+	return urls
+}
+
+// mappingValues indexes mapping entries by key
+func mappingValues(config parser.MappingConfig) map[string]string {
+	values := make(map[string]string)
+	for _, entry := range config.Entries {
+		if !entry.Disabled {
+			values[entry.Key] = entry.Value
+		}
+	}
+	return values
+}
+
+// unionKeys returns the keys of both maps in sorted order
+func unionKeys[V any](a, b map[string]V) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, m := range []map[string]V{a, b} {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fabricates/traefik7/pkg/parser"
+)
+
+// inputList collects repeated flags such as -i
+type inputList []string
+
+// String implements flag.Value
+func (l *inputList) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set implements flag.Value
+func (l *inputList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// errNoInput is returned when no input file is given and stdin is a terminal
+var errNoInput = errors.New("no input: pass a configuration file or directory, or pipe one to stdin")
+
+// inputFlags are the input options shared by every subcommand that reads load balancer configurations
+type inputFlags struct {
+	files     inputList
+	format    string
+	lenient   bool
+	namespace bool
+}
+
+// register adds the input flags to a flag set
+func (f *inputFlags) register(flags *flag.FlagSet) {
+	flags.Var(&f.files, "i", "Input L7 load balancer settings file or directory (Citrix or F5 format - use '-' or omit for stdin). "+
+		"Repeat to merge several appliances; write 'prefix=path' to namespace an input's object names")
+	flags.StringVar(&f.format, "format", "auto", "Input format: auto, citrix or f5 (auto detects the format of each input)")
+	flags.BoolVar(&f.lenient, "lenient", false, "Skip malformed commands with an error diagnostic instead of aborting")
+	flags.BoolVar(&f.namespace, "namespace", false, "Prefix object names with each input file's base name unless 'prefix=path' is given")
+}
+
+// paths returns the inputs given with -i and as positional arguments, without the '-' stdin placeholder
+func (f *inputFlags) paths(args []string) []string {
+	var inputs []string
+	for _, input := range append(append([]string{}, f.files...), args...) {
+		if input != "-" {
+			inputs = append(inputs, input)
+		}
+	}
+	return inputs
+}
+
+// describe names the inputs for progress messages
+func (f *inputFlags) describe(args []string) string {
+	inputs := f.paths(args)
+	if len(inputs) == 0 {
+		return "stdin"
+	}
+	return "'" + strings.Join(inputs, "', '") + "'"
+}
+
+// load parses the inputs given with -i and as positional arguments. Without
+// inputs it reads stdin, unless stdin is a terminal, which fails with errNoInput.
+func (f *inputFlags) load(args []string) (*parser.LBConfig, error) {
+	format, err := parser.ParseFormat(f.format)
+	if err != nil {
+		return nil, err
+	}
+	opts := loadOptions{lenient: f.lenient, namespace: f.namespace, format: format}
+
+	inputs := f.paths(args)
+	useStdin := len(inputs) == 0
+	if useStdin && stdinIsTerminal() {
+		return nil, errNoInput
+	}
+
+	return loadConfig(inputs, useStdin, opts)
+}
+
+// loadError reports a failure of inputFlags.load and returns the exit code:
+// 2 with the command usage when no input was given, 1 otherwise
+func loadError(flags *flag.FlagSet, err error) int {
+	if errors.Is(err, errNoInput) {
+		fmt.Printf("Error: %v\n\n", err)
+		flags.Usage()
+		return 2
+	}
+	printParseError("Error parsing L7 load balancer settings", err)
+	return 1
+}
+
+// stdinIsTerminal reports whether stdin is interactive rather than a pipe or redirect
+func stdinIsTerminal() bool {
+	stat, err := os.Stdin.Stat()
+	return err != nil || stat.Mode()&os.ModeCharDevice != 0
+}
+
+// printParseError renders a parse failure, compiler style when it carries a diagnostic
+func printParseError(prefix string, err error) {
+	var diagErr *parser.DiagnosticError
+	if errors.As(err, &diagErr) {
+		fmt.Printf("%s:\n", prefix)
+		parser.WriteDiagnostics(os.Stdout, parser.Diagnostics{diagErr.Diagnostic})
+		return
+	}
+	fmt.Printf("%s: %v\n", prefix, err)
+}
+
+// loadOptions controls how input files are parsed and merged
+type loadOptions struct {
+	lenient   bool              // Skip malformed commands instead of aborting
+	namespace bool              // Prefix object names with each file's base name when no explicit prefix is given
+	format    parser.ConfigType // Parse every input as this format, ConfigTypeUnknown to detect
+}
+
+// inputFile is one configuration file to parse, with its optional name prefix
+type inputFile struct {
+	path   string
+	prefix string
+}
+
+// splitInputSpec splits a "prefix=path" input argument. Arguments without a
+// prefix, or whose part before '=' looks like a path, are returned unchanged.
+func splitInputSpec(spec string) (prefix, path string) {
+	before, after, found := strings.Cut(spec, "=")
+	if !found || before == "" || strings.ContainsAny(before, `/\`) {
+		return "", spec
+	}
+	return before, after
+}
+
+// expandInputs resolves input arguments into files. Directories contribute
+// every regular, non-hidden file they contain, in name order.
+func expandInputs(specs []string, namespace bool) ([]inputFile, error) {
+	var files []inputFile
+
+	for _, spec := range specs {
+		prefix, path := splitInputSpec(spec)
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		paths := []string{path}
+		if info.IsDir() {
+			entries, err := os.ReadDir(path)
+			if err != nil {
+				return nil, err
+			}
+			paths = nil
+			for _, entry := range entries {
+				if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+					paths = append(paths, filepath.Join(path, entry.Name()))
+				}
+			}
+			sort.Strings(paths)
+			if len(paths) == 0 {
+				return nil, fmt.Errorf("input directory %s contains no files", path)
+			}
+		}
+
+		for _, p := range paths {
+			filePrefix := prefix
+			if filePrefix == "" && namespace {
+				base := filepath.Base(p)
+				filePrefix = strings.TrimSuffix(base, filepath.Ext(base))
+			}
+			files = append(files, inputFile{path: p, prefix: filePrefix})
+		}
+	}
+
+	return files, nil
+}
+
+// loadConfig parses stdin or the given input arguments with format
+// auto-detection. Several inputs are parsed separately and merged into one
+// model with conflict diagnostics.
+func loadConfig(specs []string, useStdin bool, opts loadOptions) (*parser.LBConfig, error) {
+	if useStdin {
+		return parser.ParseReader(os.Stdin, parser.ParseOptions{Filename: "<stdin>", Lenient: opts.lenient, Format: opts.format})
+	}
+
+	files, err := expandInputs(specs, opts.namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	// A single unprefixed file needs no merge
+	if len(files) == 1 && files[0].prefix == "" {
+		return parser.ParseFile(files[0].path, parser.ParseOptions{Lenient: opts.lenient, Format: opts.format})
+	}
+
+	sources := make([]parser.Source, 0, len(files))
+	for _, file := range files {
+		config, err := parser.ParseFile(file.path, parser.ParseOptions{Lenient: opts.lenient, Format: opts.format})
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, parser.Source{Name: file.path, Prefix: file.prefix, Config: config})
+	}
+
+	return parser.MergeConfigs(sources), nil
+}
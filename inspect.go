@@ -0,0 +1,280 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fabricates/traefik7/pkg/parser"
+)
+
+// inspectOptions controls the report of the inspect subcommand
+type inspectOptions struct {
+	gaps     bool   // Print the untranslated gap report instead of the inventory
+	disabled string // What convert does with disabled objects: drop or comment
+}
+
+// runInspect implements the inspect subcommand
+func runInspect(args []string) int {
+	flags := flag.NewFlagSet("inspect", flag.ExitOnError)
+	setUsage(flags, "inspect [flags] [input ...]",
+		"Prints the parsed virtual servers with their bound services and members, or with -gaps\n"+
+			"every command or object that is not translated and the virtual servers it affects.")
+
+	var input inputFlags
+	input.register(flags)
+	var opts inspectOptions
+	flags.BoolVar(&opts.gaps, "gaps", false, "Gap report mode - list every command or object that is not translated and the affected virtual servers")
+	flags.StringVar(&opts.disabled, "disabled", "drop", "What convert does with disabled servers, members and vservers: drop them, or comment them out in the generated files")
+	flags.Parse(args)
+
+	return inspectCommand(flags, &input, opts)
+}
+
+// inspectCommand parses the inputs and prints the inventory or gap report
+func inspectCommand(flags *flag.FlagSet, input *inputFlags, opts inspectOptions) int {
+	disabled, err := parser.ParseDisabledMode(opts.disabled)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 2
+	}
+	config, err := input.load(flags.Args())
+	if err != nil {
+		return loadError(flags, err)
+	}
+	parser.WriteDiagnostics(os.Stderr, config.Diagnostics)
+
+	if opts.gaps {
+		err = parser.WriteGapReport(os.Stdout, parser.BuildGapReportWithOptions(config, parser.GenerateOptions{Disabled: disabled}))
+	} else {
+		err = writeInventory(os.Stdout, config)
+	}
+	if err != nil {
+		fmt.Printf("Error writing report: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// writeInventory prints each virtual server with its bound services and their
+// members, followed by the service groups no virtual server uses
+func writeInventory(w io.Writer, config *parser.LBConfig) error {
+	vendor := config.Vendor.String()
+	if config.Vendor == parser.ConfigTypeUnknown {
+		vendor = "mixed"
+	}
+	untranslated := len(parser.UntranslatedObjects(config))
+	fmt.Fprintf(w, "Format: %s\n", vendor)
+	fmt.Fprintf(w, "Servers: %d, virtual servers: %d, cs virtual servers: %d, service groups: %d, members: %d, bindings: %d, untranslated: %d\n",
+		len(config.Servers), len(config.VServers), len(config.CSVServers), len(config.ServiceGroupNames()), len(config.ServiceGroups),
+		len(config.VServerBindings), untranslated)
+
+	bound := make(map[string]bool)
+	tlsOptions := make(map[string]parser.TLSOption)
+	for _, option := range parser.TLSOptions(config, nil) {
+		for _, vserver := range option.VServers {
+			tlsOptions[vserver] = option
+		}
+	}
+	transports := make(map[string]parser.ServersTransport)
+	for _, transport := range parser.ServersTransports(config, nil) {
+		transports[transport.Name] = transport
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Virtual servers:")
+	for _, vserver := range config.VServers {
+		settings := ""
+		if vserver.Persistence.Type != "" {
+			settings += "  persistence " + vserver.Persistence.Type
+		}
+		if vserver.Backup.VServer != "" {
+			settings += "  backup " + vserver.Backup.VServer
+		}
+		fmt.Fprintf(w, "  %s  %s %s%s  (%s)\n", vserver.Name, vserver.Protocol, parser.VIPKey(vserver.IP, vserver.Port), settings, vserver.Pos)
+
+		groups := config.VServerGroups(vserver.Name)
+		for _, group := range groups {
+			bound[group] = true
+			writeServiceGroup(w, config, transports, group, "    ")
+		}
+		if len(groups) == 0 {
+			fmt.Fprintln(w, "    (no services bound)")
+		}
+		writeResponderSteps(w, parser.ResponderSteps(config, vserver.Name, false))
+		writeRewriteSteps(w, parser.RewriteSteps(config, vserver.Name, false))
+		writeCertificates(w, config, vserver.Name)
+		writeTLSOption(w, tlsOptions, vserver.Name)
+	}
+
+	if len(config.CSVServers) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Content switching virtual servers:")
+		for _, vserver := range config.CSVServers {
+			fmt.Fprintf(w, "  %s  %s %s  (%s)\n", vserver.Name, vserver.Protocol, parser.VIPKey(vserver.IP, vserver.Port), vserver.Pos)
+			writeCSRoutes(w, config, vserver)
+			writeResponderSteps(w, parser.ResponderSteps(config, vserver.Name, true))
+			writeRewriteSteps(w, parser.RewriteSteps(config, vserver.Name, true))
+			writeCertificates(w, config, vserver.Name)
+			writeTLSOption(w, tlsOptions, vserver.Name)
+		}
+	}
+
+	var unbound []string
+	for _, name := range config.ServiceGroupNames() {
+		if !bound[name] {
+			unbound = append(unbound, name)
+		}
+	}
+	if len(unbound) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Unbound service groups:")
+		for _, name := range unbound {
+			writeServiceGroup(w, config, transports, name, "  ")
+		}
+	}
+
+	if untranslated > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "%d untranslated object(s); run 'traefik7 inspect -gaps' for details\n", untranslated)
+	}
+
+	return nil
+}
+
+// writeServiceGroup prints a service group, its members and the servers
+// transport of its SSL settings at the given indentation
+func writeServiceGroup(w io.Writer, config *parser.LBConfig, transports map[string]parser.ServersTransport, name, indent string) {
+	protocol := "?"
+	if def := config.ServiceGroupDefByName(name); def != nil {
+		protocol = def.Protocol
+	}
+	members := config.MembersOf(name)
+	fmt.Fprintf(w, "%s%s  %s, %d member(s)\n", indent, name, protocol, len(members))
+
+	for _, member := range members {
+		address := "undefined server"
+		if member.Server != nil {
+			address = member.Server.IP
+		}
+		settings := ""
+		if member.Weight > 0 {
+			settings += fmt.Sprintf("  weight %d", member.Weight)
+		}
+		if member.Priority > 0 {
+			settings += fmt.Sprintf("  priority %d", member.Priority)
+		}
+		fmt.Fprintf(w, "%s  %s  %s:%s%s\n", indent, member.ServerName, address, member.Port, settings)
+	}
+	for _, binding := range config.MonitorsOf(name) {
+		monitorType := "undefined monitor"
+		if monitor := config.LookupMonitor(binding.MonitorName); monitor != nil {
+			monitorType = monitor.Type
+		}
+		fmt.Fprintf(w, "%s  monitor %s  %s\n", indent, binding.MonitorName, monitorType)
+	}
+	if transport, exists := transports[name]; exists {
+		fmt.Fprintf(w, "%s  servers transport %s -> %s\n", indent, transport.Name, transport.Transport)
+		for _, problem := range transport.Problems {
+			fmt.Fprintf(w, "%s    not translated: %s\n", indent, problem.Message)
+		}
+	}
+}
+
+// writeCSRoutes prints the routes of a content switching virtual server in evaluation order
+func writeCSRoutes(w io.Writer, config *parser.LBConfig, vserver *parser.CSVServer) {
+	routes := parser.CSRoutes(config, vserver)
+	if len(routes) == 0 {
+		fmt.Fprintln(w, "    (no policies bound)")
+	}
+	for _, route := range routes {
+		label := "default"
+		if !route.Default() {
+			label = fmt.Sprintf("policy %s", route.Binding.PolicyName)
+			if route.Binding.Priority != "" {
+				label += " priority " + route.Binding.Priority
+			}
+		}
+		result := route.Rule
+		if route.Problem != "" {
+			result = "not translated: " + route.Problem
+		}
+		fmt.Fprintf(w, "    %s -> %s  %s\n", label, route.Target, result)
+	}
+}
+
+// writeResponderSteps prints the responder policies of a virtual server in evaluation order
+func writeResponderSteps(w io.Writer, steps []parser.ResponderStep) {
+	for _, step := range steps {
+		label := "responder " + step.PolicyName
+		if step.Priority != "" {
+			label += " priority " + step.Priority
+		}
+		var result string
+		switch {
+		case step.Problem != "":
+			result = "not translated: " + step.Problem
+		case step.Middleware == nil:
+			result = "no action"
+		case step.Condition != "":
+			result = fmt.Sprintf("%s when %s", step.Middleware, step.Condition)
+		default:
+			result = step.Middleware.String()
+		}
+		fmt.Fprintf(w, "    %s -> %s\n", label, result)
+	}
+}
+
+// writeCertificates prints the certKeys bound to a virtual server with "bind ssl vserver"
+func writeCertificates(w io.Writer, config *parser.LBConfig, vserverName string) {
+	for _, binding := range config.SSLBindingsOf(vserverName) {
+		label := "certKey " + binding.CertKeyName
+		switch {
+		case binding.CA:
+			label += " ca"
+		case binding.SNI:
+			label += " sni"
+		}
+		result := "undefined"
+		if certKey := config.CertKeyByName(binding.CertKeyName); certKey != nil {
+			result = certKey.CertFile
+		}
+		fmt.Fprintf(w, "    %s -> %s\n", label, result)
+	}
+}
+
+// writeTLSOption prints the TLS options of a virtual server with the settings they lose
+func writeTLSOption(w io.Writer, options map[string]parser.TLSOption, vserverName string) {
+	option, exists := options[vserverName]
+	if !exists {
+		return
+	}
+	fmt.Fprintf(w, "    tls options %s (%s) -> %s\n", option.Name, option.Kind(), option.Options)
+	for _, problem := range option.Problems {
+		fmt.Fprintf(w, "      not translated: %s\n", problem.Message)
+	}
+}
+
+// writeRewriteSteps prints the rewrite policies of a virtual server in evaluation order
+func writeRewriteSteps(w io.Writer, steps []parser.RewriteStep) {
+	for _, step := range steps {
+		label := "rewrite " + step.PolicyName
+		if step.Priority != "" {
+			label += " priority " + step.Priority
+		}
+		label += " " + strings.ToLower(step.Type)
+		var result string
+		switch {
+		case step.Skipped != "":
+			result = "never evaluated: " + step.Skipped
+		case step.Problem != "":
+			result = "not translated: " + step.Problem
+		case step.Middleware == nil:
+			result = "no action"
+		default:
+			result = step.Middleware.String()
+		}
+		fmt.Fprintf(w, "    %s -> %s\n", label, result)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/fabricates/traefik7/pkg/parser"
+)
+
+// runLint implements the lint subcommand
+func runLint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	setUsage(flags, "lint [flags] [input ...]",
+		"Parses the configurations and reports syntax errors, undefined references, duplicates\n"+
+			"and merge conflicts without generating anything. Exits with status 1 when an error is found.")
+
+	var input inputFlags
+	input.register(flags)
+	strict := flags.Bool("strict", false, "Also exit with status 1 when a warning is found")
+	var certDirs inputList
+	flags.Var(&certDirs, "cert-dir", "Also report bound certKeys whose certificate or key file is in none of these directories (repeatable)")
+	flags.Parse(args)
+
+	config, err := input.load(flags.Args())
+	if err != nil {
+		return loadError(flags, err)
+	}
+
+	diagnostics := verify(config, certDirs)
+	if diagnostics.HasErrors() || (*strict && diagnostics.Count(parser.SeverityWarning) > 0) {
+		return 1
+	}
+	return 0
+}
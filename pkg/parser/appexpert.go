@@ -0,0 +1,386 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// exprNode is a parsed Citrix AppExpert (advanced policy) expression: a term
+// such as HTTP.REQ.HOSTNAME.EQ("a"), or "&&", "||" or "!" over other nodes
+type exprNode struct {
+	Op    string     // "&&", "||" or "!"; empty for a term
+	Left  *exprNode  // First operand, the only one of "!"
+	Right *exprNode  // Second operand of "&&" and "||"
+	Terms []exprCall // Dotted chain of a term, e.g. HTTP, REQ, HOSTNAME, EQ("a")
+	Group bool       // Written in parentheses
+	Text  string     // Source text of the node
+}
+
+// exprCall is one element of a dotted expression chain with its arguments
+type exprCall struct {
+	Name    string
+	Args    []string // Unquoted argument values
+	HasArgs bool     // Written with parentheses, even if empty
+}
+
+// names returns the upper-case names of a term's chain joined with dots,
+// without arguments, e.g. "HTTP.REQ.URL.PATH.STARTSWITH"
+func (n *exprNode) names() string {
+	names := make([]string, len(n.Terms))
+	for i, call := range n.Terms {
+		names[i] = strings.ToUpper(call.Name)
+	}
+	return strings.Join(names, ".")
+}
+
+// exprParser is a recursive descent parser over the text of an expression
+type exprParser struct {
+	text string
+	pos  int
+}
+
+// parseExpression parses an AppExpert expression. Operators follow the
+// appliance precedence: "!" binds tighter than "&&", which binds tighter
+// than "||".
+func parseExpression(text string) (*exprNode, error) {
+	p := &exprParser{text: text}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.text) {
+		return nil, fmt.Errorf("unexpected %q at offset %d", p.text[p.pos:], p.pos)
+	}
+	return node, nil
+}
+
+// skipSpace advances past whitespace
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.text) && strings.ContainsRune(" \t\r\n", rune(p.text[p.pos])) {
+		p.pos++
+	}
+}
+
+// consume skips whitespace and the given operator, reporting whether it was there
+func (p *exprParser) consume(op string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.text[p.pos:], op) {
+		p.pos += len(op)
+		return true
+	}
+	return false
+}
+
+// parseOr parses operands joined by "||"
+func (p *exprParser) parseOr() (*exprNode, error) {
+	return p.parseBinary("||", p.parseAnd)
+}
+
+// parseAnd parses operands joined by "&&"
+func (p *exprParser) parseAnd() (*exprNode, error) {
+	return p.parseBinary("&&", p.parseUnary)
+}
+
+// parseBinary parses operands of next joined by op, grouping to the left
+func (p *exprParser) parseBinary(op string, next func() (*exprNode, error)) (*exprNode, error) {
+	p.skipSpace()
+	start := p.pos
+	left, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for p.consume(op) {
+		right, err := next()
+		if err != nil {
+			return nil, err
+		}
+		left = &exprNode{Op: op, Left: left, Right: right, Text: strings.TrimSpace(p.text[start:p.pos])}
+	}
+	return left, nil
+}
+
+// parseUnary parses a negation, a parenthesized expression or a term
+func (p *exprParser) parseUnary() (*exprNode, error) {
+	p.skipSpace()
+	start := p.pos
+	switch {
+	case p.consume("!"):
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &exprNode{Op: "!", Left: operand, Text: p.text[start:p.pos]}, nil
+	case p.consume("("):
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(")") {
+			return nil, fmt.Errorf("missing ')' for '(' at offset %d", start)
+		}
+		node.Group = true
+		node.Text = p.text[start:p.pos]
+		return node, nil
+	default:
+		return p.parseTerm()
+	}
+}
+
+// parseTerm parses a dotted chain such as HTTP.REQ.HEADER("Host").EQ("a")
+func (p *exprParser) parseTerm() (*exprNode, error) {
+	p.skipSpace()
+	start := p.pos
+	node := &exprNode{}
+	for {
+		nameStart := p.pos
+		for p.pos < len(p.text) && isExprNameChar(p.text[p.pos]) {
+			p.pos++
+		}
+		if p.pos == nameStart {
+			if p.pos >= len(p.text) {
+				return nil, fmt.Errorf("expression ends where a term was expected")
+			}
+			return nil, fmt.Errorf("unexpected %q at offset %d", p.text[p.pos:], p.pos)
+		}
+		call := exprCall{Name: p.text[nameStart:p.pos]}
+		if p.pos < len(p.text) && p.text[p.pos] == '(' {
+			p.pos++
+			args, err := p.parseArgs()
+			if err != nil {
+				return nil, err
+			}
+			call.Args, call.HasArgs = args, true
+		}
+		node.Terms = append(node.Terms, call)
+		if p.pos >= len(p.text) || p.text[p.pos] != '.' {
+			break
+		}
+		p.pos++
+	}
+	node.Text = p.text[start:p.pos]
+	return node, nil
+}
+
+// parseArgs parses comma separated arguments up to the closing parenthesis.
+// Arguments are quoted strings or bare values such as IGNORECASE or 10.0.0.0/8.
+func (p *exprParser) parseArgs() ([]string, error) {
+	var args []string
+	for {
+		p.skipSpace()
+		if p.pos < len(p.text) && p.text[p.pos] == ')' && len(args) == 0 {
+			p.pos++
+			return nil, nil
+		}
+		if p.pos < len(p.text) && p.text[p.pos] == '"' {
+			value, err := p.parseString()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, value)
+		} else {
+			start := p.pos
+			for p.pos < len(p.text) && p.text[p.pos] != ',' && p.text[p.pos] != ')' {
+				p.pos++
+			}
+			args = append(args, strings.TrimSpace(p.text[start:p.pos]))
+		}
+		p.skipSpace()
+		if p.pos >= len(p.text) {
+			return nil, fmt.Errorf("missing ')' after arguments")
+		}
+		switch p.text[p.pos] {
+		case ')':
+			p.pos++
+			return args, nil
+		case ',':
+			p.pos++
+		default:
+			return nil, fmt.Errorf("unexpected %q in arguments at offset %d", p.text[p.pos:], p.pos)
+		}
+	}
+}
+
+// parseString parses a double-quoted string, in which \r, \n and \t are
+// control characters and a backslash escapes any other character
+func (p *exprParser) parseString() (string, error) {
+	start := p.pos
+	var value strings.Builder
+	for p.pos++; p.pos < len(p.text); p.pos++ {
+		switch c := p.text[p.pos]; c {
+		case '"':
+			p.pos++
+			return value.String(), nil
+		case '\\':
+			if p.pos+1 < len(p.text) {
+				p.pos++
+			}
+			if control, exists := stringEscapes[rune(p.text[p.pos])]; exists {
+				value.WriteByte(control)
+			} else {
+				value.WriteByte(p.text[p.pos])
+			}
+		default:
+			value.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("unterminated string at offset %d", start)
+}
+
+// exprPart is a string literal or a term of a concatenation
+type exprPart struct {
+	Literal string
+	Term    *exprNode // nil for a literal
+}
+
+// parseConcatenation parses string literals and terms joined with "+", such
+// as "https://" + HTTP.REQ.HOSTNAME + HTTP.REQ.URL, into their parts
+func parseConcatenation(text string) ([]exprPart, error) {
+	p := &exprParser{text: text}
+	var parts []exprPart
+	for {
+		p.skipSpace()
+		if p.pos < len(p.text) && p.text[p.pos] == '"' {
+			literal, err := p.parseString()
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, exprPart{Literal: literal})
+		} else {
+			term, err := p.parseTerm()
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, exprPart{Term: term})
+		}
+		if !p.consume("+") {
+			break
+		}
+	}
+	p.skipSpace()
+	if p.pos < len(p.text) {
+		return nil, fmt.Errorf("unexpected %q at offset %d", p.text[p.pos:], p.pos)
+	}
+	return parts, nil
+}
+
+// isExprNameChar reports whether c can be part of an expression chain name
+func isExprNameChar(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// matchAllRule is the Traefik rule that matches every request
+const matchAllRule = "PathPrefix(`/`)"
+
+// translateRule converts an AppExpert expression to a Traefik router rule.
+// Host names, URL paths and methods compared with EQ or STARTSWITH, "true",
+// HTTP.REQ.IS_VALID, "&&", "||", "!" and parentheses are translated; anything else is reported
+// in the error with the part of the expression that has no equivalent.
+func translateRule(expression string) (string, error) {
+	node, err := parseExpression(expression)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse %q: %v", expression, err)
+	}
+	return ruleOf(node)
+}
+
+// ruleOf converts a parsed expression to a Traefik rule
+func ruleOf(node *exprNode) (string, error) {
+	var rule string
+	switch node.Op {
+	case "&&", "||":
+		left, err := ruleOf(node.Left)
+		if err != nil {
+			return "", err
+		}
+		right, err := ruleOf(node.Right)
+		if err != nil {
+			return "", err
+		}
+		rule = left + " " + node.Op + " " + right
+	case "!":
+		operand, err := ruleOf(node.Left)
+		if err != nil {
+			return "", err
+		}
+		if node.Left.Op == "&&" || node.Left.Op == "||" {
+			if !node.Left.Group {
+				operand = "(" + operand + ")"
+			}
+		}
+		rule = "!" + operand
+	default:
+		matcher, err := matcherOf(node)
+		if err != nil {
+			return "", err
+		}
+		rule = matcher
+	}
+	if node.Group {
+		rule = "(" + rule + ")"
+	}
+	return rule, nil
+}
+
+// matcherOf converts a single comparison term to a Traefik matcher
+func matcherOf(node *exprNode) (string, error) {
+	if strings.EqualFold(node.Text, "true") || strings.EqualFold(node.names(), "HTTP.REQ.IS_VALID") {
+		return matchAllRule, nil
+	}
+
+	// Host names are case-insensitive in Traefik; other values are not
+	var terms []exprCall
+	ignoreCase := false
+	for _, call := range node.Terms {
+		if strings.EqualFold(call.Name, "SET_TEXT_MODE") {
+			ignoreCase = len(call.Args) == 1 && strings.EqualFold(call.Args[0], "IGNORECASE")
+			continue
+		}
+		terms = append(terms, call)
+	}
+	unsupported := fmt.Errorf("%s has no Traefik rule equivalent", node.Text)
+	if len(terms) < 2 {
+		return "", unsupported
+	}
+
+	compare := terms[len(terms)-1]
+	if len(compare.Args) != 1 {
+		return "", unsupported
+	}
+	value := compare.Args[0]
+	subject := (&exprNode{Terms: terms[:len(terms)-1]}).names()
+	if header := terms[len(terms)-2]; strings.EqualFold(header.Name, "HEADER") && len(header.Args) == 1 &&
+		strings.EqualFold(header.Args[0], "Host") && len(terms) == 4 {
+		subject = "HTTP.REQ.HOSTNAME"
+	}
+
+	var matcher string
+	switch subject + "." + strings.ToUpper(compare.Name) {
+	case "HTTP.REQ.HOSTNAME.EQ", "HTTP.REQ.HOSTNAME.SERVER.EQ":
+		return "Host(" + ruleValue(value) + ")", nil
+	case "HTTP.REQ.URL.PATH.EQ", "HTTP.REQ.URL.EQ":
+		if strings.Contains(value, "?") {
+			return "", fmt.Errorf("%s compares the query string, which Traefik rules do not match", node.Text)
+		}
+		matcher = "Path(" + ruleValue(value) + ")"
+	case "HTTP.REQ.URL.PATH.STARTSWITH", "HTTP.REQ.URL.STARTSWITH":
+		matcher = "PathPrefix(" + ruleValue(value) + ")"
+	case "HTTP.REQ.METHOD.EQ":
+		return "Method(" + ruleValue(strings.ToUpper(value)) + ")", nil
+	default:
+		return "", unsupported
+	}
+	if ignoreCase {
+		return "", fmt.Errorf("%s compares the path case-insensitively, Traefik path matchers are case-sensitive", node.Text)
+	}
+	return matcher, nil
+}
+
+// ruleValue quotes a value for a Traefik rule, with backticks unless it contains one
+func ruleValue(value string) string {
+	if strings.Contains(value, "`") {
+		return strconv.Quote(value)
+	}
+	return "`" + value + "`"
+}
@@ -2,19 +2,18 @@ package parser
 
 import (
 	"bufio"
+	"errors"
+	"fmt"
 	"io"
 	"os"
-	"strings"
 )
 
-// ConfigType represents the type of load balancer configuration
-type ConfigType int
+// ConfigType identifies a load balancer configuration format by the name of
+// its registered parser, so a new vendor needs no constant here
+type ConfigType string
 
-const (
-	ConfigTypeUnknown ConfigType = iota
-	ConfigTypeCitrix
-	ConfigTypeF5
-)
+// ConfigTypeUnknown is the type of input that no parser recognized
+const ConfigTypeUnknown ConfigType = ""
 
 // DetectConfigType detects whether a configuration file is Citrix or F5 format
 func DetectConfigType(filename string) (ConfigType, error) {
@@ -27,124 +26,76 @@ func DetectConfigType(filename string) (ConfigType, error) {
 	return DetectConfigTypeFromReader(file)
 }
 
-// DetectConfigTypeFromReader detects configuration type from an io.Reader
+// DetectConfigTypeFromReader detects configuration type from an io.Reader by asking
+// every registered parser to score the first lines of the input
 func DetectConfigTypeFromReader(reader io.Reader) (ConfigType, error) {
-	scanner := bufio.NewScanner(reader)
-	lineCount := 0
-	maxLinesToCheck := 100 // Check first 100 lines
-
-	citrixIndicators := 0
-	f5Indicators := 0
-
-	for scanner.Scan() && lineCount < maxLinesToCheck {
-		line := strings.TrimSpace(scanner.Text())
-		lineCount++
-
-		// Skip empty lines
-		if line == "" {
-			continue
-		}
-
-		// F5 indicators
-		if strings.HasPrefix(line, "#TMSH-VERSION") {
-			f5Indicators += 10 // Strong indicator
-		}
-		if strings.HasPrefix(line, "ltm ") {
-			f5Indicators += 5 // Strong indicator
-		}
-		if strings.Contains(line, "/Common/") {
-			f5Indicators += 1 // Weak indicator
-		}
-		if strings.HasPrefix(line, "apm ") || strings.HasPrefix(line, "sys ") {
-			f5Indicators += 2 // Medium indicator
-		}
-
-		// Citrix indicators
-		if strings.HasPrefix(line, "add server ") {
-			citrixIndicators += 5 // Strong indicator
-		}
-		if strings.HasPrefix(line, "add lb vserver ") {
-			citrixIndicators += 5 // Strong indicator
-		}
-		if strings.HasPrefix(line, "add serviceGroup ") {
-			citrixIndicators += 5 // Strong indicator
-		}
-		if strings.HasPrefix(line, "bind serviceGroup ") {
-			citrixIndicators += 5 // Strong indicator
-		}
-		if strings.HasPrefix(line, "bind lb vserver ") {
-			citrixIndicators += 5 // Strong indicator
-		}
-		if strings.HasPrefix(line, "set ") && (strings.Contains(line, " server ") || strings.Contains(line, " vserver ")) {
-			citrixIndicators += 3 // Medium indicator
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
+	result, err := DetectFormat(reader)
+	if err != nil {
 		return ConfigTypeUnknown, err
 	}
+	return result.Type, nil
+}
 
-	// Determine configuration type based on indicators
-	if f5Indicators > citrixIndicators && f5Indicators > 0 {
-		return ConfigTypeF5, nil
-	} else if citrixIndicators > 0 {
-		return ConfigTypeCitrix, nil
-	}
+// ParseL7SettingsAuto automatically detects configuration type and parses accordingly
+func ParseL7SettingsAuto(filename string) (*LBConfig, error) {
+	return ParseFile(filename, ParseOptions{})
+}
 
-	return ConfigTypeUnknown, nil
+// ParseL7SettingsFromReaderAuto automatically detects configuration type and parses accordingly from a reader
+func ParseL7SettingsFromReaderAuto(reader io.Reader) (*LBConfig, error) {
+	return ParseReader(reader, ParseOptions{})
 }
 
-// ParseL7SettingsAuto automatically detects configuration type and parses accordingly
-func ParseL7SettingsAuto(filename string) ([]ServerInfo, []VServerInfo, []ServiceGroupDef, []ServiceGroup, []VServerBinding, error) {
-	configType, err := DetectConfigType(filename)
+// ParseFile detects the configuration type of a file and parses it with the matching parser.
+// The file name is used for diagnostic positions unless opts.Filename is set.
+func ParseFile(filename string, opts ParseOptions) (*LBConfig, error) {
+	if opts.Filename == "" {
+		opts.Filename = filename
+	}
+
+	file, err := os.Open(filename)
 	if err != nil {
-		return nil, nil, nil, nil, nil, err
+		return nil, err
 	}
+	defer file.Close()
 
-	switch configType {
-	case ConfigTypeF5:
-		return ParseF5SettingsFromFileSimple(filename)
-	case ConfigTypeCitrix:
-		return ParseL7Settings(filename)
-	default:
-		// Default to Citrix parser for backward compatibility
-		return ParseL7Settings(filename)
+	config, err := ParseReader(file, opts)
+	if errors.Is(err, ErrNoParserMatched) {
+		return nil, fmt.Errorf("%s: %w", filename, err)
 	}
+	return config, err
 }
 
-// ParseL7SettingsFromReaderAuto automatically detects configuration type and parses accordingly from a reader
-func ParseL7SettingsFromReaderAuto(reader io.Reader) ([]ServerInfo, []VServerInfo, []ServiceGroupDef, []ServiceGroup, []VServerBinding, error) {
-	// For readers, we need to buffer the content to detect type and then parse
-	// Read all content into memory
-	scanner := bufio.NewScanner(reader)
-	var lines []string
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+// ParseReader detects the configuration type of a reader and parses it with the
+// matching parser. The format is detected from a buffered prefix of the input,
+// which is then parsed in a single streaming pass.
+func ParseReader(reader io.Reader, opts ParseOptions) (*LBConfig, error) {
+	buffered := bufio.NewReaderSize(reader, detectPeekSize)
+
+	configType := opts.Format
+	if configType == ConfigTypeUnknown {
+		sample, err := peekSample(buffered)
+		if err != nil {
+			return nil, err
+		}
+		configType = detectFromSample(sample).Type
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, nil, nil, nil, nil, err
+	p := ParserFor(configType)
+	if p == nil {
+		return nil, ErrNoParserMatched
 	}
 
-	// Create a new reader from the buffered content for type detection
-	content := strings.Join(lines, "\n")
-	typeReader := strings.NewReader(content)
+	return p.Parse(buffered, opts)
+}
 
-	configType, err := DetectConfigTypeFromReader(typeReader)
-	if err != nil {
-		return nil, nil, nil, nil, nil, err
-	}
+// maxLineLength bounds a single configuration line. Certificates and iRules
+// can put far more than bufio.Scanner's default 64 KiB on one line.
+const maxLineLength = 64 << 20
 
-	// Create another reader for actual parsing
-	parseReader := strings.NewReader(content)
-
-	switch configType {
-	case ConfigTypeF5:
-		return ParseF5SettingsFromReaderSimple(parseReader)
-	case ConfigTypeCitrix:
-		return ParseL7SettingsFromReader(parseReader)
-	default:
-		// Default to Citrix parser for backward compatibility
-		return ParseL7SettingsFromReader(parseReader)
-	}
+// newLineScanner returns a line scanner for configuration input that accepts lines up to maxLineLength
+func newLineScanner(reader io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxLineLength)
+	return scanner
 }
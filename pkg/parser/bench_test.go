@@ -0,0 +1,154 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// longLineBytes is the length of the oversized lines mixed into generated
+// configurations, larger than bufio.Scanner's default 64 KiB token limit
+const longLineBytes = 256 << 10
+
+// benchmarkSizes are the approximate line counts of the generated inputs
+var benchmarkSizes = []int{10000, 100000, 500000}
+
+// generateConfig returns a synthetic configuration of the format with roughly
+// the given number of lines
+func generateConfig(format ConfigType, lines int) []byte {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	switch format {
+	case ConfigTypeCitrix:
+		generateCitrix(w, lines)
+	case ConfigTypeF5:
+		generateF5(w, lines)
+	}
+	w.Flush()
+	return buf.Bytes()
+}
+
+// generateCitrix writes system settings followed by applications of 13 lines
+// each: three servers, a service group with its members, a vserver and its
+// binding, a responder policy and a change that moves the vserver to another
+// port. Every 100th application is changed further: its third server is
+// unbound and removed and its service group renamed. Every 10000th
+// application carries an oversized responder action.
+func generateCitrix(w *bufio.Writer, lines int) {
+	fmt.Fprintln(w, "#NS13.1 Build 37.38")
+	fmt.Fprintln(w, "set ns config -IPAddress 10.0.0.10 -netmask 255.255.255.0")
+	fmt.Fprintln(w, "enable ns feature LB CS SSL REWRITE RESPONDER")
+
+	for app := 0; 3+app*13 < lines; app++ {
+		group := fmt.Sprintf("app%06d", app)
+		for s := 1; s <= 3; s++ {
+			fmt.Fprintf(w, "add server %s-srv%d 10.%d.%d.%d\n", group, s, app/250%250, app%250, s)
+		}
+		fmt.Fprintf(w, "add serviceGroup %s HTTP -maxClient 0 -cip ENABLED X-Forwarded-For\n", group)
+		for s := 1; s <= 3; s++ {
+			fmt.Fprintf(w, "bind serviceGroup %s %s-srv%d 8080\n", group, group, s)
+		}
+		fmt.Fprintf(w, "add lb vserver %s-vs HTTP 172.%d.%d.%d 80 -persistenceType NONE\n", group, 16+app/62500%16, app/250%250, app%250+1)
+		fmt.Fprintf(w, "bind lb vserver %s-vs %s\n", group, group)
+		if app%10000 == 0 {
+			fmt.Fprintf(w, "add responder action %s-act respondwith \"\\\"%s\\\"\"\n", group, strings.Repeat("x", longLineBytes))
+		} else {
+			fmt.Fprintf(w, "add responder action %s-act redirect \"\\\"https://%s.example.com\\\"\"\n", group, group)
+		}
+		fmt.Fprintf(w, "add responder policy %s-pol HTTP.REQ.IS_VALID %s-act\n", group, group)
+		fmt.Fprintf(w, "bind lb vserver %s-vs -policyName %s-pol -priority 100 -type REQUEST\n", group, group)
+		fmt.Fprintf(w, "set lb vserver %s-vs -port 8080\n", group)
+		if app%100 == 0 {
+			fmt.Fprintf(w, "unbind serviceGroup %s %s-srv3 8080\n", group, group)
+			fmt.Fprintf(w, "rm server %s-srv3\n", group)
+			fmt.Fprintf(w, "rename serviceGroup %s %s-sg\n", group, group)
+		}
+	}
+}
+
+// generateF5 writes applications of 26 lines each: two nodes, a pool with its
+// members and monitor, and a virtual with profiles and an iRule. Every 10000th
+// application carries an iRule with an oversized line.
+func generateF5(w *bufio.Writer, lines int) {
+	fmt.Fprintln(w, "#TMSH-VERSION: 15.1.0")
+
+	for app := 0; 1+app*26 < lines; app++ {
+		name := fmt.Sprintf("app%06d", app)
+		for s := 1; s <= 2; s++ {
+			fmt.Fprintf(w, "ltm node /Common/10.%d.%d.%d {\n    address 10.%d.%d.%d\n}\n", app/250%250, app%250, s, app/250%250, app%250, s)
+		}
+		fmt.Fprintf(w, "ltm pool /Common/%s_pool {\n", name)
+		fmt.Fprintln(w, "    members {")
+		for s := 1; s <= 2; s++ {
+			fmt.Fprintf(w, "        /Common/10.%d.%d.%d:8080 {\n            address 10.%d.%d.%d\n        }\n", app/250%250, app%250, s, app/250%250, app%250, s)
+		}
+		fmt.Fprintln(w, "    }")
+		fmt.Fprintln(w, "    monitor /Common/http")
+		fmt.Fprintln(w, "}")
+		fmt.Fprintf(w, "ltm virtual /Common/%s_vs {\n", name)
+		fmt.Fprintf(w, "    destination /Common/172.%d.%d.%d:80\n", 16+app/62500%16, app/250%250, app%250+1)
+		fmt.Fprintf(w, "    pool /Common/%s_pool\n", name)
+		fmt.Fprintln(w, "    profiles { /Common/http { } /Common/tcp { } }")
+		fmt.Fprintf(w, "    rules { /Common/%s_rule }\n", name)
+		fmt.Fprintln(w, "}")
+		if app%10000 == 0 {
+			fmt.Fprintf(w, "ltm rule /Common/%s_rule {\n    when HTTP_REQUEST { set payload \"%s\" }\n}\n", name, strings.Repeat("x", longLineBytes))
+		} else {
+			fmt.Fprintf(w, "ltm rule /Common/%s_rule {\n    when HTTP_REQUEST { HTTP::redirect https://%s.example.com }\n}\n", name, name)
+		}
+	}
+}
+
+func TestParseGeneratedConfigs(t *testing.T) {
+	tests := []struct {
+		format       ConfigType
+		lines        int
+		wantServers  int
+		wantVServers int
+	}{
+		{format: ConfigTypeCitrix, lines: 1303, wantServers: 299, wantVServers: 100},
+		{format: ConfigTypeF5, lines: 2601, wantServers: 200, wantVServers: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format.String(), func(t *testing.T) {
+			config, err := ParseReader(bytes.NewReader(generateConfig(tt.format, tt.lines)), ParseOptions{Filename: "synthetic"})
+			if err != nil {
+				t.Fatalf("ParseReader: %v", err)
+			}
+			if config.Vendor != tt.format {
+				t.Errorf("Vendor = %v, want %v", config.Vendor, tt.format)
+			}
+			if len(config.Servers) != tt.wantServers || len(config.VServers) != tt.wantVServers {
+				t.Errorf("got %d servers and %d vservers, want %d and %d",
+					len(config.Servers), len(config.VServers), tt.wantServers, tt.wantVServers)
+			}
+		})
+	}
+}
+
+// benchmarkParse parses generated configurations of each benchmark size
+func benchmarkParse(b *testing.B, format ConfigType) {
+	for _, lines := range benchmarkSizes {
+		content := generateConfig(format, lines)
+		b.Run(fmt.Sprintf("lines=%d", lines), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(content)))
+			for i := 0; i < b.N; i++ {
+				if _, err := ParseReader(bytes.NewReader(content), ParseOptions{Filename: "synthetic"}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkParseCitrix(b *testing.B) {
+	benchmarkParse(b, ConfigTypeCitrix)
+}
+
+func BenchmarkParseF5(b *testing.B) {
+	benchmarkParse(b, ConfigTypeF5)
+}
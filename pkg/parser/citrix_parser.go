@@ -7,6 +7,7 @@ import (
 
 // CitrixCommand represents a parsed Citrix command
 type CitrixCommand struct {
+	Text       string            // original command line
 	Action     string            // add, bind, set, etc.
 	ObjectType string            // server, lb vserver, serviceGroup, etc.
 	Name       string            // object name
@@ -41,6 +42,14 @@ func (p *CommandParser) readToken() {
 	}
 }
 
+// errorf returns a SyntaxError positioned at the current token
+func (p *CommandParser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{
+		Column:  p.current.Column,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
 // parseAction parses the command action (add, bind, set, etc.)
 func (p *CommandParser) parseAction() (string, error) {
 	switch p.current.Type {
@@ -68,9 +77,14 @@ func (p *CommandParser) parseAction() (string, error) {
 		token := p.current
 		p.readToken()
 		return token.Value, nil
+	case TokenIdentifier:
+		// Other verbs (rm, enable, disable, rename, ...) are passed through so the
+		// processor can apply or report them
+		token := p.current
+		p.readToken()
+		return strings.ToLower(token.Value), nil
 	default:
-		return "", fmt.Errorf("expected action (add, bind, set, etc.), got %v at line %d",
-			p.current.Type, p.current.Line)
+		return "", p.errorf("expected action (add, bind, set, etc.), got %q", p.current.Value)
 	}
 }
 
@@ -140,12 +154,13 @@ func (p *CommandParser) parseObjectType() (string, error) {
 	}
 
 	if len(parts) == 0 {
-		return "", fmt.Errorf("expected object type (server, lb vserver, serviceGroup, etc.), got %v (%s) at line %d",
-			p.current.Type, p.current.Value, p.current.Line)
+		return "", p.errorf("expected object type (server, lb vserver, serviceGroup, etc.), got %q", p.current.Value)
 	}
 
 	return strings.Join(parts, " "), nil
-} // parseObjectName parses the object name (can be quoted or unquoted)
+}
+
+// parseObjectName parses the object name (can be quoted or unquoted)
 func (p *CommandParser) parseObjectName() (string, error) {
 	switch p.current.Type {
 	case TokenString:
@@ -171,8 +186,7 @@ func (p *CommandParser) parseObjectName() (string, error) {
 			p.readToken()
 			return token.Value, nil
 		}
-		return "", fmt.Errorf("expected object name (string, identifier, number, or IP), got %v (%s) at line %d",
-			p.current.Type, p.current.Value, p.current.Line)
+		return "", p.errorf("expected object name (string, identifier, number, or IP), got %q", p.current.Value)
 	}
 }
 
@@ -202,18 +216,16 @@ func (p *CommandParser) parseParameters() map[string]string {
 		paramName := p.current.Value
 		p.readToken()
 
-		// Get parameter value
-		var paramValue string
-		switch p.current.Type {
-		case TokenString, TokenIdentifier, TokenNumber, TokenIP:
-			paramValue = p.current.Value
+		// Get parameter value; a parameter may take several values (as in
+		// "-respCode 200 302"), which are joined with spaces
+		var values []string
+		for p.current.Type == TokenString || p.current.Type == TokenIdentifier ||
+			p.current.Type == TokenNumber || p.current.Type == TokenIP {
+			values = append(values, p.current.Value)
 			p.readToken()
-		default:
-			// Parameter without value, use empty string
-			paramValue = ""
 		}
 
-		params[paramName] = paramValue
+		params[paramName] = strings.Join(values, " ")
 	}
 
 	return params
@@ -222,7 +234,7 @@ func (p *CommandParser) parseParameters() map[string]string {
 // ParseCommand parses a complete Citrix command
 func (p *CommandParser) ParseCommand() (*CitrixCommand, error) {
 	if p.current.Type == TokenEOF {
-		return nil, fmt.Errorf("empty command")
+		return nil, p.errorf("empty command")
 	}
 
 	// Parse action
@@ -272,11 +284,19 @@ func ParseCitrixCommand(commandLine string) (*CitrixCommand, error) {
 	// Check for tokenization errors
 	for _, token := range tokens {
 		if token.Type == TokenError {
-			return nil, fmt.Errorf("tokenization error: %s", token.Value)
+			return nil, &SyntaxError{
+				Column:  token.Column,
+				Message: fmt.Sprintf("tokenization error: %s", token.Value),
+			}
 		}
 	}
 
 	// Parse the command
 	parser := NewCommandParser(tokens)
-	return parser.ParseCommand()
+	command, err := parser.ParseCommand()
+	if err != nil {
+		return nil, err
+	}
+	command.Text = commandLine
+	return command, nil
 }
@@ -0,0 +1,304 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// csPolicySetters returns the functions that apply "add/set cs policy" parameters to a policy
+func csPolicySetters(policy *CSPolicy) map[string]func(value string) {
+	return map[string]func(string){
+		"-rule":   func(value string) { policy.Rule = value },
+		"-url":    func(value string) { policy.URL = value },
+		"-domain": func(value string) { policy.Domain = value },
+		"-action": func(value string) { policy.Action = value },
+	}
+}
+
+// CSRoute is a content switching policy, or the default lb vserver, resolved
+// to the lb vserver that takes the requests it matches
+type CSRoute struct {
+	Binding *CSBinding
+	Policy  *CSPolicy // nil for the default lb vserver
+	Target  string    // lb vserver the matching requests go to
+	Rule    string    // Traefik rule, empty when the route is not translated
+	Problem string    // Why the route is not translated
+}
+
+// Default reports whether the route is the default lb vserver
+func (r CSRoute) Default() bool {
+	return r.Binding.PolicyName == ""
+}
+
+// csRouteName describes a route as "policy 'name'" or "default lb vserver 'name'"
+func csRouteName(route CSRoute) string {
+	if route.Default() {
+		return fmt.Sprintf("default lb vserver '%s'", route.Target)
+	}
+	return fmt.Sprintf("policy '%s'", route.Binding.PolicyName)
+}
+
+// Pos returns where the route is best fixed: the policy when it is defined,
+// the binding otherwise
+func (r CSRoute) Pos() (Position, string) {
+	if r.Policy != nil {
+		return r.Policy.Pos, r.Policy.Source
+	}
+	return r.Binding.Pos, r.Binding.Source
+}
+
+// CSRoutes resolves the bindings of a content switching virtual server in
+// the order the appliance evaluates them: policies by ascending priority,
+// policies without a priority in binding order after them, and the default
+// lb vserver last. Only the first default binding is used. Responder and
+// rewrite policies are left to ResponderSteps and RewriteSteps.
+func CSRoutes(config *LBConfig, vserver *CSVServer) []CSRoute {
+	var routes []CSRoute
+	var fallback *CSRoute
+	for _, binding := range config.CSBindingsOf(vserver.Name) {
+		if binding.Type != "" {
+			continue
+		}
+		if binding.PolicyName == "" {
+			if fallback == nil {
+				fallback = &CSRoute{Binding: binding, Target: binding.TargetLBVServer, Rule: matchAllRule}
+			}
+			continue
+		}
+		routes = append(routes, resolveCSRoute(config, binding))
+	}
+
+	sort.SliceStable(routes, func(i, j int) bool {
+		return priorityLess(routes[i].Binding.Priority, routes[j].Binding.Priority)
+	})
+
+	if fallback != nil {
+		routes = append(routes, *fallback)
+	}
+	for i := range routes {
+		if route := &routes[i]; route.Problem == "" && config.VServerByName(route.Target) == nil {
+			route.Problem = fmt.Sprintf("lb vserver '%s' is not defined", route.Target)
+			route.Rule = ""
+		}
+	}
+	return routes
+}
+
+// resolveCSRoute follows a policy binding to its policy, action and target
+// lb vserver and translates the policy rule
+func resolveCSRoute(config *LBConfig, binding *CSBinding) CSRoute {
+	route := CSRoute{Binding: binding, Target: binding.TargetLBVServer}
+	route.Policy = config.CSPolicyByName(binding.PolicyName)
+	if route.Policy == nil {
+		route.Problem = fmt.Sprintf("cs policy '%s' is not defined", binding.PolicyName)
+		return route
+	}
+
+	if route.Target == "" && route.Policy.Action != "" {
+		action := config.CSActionByName(route.Policy.Action)
+		switch {
+		case action == nil:
+			route.Problem = fmt.Sprintf("cs action '%s' is not defined", route.Policy.Action)
+			return route
+		case action.TargetLBVServer == "":
+			route.Problem = fmt.Sprintf("cs action '%s' picks the lb vserver with -targetVserverExpr, which has no Traefik equivalent", action.Name)
+			return route
+		}
+		route.Target = action.TargetLBVServer
+	}
+	if route.Target == "" {
+		route.Problem = fmt.Sprintf("cs policy '%s' has no target lb vserver", route.Policy.Name)
+		return route
+	}
+
+	rule, err := csPolicyRule(route.Policy)
+	if err != nil {
+		route.Problem = err.Error()
+		return route
+	}
+	route.Rule = rule
+	return route
+}
+
+// csPolicyRule converts the -rule of an advanced policy, or the -domain and
+// -url of a classic one, to a Traefik rule. A classic URL ending in '*'
+// matches the path prefix before it.
+func csPolicyRule(policy *CSPolicy) (string, error) {
+	if policy.Rule != "" {
+		return translateRule(policy.Rule)
+	}
+
+	var matchers []string
+	if policy.Domain != "" {
+		matchers = append(matchers, "Host("+ruleValue(policy.Domain)+")")
+	}
+	if url := policy.URL; url != "" {
+		prefix, wildcard := strings.CutSuffix(url, "*")
+		switch {
+		case strings.Contains(prefix, "*"):
+			return "", fmt.Errorf("-url %q has a wildcard before the end, which has no Traefik rule equivalent", url)
+		case wildcard:
+			matchers = append(matchers, "PathPrefix("+ruleValue(prefix)+")")
+		default:
+			matchers = append(matchers, "Path("+ruleValue(url)+")")
+		}
+	}
+	return strings.Join(matchers, " && "), nil
+}
+
+// buildRouters creates a router for each translated content switching route,
+// named "<cs vserver>-<policy>", or "<cs vserver>-default" for the default lb
+// vserver. Routes get descending priorities in evaluation order so that the
+// first matching policy wins, and the default gets priority 1. Routes whose
+// lb vserver has no service are left out.
+//
+// Responder policies become middlewares named after the policy: those that
+// apply to every request are chained on the routers of their cs vserver, or
+// on a catch-all router named after their lb vserver, and those with a
+// condition get a "<vserver>-<policy>" router above the others. Content
+// switching routes also chain the unconditional middlewares of their lb
+// vserver, as the appliance evaluates its responder policies too. Rewrite
+// policies become middlewares the same way, chained after the responder
+// middlewares that apply to every request.
+//
+// Every router listens only on the entry point named after its lb or cs
+// vserver, which receives the traffic of the vserver's VIP (see
+// GenerateMappingConfigFromTraefik), so the routes, middlewares and TLS
+// settings of one vserver never apply to another's requests. The routers of
+// SSL vservers terminate TLS with the TLS options of their vserver, so an
+// SSL lb vserver gets its catch-all router even without policies.
+func buildRouters(config *LBConfig, services map[string]TraefikService, opts GenerateOptions) (map[string]TraefikRouter, map[string]TraefikMiddleware) {
+	routers := make(map[string]TraefikRouter)
+	middlewares := make(map[string]TraefikMiddleware)
+	chains := make(map[string][]string)
+	tlsOptions := tlsOptionNames(config)
+
+	for _, vserver := range config.VServers {
+		if _, exists := services[vserver.Name]; !exists {
+			continue
+		}
+		chain, names, conditional := addResponderSteps(ResponderSteps(config, vserver.Name, false), middlewares, opts)
+		chain = append(chain, addRewriteSteps(RewriteSteps(config, vserver.Name, false), middlewares)...)
+		chains[vserver.Name] = chain
+		vip := VIPKey(vserver.IP, vserver.Port)
+		tls := routerTLS(vserver.Protocol, vserver.Name, tlsOptions)
+		if len(chain) > 0 || tls != nil {
+			comment := fmt.Sprintf("lb vserver %s (%s)", vserver.Name, vip)
+			if len(chain) > 0 {
+				comment += " policies"
+			}
+			routers[uniqueName(routers, vserver.Name)] = TraefikRouter{
+				EntryPoints: []string{vserver.Name},
+				Rule:        matchAllRule,
+				Service:     vserver.Name,
+				Middlewares: chain,
+				Priority:    1,
+				TLS:         tls,
+				Disabled:    vserver.Disabled,
+				Comment:     comment,
+				Origin:      formatOrigin(vserver.Pos, vserver.Source),
+			}
+		}
+		addConditionalRouters(routers, vserver.Name, fmt.Sprintf("lb vserver %s (%s)", vserver.Name, vip), names, conditional, 1, tls, vserver.Disabled)
+	}
+
+	for _, vserver := range config.CSVServers {
+		if vserver.Disabled && opts.Disabled == DisabledDrop {
+			continue
+		}
+
+		var translated []CSRoute
+		for _, route := range CSRoutes(config, vserver) {
+			if _, exists := services[route.Target]; route.Problem == "" && exists {
+				translated = append(translated, route)
+			}
+		}
+		chain, names, conditional := addResponderSteps(ResponderSteps(config, vserver.Name, true), middlewares, opts)
+		chain = append(chain, addRewriteSteps(RewriteSteps(config, vserver.Name, true), middlewares)...)
+
+		vip := VIPKey(vserver.IP, vserver.Port)
+		tls := routerTLS(vserver.Protocol, vserver.Name, tlsOptions)
+		for i, route := range translated {
+			name := vserver.Name + "-" + route.Binding.PolicyName
+			comment := fmt.Sprintf("cs vserver %s (%s) policy %s", vserver.Name, vip, route.Binding.PolicyName)
+			if route.Binding.Priority != "" {
+				comment += ", priority " + route.Binding.Priority
+			}
+			priority := len(translated) - i
+			if route.Default() {
+				name = vserver.Name + "-default"
+				comment = fmt.Sprintf("cs vserver %s (%s) default lb vserver", vserver.Name, vip)
+				priority = 1
+			}
+
+			routers[uniqueName(routers, name)] = TraefikRouter{
+				EntryPoints: []string{vserver.Name},
+				Rule:        route.Rule,
+				Service:     route.Target,
+				Middlewares: appendMissing(chain, chains[route.Target]),
+				Priority:    priority,
+				TLS:         tls,
+				Disabled:    vserver.Disabled,
+				Comment:     comment,
+				Origin:      formatOrigin(route.Binding.Pos, route.Binding.Source),
+			}
+		}
+		addConditionalRouters(routers, vserver.Name, fmt.Sprintf("cs vserver %s (%s)", vserver.Name, vip), names, conditional, len(translated), tls, vserver.Disabled)
+	}
+	return routers, middlewares
+}
+
+// appendMissing returns the names of a followed by those of b it does not contain
+func appendMissing(a, b []string) []string {
+	names := append([]string(nil), a...)
+	for _, name := range b {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// addConditionalRouters adds the routers of responder policies with a
+// condition, named "<vserver>-<policy>", on the entry point of their vserver
+// with descending priorities above the given one in evaluation order and the
+// TLS setting of their vserver
+func addConditionalRouters(routers map[string]TraefikRouter, vserver, label string, names []string, conditional []TraefikRouter, above int, tls *TraefikRouterTLS, disabled bool) {
+	for i, router := range conditional {
+		router.EntryPoints = []string{vserver}
+		router.Priority = above + len(conditional) - i
+		router.TLS = tls
+		router.Disabled = disabled
+		router.Comment = fmt.Sprintf("%s responder policy %s", label, names[i])
+		routers[uniqueName(routers, vserver+"-"+names[i])] = router
+	}
+}
+
+// csRouteGaps describes the content switching routes that are not
+// translated as untranslated objects, at the policy or binding to fix
+func csRouteGaps(config *LBConfig) []*UntranslatedObject {
+	var gaps []*UntranslatedObject
+	for _, vserver := range config.CSVServers {
+		for _, route := range CSRoutes(config, vserver) {
+			if route.Problem == "" {
+				continue
+			}
+			pos, source := route.Pos()
+			name := route.Binding.PolicyName
+			if route.Default() {
+				name = route.Target
+			}
+			gaps = append(gaps, &UntranslatedObject{
+				ObjectType: "cs policy",
+				Name:       name,
+				CSVServer:  vserver.Name,
+				Reason:     fmt.Sprintf("cs vserver '%s' %s is not translated: %s", vserver.Name, csRouteName(route), route.Problem),
+				Text:       source,
+				Pos:        pos,
+			})
+		}
+	}
+	return gaps
+}
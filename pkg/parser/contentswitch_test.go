@@ -0,0 +1,120 @@
+package parser
+
+import (
+	"slices"
+	"testing"
+)
+
+// csConfig has two cs vservers that both fall back to a default lb vserver
+const csConfig = `add server s1 10.0.0.1
+add server s2 10.0.0.2
+add serviceGroup web_sg HTTP
+bind serviceGroup web_sg s1 80
+add serviceGroup api_sg HTTP
+bind serviceGroup api_sg s2 80
+add lb vserver web-vs HTTP 0.0.0.0 0
+bind lb vserver web-vs web_sg
+add lb vserver api-vs HTTP 0.0.0.0 0
+bind lb vserver api-vs api_sg
+add cs action api_act -targetLBVserver api-vs
+add cs policy api_pol -rule "HTTP.REQ.HOSTNAME.EQ(\"api.example.com\") && HTTP.REQ.URL.PATH.STARTSWITH(\"/v1\")" -action api_act
+add cs policy legacy_pol -url "/legacy*"
+add cs policy ends_pol -rule "HTTP.REQ.URL.PATH.ENDSWITH(\".php\")" -action api_act
+add cs vserver csA HTTP 10.9.9.10 80
+bind cs vserver csA -policyName ends_pol -priority 50
+bind cs vserver csA -policyName api_pol -priority 100
+bind cs vserver csA -policyName legacy_pol -targetLBVserver web-vs -priority 200
+bind cs vserver csA -lbvserver web-vs
+add cs vserver csB HTTP 10.9.9.11 80
+bind cs vserver csB -lbvserver api-vs
+add cs vserver csC HTTP 10.9.9.12 80
+`
+
+func TestCSRoutes(t *testing.T) {
+	config := parseCitrixText(t, "ns.conf", csConfig)
+
+	var got []string
+	for _, route := range CSRoutes(config, config.CSVServerByName("csA")) {
+		result := route.Rule
+		if route.Problem != "" {
+			result = "untranslated"
+		}
+		got = append(got, route.Target+" "+result)
+	}
+	want := []string{
+		"api-vs untranslated",
+		"api-vs Host(`api.example.com`) && PathPrefix(`/v1`)",
+		"web-vs PathPrefix(`/legacy`)",
+		"web-vs PathPrefix(`/`)",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("routes = %q, want %q", got, want)
+	}
+}
+
+func TestCSRoutersAreScopedToTheirVServer(t *testing.T) {
+	config := parseCitrixText(t, "ns.conf", csConfig)
+	traefik := GenerateTraefikConfig(config)
+
+	tests := []struct {
+		router     string
+		entryPoint string
+		service    string
+		priority   int
+	}{
+		{router: "csA-api_pol", entryPoint: "csA", service: "api-vs", priority: 3},
+		{router: "csA-legacy_pol", entryPoint: "csA", service: "web-vs", priority: 2},
+		{router: "csA-default", entryPoint: "csA", service: "web-vs", priority: 1},
+		{router: "csB-default", entryPoint: "csB", service: "api-vs", priority: 1},
+	}
+	if len(traefik.HTTP.Routers) != len(tests) {
+		t.Errorf("got %d routers, want %d", len(traefik.HTTP.Routers), len(tests))
+	}
+	for _, tt := range tests {
+		router, exists := traefik.HTTP.Routers[tt.router]
+		if !exists {
+			t.Errorf("router %s missing", tt.router)
+			continue
+		}
+		if !slices.Equal(router.EntryPoints, []string{tt.entryPoint}) || router.Service != tt.service || router.Priority != tt.priority {
+			t.Errorf("router %s = %s, want entryPoints=%s service=%s priority=%d", tt.router, router, tt.entryPoint, tt.service, tt.priority)
+		}
+	}
+}
+
+func TestCSVServerMappings(t *testing.T) {
+	config := parseCitrixText(t, "ns.conf", csConfig+"disable cs vserver csB\n")
+
+	tests := []struct {
+		name string
+		opts GenerateOptions
+		want []string
+	}{
+		{name: "disabled dropped", opts: GenerateOptions{}, want: []string{"10.9.9.10:80=csA@nacoscs"}},
+		{name: "disabled commented", opts: GenerateOptions{Disabled: DisabledComment}, want: []string{"10.9.9.10:80=csA@nacoscs", "#10.9.9.11:80=csB@nacoscs"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mappingPairs(GenerateMappingConfigWithOptions(config, tt.opts))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("mappings = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSVServerNamedLikeLBVServer(t *testing.T) {
+	config := parseCitrixText(t, "ns.conf", `add server s1 10.0.0.1
+add serviceGroup sg1 HTTP
+bind serviceGroup sg1 s1 80
+add lb vserver web HTTP 10.9.0.1 80
+bind lb vserver web sg1
+add cs vserver web HTTP 10.9.0.2 80
+bind cs vserver web -lbvserver web
+`)
+
+	if codes := diagnosticCodes(Verify(config)); !slices.Contains(codes, "entry-point-conflict") {
+		t.Errorf("Verify codes = %v, want entry-point-conflict", codes)
+	}
+}
@@ -0,0 +1,230 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// maxDetectLines is how many leading lines of an input are sampled for format detection
+const maxDetectLines = 1000
+
+// detectPeekSize bounds the prefix of a stream that is buffered for format detection
+const detectPeekSize = 1 << 20
+
+// definiteWeight is the score of a signature that identifies a format on its own, such as a version header
+const definiteWeight = 100
+
+// Detection is one parser's assessment of an input sample
+type Detection struct {
+	Score    int      // Accumulated signature weight, 0 when the format is not recognized
+	Definite bool     // A signature that identifies the format on its own was found
+	Evidence []string // Why the parser matched, one reason per signature
+}
+
+// Signature is a weighted indicator of a vendor format, matched against trimmed sample lines
+type Signature struct {
+	Description string
+	Weight      int
+	Definite    bool // Identifies the format on its own (e.g. a version header)
+	Match       func(line string) bool
+}
+
+// MatchSignatures scores the sample against the signatures. Definite signatures
+// are reported with the matching line; the others are counted.
+func MatchSignatures(sample []string, signatures []Signature) Detection {
+	var detection Detection
+
+	for _, signature := range signatures {
+		count, first := 0, 0
+		for i, line := range sample {
+			if !signature.Match(line) {
+				continue
+			}
+			count++
+			if first == 0 {
+				first = i + 1
+			}
+			if signature.Definite {
+				break
+			}
+		}
+		if count == 0 {
+			continue
+		}
+
+		detection.Score += count * signature.Weight
+		if signature.Definite {
+			detection.Definite = true
+			detection.Evidence = append(detection.Evidence,
+				fmt.Sprintf("line %d: %s %q", first, signature.Description, sample[first-1]))
+		} else {
+			detection.Evidence = append(detection.Evidence,
+				fmt.Sprintf("%d %s line(s), first at line %d (+%d each)", count, signature.Description, first, signature.Weight))
+		}
+	}
+
+	return detection
+}
+
+// FormatMatch is a registered parser together with its assessment of the input
+type FormatMatch struct {
+	Parser Parser
+	Detection
+}
+
+// DetectionResult explains which format was picked for an input and why
+type DetectionResult struct {
+	Type       ConfigType
+	Confidence int           // 0-100
+	Lines      int           // Number of sample lines examined
+	Matches    []FormatMatch // Every parser that scored above zero, best first
+}
+
+// DetectFormatFile detects the format of a file, see DetectFormat
+func DetectFormatFile(filename string) (DetectionResult, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return DetectionResult{}, err
+	}
+	defer file.Close()
+
+	return DetectFormat(file)
+}
+
+// DetectFormat samples the first lines of the input and asks every registered
+// parser to score them.
+//
+// Confidence is 100 when the winner found a definite signature that no other
+// parser did. Otherwise it is the winner's share of all scores, reduced when
+// the evidence is thin and capped below 100.
+func DetectFormat(reader io.Reader) (DetectionResult, error) {
+	sample, err := peekSample(bufio.NewReaderSize(reader, detectPeekSize))
+	if err != nil {
+		return DetectionResult{}, err
+	}
+
+	return detectFromSample(sample), nil
+}
+
+// peekSample returns the trimmed leading lines of a stream without consuming
+// them. At most maxDetectLines lines within the first detectPeekSize bytes are
+// returned; a line cut off by the peek limit is left out unless it is the
+// first, which is kept truncated.
+func peekSample(reader *bufio.Reader) ([]string, error) {
+	prefix, err := reader.Peek(detectPeekSize)
+	complete := err == io.EOF
+	if err != nil && !complete {
+		return nil, err
+	}
+
+	var sample []string
+	for len(prefix) > 0 && len(sample) < maxDetectLines {
+		end := bytes.IndexByte(prefix, '\n')
+		if end < 0 {
+			if !complete && len(sample) > 0 {
+				break
+			}
+			end = len(prefix)
+		}
+		sample = append(sample, strings.TrimSpace(string(prefix[:end])))
+		prefix = prefix[min(end+1, len(prefix)):]
+	}
+
+	return sample, nil
+}
+
+// detectFromSample asks every registered parser to score the sample and ranks the results
+func detectFromSample(sample []string) DetectionResult {
+	result := DetectionResult{Lines: len(sample)}
+
+	for _, p := range Parsers() {
+		if detection := p.Detect(sample); detection.Score > 0 {
+			result.Matches = append(result.Matches, FormatMatch{Parser: p, Detection: detection})
+		}
+	}
+	if len(result.Matches) == 0 {
+		return result
+	}
+
+	// Definite signatures outrank any amount of circumstantial evidence; ties keep registry order
+	sort.SliceStable(result.Matches, func(i, j int) bool {
+		a, b := result.Matches[i], result.Matches[j]
+		if a.Definite != b.Definite {
+			return a.Definite
+		}
+		return a.Score > b.Score
+	})
+
+	best := result.Matches[0]
+	result.Type = TypeOf(best.Parser)
+
+	total := 0
+	definites := 0
+	for _, match := range result.Matches {
+		total += match.Score
+		if match.Definite {
+			definites++
+		}
+	}
+
+	switch {
+	case best.Definite && definites == 1:
+		result.Confidence = 100
+	default:
+		// A handful of matching lines is weaker evidence than hundreds
+		const saturation = 25
+		strength := best.Score
+		if strength > saturation {
+			strength = saturation
+		}
+		result.Confidence = best.Score * 100 / total * strength / saturation
+		if result.Confidence > 95 {
+			result.Confidence = 95
+		}
+	}
+
+	return result
+}
+
+// WriteDetection writes a human readable explanation of a detection result
+func WriteDetection(w io.Writer, name string, result DetectionResult) error {
+	if result.Type == ConfigTypeUnknown {
+		fmt.Fprintf(w, "%s: unknown format (%d line(s) examined)\n", name, result.Lines)
+	} else {
+		fmt.Fprintf(w, "%s: %s (confidence %d%%, %d line(s) examined)\n", name, result.Type, result.Confidence, result.Lines)
+	}
+
+	for _, match := range result.Matches {
+		fmt.Fprintf(w, "  %s: score %d\n", match.Parser.Name(), match.Score)
+		for _, reason := range match.Evidence {
+			fmt.Fprintf(w, "    %s\n", reason)
+		}
+	}
+
+	return nil
+}
+
+// ParseFormat converts a format name ("auto" or a registered vendor name such
+// as "citrix" or "f5") to a configuration type. "auto" and "" return
+// ConfigTypeUnknown, which means the format is detected from the input.
+func ParseFormat(name string) (ConfigType, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || name == "auto" {
+		return ConfigTypeUnknown, nil
+	}
+
+	var names []string
+	for _, p := range Parsers() {
+		if p.Name() == name {
+			return TypeOf(p), nil
+		}
+		names = append(names, p.Name())
+	}
+
+	return ConfigTypeUnknown, fmt.Errorf("unknown format %q: expected auto, %s", name, strings.Join(names, ", "))
+}
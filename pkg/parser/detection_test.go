@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          string
+		want           ConfigType
+		wantConfidence int
+	}{
+		{name: "citrix build header", input: "#NS13.1 Build 37.38\n", want: ConfigTypeCitrix, wantConfidence: 100},
+		{name: "tmsh version header", input: "#TMSH-VERSION: 15.1.0\n", want: ConfigTypeF5, wantConfidence: 100},
+		{name: "a few citrix commands", input: "add server s1 10.0.0.1\nadd serviceGroup sg1 HTTP\n", want: ConfigTypeCitrix, wantConfidence: 40},
+		{name: "f5 blocks", input: strings.Repeat("ltm node /Common/n1 { }\n", 10), want: ConfigTypeF5, wantConfidence: 95},
+		{name: "nothing recognized", input: "hello\nworld\n", want: ConfigTypeUnknown},
+		{name: "empty input", input: "", want: ConfigTypeUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := DetectFormat(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("DetectFormat: %v", err)
+			}
+			if result.Type != tt.want {
+				t.Errorf("Type = %v, want %v", result.Type, tt.want)
+			}
+			if result.Confidence != tt.wantConfidence {
+				t.Errorf("Confidence = %d, want %d", result.Confidence, tt.wantConfidence)
+			}
+		})
+	}
+}
+
+func TestDetectFormatSamplesLeadingLines(t *testing.T) {
+	// An F5 header beyond the sampled lines does not count
+	input := strings.Repeat("add server s1 10.0.0.1\n", maxDetectLines) + "#TMSH-VERSION: 15.1.0\n"
+	result, err := DetectFormat(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("DetectFormat: %v", err)
+	}
+	if result.Type != ConfigTypeCitrix || result.Lines != maxDetectLines {
+		t.Errorf("got %v from %d lines, want citrix from %d lines", result.Type, result.Lines, maxDetectLines)
+	}
+}
+
+func TestDetectFormatTruncatesLongFirstLine(t *testing.T) {
+	// A first line beyond the peek limit is sampled up to the limit
+	input := "add server s1 10.0.0.1 -comment " + strings.Repeat("x", detectPeekSize) + "\nadd serviceGroup sg1 HTTP\n"
+	result, err := DetectFormat(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("DetectFormat: %v", err)
+	}
+	if result.Type != ConfigTypeCitrix || result.Lines != 1 {
+		t.Errorf("got %v from %d lines, want citrix from 1 line", result.Type, result.Lines)
+	}
+}
+
+func TestDetectFormatDefiniteOutranksScore(t *testing.T) {
+	input := "#TMSH-VERSION: 15.1.0\n" + strings.Repeat("add server s1 10.0.0.1\n", 100)
+	result, err := DetectFormat(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("DetectFormat: %v", err)
+	}
+	if result.Type != ConfigTypeF5 || result.Confidence != 100 {
+		t.Errorf("got %v at %d%%, want f5 at 100%%", result.Type, result.Confidence)
+	}
+	if len(result.Matches) != 2 {
+		t.Errorf("got %d matches, want both formats listed", len(result.Matches))
+	}
+}
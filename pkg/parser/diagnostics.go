@@ -0,0 +1,210 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Severity represents how serious a diagnostic is
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+// String returns the lowercase severity name used in rendered diagnostics
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// Position identifies a location in an input file. Line and Column are 1-based,
+// zero means unknown.
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+// String formats the position as file:line:column, omitting unknown parts
+func (p Position) String() string {
+	var parts []string
+	if p.File != "" {
+		parts = append(parts, p.File)
+	}
+	if p.Line > 0 {
+		parts = append(parts, fmt.Sprintf("%d", p.Line))
+		if p.Column > 0 {
+			parts = append(parts, fmt.Sprintf("%d", p.Column))
+		}
+	}
+	return strings.Join(parts, ":")
+}
+
+// Diagnostic is a single problem or note reported while parsing or verifying a configuration
+type Diagnostic struct {
+	Position
+	Severity Severity
+	Code     string // Stable machine-readable identifier (e.g. "undefined-server")
+	Message  string
+	Snippet  string // Source line the diagnostic refers to, if known
+	Skipped  bool   // The source line was not applied (lenient mode)
+}
+
+// String formats the diagnostic on one line, compiler style
+func (d Diagnostic) String() string {
+	if pos := d.Position.String(); pos != "" {
+		return fmt.Sprintf("%s: %s[%s]: %s", pos, d.Severity, d.Code, d.Message)
+	}
+	return fmt.Sprintf("%s[%s]: %s", d.Severity, d.Code, d.Message)
+}
+
+// Diagnostics is a list of diagnostics in the order they were reported
+type Diagnostics []Diagnostic
+
+// HasErrors reports whether any diagnostic has error severity
+func (ds Diagnostics) HasErrors() bool {
+	return ds.Count(SeverityError) > 0
+}
+
+// Unskipped returns the diagnostics other than the lines skipped in lenient mode
+func (ds Diagnostics) Unskipped() Diagnostics {
+	var unskipped Diagnostics
+	for _, d := range ds {
+		if !d.Skipped {
+			unskipped = append(unskipped, d)
+		}
+	}
+	return unskipped
+}
+
+// Count returns the number of diagnostics with the given severity
+func (ds Diagnostics) Count(severity Severity) int {
+	count := 0
+	for _, d := range ds {
+		if d.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+// SkipReason groups the lines skipped in lenient mode for the same reason
+type SkipReason struct {
+	Reason string
+	Lines  []int
+}
+
+// SkippedLines returns the number of skipped lines and their reasons, most frequent first
+func (ds Diagnostics) SkippedLines() (int, []SkipReason) {
+	total := 0
+	index := make(map[string]int)
+	var reasons []SkipReason
+
+	for _, d := range ds {
+		if !d.Skipped {
+			continue
+		}
+		total++
+		i, exists := index[d.Message]
+		if !exists {
+			i = len(reasons)
+			index[d.Message] = i
+			reasons = append(reasons, SkipReason{Reason: d.Message})
+		}
+		reasons[i].Lines = append(reasons[i].Lines, d.Line)
+	}
+
+	sort.SliceStable(reasons, func(i, j int) bool {
+		return len(reasons[i].Lines) > len(reasons[j].Lines)
+	})
+
+	return total, reasons
+}
+
+// DiagnosticError wraps a fatal diagnostic so it can be returned as an error
+type DiagnosticError struct {
+	Diagnostic Diagnostic
+}
+
+// Error implements the error interface
+func (e *DiagnosticError) Error() string {
+	return e.Diagnostic.String()
+}
+
+// SyntaxError reports a malformed command, with the column where parsing failed
+type SyntaxError struct {
+	Column  int
+	Message string
+}
+
+// Error implements the error interface
+func (e *SyntaxError) Error() string {
+	return e.Message
+}
+
+// AddDiagnostic appends a diagnostic to the model
+func (c *LBConfig) AddDiagnostic(d Diagnostic) {
+	c.Diagnostics = append(c.Diagnostics, d)
+}
+
+// WriteDiagnostics renders diagnostics compiler style, followed by the source
+// snippet and a caret under the reported column when they are known
+func WriteDiagnostics(w io.Writer, diagnostics Diagnostics) error {
+	for _, d := range diagnostics {
+		if _, err := fmt.Fprintln(w, d.String()); err != nil {
+			return err
+		}
+		if d.Snippet == "" {
+			continue
+		}
+
+		gutter := fmt.Sprintf("%5d", d.Line)
+		if d.Line == 0 {
+			gutter = strings.Repeat(" ", 5)
+		}
+		fmt.Fprintf(w, "%s | %s\n", gutter, d.Snippet)
+		if d.Column > 0 {
+			fmt.Fprintf(w, "%s | %s^\n", strings.Repeat(" ", 5), strings.Repeat(" ", d.Column-1))
+		}
+	}
+	return nil
+}
+
+// WriteSkipSummary renders how many lines were skipped in lenient mode and why.
+// Nothing is written when no line was skipped.
+func WriteSkipSummary(w io.Writer, diagnostics Diagnostics) error {
+	total, reasons := diagnostics.SkippedLines()
+	if total == 0 {
+		return nil
+	}
+
+	if _, err := fmt.Fprintf(w, "Skipped %d malformed line(s):\n", total); err != nil {
+		return err
+	}
+	for _, reason := range reasons {
+		lines := make([]string, 0, len(reason.Lines))
+		for i, line := range reason.Lines {
+			// Keep the summary readable for inputs with thousands of bad lines
+			if i == 10 {
+				lines = append(lines, "...")
+				break
+			}
+			lines = append(lines, fmt.Sprintf("%d", line))
+		}
+		fmt.Fprintf(w, "  %5d  %s (lines %s)\n", len(reason.Lines), reason.Reason, strings.Join(lines, ", "))
+	}
+	return nil
+}
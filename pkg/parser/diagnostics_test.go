@@ -0,0 +1,223 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPositionString(t *testing.T) {
+	tests := []struct {
+		pos  Position
+		want string
+	}{
+		{pos: Position{File: "ns.conf", Line: 12, Column: 5}, want: "ns.conf:12:5"},
+		{pos: Position{File: "ns.conf", Line: 12}, want: "ns.conf:12"},
+		{pos: Position{File: "ns.conf", Column: 5}, want: "ns.conf"},
+		{pos: Position{Line: 3, Column: 1}, want: "3:1"},
+		{pos: Position{}, want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := tt.pos.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.pos, got, tt.want)
+		}
+	}
+}
+
+func TestDiagnosticString(t *testing.T) {
+	tests := []struct {
+		d    Diagnostic
+		want string
+	}{
+		{
+			d:    Diagnostic{Position: Position{File: "ns.conf", Line: 4, Column: 1}, Severity: SeverityError, Code: "undefined-server", Message: "missing"},
+			want: "ns.conf:4:1: error[undefined-server]: missing",
+		},
+		{
+			d:    Diagnostic{Severity: SeverityWarning, Code: "merged-duplicates", Message: "merged"},
+			want: "warning[merged-duplicates]: merged",
+		},
+		{
+			d:    Diagnostic{Severity: SeverityInfo, Code: "note", Message: "fyi"},
+			want: "info[note]: fyi",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tt.d.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestWriteDiagnosticsSnippet(t *testing.T) {
+	var out bytes.Buffer
+	err := WriteDiagnostics(&out, Diagnostics{{
+		Position: Position{File: "ns.conf", Line: 7, Column: 5},
+		Severity: SeverityError,
+		Code:     "syntax",
+		Message:  "bad",
+		Snippet:  "add (server",
+	}})
+	if err != nil {
+		t.Fatalf("WriteDiagnostics: %v", err)
+	}
+	want := "ns.conf:7:5: error[syntax]: bad\n    7 | add (server\n      |     ^\n"
+	if out.String() != want {
+		t.Errorf("output =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestParseErrorPosition(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "missing argument",
+			text: "add server s1 10.0.0.1\nadd server s2\n",
+			want: "ns.conf:2:1: error[invalid-command]: add server command requires IP address argument",
+		},
+		{
+			name: "syntax error column",
+			text: "add server s1 10.0.0.1\nadd lb vserver vs1 HTTP 10.9.0.1 80 (\n",
+			want: "ns.conf:2:37: error[syntax]: tokenization error: unexpected character: (",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCitrix(strings.NewReader(tt.text), ParseOptions{Filename: "ns.conf"})
+			var diagnostic *DiagnosticError
+			if !errors.As(err, &diagnostic) {
+				t.Fatalf("err = %v, want a *DiagnosticError", err)
+			}
+			if got := diagnostic.Error(); got != tt.want {
+				t.Errorf("error = %q, want %q", got, tt.want)
+			}
+			if diagnostic.Diagnostic.Snippet == "" {
+				t.Error("diagnostic has no source snippet")
+			}
+		})
+	}
+}
+
+func TestVerifyReportsReferencePositions(t *testing.T) {
+	config := parseCitrixText(t, "ns.conf", "add serviceGroup sg1 HTTP\nbind serviceGroup sg1 s9 80\n")
+
+	for _, d := range Verify(config) {
+		if d.Code != "undefined-server" {
+			continue
+		}
+		if d.File != "ns.conf" || d.Line != 2 || d.Severity != SeverityError {
+			t.Errorf("undefined-server at %s (%s), want ns.conf:2 (error)", d.Position, d.Severity)
+		}
+		return
+	}
+	t.Error("no undefined-server diagnostic")
+}
+
+func TestParseCitrixLenient(t *testing.T) {
+	text := `add server s1 10.0.0.1
+add server s2
+add serviceGroup sg1 HTTP
+bind serviceGroup sg1 s1 80
+add lb vserver vs1 HTTP 10.9.0.1 80 (
+add server s3
+add lb vserver vs2 HTTP 10.9.0.2 80
+bind lb vserver vs2 sg1
+`
+	config, err := ParseCitrix(strings.NewReader(text), ParseOptions{Filename: "ns.conf", Lenient: true})
+	if err != nil {
+		t.Fatalf("ParseCitrix: %v", err)
+	}
+
+	if len(config.Servers) != 1 || len(config.VServers) != 1 || len(config.VServerBindings) != 1 {
+		t.Errorf("got %d servers, %d vservers, %d bindings, want the 1, 1 and 1 well-formed ones",
+			len(config.Servers), len(config.VServers), len(config.VServerBindings))
+	}
+	if len(config.Diagnostics.Unskipped()) != 0 {
+		t.Errorf("unskipped diagnostics = %v, want none", config.Diagnostics.Unskipped())
+	}
+
+	total, reasons := config.Diagnostics.SkippedLines()
+	if total != 3 {
+		t.Errorf("skipped %d lines, want 3", total)
+	}
+	want := []SkipReason{
+		{Reason: "add server command requires IP address argument", Lines: []int{2, 6}},
+		{Reason: "tokenization error: unexpected character: (", Lines: []int{5}},
+	}
+	if len(reasons) != len(want) {
+		t.Fatalf("reasons = %+v, want %+v", reasons, want)
+	}
+	for i := range want {
+		if reasons[i].Reason != want[i].Reason || !slices.Equal(reasons[i].Lines, want[i].Lines) {
+			t.Errorf("reason %d = %+v, want %+v", i, reasons[i], want[i])
+		}
+	}
+}
+
+func TestWriteSkipSummary(t *testing.T) {
+	skipped := func(line int) Diagnostic {
+		return Diagnostic{Position: Position{Line: line}, Severity: SeverityError, Code: "invalid-command", Message: "bad", Skipped: true}
+	}
+	var many Diagnostics
+	for line := 1; line <= 12; line++ {
+		many = append(many, skipped(line))
+	}
+
+	tests := []struct {
+		name        string
+		diagnostics Diagnostics
+		want        string
+	}{
+		{name: "nothing skipped", diagnostics: Diagnostics{{Severity: SeverityWarning, Code: "w", Message: "kept"}}, want: ""},
+		{name: "few lines", diagnostics: Diagnostics{skipped(3), skipped(9)}, want: "Skipped 2 malformed line(s):\n      2  bad (lines 3, 9)\n"},
+		{name: "many lines", diagnostics: many, want: "Skipped 12 malformed line(s):\n     12  bad (lines 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, ...)\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := WriteSkipSummary(&out, tt.diagnostics); err != nil {
+				t.Fatalf("WriteSkipSummary: %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestDiagnosticsUnskipped(t *testing.T) {
+	tests := []struct {
+		name        string
+		diagnostics Diagnostics
+		want        []string
+	}{
+		{name: "none", diagnostics: nil, want: nil},
+		{
+			name: "lint keeps the findings about the parsed model",
+			diagnostics: Diagnostics{
+				{Severity: SeverityError, Code: "invalid-command", Skipped: true},
+				{Severity: SeverityError, Code: "undefined-server"},
+				{Severity: SeverityWarning, Code: "unbound-vserver"},
+				{Severity: SeverityError, Code: "syntax", Skipped: true},
+			},
+			want: []string{"undefined-server", "unbound-vserver"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diagnosticCodes(tt.diagnostics.Unskipped()); !slices.Equal(got, tt.want) {
+				t.Errorf("Unskipped() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
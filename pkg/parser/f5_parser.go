@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"fmt"
 	"io"
 	"os"
 	"regexp"
@@ -8,10 +9,44 @@ import (
 	"strings"
 )
 
+func init() {
+	RegisterParser(f5Parser{})
+}
+
+// ConfigTypeF5 is the type of F5 BIG-IP configurations
+const ConfigTypeF5 ConfigType = "f5"
+
+// f5Parser is the registered Parser for F5 BIG-IP tmsh configurations
+type f5Parser struct{}
+
+func (f5Parser) Name() string { return string(ConfigTypeF5) }
+
+func (f5Parser) Parse(reader io.Reader, opts ParseOptions) (*LBConfig, error) {
+	return ParseF5(reader, opts)
+}
+
+// f5Signatures are the indicators scored by f5Parser.Detect
+var f5Signatures = []Signature{
+	{Description: "tmsh version header", Weight: definiteWeight, Definite: true, Match: hasPrefix("#TMSH-VERSION")},
+	{Description: "'ltm' block", Weight: 5, Match: hasPrefix("ltm ")},
+	{Description: "'apm' or 'sys' block", Weight: 2, Match: func(line string) bool {
+		return strings.HasPrefix(line, "apm ") || strings.HasPrefix(line, "sys ")
+	}},
+	{Description: "/Common/ partition path", Weight: 1, Match: func(line string) bool {
+		return strings.Contains(line, "/Common/")
+	}},
+}
+
+// Detect scores the sample by matching the tmsh version header, block headers and /Common/ paths
+func (f5Parser) Detect(sample []string) Detection {
+	return MatchSignatures(sample, f5Signatures)
+}
+
 // F5 configuration structures for simple parser
 type F5NodeSimple struct {
 	Name    string
 	Address string
+	Line    int
 }
 
 type F5PoolSimple struct {
@@ -19,11 +54,17 @@ type F5PoolSimple struct {
 	Description string
 	Members     []F5PoolMemberSimple
 	Monitor     string
+	LBMode      string // load-balancing-mode, round-robin when not set
+	MinActive   int    // min-active-members, priority group activation when above 0
+	Line        int
 }
 
 type F5PoolMemberSimple struct {
-	Address string
-	Port    int
+	Path     string // Member node path without port (e.g. /Common/10.1.2.3)
+	Address  string
+	Port     int
+	Priority int // priority-group, 0 when not set
+	Line     int
 }
 
 type F5VirtualSimple struct {
@@ -31,205 +72,556 @@ type F5VirtualSimple struct {
 	Description string
 	Destination string
 	Pool        string
-	Profiles    []string
+	Profiles    []F5ReferenceSimple
+	Rules       []F5ReferenceSimple
+	Persist     []F5ReferenceSimple
+	Line        int
+}
+
+// F5ServerSSLSimple is an "ltm profile server-ssl", the TLS settings of the
+// connections from a virtual to its pool members. Fields are empty when not set.
+type F5ServerSSLSimple struct {
+	Name             string
+	DefaultsFrom     string // Parent profile whose settings are inherited
+	PeerCertMode     string // require verifies the server certificates, ignore (the default) does not
+	AuthenticateName string // Name the server certificates must be issued to
+	ServerName       string // Sent with SNI
+	CAFile           string // Trusted to issue server certificates
+	Cert             string // Client certificate presented to the servers
+	Key              string
+	Line             int
+}
+
+// F5ReferenceSimple is a named reference inside a virtual (profile, iRule, persistence)
+type F5ReferenceSimple struct {
+	Name string
+	Line int
+}
+
+// F5ObjectSimple is a top-level tmsh object that is not translated
+type F5ObjectSimple struct {
+	Type   string // e.g. "ltm monitor http"
+	Name   string
+	Header string
+	Line   int
 }
 
+// f5ServerSSLProfiles lists the built-in server-ssl profiles, which verify no
+// server certificate
+var f5ServerSSLProfiles = map[string]bool{
+	"serverssl":                     true,
+	"serverssl-insecure-compatible": true,
+}
+
+// f5TranslatedProfiles lists virtual profiles whose behavior Traefik provides implicitly
+var f5TranslatedProfiles = map[string]bool{
+	"http":                 true,
+	"tcp":                  true,
+	"fastL4":               true,
+	"f5-tcp-progressive":   true,
+	"f5-tcp-lan":           true,
+	"f5-tcp-wan":           true,
+	"tcp-lan-optimized":    true,
+	"tcp-wan-optimized":    true,
+	"tcp-mobile-optimized": true,
+}
+
+// Patterns matched against the trimmed lines of an F5 configuration
+var (
+	f5NodeHeader         = regexp.MustCompile(`^ltm node (/[^/\s]+/[^\s]+)\s*\{(.*)$`)
+	f5PoolHeader         = regexp.MustCompile(`^ltm pool (/[^/\s]+/[^\s]+)\s*\{`)
+	f5VirtualHeader      = regexp.MustCompile(`^ltm virtual (/[^/\s]+/[^\s]+)\s*\{`)
+	f5ServerSSLHeader    = regexp.MustCompile(`^ltm profile server-ssl (/[^/\s]+/[^\s]+)\s*\{`)
+	f5NodeAddress        = regexp.MustCompile(`address\s+([^\s\n]+)`)
+	f5Description        = regexp.MustCompile(`description\s+(.+)`)
+	f5PoolMember         = regexp.MustCompile(`(/[^/\s]+/\d{1,3}(?:\.\d{1,3}){3}):(\d+)\s*\{`)
+	f5PoolMonitor        = regexp.MustCompile(`monitor\s+(.+)`)
+	f5PoolLBMode         = regexp.MustCompile(`^load-balancing-mode\s+(\S+)`)
+	f5PoolMinActive      = regexp.MustCompile(`^min-active-members\s+(\d+)`)
+	f5MemberPriority     = regexp.MustCompile(`^priority-group\s+(\d+)`)
+	f5VirtualDestination = regexp.MustCompile(`destination\s+(/[^/\s]+/[^:]+):(\d+)`)
+	f5VirtualPool        = regexp.MustCompile(`pool\s+(.+)`)
+)
+
 // ParseF5SettingsFromFileSimple parses F5 configuration from a file using simple approach
-func ParseF5SettingsFromFileSimple(filename string) ([]ServerInfo, []VServerInfo, []ServiceGroupDef, []ServiceGroup, []VServerBinding, error) {
-	content, err := os.ReadFile(filename)
+func ParseF5SettingsFromFileSimple(filename string) (*LBConfig, error) {
+	file, err := os.Open(filename)
 	if err != nil {
-		return nil, nil, nil, nil, nil, err
+		return nil, err
 	}
+	defer file.Close()
 
-	return ParseF5ConfigSimple(string(content))
+	return ParseF5(file, ParseOptions{Filename: filename})
 }
 
 // ParseF5SettingsFromReaderSimple parses F5 configuration from an io.Reader using simple approach
-func ParseF5SettingsFromReaderSimple(reader io.Reader) ([]ServerInfo, []VServerInfo, []ServiceGroupDef, []ServiceGroup, []VServerBinding, error) {
-	content, err := io.ReadAll(reader)
-	if err != nil {
-		return nil, nil, nil, nil, nil, err
-	}
-
-	return ParseF5ConfigSimple(string(content))
+func ParseF5SettingsFromReaderSimple(reader io.Reader) (*LBConfig, error) {
+	return ParseF5(reader, ParseOptions{})
 }
 
 // ParseF5ConfigSimple parses F5 configuration using simple regex approach
-func ParseF5ConfigSimple(content string) ([]ServerInfo, []VServerInfo, []ServiceGroupDef, []ServiceGroup, []VServerBinding, error) {
-	// Parse nodes, pools, and virtuals using simple regex approach
-	nodes := parseF5NodesSimple(content)
-	pools := parseF5PoolsSimple(content)
-	virtuals := parseF5VirtualsSimple(content)
+func ParseF5ConfigSimple(content string) (*LBConfig, error) {
+	return ParseF5(strings.NewReader(content), ParseOptions{})
+}
+
+// ParseF5 parses F5 configuration from a reader with the given options. The
+// input is read line by line in a single pass.
+func ParseF5(reader io.Reader, opts ParseOptions) (*LBConfig, error) {
+	scanner := newLineScanner(reader)
+	f5 := &f5Reader{}
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		f5.readLine(scanner.Text(), lineNumber)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	f5.endBlock()
+
+	// Convert to the vendor-neutral model
+	config := convertF5ToTraefikFormat(f5.nodes, f5.pools, f5.virtuals, f5.serverSSL, opts.Filename)
+	for _, object := range f5.others {
+		config.AddUntranslated(&UntranslatedObject{
+			ObjectType: object.Type,
+			Name:       object.Name,
+			Text:       object.Header,
+			Pos:        Position{File: opts.Filename, Line: object.Line, Column: 1},
+		})
+	}
+	config.Link()
 
-	// Convert to Citrix-compatible format
-	servers, vservers, serviceGroupDefs, serviceGroups, vserverBindings := convertF5ToTraefikFormat(nodes, pools, virtuals)
+	return config, nil
+}
 
-	return servers, vservers, serviceGroupDefs, serviceGroups, vserverBindings, nil
+// f5Reader collects nodes, pools, virtuals and the other top-level objects of
+// an F5 configuration from its lines
+type f5Reader struct {
+	nodes     []F5NodeSimple
+	pools     []F5PoolSimple
+	virtuals  []F5VirtualSimple
+	serverSSL []F5ServerSSLSimple
+	others    []F5ObjectSimple
+
+	// Block being read, at most one is set
+	node             *F5NodeSimple
+	pool             *F5PoolSimple
+	virtual          *F5VirtualSimple
+	serverSSLProfile *F5ServerSSLSimple
+
+	braceLevel int
+	section    string // Nested virtual block being read (profiles, rules, persist)
 }
 
-// Simple regex-based parsers that extract key information line by line
-func parseF5NodesSimple(content string) []F5NodeSimple {
-	var nodes []F5NodeSimple
+// readLine processes the next line of the configuration
+func (r *f5Reader) readLine(line string, number int) {
+	trimmed := strings.TrimSpace(line)
+
+	// Check for node, pool and virtual starts
+	if strings.HasPrefix(trimmed, "ltm ") {
+		if match := f5NodeHeader.FindStringSubmatch(trimmed); match != nil {
+			r.endBlock()
+			r.node = &F5NodeSimple{Name: match[1], Line: number}
+			r.readNode(match[2])
+			return
+		}
+		if match := f5PoolHeader.FindStringSubmatch(trimmed); match != nil {
+			r.endBlock()
+			r.pool = &F5PoolSimple{Name: match[1], Line: number}
+			r.braceLevel = 1
+			return
+		}
+		if match := f5VirtualHeader.FindStringSubmatch(trimmed); match != nil {
+			r.endBlock()
+			r.virtual = &F5VirtualSimple{Name: match[1], Line: number}
+			r.braceLevel = 1
+			r.section = ""
+			return
+		}
+		if match := f5ServerSSLHeader.FindStringSubmatch(trimmed); match != nil {
+			r.endBlock()
+			r.serverSSLProfile = &F5ServerSSLSimple{Name: match[1], Line: number}
+			r.braceLevel = 1
+			return
+		}
+	}
+
+	r.readOther(line, number)
+
+	switch {
+	case r.node != nil:
+		r.readNode(trimmed)
+	case r.pool != nil:
+		r.readPool(trimmed, number)
+	case r.virtual != nil:
+		r.readVirtual(trimmed, number)
+	case r.serverSSLProfile != nil:
+		r.readServerSSL(trimmed)
+	}
+}
 
-	// Find all ltm node blocks
-	nodePattern := regexp.MustCompile(`(?s)ltm node (/Common/[^\s]+)\s*\{([^}]*)\}`)
-	matches := nodePattern.FindAllStringSubmatch(content, -1)
+// endBlock drops a block that was not closed before the next one started or the input ended
+func (r *f5Reader) endBlock() {
+	r.node, r.pool, r.virtual, r.serverSSLProfile = nil, nil, nil, nil
+}
 
-	for _, match := range matches {
-		nodeName := match[1]
-		nodeBlock := match[2]
+// readNode extracts the address of the current node. Node blocks end at the first closing brace.
+func (r *f5Reader) readNode(text string) {
+	closing := strings.IndexByte(text, '}')
+	if closing >= 0 {
+		text = text[:closing]
+	}
 
-		// Extract address from the block
-		addressPattern := regexp.MustCompile(`address\s+([^\s\n]+)`)
-		if addrMatch := addressPattern.FindStringSubmatch(nodeBlock); addrMatch != nil {
-			nodes = append(nodes, F5NodeSimple{
-				Name:    nodeName,
-				Address: addrMatch[1],
-			})
+	if r.node.Address == "" {
+		if match := f5NodeAddress.FindStringSubmatch(text); match != nil {
+			r.node.Address = match[1]
 		}
 	}
 
-	return nodes
+	// Node block ended
+	if closing >= 0 {
+		if r.node.Address != "" {
+			r.nodes = append(r.nodes, *r.node)
+		}
+		r.node = nil
+	}
 }
 
-func parseF5PoolsSimple(content string) []F5PoolSimple {
-	var pools []F5PoolSimple
+// readPool extracts the description, members, monitor and load balancing
+// settings of the current pool
+func (r *f5Reader) readPool(trimmed string, number int) {
+	// Count braces to track nesting
+	r.braceLevel += strings.Count(trimmed, "{") - strings.Count(trimmed, "}")
+
+	// Extract information from within the pool block
+	if r.braceLevel > 0 {
+		// Description
+		if descMatch := f5Description.FindStringSubmatch(trimmed); descMatch != nil {
+			r.pool.Description = descMatch[1]
+		}
 
-	// Find all ltm pool blocks - handle nested braces carefully
-	lines := strings.Split(content, "\n")
-	var currentPool *F5PoolSimple
-	var braceLevel int
-	var inPool bool
+		// Pool member
+		if memberMatch := f5PoolMember.FindStringSubmatch(trimmed); memberMatch != nil {
+			port, _ := strconv.Atoi(memberMatch[2])
+			r.pool.Members = append(r.pool.Members, F5PoolMemberSimple{
+				Path:    memberMatch[1],
+				Address: f5Address(memberMatch[1]),
+				Port:    port,
+				Line:    number,
+			})
+		}
 
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
+		// Monitor
+		if monMatch := f5PoolMonitor.FindStringSubmatch(trimmed); monMatch != nil {
+			r.pool.Monitor = monMatch[1]
+		}
 
-		// Check for pool start
-		poolPattern := regexp.MustCompile(`^ltm pool (/Common/[^\s]+)\s*\{`)
-		if match := poolPattern.FindStringSubmatch(trimmed); match != nil {
-			currentPool = &F5PoolSimple{Name: match[1]}
-			inPool = true
-			braceLevel = 1
-			continue
+		// Load balancing method
+		if modeMatch := f5PoolLBMode.FindStringSubmatch(trimmed); modeMatch != nil {
+			r.pool.LBMode = modeMatch[1]
 		}
 
-		if !inPool || currentPool == nil {
-			continue
+		// Priority group activation
+		if minMatch := f5PoolMinActive.FindStringSubmatch(trimmed); minMatch != nil {
+			r.pool.MinActive, _ = strconv.Atoi(minMatch[1])
+		}
+		if priorityMatch := f5MemberPriority.FindStringSubmatch(trimmed); priorityMatch != nil && len(r.pool.Members) > 0 {
+			r.pool.Members[len(r.pool.Members)-1].Priority, _ = strconv.Atoi(priorityMatch[1])
 		}
+	}
 
-		// Count braces to track nesting
-		braceLevel += strings.Count(trimmed, "{") - strings.Count(trimmed, "}")
+	// Pool block ended
+	if r.braceLevel <= 0 {
+		r.pools = append(r.pools, *r.pool)
+		r.pool = nil
+	}
+}
 
-		// Extract information from within the pool block
-		if braceLevel > 0 {
-			// Description
-			if descMatch := regexp.MustCompile(`description\s+(.+)`).FindStringSubmatch(trimmed); descMatch != nil {
-				currentPool.Description = descMatch[1]
+// readVirtual extracts the destination, pool and references of the current virtual
+func (r *f5Reader) readVirtual(trimmed string, number int) {
+	// Count braces to track nesting
+	previousLevel := r.braceLevel
+	r.braceLevel += strings.Count(trimmed, "{") - strings.Count(trimmed, "}")
+
+	// Collect references from the profiles, rules and persist sections,
+	// either spread over several lines or inline ("rules { /Common/a }")
+	fields := strings.Fields(trimmed)
+	switch {
+	case previousLevel == 1 && r.braceLevel == 2 && len(fields) == 2 && fields[1] == "{":
+		r.section = fields[0]
+	case previousLevel == 1 && r.braceLevel == 1 && len(fields) > 3 && fields[1] == "{" && fields[len(fields)-1] == "}":
+		for _, name := range fields[2 : len(fields)-1] {
+			if strings.HasPrefix(name, "/") {
+				r.virtual.addReference(fields[0], name, number)
 			}
+		}
+	case previousLevel == 2 && r.section != "" && len(fields) > 0 && strings.HasPrefix(fields[0], "/"):
+		r.virtual.addReference(r.section, fields[0], number)
+	}
+	if r.braceLevel <= 1 {
+		r.section = ""
+	}
 
-			// Pool member
-			if memberMatch := regexp.MustCompile(`(/Common/\d{1,3}(?:\.\d{1,3}){3}):(\d+)\s*\{`).FindStringSubmatch(trimmed); memberMatch != nil {
-				port, _ := strconv.Atoi(memberMatch[2])
-				currentPool.Members = append(currentPool.Members, F5PoolMemberSimple{
-					Address: strings.TrimPrefix(memberMatch[1], "/Common/"),
-					Port:    port,
-				})
-			}
+	// Extract information from within the virtual block
+	if r.braceLevel > 0 {
+		// Description
+		if descMatch := f5Description.FindStringSubmatch(trimmed); descMatch != nil {
+			r.virtual.Description = descMatch[1]
+		}
 
-			// Monitor
-			if monMatch := regexp.MustCompile(`monitor\s+(.+)`).FindStringSubmatch(trimmed); monMatch != nil {
-				currentPool.Monitor = monMatch[1]
-			}
+		// Destination (VIP:port)
+		if destMatch := f5VirtualDestination.FindStringSubmatch(trimmed); destMatch != nil {
+			r.virtual.Destination = f5Address(destMatch[1]) + ":" + destMatch[2]
 		}
 
-		// Pool block ended
-		if braceLevel == 0 {
-			pools = append(pools, *currentPool)
-			currentPool = nil
-			inPool = false
+		// Pool
+		if poolMatch := f5VirtualPool.FindStringSubmatch(trimmed); poolMatch != nil {
+			r.virtual.Pool = poolMatch[1]
 		}
 	}
 
-	return pools
+	// Virtual block ended
+	if r.braceLevel <= 0 {
+		r.virtuals = append(r.virtuals, *r.virtual)
+		r.virtual = nil
+	}
 }
 
-func parseF5VirtualsSimple(content string) []F5VirtualSimple {
-	var virtuals []F5VirtualSimple
+// readServerSSL extracts the settings of the current server-ssl profile
+func (r *f5Reader) readServerSSL(trimmed string) {
+	r.braceLevel += strings.Count(trimmed, "{") - strings.Count(trimmed, "}")
+
+	// Settings are read at the top level of the block only
+	if fields := strings.Fields(trimmed); r.braceLevel == 1 && len(fields) == 2 && fields[1] != "none" {
+		profile := r.serverSSLProfile
+		switch fields[0] {
+		case "defaults-from":
+			profile.DefaultsFrom = fields[1]
+		case "peer-cert-mode":
+			profile.PeerCertMode = fields[1]
+		case "authenticate-name":
+			profile.AuthenticateName = fields[1]
+		case "server-name":
+			profile.ServerName = fields[1]
+		case "ca-file":
+			profile.CAFile = fields[1]
+		case "cert":
+			profile.Cert = fields[1]
+		case "key":
+			profile.Key = fields[1]
+		}
+	}
 
-	// Find all ltm virtual blocks
-	lines := strings.Split(content, "\n")
-	var currentVirtual *F5VirtualSimple
-	var braceLevel int
-	var inVirtual bool
+	// Profile block ended
+	if r.braceLevel <= 0 {
+		r.serverSSL = append(r.serverSSL, *r.serverSSLProfile)
+		r.serverSSLProfile = nil
+	}
+}
 
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
+// readOther records top-level tmsh objects other than nodes, pools, virtuals
+// and server-ssl profiles
+func (r *f5Reader) readOther(line string, number int) {
+	// Top-level objects start in the first column and open a block
+	if line == "" || line[0] == ' ' || line[0] == '\t' || line[0] == '#' || line[0] == '}' || !strings.Contains(line, "{") {
+		return
+	}
+	header := strings.TrimSpace(line)
+	if strings.HasPrefix(header, "ltm node ") || strings.HasPrefix(header, "ltm pool ") || strings.HasPrefix(header, "ltm virtual ") ||
+		strings.HasPrefix(header, "ltm profile server-ssl ") {
+		return
+	}
 
-		// Check for virtual start
-		virtualPattern := regexp.MustCompile(`^ltm virtual (/Common/[^\s]+)\s*\{`)
-		if match := virtualPattern.FindStringSubmatch(trimmed); match != nil {
-			currentVirtual = &F5VirtualSimple{Name: match[1]}
-			inVirtual = true
-			braceLevel = 1
-			continue
+	var typeParts []string
+	name := ""
+	for _, field := range strings.Fields(header) {
+		if field == "{" || strings.HasPrefix(field, "{") {
+			break
 		}
+		if strings.HasPrefix(field, "/") {
+			name = field
+			break
+		}
+		typeParts = append(typeParts, field)
+	}
+
+	r.others = append(r.others, F5ObjectSimple{
+		Type:   strings.Join(typeParts, " "),
+		Name:   name,
+		Header: header,
+		Line:   number,
+	})
+}
+
+// f5ObjectName converts an F5 object path into a model name. Objects in the
+// Common partition keep their bare name, objects in other partitions are
+// prefixed with the partition so that names stay unique.
+func f5ObjectName(path string) string {
+	trimmed := strings.TrimPrefix(path, "/")
+	partition, name, found := strings.Cut(trimmed, "/")
+	if !found {
+		return path
+	}
+	if partition == "Common" {
+		return name
+	}
+	return partition + "-" + strings.ReplaceAll(name, "/", "-")
+}
 
-		if !inVirtual || currentVirtual == nil {
+// f5Address strips the partition folder from an address path (e.g. /Common/10.1.2.3)
+func f5Address(path string) string {
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+// addReference records a profile, iRule or persistence reference found in the given section
+func (v *F5VirtualSimple) addReference(section, name string, line int) {
+	ref := F5ReferenceSimple{Name: name, Line: line}
+	switch section {
+	case "profiles":
+		v.Profiles = append(v.Profiles, ref)
+	case "rules":
+		v.Rules = append(v.Rules, ref)
+	case "persist":
+		v.Persist = append(v.Persist, ref)
+	}
+}
+
+// f5ServerSSL returns the server-ssl profile of a virtual with the settings
+// it inherits through defaults-from, and whether the virtual has one.
+// Built-in profiles and undefined parents add no settings.
+func f5ServerSSL(virtual F5VirtualSimple, profiles map[string]F5ServerSSLSimple) (F5ServerSSLSimple, bool) {
+	for _, ref := range virtual.Profiles {
+		if _, defined := profiles[ref.Name]; !defined && !f5ServerSSLProfiles[f5Address(ref.Name)] {
 			continue
 		}
+		resolved := F5ServerSSLSimple{Name: ref.Name, Line: ref.Line}
+		if profile, defined := profiles[ref.Name]; defined {
+			resolved.Line = profile.Line
+		}
 
-		// Count braces to track nesting
-		braceLevel += strings.Count(trimmed, "{") - strings.Count(trimmed, "}")
-
-		// Extract information from within the virtual block
-		if braceLevel > 0 {
-			// Description
-			if descMatch := regexp.MustCompile(`description\s+(.+)`).FindStringSubmatch(trimmed); descMatch != nil {
-				currentVirtual.Description = descMatch[1]
+		// Settings of a profile override those of its parents
+		seen := make(map[string]bool)
+		for name := ref.Name; name != "" && !seen[name]; {
+			seen[name] = true
+			profile, defined := profiles[name]
+			if !defined {
+				break
 			}
-
-			// Destination (VIP:port)
-			if destMatch := regexp.MustCompile(`destination\s+(/Common/[^:]+):(\d+)`).FindStringSubmatch(trimmed); destMatch != nil {
-				currentVirtual.Destination = strings.TrimPrefix(destMatch[1], "/Common/") + ":" + destMatch[2]
+			if resolved.PeerCertMode == "" {
+				resolved.PeerCertMode = profile.PeerCertMode
 			}
-
-			// Pool
-			if poolMatch := regexp.MustCompile(`pool\s+(.+)`).FindStringSubmatch(trimmed); poolMatch != nil {
-				currentVirtual.Pool = poolMatch[1]
+			if resolved.AuthenticateName == "" {
+				resolved.AuthenticateName = profile.AuthenticateName
+			}
+			if resolved.ServerName == "" {
+				resolved.ServerName = profile.ServerName
+			}
+			if resolved.CAFile == "" {
+				resolved.CAFile = profile.CAFile
+			}
+			if resolved.Cert == "" {
+				resolved.Cert, resolved.Key = profile.Cert, profile.Key
 			}
+			name = profile.DefaultsFrom
 		}
+		return resolved, true
+	}
+	return F5ServerSSLSimple{}, false
+}
+
+// addF5ServerSSL records the server-ssl profile of a virtual as the SSL
+// settings of its service group. The CA and client certificate files become
+// certKeys named after them.
+func addF5ServerSSL(config *LBConfig, group string, profile F5ServerSSLSimple, at func(int) Position) {
+	source := "ltm profile server-ssl " + profile.Name
+	ssl := &SSLServiceGroup{
+		Name:       group,
+		ServerAuth: profile.PeerCertMode == "require",
+		CommonName: profile.AuthenticateName,
+		SNI:        profile.ServerName != "",
+		ServerName: profile.ServerName,
+		Pos:        at(profile.Line),
+		Source:     source,
+	}
+	certKey := func(cert, key string) string {
+		name := f5ObjectName(cert)
+		if config.CertKeyByName(name) == nil {
+			config.AddCertKey(&CertKey{Name: name, CertFile: cert, KeyFile: key, Pos: at(profile.Line), Source: source})
+		}
+		return name
+	}
+	if profile.CAFile != "" {
+		ssl.CAs = []string{certKey(profile.CAFile, "")}
+	}
+	if profile.Cert != "" && profile.Key != "" {
+		ssl.ClientCert = certKey(profile.Cert, profile.Key)
+	}
+	config.AddSSLServiceGroup(ssl)
+}
+
+// recordF5VirtualGaps records the profiles, iRules, persistence and monitors of a
+// virtual that have no Traefik translation. Server-ssl profiles are translated
+// with the pool.
+func recordF5VirtualGaps(config *LBConfig, virtual F5VirtualSimple, pool *F5PoolSimple, serverSSL map[string]F5ServerSSLSimple, at func(int) Position) {
+	vserverName := f5ObjectName(virtual.Name)
+	record := func(objectType, name, reason string, line int) {
+		config.AddUntranslated(&UntranslatedObject{
+			ObjectType: objectType,
+			Name:       name,
+			VServer:    vserverName,
+			Reason:     reason,
+			Text:       name,
+			Pos:        at(line),
+		})
+	}
 
-		// Virtual block ended
-		if braceLevel == 0 {
-			virtuals = append(virtuals, *currentVirtual)
-			currentVirtual = nil
-			inVirtual = false
+	for _, rule := range virtual.Rules {
+		record("ltm virtual rules", rule.Name, fmt.Sprintf("iRule '%s' is not translated", rule.Name), rule.Line)
+	}
+	for _, persist := range virtual.Persist {
+		record("ltm virtual persist", persist.Name, fmt.Sprintf("persistence profile '%s' is not translated", persist.Name), persist.Line)
+	}
+	for _, profile := range virtual.Profiles {
+		baseName := profile.Name[strings.LastIndex(profile.Name, "/")+1:]
+		if _, defined := serverSSL[profile.Name]; !f5TranslatedProfiles[baseName] && !f5ServerSSLProfiles[baseName] && !defined {
+			record("ltm virtual profiles", profile.Name, fmt.Sprintf("profile '%s' is not translated", profile.Name), profile.Line)
 		}
 	}
 
-	return virtuals
+	if pool != nil && pool.Monitor != "" {
+		config.AddUntranslated(&UntranslatedObject{
+			ObjectType:   "ltm pool monitor",
+			Name:         pool.Monitor,
+			ServiceGroup: vserverName,
+			Reason:       fmt.Sprintf("monitor '%s' on pool '%s' is not translated", pool.Monitor, pool.Name),
+			Text:         fmt.Sprintf("monitor %s (pool %s, virtual %s)", pool.Monitor, pool.Name, virtual.Name),
+			Pos:          at(pool.Line),
+		})
+	}
 }
 
-func convertF5ToTraefikFormat(nodes []F5NodeSimple, pools []F5PoolSimple, virtuals []F5VirtualSimple) ([]ServerInfo, []VServerInfo, []ServiceGroupDef, []ServiceGroup, []VServerBinding) {
-	var servers []ServerInfo
-	var vservers []VServerInfo
-	var serviceGroupDefs []ServiceGroupDef
-	var serviceGroups []ServiceGroup
-	var vserverBindings []VServerBinding
+func convertF5ToTraefikFormat(nodes []F5NodeSimple, pools []F5PoolSimple, virtuals []F5VirtualSimple, serverSSL []F5ServerSSLSimple, filename string) *LBConfig {
+	config := NewLBConfig(ConfigTypeF5)
+	at := func(line int) Position {
+		return Position{File: filename, Line: line, Column: 1}
+	}
 
-	// Create a map to track unique server IP addresses and their names
-	serverMap := make(map[string]bool)
+	// Create a map to track server names by IP address
 	ipToServerName := make(map[string]string)
 
 	// Convert F5 nodes to ServerInfo
 	for _, node := range nodes {
-		cleanName := strings.TrimPrefix(node.Name, "/Common/")
-		servers = append(servers, ServerInfo{
-			Name:    cleanName,
-			IP:      node.Address,
-			Comment: "F5 Node",
+		cleanName := f5ObjectName(node.Name)
+		config.AddServer(&ServerInfo{
+			Name:     cleanName,
+			IP:       node.Address,
+			Comment:  "F5 Node",
+			Metadata: map[string]string{"f5.path": node.Name},
+			Pos:      at(node.Line),
+			Source:   "ltm node " + node.Name,
 		})
-		serverMap[node.Address] = true
 		ipToServerName[node.Address] = cleanName
 	}
 
@@ -238,92 +630,156 @@ func convertF5ToTraefikFormat(nodes []F5NodeSimple, pools []F5PoolSimple, virtua
 	for _, pool := range pools {
 		poolMap[pool.Name] = pool
 	}
+	serverSSLMap := make(map[string]F5ServerSSLSimple)
+	for _, profile := range serverSSL {
+		serverSSLMap[profile.Name] = profile
+	}
 
 	// Convert F5 virtual servers to VServerInfo and create service groups using virtual server names
 	for _, virtual := range virtuals {
-		cleanVirtualName := strings.TrimPrefix(virtual.Name, "/Common/")
-
-		if virtual.Destination != "" {
-			// Split destination IP:port
-			parts := strings.Split(virtual.Destination, ":")
-			if len(parts) == 2 {
-				vservers = append(vservers, VServerInfo{
-					Name:     cleanVirtualName,
-					Protocol: "HTTP", // Default to HTTP for F5 virtuals
-					IP:       parts[0],
-					Port:     parts[1],
-				})
-
-				// If this virtual server has a pool, create service group using virtual server name
-				if virtual.Pool != "" {
-					if pool, exists := poolMap[virtual.Pool]; exists {
-						// Create service group definition using virtual server name
-						serviceGroupDefs = append(serviceGroupDefs, ServiceGroupDef{
-							Name:     cleanVirtualName, // Use virtual server name instead of pool name
-							Protocol: "HTTP",
-							Comment:  pool.Description,
-						})
-
-						// Create service group bindings for each pool member
-						for _, member := range pool.Members {
-							// Determine the server name to use
-							var serverName string
-							if existingName, exists := ipToServerName[member.Address]; exists {
-								// Use the existing node name
-								serverName = existingName
-							} else {
-								// Ensure we have a server entry for this IP
-								if !serverMap[member.Address] && member.Address != "" {
-									servers = append(servers, ServerInfo{
-										Name:    member.Address, // Use IP as name if no node definition exists
-										IP:      member.Address,
-										Comment: "Auto-generated from F5 pool member",
-									})
-									serverMap[member.Address] = true
-									ipToServerName[member.Address] = member.Address
-								}
-								serverName = member.Address
-							}
-
-							serviceGroups = append(serviceGroups, ServiceGroup{
-								Name:       cleanVirtualName, // Use virtual server name
-								ServerName: serverName,
-								Port:       strconv.Itoa(member.Port),
-								Comment:    pool.Description,
-							})
-						}
-
-						// Create vserver binding using virtual server name
-						vserverBindings = append(vserverBindings, VServerBinding{
-							VServerName: cleanVirtualName,
-							ServiceName: cleanVirtualName, // Service group also uses virtual server name
-							Comment:     virtual.Description,
-						})
-					}
-				} else {
-					// Virtual server without pool - create empty service group
-					serviceGroupDefs = append(serviceGroupDefs, ServiceGroupDef{
-						Name:     cleanVirtualName,
-						Protocol: "HTTP",
-						Comment:  "F5 Virtual Server without pool",
+		cleanVirtualName := f5ObjectName(virtual.Name)
+
+		if virtual.Destination == "" {
+			config.AddDiagnostic(Diagnostic{
+				Position: at(virtual.Line),
+				Severity: SeverityWarning,
+				Code:     "missing-destination",
+				Message:  fmt.Sprintf("virtual '%s' has no IPv4 destination and is skipped", virtual.Name),
+			})
+			continue
+		}
+
+		// Split destination IP:port
+		parts := strings.Split(virtual.Destination, ":")
+		if len(parts) != 2 {
+			config.AddDiagnostic(Diagnostic{
+				Position: at(virtual.Line),
+				Severity: SeverityWarning,
+				Code:     "invalid-destination",
+				Message:  fmt.Sprintf("virtual '%s' has unsupported destination '%s' and is skipped", virtual.Name, virtual.Destination),
+			})
+			continue
+		}
+
+		pool, poolExists := poolMap[virtual.Pool]
+		lbMethod := defaultF5LBMethod
+		if pool.LBMode != "" {
+			lbMethod = pool.LBMode
+		}
+
+		config.AddVServer(&VServerInfo{
+			Name:      cleanVirtualName,
+			Protocol:  "HTTP", // Default to HTTP for F5 virtuals
+			IP:        parts[0],
+			Port:      parts[1],
+			LBMethod:  lbMethod,
+			LBDefault: pool.LBMode == "",
+			Metadata:  map[string]string{"f5.path": virtual.Name, "f5.pool": virtual.Pool},
+			Pos:       at(virtual.Line),
+			Source:    "ltm virtual " + virtual.Name,
+		})
+
+		if poolExists {
+			recordF5VirtualGaps(config, virtual, &pool, serverSSLMap, at)
+		} else {
+			recordF5VirtualGaps(config, virtual, nil, serverSSLMap, at)
+		}
+
+		// Virtual server without pool - create empty service group
+		if virtual.Pool == "" {
+			config.AddServiceGroupDef(&ServiceGroupDef{
+				Name:     cleanVirtualName,
+				Protocol: "HTTP",
+				Comment:  "F5 Virtual Server without pool",
+				Pos:      at(virtual.Line),
+				Source:   "ltm virtual " + virtual.Name,
+			})
+			continue
+		}
+
+		// If this virtual server has a pool, create service group using virtual server name
+		if !poolExists {
+			config.AddDiagnostic(Diagnostic{
+				Position: at(virtual.Line),
+				Severity: SeverityWarning,
+				Code:     "undefined-pool",
+				Message:  fmt.Sprintf("virtual '%s' references undefined pool '%s'", virtual.Name, virtual.Pool),
+			})
+			continue
+		}
+
+		// Create service group definition using virtual server name instead of
+		// pool name; a server-ssl profile re-encrypts the traffic to the members
+		protocol := "HTTP"
+		profile, reencrypt := f5ServerSSL(virtual, serverSSLMap)
+		if reencrypt {
+			protocol = "SSL"
+			addF5ServerSSL(config, cleanVirtualName, profile, at)
+		}
+		config.AddServiceGroupDef(&ServiceGroupDef{
+			Name:      cleanVirtualName,
+			Protocol:  protocol,
+			Comment:   pool.Description,
+			MinActive: pool.MinActive,
+			Metadata:  map[string]string{"f5.path": pool.Name, "f5.monitor": pool.Monitor},
+			Pos:       at(pool.Line),
+			Source:    "ltm pool " + pool.Name,
+		})
+
+		// Create service group bindings for each pool member
+		for _, member := range pool.Members {
+			memberPath := fmt.Sprintf("ltm pool %s members %s:%d", pool.Name, member.Path, member.Port)
+
+			// Determine the server name to use
+			serverName, exists := ipToServerName[member.Address]
+			if !exists {
+				// Ensure we have a server entry for this IP
+				if member.Address != "" {
+					config.AddServer(&ServerInfo{
+						Name:    member.Address, // Use IP as name if no node definition exists
+						IP:      member.Address,
+						Comment: "Auto-generated from F5 pool member",
+						Pos:     at(member.Line),
+						Source:  memberPath,
 					})
+					ipToServerName[member.Address] = member.Address
 				}
+				serverName = member.Address
 			}
+
+			config.AddServiceGroup(&ServiceGroup{
+				Name:       cleanVirtualName, // Use virtual server name
+				ServerName: serverName,
+				Port:       strconv.Itoa(member.Port),
+				Priority:   member.Priority,
+				Comment:    pool.Description,
+				Pos:        at(member.Line),
+				Source:     memberPath,
+			})
 		}
+
+		// Create vserver binding using virtual server name
+		config.AddVServerBinding(&VServerBinding{
+			VServerName: cleanVirtualName,
+			ServiceName: cleanVirtualName, // Service group also uses virtual server name
+			Comment:     virtual.Description,
+			Pos:         at(virtual.Line),
+			Source:      fmt.Sprintf("ltm virtual %s pool %s", virtual.Name, virtual.Pool),
+		})
 	}
 
-	return servers, vservers, serviceGroupDefs, serviceGroups, vserverBindings
+	return config
 }
 
 // Legacy functions for backward compatibility (if needed)
 // Keep the old complex parser functions as backup, but use simple parser by default
 
 // ParseF5Settings parses F5 configuration file (backward compatibility - now uses simple parser)
-func ParseF5Settings(filename string) ([]ServerInfo, []VServerInfo, []ServiceGroupDef, []ServiceGroup, []VServerBinding, error) {
+func ParseF5Settings(filename string) (*LBConfig, error) {
 	return ParseF5SettingsFromFileSimple(filename)
 }
 
 // ParseF5SettingsFromReader parses F5 configuration from reader (backward compatibility - now uses simple parser)
-func ParseF5SettingsFromReader(reader io.Reader) ([]ServerInfo, []VServerInfo, []ServiceGroupDef, []ServiceGroup, []VServerBinding, error) {
+func ParseF5SettingsFromReader(reader io.Reader) (*LBConfig, error) {
 	return ParseF5SettingsFromReaderSimple(reader)
 }
@@ -0,0 +1,180 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+)
+
+// backupSetters returns the functions that apply "add/set lb vserver" backup
+// parameters to a virtual server
+func backupSetters(vserver *VServerInfo) map[string]func(value string) {
+	return map[string]func(string){
+		"-backupVServer":            func(value string) { vserver.Backup.VServer = value },
+		"-backupPersistenceTimeout": func(value string) { vserver.Backup.PersistenceTimeout = value },
+	}
+}
+
+// backupChain follows the backup virtual servers of a virtual server and
+// returns their names in order. cycle is true when the chain leads back to a
+// virtual server already in it; the chain then ends with that repeated name.
+func backupChain(config *LBConfig, vserver *VServerInfo) (chain []string, cycle bool) {
+	seen := map[string]bool{vserver.Name: true}
+	for next := vserver.Backup.VServer; next != ""; {
+		if seen[next] {
+			return append(chain, next), true
+		}
+		seen[next] = true
+		chain = append(chain, next)
+
+		backup := config.VServerByName(next)
+		if backup == nil {
+			break
+		}
+		next = backup.Backup.VServer
+	}
+	return chain, false
+}
+
+// priorityTiers returns the member priorities of the given service groups in
+// the order they take traffic, highest first. It returns nil unless a group
+// uses priority group activation and its members have different priorities.
+func priorityTiers(config *LBConfig, groups []string) []int {
+	var tiers []int
+	seen := make(map[int]bool)
+	for _, group := range groups {
+		def := config.ServiceGroupDefByName(group)
+		if def == nil || def.MinActive == 0 {
+			continue
+		}
+		for _, member := range config.MembersOf(group) {
+			if !seen[member.Priority] {
+				seen[member.Priority] = true
+				tiers = append(tiers, member.Priority)
+			}
+		}
+	}
+	if len(tiers) < 2 {
+		return nil
+	}
+
+	sort.Sort(sort.Reverse(sort.IntSlice(tiers)))
+	return tiers
+}
+
+// addPriorityFailover replaces the service of a virtual server whose members
+// are split into priority groups with a chain of failover services, one
+// load balancer per priority group. Priority groups without enabled members
+// are left out; when fewer than two remain the service is kept as it is.
+func addPriorityFailover(services map[string]TraefikService, config *LBConfig, vserver *VServerInfo, groups []string, weights map[string]int, opts GenerateOptions) {
+	tiers := priorityTiers(config, groups)
+	if tiers == nil {
+		return
+	}
+	service := services[vserver.Name]
+
+	var names []string
+	for _, priority := range tiers {
+		tier, ok := buildService(config, groups, weights, opts, service.Origin, func(member *ServiceGroup) bool {
+			return member.Priority == priority
+		})
+		if !ok {
+			continue
+		}
+		tier.LoadBalancer.Sticky = service.LoadBalancer.Sticky
+		tier.LoadBalancer.Strategy = service.LoadBalancer.Strategy
+		tier.Comment = fmt.Sprintf("priority group %d of %s", priority, vserver.Name)
+
+		name := uniqueName(services, fmt.Sprintf("%s-priority%d", vserver.Name, priority))
+		services[name] = tier
+		names = append(names, name)
+	}
+	if len(names) < 2 {
+		for _, name := range names {
+			delete(services, name)
+		}
+		return
+	}
+
+	// Each priority group falls back to a failover over the groups below it
+	fallback := names[len(names)-1]
+	for i := len(names) - 2; i > 0; i-- {
+		name := uniqueName(services, names[i]+"-failover")
+		services[name] = TraefikService{
+			Failover: newFailover(services, names[i], fallback),
+			Comment:  fmt.Sprintf("priority groups of %s from %s down", vserver.Name, names[i]),
+			Origin:   service.Origin,
+		}
+		fallback = name
+	}
+	services[vserver.Name] = TraefikService{
+		Failover: newFailover(services, names[0], fallback),
+		Comment:  service.Comment,
+		Origin:   service.Origin,
+	}
+}
+
+// addBackupFailovers replaces the service of each virtual server that has an
+// enabled backup virtual server with a failover service: its own servers
+// become the "<name>-primary" service and the backup's service is the
+// fallback. A backup that has a backup of its own is itself a failover
+// service, so chains nest. Virtual servers in a backup cycle are left as
+// they are.
+func addBackupFailovers(services map[string]TraefikService, config *LBConfig) {
+	for _, vserver := range config.VServers {
+		backup := config.VServerByName(vserver.Backup.VServer)
+		if backup == nil || backup.Disabled {
+			continue
+		}
+		if _, cycle := backupChain(config, vserver); cycle {
+			continue
+		}
+		service, exists := services[vserver.Name]
+		if _, hasFallback := services[backup.Name]; !exists || !hasFallback {
+			continue
+		}
+
+		primary := uniqueName(services, vserver.Name+"-primary")
+		primaryService := service
+		primaryService.Comment = fmt.Sprintf("primary servers of %s", vserver.Name)
+		services[primary] = primaryService
+		services[vserver.Name] = TraefikService{
+			Failover: newFailover(services, primary, backup.Name),
+			Comment:  service.Comment,
+			Origin:   service.Origin,
+		}
+	}
+}
+
+// newFailover returns a failover from the primary service to the fallback.
+// When the primary has a health check the failover reports its health, so
+// that it can be the primary of another failover.
+func newFailover(services map[string]TraefikService, primary, fallback string) *TraefikFailover {
+	failover := &TraefikFailover{Service: primary, Fallback: fallback}
+	if hasHealthCheck(services[primary]) {
+		failover.HealthCheck = &TraefikHealthStatus{}
+	}
+	return failover
+}
+
+// hasHealthCheck reports whether Traefik knows when the service is down
+func hasHealthCheck(service TraefikService) bool {
+	switch {
+	case service.Failover != nil:
+		return service.Failover.HealthCheck != nil
+	case service.Weighted != nil:
+		return service.Weighted.HealthCheck != nil
+	default:
+		return service.LoadBalancer.HealthCheck != nil
+	}
+}
+
+// uniqueName returns name, or name with a numeric suffix when a service or router of that name exists
+func uniqueName[V any](existing map[string]V, name string) string {
+	unique := name
+	for suffix := 2; ; suffix++ {
+		if _, exists := existing[unique]; !exists {
+			return unique
+		}
+		unique = fmt.Sprintf("%s-%d", name, suffix)
+	}
+}
@@ -0,0 +1,218 @@
+package parser
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// failoverBase defines a primary and a disaster recovery vserver, each with
+// its own service group
+const failoverBase = `add server s1 10.0.0.1
+add server s2 10.0.0.2
+add server s3 10.0.0.3
+add serviceGroup web_sg HTTP
+bind serviceGroup web_sg s1 80
+add serviceGroup dr_sg HTTP
+bind serviceGroup dr_sg s2 80
+bind lb vserver web-vs web_sg
+bind lb vserver dr-vs dr_sg
+`
+
+func TestBackupFailover(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    string
+		services  map[string][]string
+		health    bool // Whether the web-vs failover reports its health
+		mappings  []string
+		wantCodes []string
+	}{
+		{
+			name: "backup vserver",
+			config: `add lb vserver web-vs HTTP 10.9.0.1 80 -backupVServer dr-vs
+add lb vserver dr-vs HTTP 0.0.0.0 0
+bind serviceGroup web_sg -monitorName http
+`,
+			services: map[string][]string{
+				"web-vs":         {"service web-vs-primary", "fallback dr-vs"},
+				"web-vs-primary": {"http://10.0.0.1:80"},
+				"dr-vs":          {"http://10.0.0.2:80"},
+			},
+			health:   true,
+			mappings: []string{"10.9.0.1:80=web-vs@nacoscs"},
+		},
+		{
+			name: "backup set later",
+			config: `add lb vserver web-vs HTTP 10.9.0.1 80
+add lb vserver dr-vs HTTP 10.9.0.2 80
+set lb vserver web-vs -backupVServer dr-vs
+bind serviceGroup web_sg -monitorName http
+`,
+			services: map[string][]string{
+				"web-vs":         {"service web-vs-primary", "fallback dr-vs"},
+				"web-vs-primary": {"http://10.0.0.1:80"},
+				"dr-vs":          {"http://10.0.0.2:80"},
+			},
+			health:   true,
+			mappings: []string{"10.9.0.1:80=web-vs@nacoscs", "10.9.0.2:80=dr-vs@nacoscs"},
+		},
+		{
+			name: "backup chain nests",
+			config: `add lb vserver web-vs HTTP 10.9.0.1 80 -backupVServer dr-vs
+add lb vserver dr-vs HTTP 0.0.0.0 0 -backupVServer last-vs
+add lb vserver last-vs HTTP 0.0.0.0 0
+add serviceGroup last_sg HTTP
+bind serviceGroup last_sg s3 80
+bind lb vserver last-vs last_sg
+bind serviceGroup web_sg -monitorName http
+bind serviceGroup dr_sg -monitorName http
+`,
+			services: map[string][]string{
+				"web-vs":         {"service web-vs-primary", "fallback dr-vs"},
+				"web-vs-primary": {"http://10.0.0.1:80"},
+				"dr-vs":          {"service dr-vs-primary", "fallback last-vs"},
+				"dr-vs-primary":  {"http://10.0.0.2:80"},
+				"last-vs":        {"http://10.0.0.3:80"},
+			},
+			health:   true,
+			mappings: []string{"10.9.0.1:80=web-vs@nacoscs"},
+		},
+		{
+			name: "primary without health check",
+			config: `add lb vserver web-vs HTTP 10.9.0.1 80 -backupVServer dr-vs
+add lb vserver dr-vs HTTP 0.0.0.0 0
+`,
+			services: map[string][]string{
+				"web-vs":         {"service web-vs-primary", "fallback dr-vs"},
+				"web-vs-primary": {"http://10.0.0.1:80"},
+				"dr-vs":          {"http://10.0.0.2:80"},
+			},
+			mappings:  []string{"10.9.0.1:80=web-vs@nacoscs"},
+			wantCodes: []string{"failover-without-health-check"},
+		},
+		{
+			name: "backup cycle",
+			config: `add lb vserver web-vs HTTP 10.9.0.1 80 -backupVServer dr-vs
+add lb vserver dr-vs HTTP 10.9.0.2 80 -backupVServer web-vs
+bind serviceGroup web_sg -monitorName http
+bind serviceGroup dr_sg -monitorName http
+`,
+			services: map[string][]string{
+				"web-vs": {"http://10.0.0.1:80"},
+				"dr-vs":  {"http://10.0.0.2:80"},
+			},
+			mappings:  []string{"10.9.0.1:80=web-vs@nacoscs", "10.9.0.2:80=dr-vs@nacoscs"},
+			wantCodes: []string{"backup-cycle", "backup-cycle"},
+		},
+		{
+			name: "disabled backup",
+			config: `add lb vserver web-vs HTTP 10.9.0.1 80 -backupVServer dr-vs
+add lb vserver dr-vs HTTP 0.0.0.0 0 -state DISABLED
+bind serviceGroup web_sg -monitorName http
+`,
+			services: map[string][]string{
+				"web-vs": {"http://10.0.0.1:80"},
+			},
+			mappings: []string{"10.9.0.1:80=web-vs@nacoscs"},
+		},
+		{
+			name: "undefined backup",
+			config: `add lb vserver web-vs HTTP 10.9.0.1 80 -backupVServer missing-vs
+add lb vserver dr-vs HTTP 10.9.0.2 80
+`,
+			services: map[string][]string{
+				"web-vs": {"http://10.0.0.1:80"},
+				"dr-vs":  {"http://10.0.0.2:80"},
+			},
+			mappings:  []string{"10.9.0.1:80=web-vs@nacoscs", "10.9.0.2:80=dr-vs@nacoscs"},
+			wantCodes: []string{"undefined-backup"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := parseCitrixText(t, "ns.conf", failoverBase+tt.config)
+			services := GenerateTraefikConfig(config).HTTP.Services
+
+			if len(services) != len(tt.services) {
+				t.Errorf("got %d services, want %d", len(services), len(tt.services))
+			}
+			for name, want := range tt.services {
+				if got := services[name].Backends(); !slices.Equal(got, want) {
+					t.Errorf("service %s backends = %v, want %v", name, got, want)
+				}
+			}
+			if failover := services["web-vs"].Failover; failover != nil && (failover.HealthCheck != nil) != tt.health {
+				t.Errorf("web-vs failover health check = %v, want %v", failover.HealthCheck != nil, tt.health)
+			}
+			if got := mappingPairs(GenerateMappingConfig(config)); !slices.Equal(got, tt.mappings) {
+				t.Errorf("mappings = %v, want %v", got, tt.mappings)
+			}
+
+			var got []string
+			for _, code := range diagnosticCodes(Verify(config)) {
+				if strings.Contains(code, "backup") || strings.Contains(code, "failover") {
+					got = append(got, code)
+				}
+			}
+			if !slices.Equal(got, tt.wantCodes) {
+				t.Errorf("failover diagnostics = %v, want %v", got, tt.wantCodes)
+			}
+		})
+	}
+}
+
+func TestPriorityGroupFailover(t *testing.T) {
+	config, err := ParseF5(strings.NewReader(`ltm pool /Common/web_pool {
+    members {
+        /Common/10.0.0.1:80 {
+            address 10.0.0.1
+            priority-group 10
+        }
+        /Common/10.0.0.2:80 {
+            address 10.0.0.2
+            priority-group 5
+        }
+        /Common/10.0.0.3:80 {
+            address 10.0.0.3
+            priority-group 5
+        }
+        /Common/10.0.0.4:80 {
+            address 10.0.0.4
+            priority-group 1
+        }
+    }
+    min-active-members 1
+    monitor /Common/http
+}
+ltm virtual /Common/web_vs {
+    destination /Common/10.9.0.1:80
+    pool /Common/web_pool
+}
+`), ParseOptions{Filename: "bigip.conf"})
+	if err != nil {
+		t.Fatalf("ParseF5: %v", err)
+	}
+
+	services := GenerateTraefikConfig(config).HTTP.Services
+	var names []string
+	for name, service := range services {
+		names = append(names, name+"="+strings.Join(service.Backends(), ","))
+	}
+	slices.Sort(names)
+	want := []string{
+		"web_vs-priority10=http://10.0.0.1:80",
+		"web_vs-priority1=http://10.0.0.4:80",
+		"web_vs-priority5-failover=service web_vs-priority5,fallback web_vs-priority1",
+		"web_vs-priority5=http://10.0.0.2:80,http://10.0.0.3:80",
+		"web_vs=service web_vs-priority10,fallback web_vs-priority5-failover",
+	}
+	if !slices.Equal(names, want) {
+		t.Errorf("services:\n%s\nwant:\n%s", strings.Join(names, "\n"), strings.Join(want, "\n"))
+	}
+	// F5 pool monitors are not translated, so the priority groups never fail over
+	if codes := diagnosticCodes(Verify(config)); !slices.Contains(codes, "failover-without-health-check") {
+		t.Errorf("expected failover-without-health-check, got %v", codes)
+	}
+}
@@ -0,0 +1,263 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// GapGroup lists the untranslated objects of one object type
+type GapGroup struct {
+	ObjectType string
+	Objects    []*UntranslatedObject
+}
+
+// AffectedVServer is a virtual server whose behavior changes because part of
+// its configuration is not translated; exactly one of VServer and CSVServer
+// is set
+type AffectedVServer struct {
+	VServer   *VServerInfo
+	CSVServer *CSVServer
+	Reasons   []string
+}
+
+// ExcludedObject is a disabled object that convert leaves out or comments out
+type ExcludedObject struct {
+	Kind   string // "server", "service group", "member", "vserver" or "cs vserver"
+	Name   string
+	Detail string // Address or member binding
+	Pos    Position
+}
+
+// GapReport lists everything that was seen in the input but not translated
+type GapReport struct {
+	Groups   []GapGroup
+	Affected []AffectedVServer
+	Excluded []ExcludedObject // Disabled objects, in model order
+	Disabled DisabledMode     // Whether the disabled objects are left out or commented out
+}
+
+// Total returns the number of untranslated objects in the report
+func (r GapReport) Total() int {
+	total := 0
+	for _, group := range r.Groups {
+		total += len(group.Objects)
+	}
+	return total
+}
+
+// UntranslatedObjects returns everything that is not translated: the
+// commands the parser did not convert, in source order, followed by the
+// parsed settings that have no Traefik equivalent
+func UntranslatedObjects(config *LBConfig) []*UntranslatedObject {
+	return append(append([]*UntranslatedObject(nil), config.Untranslated...), settingGaps(config)...)
+}
+
+// BuildGapReport groups the untranslated objects of a configuration by object
+// type and works out which lb and cs virtual servers are affected by them
+func BuildGapReport(config *LBConfig) GapReport {
+	return BuildGapReportWithOptions(config, GenerateOptions{})
+}
+
+// BuildGapReportWithOptions builds the gap report of the configuration
+// generated with opts, see BuildGapReport
+func BuildGapReportWithOptions(config *LBConfig, opts GenerateOptions) GapReport {
+	report := GapReport{Disabled: opts.Disabled}
+
+	objects := UntranslatedObjects(config)
+
+	// Group by case-insensitive object type, largest groups first
+	groupIndex := make(map[string]int)
+	for _, object := range objects {
+		key := strings.ToLower(object.ObjectType)
+		i, exists := groupIndex[key]
+		if !exists {
+			i = len(report.Groups)
+			groupIndex[key] = i
+			report.Groups = append(report.Groups, GapGroup{ObjectType: object.ObjectType})
+		}
+		report.Groups[i].Objects = append(report.Groups[i].Objects, object)
+	}
+	sort.SliceStable(report.Groups, func(i, j int) bool {
+		return len(report.Groups[i].Objects) > len(report.Groups[j].Objects)
+	})
+
+	// Collect reasons per virtual server, for objects attached to the vserver
+	// itself or to a service group bound to it. Content switching vservers
+	// only have their own objects.
+	reasons := make(map[string][]string)
+	csReasons := make(map[string][]string)
+	addReason := func(reasons map[string][]string, vserver string, object *UntranslatedObject) {
+		reason := object.Reason
+		if reason == "" {
+			reason = fmt.Sprintf("'%s' is not translated", object.Text)
+		}
+		if object.Pos.Line > 0 {
+			reason = fmt.Sprintf("%s (line %d)", reason, object.Pos.Line)
+		}
+		for _, existing := range reasons[vserver] {
+			if existing == reason {
+				return
+			}
+		}
+		reasons[vserver] = append(reasons[vserver], reason)
+	}
+
+	for _, object := range objects {
+		if object.VServer != "" {
+			addReason(reasons, object.VServer, object)
+		}
+		if object.CSVServer != "" {
+			addReason(csReasons, object.CSVServer, object)
+		}
+		if object.ServiceGroup != "" {
+			for _, binding := range config.VServerBindings {
+				if binding.ServiceName == object.ServiceGroup {
+					addReason(reasons, binding.VServerName, object)
+				}
+			}
+		}
+	}
+
+	for _, vserver := range config.VServers {
+		if list, exists := reasons[vserver.Name]; exists {
+			report.Affected = append(report.Affected, AffectedVServer{VServer: vserver, Reasons: list})
+		}
+	}
+	for _, vserver := range config.CSVServers {
+		if list, exists := csReasons[vserver.Name]; exists {
+			report.Affected = append(report.Affected, AffectedVServer{CSVServer: vserver, Reasons: list})
+		}
+	}
+
+	report.Excluded = disabledObjects(config)
+
+	return report
+}
+
+// settingGaps describes the parsed settings that have no Traefik equivalent
+// as untranslated objects: persistence types other than cookie insertion,
+// backup persistence timeouts, F5 minimum active member counts, content
+// switching policies that cannot be routed, responder and rewrite policies
+// that cannot be applied and SSL settings that TLS options and servers
+// transports cannot express
+func settingGaps(config *LBConfig) []*UntranslatedObject {
+	gaps := append(append(csRouteGaps(config), responderGaps(config)...), rewriteGaps(config)...)
+	gaps = append(append(gaps, tlsGaps(config)...), transportGaps(config)...)
+	for _, vserver := range config.VServers {
+		if _, unsupported := translatePersistence(vserver); unsupported != "" {
+			gaps = append(gaps, &UntranslatedObject{
+				ObjectType: "lb vserver persistence",
+				Name:       vserver.Name,
+				VServer:    vserver.Name,
+				Reason:     unsupported,
+				Text:       vserver.Source,
+				Pos:        vserver.Pos,
+			})
+		}
+		if timeout := vserver.Backup.PersistenceTimeout; timeout != "" && timeout != "0" {
+			gaps = append(gaps, &UntranslatedObject{
+				ObjectType: "lb vserver backupPersistenceTimeout",
+				Name:       vserver.Name,
+				VServer:    vserver.Name,
+				Reason:     fmt.Sprintf("-backupPersistenceTimeout %s is not translated, clients return to the primary as soon as it is healthy", timeout),
+				Text:       vserver.Source,
+				Pos:        vserver.Pos,
+			})
+		}
+	}
+	for _, def := range config.ServiceGroupDefs {
+		if def.MinActive > 1 && priorityTiers(config, []string{def.Name}) != nil {
+			gaps = append(gaps, &UntranslatedObject{
+				ObjectType:   "ltm pool min-active-members",
+				Name:         def.Name,
+				ServiceGroup: def.Name,
+				Reason:       fmt.Sprintf("min-active-members %d is not translated, a priority group only fails over when all of its members are down", def.MinActive),
+				Text:         def.Source,
+				Pos:          def.Pos,
+			})
+		}
+	}
+	return gaps
+}
+
+// disabledObjects lists the objects that are administratively disabled
+// themselves; members disabled through their server or group are not repeated
+func disabledObjects(config *LBConfig) []ExcludedObject {
+	var excluded []ExcludedObject
+	for _, vserver := range config.VServers {
+		if vserver.Disabled {
+			excluded = append(excluded, ExcludedObject{Kind: "vserver", Name: vserver.Name, Detail: VIPKey(vserver.IP, vserver.Port), Pos: vserver.Pos})
+		}
+	}
+	for _, vserver := range config.CSVServers {
+		if vserver.Disabled {
+			excluded = append(excluded, ExcludedObject{Kind: "cs vserver", Name: vserver.Name, Detail: VIPKey(vserver.IP, vserver.Port), Pos: vserver.Pos})
+		}
+	}
+	for _, server := range config.Servers {
+		if server.Disabled {
+			excluded = append(excluded, ExcludedObject{Kind: "server", Name: server.Name, Detail: server.IP, Pos: server.Pos})
+		}
+	}
+	for _, def := range config.ServiceGroupDefs {
+		if def.Disabled {
+			excluded = append(excluded, ExcludedObject{Kind: "service group", Name: def.Name, Detail: fmt.Sprintf("%d member(s)", len(config.MembersOf(def.Name))), Pos: def.Pos})
+		}
+	}
+	for _, member := range config.ServiceGroups {
+		if member.Disabled {
+			excluded = append(excluded, ExcludedObject{Kind: "member", Name: member.Name, Detail: member.ServerName + ":" + member.Port, Pos: member.Pos})
+		}
+	}
+	return excluded
+}
+
+// WriteGapReport renders a gap report for migration reviewers
+func WriteGapReport(w io.Writer, report GapReport) error {
+	if report.Total() == 0 {
+		fmt.Fprintln(w, "Untranslated configuration: none, every command was translated")
+		writeExcluded(w, report)
+		return nil
+	}
+
+	fmt.Fprintf(w, "Untranslated configuration: %d object(s) in %d type(s)\n", report.Total(), len(report.Groups))
+	for _, group := range report.Groups {
+		fmt.Fprintf(w, "\n%s: %d\n", group.ObjectType, len(group.Objects))
+		for _, object := range group.Objects {
+			fmt.Fprintf(w, "  %s: %s\n", object.Pos, object.Text)
+		}
+	}
+
+	fmt.Fprintf(w, "\nAffected virtual servers: %d\n", len(report.Affected))
+	for _, affected := range report.Affected {
+		if vserver := affected.VServer; vserver != nil {
+			fmt.Fprintf(w, "  %s (%s)\n", vserver.Name, VIPKey(vserver.IP, vserver.Port))
+		} else {
+			vserver := affected.CSVServer
+			fmt.Fprintf(w, "  cs vserver %s (%s)\n", vserver.Name, VIPKey(vserver.IP, vserver.Port))
+		}
+		for _, reason := range affected.Reasons {
+			fmt.Fprintf(w, "    - %s\n", reason)
+		}
+	}
+
+	writeExcluded(w, report)
+	return nil
+}
+
+// writeExcluded renders the disabled objects section of a gap report
+func writeExcluded(w io.Writer, report GapReport) {
+	if len(report.Excluded) == 0 {
+		return
+	}
+	treatment := "excluded from"
+	if report.Disabled == DisabledComment {
+		treatment = "commented out in"
+	}
+	fmt.Fprintf(w, "\nDisabled (%s the generated configuration): %d\n", treatment, len(report.Excluded))
+	for _, object := range report.Excluded {
+		fmt.Fprintf(w, "  %s: %s %s (%s)\n", object.Pos, object.Kind, object.Name, object.Detail)
+	}
+}
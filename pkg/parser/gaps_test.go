@@ -0,0 +1,185 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// gapConfig has untranslated objects of several types, attached to a
+// vserver, to a service group bound to two vservers and to neither
+const gapConfig = `add server s1 10.0.0.1
+add serviceGroup sg1 HTTP
+bind serviceGroup sg1 s1 80
+add lb monitor tcp_mon TCP
+bind serviceGroup sg1 -monitorName tcp_mon
+add lb vserver vs1 HTTP 10.9.0.1 80
+bind lb vserver vs1 sg1
+add lb vserver vs2 HTTP 10.9.0.2 80
+bind lb vserver vs2 sg1
+add lb vserver vs3 HTTP 10.9.0.3 80
+add authentication vserver auth1 SSL 10.9.0.3 443
+add authentication vserver auth2 SSL 10.9.0.4 443
+add authorization policy authz1 true ALLOW
+bind lb vserver vs2 -policyName authz1 -priority 10
+`
+
+func TestBuildGapReport(t *testing.T) {
+	report := BuildGapReport(parseCitrixText(t, "ns.conf", gapConfig))
+
+	var groups []string
+	for _, group := range report.Groups {
+		groups = append(groups, fmt.Sprintf("%s: %d", group.ObjectType, len(group.Objects)))
+	}
+	wantGroups := []string{"authentication vserver: 2", "serviceGroup: 1", "authorization policy: 1", "lb vserver: 1"}
+	if !slices.Equal(groups, wantGroups) {
+		t.Errorf("groups = %v, want %v", groups, wantGroups)
+	}
+	if report.Total() != 5 {
+		t.Errorf("Total() = %d, want 5", report.Total())
+	}
+
+	affected := make(map[string][]string)
+	for _, vserver := range report.Affected {
+		affected[vserver.VServer.Name] = vserver.Reasons
+	}
+	tests := []struct {
+		vserver string
+		want    []string
+	}{
+		{vserver: "vs1", want: []string{"monitor 'tcp_mon' is not translated: TCP monitors have no Traefik equivalent, Traefik health checks send HTTP requests (line 5)"}},
+		{vserver: "vs2", want: []string{
+			"monitor 'tcp_mon' is not translated: TCP monitors have no Traefik equivalent, Traefik health checks send HTTP requests (line 5)",
+			"authorization policy 'authz1' (priority 10) is not applied (line 14)",
+		}},
+		{vserver: "vs3"},
+	}
+	for _, tt := range tests {
+		if got := affected[tt.vserver]; !slices.Equal(got, tt.want) {
+			t.Errorf("%s reasons = %q, want %q", tt.vserver, got, tt.want)
+		}
+	}
+}
+
+func TestWriteGapReportWithoutGaps(t *testing.T) {
+	config := parseCitrixText(t, "ns.conf", "add server s1 10.0.0.1\nadd serviceGroup sg1 HTTP\nbind serviceGroup sg1 s1 80\n")
+
+	var out bytes.Buffer
+	if err := WriteGapReport(&out, BuildGapReport(config)); err != nil {
+		t.Fatalf("WriteGapReport: %v", err)
+	}
+	if want := "Untranslated configuration: none, every command was translated\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+// disabledConfig has a disabled object of every kind the gap report lists
+const disabledConfig = `add server s1 10.0.0.1
+add server s2 10.0.0.2 -state DISABLED
+add serviceGroup sg1 HTTP
+bind serviceGroup sg1 s1 80
+bind serviceGroup sg1 s1 81 -state DISABLED
+add serviceGroup sg2 HTTP -state DISABLED
+bind serviceGroup sg2 s1 90
+add lb vserver vs1 HTTP 10.9.0.1 80
+bind lb vserver vs1 sg1
+disable lb vserver vs1
+add cs vserver cs1 HTTP 10.9.0.2 80
+disable cs vserver cs1
+`
+
+func TestGapReportExcludedObjects(t *testing.T) {
+	report := BuildGapReport(parseCitrixText(t, "ns.conf", disabledConfig))
+
+	var got []string
+	for _, object := range report.Excluded {
+		got = append(got, object.Kind+" "+object.Name)
+	}
+	want := []string{"vserver vs1", "cs vserver cs1", "server s2", "service group sg2", "member sg1"}
+	if !slices.Equal(got, want) {
+		t.Errorf("excluded = %v, want %v", got, want)
+	}
+}
+
+func TestWriteGapReportDisabledHeading(t *testing.T) {
+	config := parseCitrixText(t, "ns.conf", disabledConfig)
+
+	tests := []struct {
+		name string
+		opts GenerateOptions
+		want string
+	}{
+		{name: "drop", opts: GenerateOptions{Disabled: DisabledDrop}, want: "Disabled (excluded from the generated configuration): 5"},
+		{name: "comment", opts: GenerateOptions{Disabled: DisabledComment}, want: "Disabled (commented out in the generated configuration): 5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := WriteGapReport(&out, BuildGapReportWithOptions(config, tt.opts)); err != nil {
+				t.Fatalf("WriteGapReport: %v", err)
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("report does not contain %q:\n%s", tt.want, out.String())
+			}
+		})
+	}
+}
+
+// csGapConfig has an lb vserver and a cs vserver with a gap of their own
+const csGapConfig = `add server s1 10.0.0.1
+add serviceGroup sg1 HTTP
+bind serviceGroup sg1 s1 80
+add lb vserver vs1 HTTP 10.9.0.1 80 -persistenceType SOURCEIP
+bind lb vserver vs1 sg1
+add cs policy php_pol -rule "HTTP.REQ.URL.PATH.ENDSWITH(\".php\")"
+add cs vserver cs1 HTTP 10.9.0.2 80
+bind cs vserver cs1 -policyName php_pol -targetLBVserver vs1 -priority 10
+bind cs vserver cs1 -lbvserver vs1
+add responder policy page_pol true act_page
+add responder action act_page respondwithhtmlpage page1
+bind cs vserver cs1 -policyName page_pol -priority 10 -type REQUEST
+add authentication vserver auth1 SSL 10.9.0.3 443
+`
+
+func TestGapReportAffectedVServers(t *testing.T) {
+	report := BuildGapReport(parseCitrixText(t, "ns.conf", csGapConfig))
+
+	var got []string
+	for _, affected := range report.Affected {
+		var name string
+		if affected.VServer != nil {
+			name = "vserver " + affected.VServer.Name
+		} else {
+			name = "cs vserver " + affected.CSVServer.Name
+		}
+		got = append(got, fmt.Sprintf("%s: %d", name, len(affected.Reasons)))
+	}
+	want := []string{"vserver vs1: 1", "cs vserver cs1: 2"}
+	if !slices.Equal(got, want) {
+		t.Errorf("affected = %v, want %v", got, want)
+	}
+
+	var out bytes.Buffer
+	if err := WriteGapReport(&out, report); err != nil {
+		t.Fatalf("WriteGapReport: %v", err)
+	}
+	if !strings.Contains(out.String(), "  cs vserver cs1 (10.9.0.2:80)\n") {
+		t.Errorf("report does not list cs1:\n%s", out.String())
+	}
+}
+
+func TestUntranslatedObjectsMatchGapReport(t *testing.T) {
+	config := parseCitrixText(t, "ns.conf", csGapConfig)
+
+	// The parsed authentication vserver, the SOURCEIP persistence, the
+	// ENDSWITH route and the HTML page responder
+	if got, want := len(UntranslatedObjects(config)), 4; got != want {
+		t.Errorf("UntranslatedObjects = %d, want %d", got, want)
+	}
+	if got, want := BuildGapReport(config).Total(), len(UntranslatedObjects(config)); got != want {
+		t.Errorf("gap report total = %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,177 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Vendor load balancing methods used when a virtual server does not set one
+const (
+	defaultCitrixLBMethod = "LEASTCONNECTION"
+	defaultF5LBMethod     = "round-robin"
+)
+
+// Traefik load balancing strategies
+const (
+	strategyWRR = "wrr" // Weighted round robin, the default of every version
+	strategyP2C = "p2c" // Power of two choices by in-flight requests, Traefik 3.4 and later
+	strategyHRW = "hrw" // Highest random weight of the client IP, Traefik 3.5 and later
+)
+
+// lbMethodKind groups vendor methods by the Traefik strategy closest to them
+type lbMethodKind int
+
+const (
+	methodRoundRobin lbMethodKind = iota // Translates exactly to wrr
+	methodLeastLoad                      // Least connections or response time, approximated by p2c
+	methodSourceHash                     // Client IP affinity, translated to hrw
+	methodOther                          // No Traefik equivalent
+)
+
+// lbMethodKinds classifies the Citrix -lbMethod and F5 load-balancing-mode
+// values, lowercased. Unlisted methods have no Traefik equivalent.
+var lbMethodKinds = map[string]lbMethodKind{
+	"roundrobin":                        methodRoundRobin,
+	"round-robin":                       methodRoundRobin,
+	"leastconnection":                   methodLeastLoad,
+	"leastresponsetime":                 methodLeastLoad,
+	"lrtm":                              methodLeastLoad,
+	"least-connections-member":          methodLeastLoad,
+	"least-connections-node":            methodLeastLoad,
+	"weighted-least-connections-member": methodLeastLoad,
+	"weighted-least-connections-node":   methodLeastLoad,
+	"least-sessions":                    methodLeastLoad,
+	"fastest-node":                      methodLeastLoad,
+	"fastest-app-response":              methodLeastLoad,
+	"observed-member":                   methodLeastLoad,
+	"observed-node":                     methodLeastLoad,
+	"predictive-member":                 methodLeastLoad,
+	"predictive-node":                   methodLeastLoad,
+	"sourceiphash":                      methodSourceHash,
+}
+
+// LBMethodTranslation is the Traefik strategy chosen for a vendor load
+// balancing method
+type LBMethodTranslation struct {
+	Method   string // Vendor method
+	Strategy string // Traefik strategy
+	Exact    bool   // The strategy distributes requests the way the method does
+	Note     string // How the behavior changes when it is not exact
+}
+
+// setting returns the strategy to write, empty for the default wrr
+func (t LBMethodTranslation) setting() string {
+	if t.Strategy == strategyWRR {
+		return ""
+	}
+	return t.Strategy
+}
+
+// translateLBMethod picks the Traefik strategy closest to a load balancing
+// method among those the target version supports. An empty method is
+// treated as round robin.
+func translateLBMethod(method string, version TraefikVersion) LBMethodTranslation {
+	translation := LBMethodTranslation{Method: method, Strategy: strategyWRR}
+	if method == "" {
+		translation.Exact = true
+		return translation
+	}
+
+	kind, known := lbMethodKinds[strings.ToLower(method)]
+	if !known {
+		kind = methodOther
+	}
+
+	switch kind {
+	case methodRoundRobin:
+		translation.Exact = true
+	case methodLeastLoad:
+		if version.AtLeast(3, 4) {
+			translation.Strategy = strategyP2C
+			translation.Note = "approximated by picking the less busy of two random servers"
+		} else {
+			translation.Note = "requests are spread evenly regardless of server load; Traefik 3.4 adds p2c"
+		}
+	case methodSourceHash:
+		if version.AtLeast(3, 5) {
+			translation.Strategy = strategyHRW
+			translation.Exact = true
+		} else {
+			translation.Note = "clients are no longer pinned to a server by address; Traefik 3.5 adds hrw"
+		}
+	default:
+		translation.Note = "no Traefik equivalent, requests are spread evenly"
+	}
+
+	return translation
+}
+
+// MethodChange is a virtual server whose load balancing behaves differently
+// after the migration
+type MethodChange struct {
+	VServer *VServerInfo
+	Default bool // The method is the vendor default, not set in the configuration
+	LBMethodTranslation
+}
+
+// LBMethodChanges lists the enabled virtual servers with members whose load
+// balancing method has no exact equivalent in the target Traefik version
+func LBMethodChanges(config *LBConfig, version TraefikVersion) []MethodChange {
+	var changes []MethodChange
+	for _, vserver := range config.VServers {
+		if vserver.Disabled || !hasMembers(config, config.VServerGroups(vserver.Name)) {
+			continue
+		}
+		if translation := translateLBMethod(vserver.LBMethod, version); !translation.Exact {
+			changes = append(changes, MethodChange{VServer: vserver, Default: vserver.LBDefault, LBMethodTranslation: translation})
+		}
+	}
+	return changes
+}
+
+// hasMembers reports whether any of the service groups has a member
+func hasMembers(config *LBConfig, groups []string) bool {
+	for _, group := range groups {
+		if len(config.MembersOf(group)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteMethodChanges renders the load balancing method changes for capacity planning
+func WriteMethodChanges(w io.Writer, changes []MethodChange, version TraefikVersion) error {
+	target := "Traefik " + version.String()
+	if version == (TraefikVersion{}) {
+		target = "any Traefik version"
+	}
+	if len(changes) == 0 {
+		fmt.Fprintf(w, "Every load balancing method has an equivalent in %s\n", target)
+		return nil
+	}
+
+	fmt.Fprintf(w, "Load balancing methods that change in %s: %d virtual server(s)\n", target, len(changes))
+	var configured, defaults []MethodChange
+	for _, change := range changes {
+		if change.Default {
+			defaults = append(defaults, change)
+		} else {
+			configured = append(configured, change)
+		}
+	}
+	writeChanges := func(title string, changes []MethodChange) {
+		if len(changes) == 0 {
+			return
+		}
+		fmt.Fprintf(w, "  %s: %d\n", title, len(changes))
+		for _, change := range changes {
+			vserver := change.VServer
+			fmt.Fprintf(w, "    %s (%s): %s -> %s, %s\n", vserver.Name, VIPKey(vserver.IP, vserver.Port),
+				change.Method, change.Strategy, change.Note)
+		}
+	}
+	writeChanges("Configured methods", configured)
+	writeChanges("Vendor default methods (not set in the configuration)", defaults)
+	return nil
+}
@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTranslateLBMethod(t *testing.T) {
+	tests := []struct {
+		method   string
+		version  string
+		strategy string
+		exact    bool
+	}{
+		{method: "", version: "", strategy: strategyWRR, exact: true},
+		{method: "ROUNDROBIN", version: "", strategy: strategyWRR, exact: true},
+		{method: "round-robin", version: "3.5", strategy: strategyWRR, exact: true},
+		{method: "LEASTCONNECTION", version: "", strategy: strategyWRR},
+		{method: "LEASTCONNECTION", version: "3.3", strategy: strategyWRR},
+		{method: "LEASTCONNECTION", version: "3.4", strategy: strategyP2C},
+		{method: "least-connections-member", version: "3.4", strategy: strategyP2C},
+		{method: "SOURCEIPHASH", version: "3.4", strategy: strategyWRR},
+		{method: "SOURCEIPHASH", version: "3.5", strategy: strategyHRW, exact: true},
+		{method: "TOKEN", version: "3.5", strategy: strategyWRR},
+	}
+
+	for _, tt := range tests {
+		version, err := ParseTraefikVersion(tt.version)
+		if err != nil {
+			t.Fatalf("ParseTraefikVersion(%q): %v", tt.version, err)
+		}
+		got := translateLBMethod(tt.method, version)
+		if got.Strategy != tt.strategy || got.Exact != tt.exact {
+			t.Errorf("translateLBMethod(%q, %q) = %s exact=%t, want %s exact=%t",
+				tt.method, tt.version, got.Strategy, got.Exact, tt.strategy, tt.exact)
+		}
+		if !got.Exact && got.Note == "" {
+			t.Errorf("translateLBMethod(%q, %q) has no note for an inexact translation", tt.method, tt.version)
+		}
+	}
+}
+
+func TestLBMethodChanges(t *testing.T) {
+	config := parseCitrixText(t, "ns.conf", `add server s1 10.0.0.1
+add serviceGroup sg1 HTTP
+bind serviceGroup sg1 s1 80
+add lb vserver implicit HTTP 10.9.0.1 80
+bind lb vserver implicit sg1
+add lb vserver explicit HTTP 10.9.0.2 80 -lbMethod LEASTRESPONSETIME
+bind lb vserver explicit sg1
+add lb vserver restored HTTP 10.9.0.3 80 -lbMethod TOKEN
+bind lb vserver restored sg1
+unset lb vserver restored -lbMethod
+add lb vserver roundrobin HTTP 10.9.0.4 80 -lbMethod ROUNDROBIN
+bind lb vserver roundrobin sg1
+add lb vserver unbound-vs HTTP 10.9.0.5 80 -lbMethod TOKEN
+add lb vserver disabled HTTP 10.9.0.6 80 -lbMethod TOKEN -state DISABLED
+bind lb vserver disabled sg1
+`)
+
+	var got []string
+	for _, change := range LBMethodChanges(config, TraefikVersion{}) {
+		entry := change.VServer.Name + "=" + change.Method
+		if change.Default {
+			entry += " (default)"
+		}
+		got = append(got, entry)
+	}
+	want := []string{"implicit=LEASTCONNECTION (default)", "explicit=LEASTRESPONSETIME", "restored=LEASTCONNECTION (default)"}
+	if !slices.Equal(got, want) {
+		t.Errorf("changes = %v, want %v", got, want)
+	}
+}
+
+func TestWriteMethodChangesSeparatesDefaults(t *testing.T) {
+	config := parseCitrixText(t, "ns.conf", `add server s1 10.0.0.1
+add serviceGroup sg1 HTTP
+bind serviceGroup sg1 s1 80
+add lb vserver implicit HTTP 10.9.0.1 80
+bind lb vserver implicit sg1
+add lb vserver explicit HTTP 10.9.0.2 80 -lbMethod LEASTRESPONSETIME
+bind lb vserver explicit sg1
+`)
+
+	var out bytes.Buffer
+	WriteMethodChanges(&out, LBMethodChanges(config, TraefikVersion{}), TraefikVersion{})
+	report := out.String()
+	configured := strings.Index(report, "Configured methods: 1")
+	defaults := strings.Index(report, "Vendor default methods (not set in the configuration): 1")
+	if configured < 0 || defaults < 0 {
+		t.Fatalf("missing sections in report:\n%s", report)
+	}
+	if explicit := strings.Index(report, "explicit"); explicit < configured || explicit > defaults {
+		t.Errorf("explicit is not listed under the configured methods:\n%s", report)
+	}
+}
@@ -0,0 +1,592 @@
+package parser
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Source is one parsed appliance configuration taking part in a merge
+type Source struct {
+	Name   string // Shown in diagnostics, usually the input file path
+	Prefix string // Optional namespace prepended to every object name
+	Config *LBConfig
+}
+
+// ApplyPrefix namespaces every object name in the configuration as
+// "<prefix>-<name>" and relinks the model. An empty prefix is a no-op.
+func (c *LBConfig) ApplyPrefix(prefix string) {
+	if prefix == "" {
+		return
+	}
+	rename := func(name string) string {
+		if name == "" {
+			return name
+		}
+		return prefix + "-" + name
+	}
+
+	for _, server := range c.Servers {
+		server.Name = rename(server.Name)
+	}
+	for _, vserver := range c.VServers {
+		vserver.Name = rename(vserver.Name)
+		vserver.Backup.VServer = rename(vserver.Backup.VServer)
+	}
+	for _, def := range c.ServiceGroupDefs {
+		def.Name = rename(def.Name)
+	}
+	for _, member := range c.ServiceGroups {
+		member.Name = rename(member.Name)
+		member.ServerName = rename(member.ServerName)
+	}
+	for _, binding := range c.VServerBindings {
+		binding.VServerName = rename(binding.VServerName)
+		binding.ServiceName = rename(binding.ServiceName)
+		// Untranslated policies keep the name they are reported with
+		if c.ResponderPolicyByName(binding.PolicyName) != nil || c.RewritePolicyByName(binding.PolicyName) != nil {
+			binding.PolicyName = rename(binding.PolicyName)
+		}
+	}
+	definedMonitors := make(map[string]bool)
+	for _, monitor := range c.Monitors {
+		definedMonitors[monitor.Name] = true
+		monitor.Name = rename(monitor.Name)
+	}
+	for _, binding := range c.MonitorBindings {
+		binding.ServiceName = rename(binding.ServiceName)
+		// Built-in monitors exist on every appliance and keep their names
+		if definedMonitors[binding.MonitorName] || builtinMonitors[strings.ToLower(binding.MonitorName)] == nil {
+			binding.MonitorName = rename(binding.MonitorName)
+		}
+	}
+	for _, vserver := range c.CSVServers {
+		vserver.Name = rename(vserver.Name)
+	}
+	for _, action := range c.CSActions {
+		action.Name = rename(action.Name)
+		action.TargetLBVServer = rename(action.TargetLBVServer)
+	}
+	for _, policy := range c.CSPolicies {
+		policy.Name = rename(policy.Name)
+		policy.Action = rename(policy.Action)
+	}
+	for _, binding := range c.CSBindings {
+		binding.VServerName = rename(binding.VServerName)
+		binding.PolicyName = rename(binding.PolicyName)
+		binding.TargetLBVServer = rename(binding.TargetLBVServer)
+	}
+	for _, action := range c.ResponderActions {
+		action.Name = rename(action.Name)
+	}
+	for _, policy := range c.ResponderPolicies {
+		policy.Name = rename(policy.Name)
+		if !isBuiltinResponderAction(policy.Action) {
+			policy.Action = rename(policy.Action)
+		}
+	}
+	for _, action := range c.RewriteActions {
+		action.Name = rename(action.Name)
+	}
+	for _, policy := range c.RewritePolicies {
+		policy.Name = rename(policy.Name)
+		if !isBuiltinRewriteAction(policy.Action) {
+			policy.Action = rename(policy.Action)
+		}
+	}
+	for _, certKey := range c.CertKeys {
+		certKey.Name = rename(certKey.Name)
+	}
+	for _, binding := range c.SSLBindings {
+		binding.VServerName = rename(binding.VServerName)
+		binding.CertKeyName = rename(binding.CertKeyName)
+	}
+	// Built-in cipher groups and ciphers exist on every appliance and keep their names
+	definedGroups := make(map[string]bool)
+	for _, group := range c.CipherGroups {
+		definedGroups[group.Name] = true
+	}
+	renameCiphers := func(ciphers []SSLCipher) {
+		for i := range ciphers {
+			if definedGroups[ciphers[i].Name] {
+				ciphers[i].Name = rename(ciphers[i].Name)
+			}
+		}
+	}
+	for _, ssl := range c.SSLVServers {
+		ssl.Name = rename(ssl.Name)
+		ssl.Profile = rename(ssl.Profile)
+		renameCiphers(ssl.Ciphers)
+	}
+	for _, profile := range c.SSLProfiles {
+		profile.Name = rename(profile.Name)
+		renameCiphers(profile.Ciphers)
+	}
+	for _, group := range c.CipherGroups {
+		group.Name = rename(group.Name)
+	}
+	for _, ssl := range c.SSLServiceGroups {
+		ssl.Name = rename(ssl.Name)
+		for i := range ssl.CAs {
+			ssl.CAs[i] = rename(ssl.CAs[i])
+		}
+		ssl.ClientCert = rename(ssl.ClientCert)
+	}
+	for _, object := range c.Untranslated {
+		object.VServer = rename(object.VServer)
+		object.CSVServer = rename(object.CSVServer)
+		object.ServiceGroup = rename(object.ServiceGroup)
+	}
+
+	c.Link()
+}
+
+// MergeConfigs merges several appliance configurations into one model. Each
+// source is namespaced with its prefix first. Objects that are identical to
+// one from an earlier source (as in an HA pair) are merged silently; objects
+// that clash with an earlier source are reported as conflicts and dropped,
+// so the earliest source wins.
+func MergeConfigs(sources []Source) *LBConfig {
+	merged := NewLBConfig(ConfigTypeUnknown)
+	if len(sources) > 0 {
+		merged.Vendor = sources[0].Config.Vendor
+	}
+
+	var names []string
+	seenUntranslated := make(map[string]bool)
+	for i, source := range sources {
+		config := source.Config
+		config.ApplyPrefix(source.Prefix)
+		names = append(names, source.Name)
+		if config.Vendor != merged.Vendor {
+			merged.Vendor = ConfigTypeUnknown
+		}
+
+		// Parser findings keep their own file positions
+		merged.Diagnostics = append(merged.Diagnostics, config.Diagnostics...)
+
+		conflict := func(pos Position, code, format string, args ...interface{}) {
+			merged.AddDiagnostic(Diagnostic{
+				Position: pos,
+				Severity: SeverityError,
+				Code:     code,
+				Message:  fmt.Sprintf(format, args...),
+			})
+		}
+		identical := 0
+
+		// Servers clash when the same name points at a different address. The
+		// members of this source must keep reaching their own address, so the
+		// server is namespaced together with every member that refers to it.
+		for _, server := range config.Servers {
+			existing := merged.ServerByName(server.Name)
+			switch {
+			case existing == nil:
+			case existing.IP == server.IP:
+				identical++
+				continue
+			default:
+				name := server.Name
+				config.RenameServer(name, uniqueServerName(merged, config, source, name))
+				merged.AddDiagnostic(Diagnostic{
+					Position: server.Pos,
+					Severity: SeverityWarning,
+					Code:     "server-conflict",
+					Message: fmt.Sprintf("server '%s' (%s) conflicts with '%s' (%s) defined at %s; renamed to '%s' with its members",
+						name, server.IP, existing.Name, existing.IP, existing.Pos, server.Name),
+				})
+			}
+			merged.AddServer(server)
+		}
+
+		// Service groups clash when the same name has a different protocol or member list
+		skippedGroups := make(map[string]bool)
+		for _, name := range config.ServiceGroupNames() {
+			if !merged.hasServiceGroup(name) {
+				continue
+			}
+			skippedGroups[name] = true
+			if groupSignature(merged, name) == groupSignature(config, name) {
+				identical++
+				continue
+			}
+			conflict(groupPosition(config, name), "service-conflict", "service '%s' from %s conflicts with the service of the same name defined at %s",
+				name, source.Name, groupPosition(merged, name))
+		}
+		for _, def := range config.ServiceGroupDefs {
+			if !skippedGroups[def.Name] {
+				merged.AddServiceGroupDef(def)
+			}
+		}
+		for _, member := range config.ServiceGroups {
+			if !skippedGroups[member.Name] {
+				merged.AddServiceGroup(member)
+			}
+		}
+
+		// Monitors clash when the same name is defined with different settings
+		for _, monitor := range config.Monitors {
+			existing := merged.MonitorByName(monitor.Name)
+			switch {
+			case existing == nil:
+				merged.AddMonitor(monitor)
+			case monitorSignature(existing) == monitorSignature(monitor):
+				identical++
+			default:
+				conflict(monitor.Pos, "monitor-conflict", "monitor '%s' conflicts with the monitor of the same name defined at %s",
+					monitor.Name, existing.Pos)
+			}
+		}
+		for _, binding := range config.MonitorBindings {
+			if !skippedGroups[binding.ServiceName] {
+				merged.AddMonitorBinding(binding)
+			}
+		}
+		for _, ssl := range config.SSLServiceGroups {
+			if !skippedGroups[ssl.Name] {
+				merged.AddSSLServiceGroup(ssl)
+			}
+		}
+
+		// Virtual servers clash when they listen on the same VIP:port as an lb
+		// or cs vserver, or reuse a name; those without an address only clash
+		// by name
+		skippedVServers := make(map[string]bool)
+		for _, vserver := range config.VServers {
+			var byVIP *VServerInfo
+			var csByVIP *CSVServer
+			if vserver.Addressable() {
+				byVIP = merged.VServerByVIP(vserver.IP, vserver.Port)
+				csByVIP = merged.CSVServerByVIP(vserver.IP, vserver.Port)
+			}
+			byName := merged.VServerByName(vserver.Name)
+			switch {
+			case byVIP == nil && csByVIP == nil && byName == nil:
+				merged.AddVServer(vserver)
+				continue
+			case byName != nil && (byVIP == byName || !(vserver.Addressable() || byName.Addressable())) &&
+				vserverSignature(merged, byName) == vserverSignature(config, vserver):
+				identical++
+			case byVIP != nil:
+				conflict(vserver.Pos, "vip-conflict", "vserver '%s' listens on %s, already used by vserver '%s' defined at %s",
+					vserver.Name, VIPKey(vserver.IP, vserver.Port), byVIP.Name, byVIP.Pos)
+			case csByVIP != nil:
+				conflict(vserver.Pos, "vip-conflict", "vserver '%s' listens on %s, already used by cs vserver '%s' defined at %s",
+					vserver.Name, VIPKey(vserver.IP, vserver.Port), csByVIP.Name, csByVIP.Pos)
+			default:
+				conflict(vserver.Pos, "vserver-conflict", "vserver '%s' on %s conflicts with the vserver of the same name on %s defined at %s",
+					vserver.Name, VIPKey(vserver.IP, vserver.Port), VIPKey(byName.IP, byName.Port), byName.Pos)
+			}
+			skippedVServers[vserver.Name] = true
+		}
+		for _, binding := range config.VServerBindings {
+			if !skippedVServers[binding.VServerName] {
+				merged.AddVServerBinding(binding)
+			}
+		}
+		// Content switching objects clash when the same name is defined differently
+		for _, action := range config.CSActions {
+			existing := merged.CSActionByName(action.Name)
+			switch {
+			case existing == nil:
+				merged.AddCSAction(action)
+			case existing.TargetLBVServer == action.TargetLBVServer && existing.TargetExpr == action.TargetExpr:
+				identical++
+			default:
+				conflict(action.Pos, "cs-conflict", "cs action '%s' conflicts with the cs action of the same name defined at %s",
+					action.Name, existing.Pos)
+			}
+		}
+		for _, policy := range config.CSPolicies {
+			existing := merged.CSPolicyByName(policy.Name)
+			switch {
+			case existing == nil:
+				merged.AddCSPolicy(policy)
+			case csPolicySignature(existing) == csPolicySignature(policy):
+				identical++
+			default:
+				conflict(policy.Pos, "cs-conflict", "cs policy '%s' conflicts with the cs policy of the same name defined at %s",
+					policy.Name, existing.Pos)
+			}
+		}
+		for _, action := range config.ResponderActions {
+			existing := merged.ResponderActionByName(action.Name)
+			switch {
+			case existing == nil:
+				merged.AddResponderAction(action)
+			case responderActionSignature(existing) == responderActionSignature(action):
+				identical++
+			default:
+				conflict(action.Pos, "responder-conflict", "responder action '%s' conflicts with the responder action of the same name defined at %s",
+					action.Name, existing.Pos)
+			}
+		}
+		for _, policy := range config.ResponderPolicies {
+			existing := merged.ResponderPolicyByName(policy.Name)
+			switch {
+			case existing == nil:
+				merged.AddResponderPolicy(policy)
+			case existing.Rule == policy.Rule && existing.Action == policy.Action:
+				identical++
+			default:
+				conflict(policy.Pos, "responder-conflict", "responder policy '%s' conflicts with the responder policy of the same name defined at %s",
+					policy.Name, existing.Pos)
+			}
+		}
+		for _, action := range config.RewriteActions {
+			existing := merged.RewriteActionByName(action.Name)
+			switch {
+			case existing == nil:
+				merged.AddRewriteAction(action)
+			case rewriteActionSignature(existing) == rewriteActionSignature(action):
+				identical++
+			default:
+				conflict(action.Pos, "rewrite-conflict", "rewrite action '%s' conflicts with the rewrite action of the same name defined at %s",
+					action.Name, existing.Pos)
+			}
+		}
+		for _, policy := range config.RewritePolicies {
+			existing := merged.RewritePolicyByName(policy.Name)
+			switch {
+			case existing == nil:
+				merged.AddRewritePolicy(policy)
+			case existing.Rule == policy.Rule && existing.Action == policy.Action:
+				identical++
+			default:
+				conflict(policy.Pos, "rewrite-conflict", "rewrite policy '%s' conflicts with the rewrite policy of the same name defined at %s",
+					policy.Name, existing.Pos)
+			}
+		}
+		// Content switching vservers clash like lb vservers, on the VIP:port of
+		// either kind or by name
+		skippedCSVServers := make(map[string]bool)
+		for _, vserver := range config.CSVServers {
+			var byVIP *CSVServer
+			var lbByVIP *VServerInfo
+			if vserver.Addressable() {
+				byVIP = merged.CSVServerByVIP(vserver.IP, vserver.Port)
+				lbByVIP = merged.VServerByVIP(vserver.IP, vserver.Port)
+			}
+			existing := merged.CSVServerByName(vserver.Name)
+			switch {
+			case byVIP == nil && lbByVIP == nil && existing == nil:
+				merged.AddCSVServer(vserver)
+				continue
+			case existing != nil && (byVIP == existing || !(vserver.Addressable() || existing.Addressable())) &&
+				csVServerSignature(merged, existing) == csVServerSignature(config, vserver):
+				identical++
+			case byVIP != nil:
+				conflict(vserver.Pos, "vip-conflict", "cs vserver '%s' listens on %s, already used by cs vserver '%s' defined at %s",
+					vserver.Name, VIPKey(vserver.IP, vserver.Port), byVIP.Name, byVIP.Pos)
+			case lbByVIP != nil:
+				conflict(vserver.Pos, "vip-conflict", "cs vserver '%s' listens on %s, already used by vserver '%s' defined at %s",
+					vserver.Name, VIPKey(vserver.IP, vserver.Port), lbByVIP.Name, lbByVIP.Pos)
+			default:
+				conflict(vserver.Pos, "cs-conflict", "cs vserver '%s' on %s conflicts with the cs vserver of the same name on %s defined at %s",
+					vserver.Name, VIPKey(vserver.IP, vserver.Port), VIPKey(existing.IP, existing.Port), existing.Pos)
+			}
+			skippedCSVServers[vserver.Name] = true
+		}
+		for _, binding := range config.CSBindings {
+			if !skippedCSVServers[binding.VServerName] {
+				merged.AddCSBinding(binding)
+			}
+		}
+
+		// Certificates clash when the same certKey name uses different files
+		for _, certKey := range config.CertKeys {
+			existing := merged.CertKeyByName(certKey.Name)
+			switch {
+			case existing == nil:
+				merged.AddCertKey(certKey)
+			case existing.CertFile == certKey.CertFile && existing.KeyFile == certKey.KeyFile:
+				identical++
+			default:
+				conflict(certKey.Pos, "certkey-conflict", "certKey '%s' conflicts with the certKey of the same name defined at %s",
+					certKey.Name, existing.Pos)
+			}
+		}
+		for _, binding := range config.SSLBindings {
+			if !skippedVServers[binding.VServerName] && !skippedCSVServers[binding.VServerName] {
+				merged.AddSSLBinding(binding)
+			}
+		}
+		for _, profile := range config.SSLProfiles {
+			existing := merged.SSLProfileByName(profile.Name)
+			switch {
+			case existing == nil:
+				merged.AddSSLProfile(profile)
+			case sslSettingsSignature(existing.SSLSettings) == sslSettingsSignature(profile.SSLSettings):
+				identical++
+			default:
+				conflict(profile.Pos, "ssl-profile-conflict", "ssl profile '%s' conflicts with the ssl profile of the same name defined at %s",
+					profile.Name, existing.Pos)
+			}
+		}
+		for _, group := range config.CipherGroups {
+			existing := merged.CipherGroupByName(group.Name)
+			switch {
+			case existing == nil:
+				merged.AddCipherGroup(group)
+			case cipherNames(existing.Ciphers) == cipherNames(group.Ciphers):
+				identical++
+			default:
+				conflict(group.Pos, "cipher-group-conflict", "cipher group '%s' conflicts with the cipher group of the same name defined at %s",
+					group.Name, existing.Pos)
+			}
+		}
+		for _, ssl := range config.SSLVServers {
+			if !skippedVServers[ssl.Name] && !skippedCSVServers[ssl.Name] {
+				merged.AddSSLVServer(ssl)
+			}
+		}
+
+		for _, object := range config.Untranslated {
+			key := untranslatedKey(object)
+			if skippedVServers[object.VServer] || skippedCSVServers[object.CSVServer] || seenUntranslated[key] {
+				continue
+			}
+			seenUntranslated[key] = true
+			merged.AddUntranslated(object)
+		}
+
+		for key, value := range config.Metadata {
+			merged.Metadata[fmt.Sprintf("source.%d.%s", i, key)] = value
+		}
+		if identical > 0 {
+			merged.AddDiagnostic(Diagnostic{
+				Position: Position{File: source.Name},
+				Severity: SeverityInfo,
+				Code:     "merged-duplicates",
+				Message:  fmt.Sprintf("%d object(s) identical to earlier sources were merged", identical),
+			})
+		}
+	}
+
+	merged.Metadata["sources"] = strings.Join(names, ",")
+	merged.Link()
+	return merged
+}
+
+// uniqueServerName returns a name for a server of the source that clashes
+// with an earlier source: the name prefixed with the source's file name, and
+// numbered if that is taken too
+func uniqueServerName(merged, config *LBConfig, source Source, name string) string {
+	base := filepath.Base(source.Name)
+	candidate := strings.TrimSuffix(base, filepath.Ext(base)) + "-" + name
+	for n := 2; merged.ServerByName(candidate) != nil || config.ServerByName(candidate) != nil; n++ {
+		candidate = fmt.Sprintf("%s-%s-%d", strings.TrimSuffix(base, filepath.Ext(base)), name, n)
+	}
+	return candidate
+}
+
+// untranslatedKey identifies an untranslated object independently of the file it came from
+func untranslatedKey(object *UntranslatedObject) string {
+	return strings.Join([]string{object.ObjectType, object.Text, object.VServer, object.ServiceGroup}, "\x00")
+}
+
+// groupSignature describes a service group by protocol, resolved members and
+// SSL settings so that copies from different appliances can be compared
+func groupSignature(config *LBConfig, name string) string {
+	protocol := ""
+	if def := config.ServiceGroupDefByName(name); def != nil {
+		protocol = strings.ToUpper(def.Protocol)
+	}
+
+	var members []string
+	for _, member := range config.MembersOf(name) {
+		address := member.ServerName
+		if server := config.ServerByName(member.ServerName); server != nil {
+			address = server.IP
+		}
+		members = append(members, address+":"+member.Port)
+	}
+	sort.Strings(members)
+
+	signature := protocol + "|" + strings.Join(members, ",")
+	if ssl := config.SSLServiceGroupByName(name); ssl != nil {
+		signature += fmt.Sprintf("|%t|%s|%t|%s|%s|%s", ssl.ServerAuth, ssl.CommonName, ssl.SNI, ssl.ServerName,
+			strings.Join(ssl.CAs, ","), ssl.ClientCert)
+	}
+	return signature
+}
+
+// groupPosition returns where a service group was first defined or bound
+func groupPosition(config *LBConfig, name string) Position {
+	if def := config.ServiceGroupDefByName(name); def != nil {
+		return def.Pos
+	}
+	if members := config.MembersOf(name); len(members) > 0 {
+		return members[0].Pos
+	}
+	return Position{}
+}
+
+// monitorSignature describes a monitor by its settings
+func monitorSignature(monitor *Monitor) string {
+	copied := *monitor
+	copied.Name, copied.Pos, copied.Source = "", Position{}, ""
+	return fmt.Sprintf("%+v", copied)
+}
+
+// vserverSignature describes a virtual server by protocol, load balancing
+// method, persistence, backup and bound services
+func vserverSignature(config *LBConfig, vserver *VServerInfo) string {
+	var services []string
+	for _, binding := range config.BindingsOf(vserver.Name) {
+		services = append(services, binding.ServiceName+"/"+binding.PolicyName+"/"+binding.Priority)
+	}
+	sort.Strings(services)
+
+	return fmt.Sprintf("%s|%s|%+v|%+v|%s", strings.ToUpper(vserver.Protocol), strings.ToUpper(vserver.LBMethod),
+		vserver.Persistence, vserver.Backup, strings.Join(services, ","))
+}
+
+// csPolicySignature describes a content switching policy by its settings
+func csPolicySignature(policy *CSPolicy) string {
+	return strings.Join([]string{policy.Rule, policy.URL, policy.Domain, policy.Action}, "\x00")
+}
+
+// responderActionSignature describes a responder action by its settings
+func responderActionSignature(action *ResponderAction) string {
+	return strings.Join([]string{strings.ToLower(action.Type), action.Target, action.StatusCode}, "\x00")
+}
+
+// rewriteActionSignature describes a rewrite action by its settings
+func rewriteActionSignature(action *RewriteAction) string {
+	return strings.Join([]string{strings.ToLower(action.Type), action.Target, action.Value, action.Search}, "\x00")
+}
+
+// sslSettingsSignature describes the SSL settings of a profile by protocols,
+// client authentication and ciphers
+func sslSettingsSignature(settings SSLSettings) string {
+	var protocols []string
+	for name, enabled := range settings.Protocols {
+		protocols = append(protocols, fmt.Sprintf("%s=%t", name, enabled))
+	}
+	sort.Strings(protocols)
+	return strings.Join([]string{strings.Join(protocols, ","), strings.ToUpper(settings.SNIHostMatch),
+		fmt.Sprint(settings.ClientAuth), strings.ToLower(settings.ClientCert), cipherNames(settings.Ciphers)}, "\x00")
+}
+
+// cipherNames lists bound ciphers in binding order
+func cipherNames(ciphers []SSLCipher) string {
+	names := make([]string, len(ciphers))
+	for i, cipher := range ciphers {
+		names[i] = cipher.Name
+	}
+	return strings.Join(names, ",")
+}
+
+// csVServerSignature describes a content switching virtual server by
+// protocol, address and bound policies
+func csVServerSignature(config *LBConfig, vserver *CSVServer) string {
+	var bindings []string
+	for _, binding := range config.CSBindingsOf(vserver.Name) {
+		bindings = append(bindings, binding.PolicyName+"/"+binding.Priority+"/"+binding.TargetLBVServer+"/"+binding.GotoExpression)
+	}
+	sort.Strings(bindings)
+
+	return fmt.Sprintf("%s|%s|%s", strings.ToUpper(vserver.Protocol), VIPKey(vserver.IP, vserver.Port), strings.Join(bindings, ","))
+}
@@ -0,0 +1,178 @@
+package parser
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMergeConfigsServerConflicts(t *testing.T) {
+	tests := []struct {
+		name      string
+		a, b      string
+		wantURLs  map[string][]string
+		wantCodes []string
+	}{
+		{
+			name: "same name, different address keeps each member on its own server",
+			a: "add server s1 10.0.0.1\nadd serviceGroup sgA HTTP\nbind serviceGroup sgA s1 80\n" +
+				"add lb vserver vsA HTTP 10.9.0.1 80\nbind lb vserver vsA sgA\n",
+			b: "add server s1 10.0.0.2\nadd serviceGroup sgB HTTP\nbind serviceGroup sgB s1 80\n" +
+				"add lb vserver vsB HTTP 10.9.0.2 80\nbind lb vserver vsB sgB\n",
+			wantURLs: map[string][]string{
+				"vsA": {"http://10.0.0.1:80"},
+				"vsB": {"http://10.0.0.2:80"},
+			},
+			wantCodes: []string{"server-conflict"},
+		},
+		{
+			name: "standalone service follows its renamed server",
+			a:    "add server s1 10.0.0.1\nadd service svcA s1 HTTP 80\n",
+			b:    "add server s1 10.0.0.2\nadd service svcB s1 HTTP 8080\n",
+			wantURLs: map[string][]string{
+				"svcA": {"http://10.0.0.1:80"},
+				"svcB": {"http://10.0.0.2:8080"},
+			},
+			wantCodes: []string{"server-conflict"},
+		},
+		{
+			name: "identical servers of an HA pair are merged",
+			a:    "add server s1 10.0.0.1\nadd serviceGroup sg1 HTTP\nbind serviceGroup sg1 s1 80\n",
+			b:    "add server s1 10.0.0.1\nadd serviceGroup sg1 HTTP\nbind serviceGroup sg1 s1 80\n",
+			wantURLs: map[string][]string{
+				"sg1": {"http://10.0.0.1:80"},
+			},
+			wantCodes: []string{"merged-duplicates"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := MergeConfigs([]Source{
+				{Name: "a.conf", Config: parseCitrixText(t, "a.conf", tt.a)},
+				{Name: "b.conf", Config: parseCitrixText(t, "b.conf", tt.b)},
+			})
+
+			if got := diagnosticCodes(merged.Diagnostics); !slices.Equal(got, tt.wantCodes) {
+				t.Errorf("diagnostics = %v, want %v", got, tt.wantCodes)
+			}
+			services := GenerateTraefikConfig(merged).HTTP.Services
+			if len(services) != len(tt.wantURLs) {
+				t.Errorf("got %d services, want %d", len(services), len(tt.wantURLs))
+			}
+			for name, want := range tt.wantURLs {
+				if got := serverURLs(services[name]); !slices.Equal(got, want) {
+					t.Errorf("service %s servers = %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeConfigsRenamedServerName(t *testing.T) {
+	merged := MergeConfigs([]Source{
+		{Name: "dc1/a.conf", Config: parseCitrixText(t, "a.conf", "add server s1 10.0.0.1\n")},
+		{Name: "dc2/b.conf", Config: parseCitrixText(t, "b.conf", "add server s1 10.0.0.2\nadd server b-s1 10.0.0.3\n")},
+	})
+
+	server := merged.ServerByName("b-s1-2")
+	if server == nil || server.IP != "10.0.0.2" {
+		t.Fatalf("renamed server = %+v, want b-s1-2 on 10.0.0.2", server)
+	}
+}
+
+func TestMergeConfigsPrefixes(t *testing.T) {
+	text := "add server s1 10.0.0.1\nadd serviceGroup sg1 HTTP\nbind serviceGroup sg1 s1 80\n" +
+		"add lb vserver vs1 HTTP 10.9.0.1 80\nbind lb vserver vs1 sg1\n"
+	merged := MergeConfigs([]Source{
+		{Name: "a.conf", Prefix: "dc1", Config: parseCitrixText(t, "a.conf", text)},
+		{Name: "b.conf", Prefix: "dc2", Config: parseCitrixText(t, "b.conf", text)},
+	})
+
+	if merged.VServerByName("dc1-vs1") == nil {
+		t.Errorf("dc1-vs1 missing")
+	}
+	if merged.VServerByName("dc2-vs1") != nil {
+		t.Errorf("dc2-vs1 should be dropped: it listens on the VIP of dc1-vs1")
+	}
+	if got := diagnosticCodes(merged.Diagnostics); !slices.Equal(got, []string{"vip-conflict"}) {
+		t.Errorf("diagnostics = %v, want [vip-conflict]", got)
+	}
+	if members := merged.MembersOf("dc2-sg1"); len(members) != 1 || members[0].ServerName != "dc2-s1" {
+		t.Errorf("dc2-sg1 members = %v, want one member on dc2-s1", members)
+	}
+}
+
+func TestMergeConfigsVIPConflicts(t *testing.T) {
+	const pool = "add server s1 10.0.0.1\nadd serviceGroup sg1 HTTP\nbind serviceGroup sg1 s1 80\n" +
+		"add lb vserver web HTTP 0.0.0.0 0\nbind lb vserver web sg1\n"
+	tests := []struct {
+		name      string
+		a, b      string
+		wantLB    []string
+		wantCS    []string
+		wantCodes []string
+	}{
+		{
+			name:      "cs vservers on the same VIP",
+			a:         pool + "add cs vserver csA HTTP 10.9.0.1 80\nbind cs vserver csA -lbvserver web\n",
+			b:         pool + "add cs vserver csB HTTP 10.9.0.1 80\nbind cs vserver csB -lbvserver web\n",
+			wantLB:    []string{"web"},
+			wantCS:    []string{"csA"},
+			wantCodes: []string{"vip-conflict", "merged-duplicates"},
+		},
+		{
+			name:      "cs vserver on the VIP of an lb vserver",
+			a:         pool + "add lb vserver vsA HTTP 10.9.0.1 80\nbind lb vserver vsA sg1\n",
+			b:         pool + "add cs vserver csB HTTP 10.9.0.1 80\nbind cs vserver csB -lbvserver web\n",
+			wantLB:    []string{"web", "vsA"},
+			wantCodes: []string{"vip-conflict", "merged-duplicates"},
+		},
+		{
+			name:      "lb vserver on the VIP of a cs vserver",
+			a:         pool + "add cs vserver csA HTTP 10.9.0.1 80\nbind cs vserver csA -lbvserver web\n",
+			b:         pool + "add lb vserver vsB HTTP 10.9.0.1 80\nbind lb vserver vsB sg1\n",
+			wantLB:    []string{"web"},
+			wantCS:    []string{"csA"},
+			wantCodes: []string{"vip-conflict", "merged-duplicates"},
+		},
+		{
+			name:      "identical cs vservers of an HA pair",
+			a:         pool + "add cs vserver csA HTTP 10.9.0.1 80\nbind cs vserver csA -lbvserver web\n",
+			b:         pool + "add cs vserver csA HTTP 10.9.0.1 80\nbind cs vserver csA -lbvserver web\n",
+			wantLB:    []string{"web"},
+			wantCS:    []string{"csA"},
+			wantCodes: []string{"merged-duplicates"},
+		},
+		{
+			name:      "cs vservers without an address",
+			a:         pool + "add cs vserver csA HTTP 0.0.0.0 0\n",
+			b:         pool + "add cs vserver csB HTTP 0.0.0.0 0\n",
+			wantLB:    []string{"web"},
+			wantCS:    []string{"csA", "csB"},
+			wantCodes: []string{"merged-duplicates"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := MergeConfigs([]Source{
+				{Name: "a.conf", Config: parseCitrixText(t, "a.conf", tt.a)},
+				{Name: "b.conf", Config: parseCitrixText(t, "b.conf", tt.b)},
+			})
+
+			if got := diagnosticCodes(merged.Diagnostics); !slices.Equal(got, tt.wantCodes) {
+				t.Errorf("diagnostics = %v, want %v", got, tt.wantCodes)
+			}
+			var lb, cs []string
+			for _, vserver := range merged.VServers {
+				lb = append(lb, vserver.Name)
+			}
+			for _, vserver := range merged.CSVServers {
+				cs = append(cs, vserver.Name)
+			}
+			if !slices.Equal(lb, tt.wantLB) || !slices.Equal(cs, tt.wantCS) {
+				t.Errorf("vservers = %v, cs vservers = %v, want %v and %v", lb, cs, tt.wantLB, tt.wantCS)
+			}
+		})
+	}
+}
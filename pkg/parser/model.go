@@ -0,0 +1,1214 @@
+package parser
+
+import "slices"
+
+// LBConfig is the vendor-neutral load balancer model produced by every parser.
+// Objects are kept in source order, typed references between them are
+// resolved by Link, and indexed lookups are maintained as objects are added.
+type LBConfig struct {
+	Vendor            ConfigType
+	Servers           []*ServerInfo
+	VServers          []*VServerInfo
+	ServiceGroupDefs  []*ServiceGroupDef
+	ServiceGroups     []*ServiceGroup
+	VServerBindings   []*VServerBinding
+	Monitors          []*Monitor
+	MonitorBindings   []*MonitorBinding
+	CSVServers        []*CSVServer
+	CSActions         []*CSAction
+	CSPolicies        []*CSPolicy
+	CSBindings        []*CSBinding
+	ResponderActions  []*ResponderAction
+	ResponderPolicies []*ResponderPolicy
+	RewriteActions    []*RewriteAction
+	RewritePolicies   []*RewritePolicy
+	CertKeys          []*CertKey
+	SSLBindings       []*SSLBinding
+	SSLVServers       []*SSLVServer
+	SSLProfiles       []*SSLProfile
+	CipherGroups      []*CipherGroup
+	SSLServiceGroups  []*SSLServiceGroup
+	Untranslated      []*UntranslatedObject // Objects seen but not converted, in source order
+	Metadata          map[string]string     // Vendor-specific, configuration-wide metadata
+	Diagnostics       Diagnostics           // Problems reported while parsing, in source order
+
+	serversByName      map[string]*ServerInfo
+	groupsByName       map[string]*ServiceGroupDef
+	membersByGroup     map[string][]*ServiceGroup
+	vserversByName     map[string]*VServerInfo
+	vserversByVIP      map[string][]*VServerInfo
+	bindingsByVServer  map[string][]*VServerBinding
+	monitorsByName     map[string]*Monitor
+	monitorsByGroup    map[string][]*MonitorBinding
+	csVServersByName   map[string]*CSVServer
+	csVServersByVIP    map[string][]*CSVServer
+	csActionsByName    map[string]*CSAction
+	csPoliciesByName   map[string]*CSPolicy
+	csBindingsByName   map[string][]*CSBinding
+	respActionsByName  map[string]*ResponderAction
+	respPoliciesByName map[string]*ResponderPolicy
+	rwActionsByName    map[string]*RewriteAction
+	rwPoliciesByName   map[string]*RewritePolicy
+	certKeysByName     map[string]*CertKey
+	sslBindingsByName  map[string][]*SSLBinding
+	sslVServersByName  map[string]*SSLVServer
+	sslProfilesByName  map[string]*SSLProfile
+	cipherGroupsByName map[string]*CipherGroup
+	sslGroupsByName    map[string]*SSLServiceGroup
+	groupSeen          map[string]bool
+	groupOrder         []string
+}
+
+// NewLBConfig creates an empty load balancer model for the given vendor
+func NewLBConfig(vendor ConfigType) *LBConfig {
+	cfg := &LBConfig{
+		Vendor:   vendor,
+		Metadata: make(map[string]string),
+	}
+	cfg.Reindex()
+	return cfg
+}
+
+// Reindex rebuilds every lookup index from the object slices
+func (c *LBConfig) Reindex() {
+	c.serversByName = make(map[string]*ServerInfo)
+	c.groupsByName = make(map[string]*ServiceGroupDef)
+	c.membersByGroup = make(map[string][]*ServiceGroup)
+	c.vserversByName = make(map[string]*VServerInfo)
+	c.vserversByVIP = make(map[string][]*VServerInfo)
+	c.bindingsByVServer = make(map[string][]*VServerBinding)
+	c.monitorsByName = make(map[string]*Monitor)
+	c.monitorsByGroup = make(map[string][]*MonitorBinding)
+	c.csVServersByName = make(map[string]*CSVServer)
+	c.csVServersByVIP = make(map[string][]*CSVServer)
+	c.csActionsByName = make(map[string]*CSAction)
+	c.csPoliciesByName = make(map[string]*CSPolicy)
+	c.csBindingsByName = make(map[string][]*CSBinding)
+	c.respActionsByName = make(map[string]*ResponderAction)
+	c.respPoliciesByName = make(map[string]*ResponderPolicy)
+	c.rwActionsByName = make(map[string]*RewriteAction)
+	c.rwPoliciesByName = make(map[string]*RewritePolicy)
+	c.certKeysByName = make(map[string]*CertKey)
+	c.sslBindingsByName = make(map[string][]*SSLBinding)
+	c.sslVServersByName = make(map[string]*SSLVServer)
+	c.sslProfilesByName = make(map[string]*SSLProfile)
+	c.cipherGroupsByName = make(map[string]*CipherGroup)
+	c.sslGroupsByName = make(map[string]*SSLServiceGroup)
+	c.groupSeen = make(map[string]bool)
+	c.groupOrder = nil
+
+	for _, server := range c.Servers {
+		c.indexServer(server)
+	}
+	for _, vserver := range c.VServers {
+		c.indexVServer(vserver)
+	}
+	for _, def := range c.ServiceGroupDefs {
+		c.indexServiceGroupDef(def)
+	}
+	for _, member := range c.ServiceGroups {
+		c.indexServiceGroup(member)
+	}
+	for _, binding := range c.VServerBindings {
+		c.indexVServerBinding(binding)
+	}
+	for _, monitor := range c.Monitors {
+		c.indexMonitor(monitor)
+	}
+	for _, binding := range c.MonitorBindings {
+		c.indexMonitorBinding(binding)
+	}
+	for _, vserver := range c.CSVServers {
+		c.indexCSVServer(vserver)
+	}
+	for _, action := range c.CSActions {
+		c.indexCSAction(action)
+	}
+	for _, policy := range c.CSPolicies {
+		c.indexCSPolicy(policy)
+	}
+	for _, binding := range c.CSBindings {
+		c.indexCSBinding(binding)
+	}
+	for _, action := range c.ResponderActions {
+		c.indexResponderAction(action)
+	}
+	for _, policy := range c.ResponderPolicies {
+		c.indexResponderPolicy(policy)
+	}
+	for _, action := range c.RewriteActions {
+		c.indexRewriteAction(action)
+	}
+	for _, policy := range c.RewritePolicies {
+		c.indexRewritePolicy(policy)
+	}
+	for _, certKey := range c.CertKeys {
+		c.indexCertKey(certKey)
+	}
+	for _, binding := range c.SSLBindings {
+		c.indexSSLBinding(binding)
+	}
+	for _, vserver := range c.SSLVServers {
+		c.indexSSLVServer(vserver)
+	}
+	for _, profile := range c.SSLProfiles {
+		c.indexSSLProfile(profile)
+	}
+	for _, group := range c.CipherGroups {
+		c.indexCipherGroup(group)
+	}
+	for _, group := range c.SSLServiceGroups {
+		c.indexSSLServiceGroup(group)
+	}
+}
+
+func (c *LBConfig) indexServer(server *ServerInfo) {
+	// The appliance rejects a second add with the same name, so the first definition wins
+	if _, exists := c.serversByName[server.Name]; !exists {
+		c.serversByName[server.Name] = server
+	}
+}
+
+func (c *LBConfig) indexVServer(vserver *VServerInfo) {
+	if _, exists := c.vserversByName[vserver.Name]; !exists {
+		c.vserversByName[vserver.Name] = vserver
+	}
+	vip := VIPKey(vserver.IP, vserver.Port)
+	c.vserversByVIP[vip] = append(c.vserversByVIP[vip], vserver)
+}
+
+func (c *LBConfig) indexServiceGroupDef(def *ServiceGroupDef) {
+	if _, exists := c.groupsByName[def.Name]; !exists {
+		c.groupsByName[def.Name] = def
+		c.noteGroupName(def.Name)
+	}
+}
+
+func (c *LBConfig) indexServiceGroup(member *ServiceGroup) {
+	c.membersByGroup[member.Name] = append(c.membersByGroup[member.Name], member)
+	c.noteGroupName(member.Name)
+}
+
+func (c *LBConfig) indexVServerBinding(binding *VServerBinding) {
+	c.bindingsByVServer[binding.VServerName] = append(c.bindingsByVServer[binding.VServerName], binding)
+}
+
+func (c *LBConfig) indexMonitor(monitor *Monitor) {
+	if _, exists := c.monitorsByName[monitor.Name]; !exists {
+		c.monitorsByName[monitor.Name] = monitor
+	}
+}
+
+func (c *LBConfig) indexMonitorBinding(binding *MonitorBinding) {
+	c.monitorsByGroup[binding.ServiceName] = append(c.monitorsByGroup[binding.ServiceName], binding)
+}
+
+func (c *LBConfig) indexCSVServer(vserver *CSVServer) {
+	if _, exists := c.csVServersByName[vserver.Name]; !exists {
+		c.csVServersByName[vserver.Name] = vserver
+	}
+	vip := VIPKey(vserver.IP, vserver.Port)
+	c.csVServersByVIP[vip] = append(c.csVServersByVIP[vip], vserver)
+}
+
+func (c *LBConfig) indexCSAction(action *CSAction) {
+	if _, exists := c.csActionsByName[action.Name]; !exists {
+		c.csActionsByName[action.Name] = action
+	}
+}
+
+func (c *LBConfig) indexCSPolicy(policy *CSPolicy) {
+	if _, exists := c.csPoliciesByName[policy.Name]; !exists {
+		c.csPoliciesByName[policy.Name] = policy
+	}
+}
+
+func (c *LBConfig) indexCSBinding(binding *CSBinding) {
+	c.csBindingsByName[binding.VServerName] = append(c.csBindingsByName[binding.VServerName], binding)
+}
+
+func (c *LBConfig) indexResponderAction(action *ResponderAction) {
+	if _, exists := c.respActionsByName[action.Name]; !exists {
+		c.respActionsByName[action.Name] = action
+	}
+}
+
+func (c *LBConfig) indexResponderPolicy(policy *ResponderPolicy) {
+	if _, exists := c.respPoliciesByName[policy.Name]; !exists {
+		c.respPoliciesByName[policy.Name] = policy
+	}
+}
+
+func (c *LBConfig) indexRewriteAction(action *RewriteAction) {
+	if _, exists := c.rwActionsByName[action.Name]; !exists {
+		c.rwActionsByName[action.Name] = action
+	}
+}
+
+func (c *LBConfig) indexRewritePolicy(policy *RewritePolicy) {
+	if _, exists := c.rwPoliciesByName[policy.Name]; !exists {
+		c.rwPoliciesByName[policy.Name] = policy
+	}
+}
+
+func (c *LBConfig) indexCertKey(certKey *CertKey) {
+	if _, exists := c.certKeysByName[certKey.Name]; !exists {
+		c.certKeysByName[certKey.Name] = certKey
+	}
+}
+
+func (c *LBConfig) indexSSLBinding(binding *SSLBinding) {
+	c.sslBindingsByName[binding.VServerName] = append(c.sslBindingsByName[binding.VServerName], binding)
+}
+
+func (c *LBConfig) indexSSLVServer(vserver *SSLVServer) {
+	if _, exists := c.sslVServersByName[vserver.Name]; !exists {
+		c.sslVServersByName[vserver.Name] = vserver
+	}
+}
+
+func (c *LBConfig) indexSSLProfile(profile *SSLProfile) {
+	if _, exists := c.sslProfilesByName[profile.Name]; !exists {
+		c.sslProfilesByName[profile.Name] = profile
+	}
+}
+
+func (c *LBConfig) indexCipherGroup(group *CipherGroup) {
+	if _, exists := c.cipherGroupsByName[group.Name]; !exists {
+		c.cipherGroupsByName[group.Name] = group
+	}
+}
+
+func (c *LBConfig) indexSSLServiceGroup(group *SSLServiceGroup) {
+	if _, exists := c.sslGroupsByName[group.Name]; !exists {
+		c.sslGroupsByName[group.Name] = group
+	}
+}
+
+// noteGroupName records the first appearance of a service group name, whether
+// it came from a definition or only from member bindings
+func (c *LBConfig) noteGroupName(name string) {
+	if !c.groupSeen[name] {
+		c.groupSeen[name] = true
+		c.groupOrder = append(c.groupOrder, name)
+	}
+}
+
+// AddServer appends a server to the model
+func (c *LBConfig) AddServer(server *ServerInfo) *ServerInfo {
+	c.Servers = append(c.Servers, server)
+	c.indexServer(server)
+	return server
+}
+
+// AddVServer appends a virtual server to the model
+func (c *LBConfig) AddVServer(vserver *VServerInfo) *VServerInfo {
+	c.VServers = append(c.VServers, vserver)
+	c.indexVServer(vserver)
+	return vserver
+}
+
+// AddServiceGroupDef appends a service group definition to the model
+func (c *LBConfig) AddServiceGroupDef(def *ServiceGroupDef) *ServiceGroupDef {
+	c.ServiceGroupDefs = append(c.ServiceGroupDefs, def)
+	c.indexServiceGroupDef(def)
+	return def
+}
+
+// AddServiceGroup appends a service group member binding to the model
+func (c *LBConfig) AddServiceGroup(member *ServiceGroup) *ServiceGroup {
+	c.ServiceGroups = append(c.ServiceGroups, member)
+	c.indexServiceGroup(member)
+	return member
+}
+
+// AddVServerBinding appends a vserver binding to the model
+func (c *LBConfig) AddVServerBinding(binding *VServerBinding) *VServerBinding {
+	c.VServerBindings = append(c.VServerBindings, binding)
+	c.indexVServerBinding(binding)
+	return binding
+}
+
+// AddMonitor appends a health monitor to the model
+func (c *LBConfig) AddMonitor(monitor *Monitor) *Monitor {
+	c.Monitors = append(c.Monitors, monitor)
+	c.indexMonitor(monitor)
+	return monitor
+}
+
+// AddMonitorBinding appends a monitor binding to the model
+func (c *LBConfig) AddMonitorBinding(binding *MonitorBinding) *MonitorBinding {
+	c.MonitorBindings = append(c.MonitorBindings, binding)
+	c.indexMonitorBinding(binding)
+	return binding
+}
+
+// AddCSVServer appends a content switching virtual server to the model
+func (c *LBConfig) AddCSVServer(vserver *CSVServer) *CSVServer {
+	c.CSVServers = append(c.CSVServers, vserver)
+	c.indexCSVServer(vserver)
+	return vserver
+}
+
+// AddCSAction appends a content switching action to the model
+func (c *LBConfig) AddCSAction(action *CSAction) *CSAction {
+	c.CSActions = append(c.CSActions, action)
+	c.indexCSAction(action)
+	return action
+}
+
+// AddCSPolicy appends a content switching policy to the model
+func (c *LBConfig) AddCSPolicy(policy *CSPolicy) *CSPolicy {
+	c.CSPolicies = append(c.CSPolicies, policy)
+	c.indexCSPolicy(policy)
+	return policy
+}
+
+// AddResponderAction appends a responder action to the model
+func (c *LBConfig) AddResponderAction(action *ResponderAction) *ResponderAction {
+	c.ResponderActions = append(c.ResponderActions, action)
+	c.indexResponderAction(action)
+	return action
+}
+
+// AddResponderPolicy appends a responder policy to the model
+func (c *LBConfig) AddResponderPolicy(policy *ResponderPolicy) *ResponderPolicy {
+	c.ResponderPolicies = append(c.ResponderPolicies, policy)
+	c.indexResponderPolicy(policy)
+	return policy
+}
+
+// AddRewriteAction appends a rewrite action to the model
+func (c *LBConfig) AddRewriteAction(action *RewriteAction) *RewriteAction {
+	c.RewriteActions = append(c.RewriteActions, action)
+	c.indexRewriteAction(action)
+	return action
+}
+
+// AddRewritePolicy appends a rewrite policy to the model
+func (c *LBConfig) AddRewritePolicy(policy *RewritePolicy) *RewritePolicy {
+	c.RewritePolicies = append(c.RewritePolicies, policy)
+	c.indexRewritePolicy(policy)
+	return policy
+}
+
+// AddCertKey appends a certificate and key pair to the model
+func (c *LBConfig) AddCertKey(certKey *CertKey) *CertKey {
+	c.CertKeys = append(c.CertKeys, certKey)
+	c.indexCertKey(certKey)
+	return certKey
+}
+
+// AddSSLBinding appends a certificate binding of a virtual server to the model
+func (c *LBConfig) AddSSLBinding(binding *SSLBinding) *SSLBinding {
+	c.SSLBindings = append(c.SSLBindings, binding)
+	c.indexSSLBinding(binding)
+	return binding
+}
+
+// AddSSLVServer appends the SSL settings of a virtual server to the model
+func (c *LBConfig) AddSSLVServer(vserver *SSLVServer) *SSLVServer {
+	c.SSLVServers = append(c.SSLVServers, vserver)
+	c.indexSSLVServer(vserver)
+	return vserver
+}
+
+// AddSSLProfile appends an ssl profile to the model
+func (c *LBConfig) AddSSLProfile(profile *SSLProfile) *SSLProfile {
+	c.SSLProfiles = append(c.SSLProfiles, profile)
+	c.indexSSLProfile(profile)
+	return profile
+}
+
+// AddCipherGroup appends a user-defined cipher group to the model
+func (c *LBConfig) AddCipherGroup(group *CipherGroup) *CipherGroup {
+	c.CipherGroups = append(c.CipherGroups, group)
+	c.indexCipherGroup(group)
+	return group
+}
+
+// AddSSLServiceGroup appends the SSL settings of a service group to the model
+func (c *LBConfig) AddSSLServiceGroup(group *SSLServiceGroup) *SSLServiceGroup {
+	c.SSLServiceGroups = append(c.SSLServiceGroups, group)
+	c.indexSSLServiceGroup(group)
+	return group
+}
+
+// AddCSBinding appends a content switching binding to the model
+func (c *LBConfig) AddCSBinding(binding *CSBinding) *CSBinding {
+	c.CSBindings = append(c.CSBindings, binding)
+	c.indexCSBinding(binding)
+	return binding
+}
+
+// AddUntranslated records an object that is not translated
+func (c *LBConfig) AddUntranslated(object *UntranslatedObject) *UntranslatedObject {
+	c.Untranslated = append(c.Untranslated, object)
+	return object
+}
+
+// removeWhere drops the items for which drop returns true, keeping the order
+// of the rest, and returns the remaining items and how many were dropped
+func removeWhere[T any](items []T, drop func(T) bool) ([]T, int) {
+	kept := items[:0]
+	for _, item := range items {
+		if !drop(item) {
+			kept = append(kept, item)
+		}
+	}
+	removed := len(items) - len(kept)
+	clear(items[len(kept):])
+	return kept, removed
+}
+
+// unindexWhere drops the items of the key entry of a slice index for which
+// drop returns true, and the entry once it is empty
+func unindexWhere[T any](index map[string][]T, key string, drop func(T) bool) {
+	kept, removed := removeWhere(index[key], drop)
+	switch {
+	case removed == 0:
+	case len(kept) == 0:
+		delete(index, key)
+	default:
+		index[key] = kept
+	}
+}
+
+// renameEntry moves the entry of name in a name index to newName, unless
+// newName already has one
+func renameEntry[T any](index map[string]T, name, newName string) {
+	value, exists := index[name]
+	if !exists {
+		return
+	}
+	delete(index, name)
+	if _, exists := index[newName]; !exists {
+		index[newName] = value
+	}
+}
+
+// renameEntries moves the items of name in a slice index to the end of the
+// items of newName
+func renameEntries[T any](index map[string][]T, name, newName string) {
+	values, exists := index[name]
+	if !exists {
+		return
+	}
+	delete(index, name)
+	index[newName] = append(index[newName], values...)
+}
+
+// forgetGroupName drops a service group name that is neither defined nor
+// bound anymore from the order of first appearance
+func (c *LBConfig) forgetGroupName(name string) {
+	if !c.groupSeen[name] || c.hasServiceGroup(name) {
+		return
+	}
+	delete(c.groupSeen, name)
+	c.groupOrder, _ = removeWhere(c.groupOrder, func(group string) bool { return group == name })
+}
+
+// renameGroupName renames a service group in the order of first appearance
+func (c *LBConfig) renameGroupName(name, newName string) {
+	if !c.groupSeen[name] || name == newName {
+		return
+	}
+	delete(c.groupSeen, name)
+	if c.groupSeen[newName] {
+		c.groupOrder, _ = removeWhere(c.groupOrder, func(group string) bool { return group == name })
+		return
+	}
+	c.groupSeen[newName] = true
+	for i, group := range c.groupOrder {
+		if group == name {
+			c.groupOrder[i] = newName
+		}
+	}
+}
+
+// indexInOrder adds an item to the key entry of a slice index, keeping the
+// entry in the order of all
+func indexInOrder[T comparable](index map[string][]T, key string, item T, all []T) {
+	entry := append(index[key], item)
+	if len(entry) > 1 {
+		slices.SortStableFunc(entry, func(a, b T) int { return slices.Index(all, a) - slices.Index(all, b) })
+	}
+	index[key] = entry
+}
+
+// rekeyVServer moves a virtual server in the VIP index after its address
+// changed from oldVIP
+func (c *LBConfig) rekeyVServer(vserver *VServerInfo, oldVIP string) {
+	vip := VIPKey(vserver.IP, vserver.Port)
+	if vip == oldVIP {
+		return
+	}
+	unindexWhere(c.vserversByVIP, oldVIP, func(other *VServerInfo) bool { return other == vserver })
+	indexInOrder(c.vserversByVIP, vip, vserver, c.VServers)
+}
+
+// rekeyCSVServer moves a content switching virtual server in the VIP index
+// after its address changed from oldVIP
+func (c *LBConfig) rekeyCSVServer(vserver *CSVServer, oldVIP string) {
+	vip := VIPKey(vserver.IP, vserver.Port)
+	if vip == oldVIP {
+		return
+	}
+	unindexWhere(c.csVServersByVIP, oldVIP, func(other *CSVServer) bool { return other == vserver })
+	indexInOrder(c.csVServersByVIP, vip, vserver, c.CSVServers)
+}
+
+// RemoveServer removes the named server together with the service group
+// members that use it, as the appliance does
+func (c *LBConfig) RemoveServer(name string) bool {
+	var removed int
+	c.Servers, removed = removeWhere(c.Servers, func(server *ServerInfo) bool { return server.Name == name })
+	if removed == 0 {
+		return false
+	}
+	delete(c.serversByName, name)
+
+	uses := func(member *ServiceGroup) bool { return member.ServerName == name }
+	groups := make(map[string]bool)
+	c.ServiceGroups, _ = removeWhere(c.ServiceGroups, func(member *ServiceGroup) bool {
+		if !uses(member) {
+			return false
+		}
+		groups[member.Name] = true
+		return true
+	})
+	for group := range groups {
+		unindexWhere(c.membersByGroup, group, uses)
+		c.forgetGroupName(group)
+	}
+	return true
+}
+
+// RemoveVServer removes the named virtual server with its bindings and the
+// untranslated objects attached to it
+func (c *LBConfig) RemoveVServer(name string) bool {
+	var vservers []*VServerInfo
+	c.VServers, _ = removeWhere(c.VServers, func(vserver *VServerInfo) bool {
+		if vserver.Name != name {
+			return false
+		}
+		vservers = append(vservers, vserver)
+		return true
+	})
+	if len(vservers) == 0 {
+		return false
+	}
+	delete(c.vserversByName, name)
+	for _, vserver := range vservers {
+		unindexWhere(c.vserversByVIP, VIPKey(vserver.IP, vserver.Port), func(other *VServerInfo) bool { return other == vserver })
+	}
+
+	c.VServerBindings, _ = removeWhere(c.VServerBindings, func(binding *VServerBinding) bool { return binding.VServerName == name })
+	c.SSLBindings, _ = removeWhere(c.SSLBindings, func(binding *SSLBinding) bool { return binding.VServerName == name })
+	c.SSLVServers, _ = removeWhere(c.SSLVServers, func(vserver *SSLVServer) bool { return vserver.Name == name })
+	c.Untranslated, _ = removeWhere(c.Untranslated, func(object *UntranslatedObject) bool { return object.VServer == name })
+	delete(c.bindingsByVServer, name)
+	delete(c.sslBindingsByName, name)
+	delete(c.sslVServersByName, name)
+	return true
+}
+
+// RemoveServiceGroup removes the named service group, its members, the
+// virtual server and monitor bindings to it, its SSL settings and the
+// untranslated objects attached to it
+func (c *LBConfig) RemoveServiceGroup(name string) bool {
+	if !c.hasServiceGroup(name) {
+		return false
+	}
+	bound := func(binding *VServerBinding) bool { return binding.ServiceName == name }
+	vservers := make(map[string]bool)
+	c.ServiceGroupDefs, _ = removeWhere(c.ServiceGroupDefs, func(def *ServiceGroupDef) bool { return def.Name == name })
+	c.ServiceGroups, _ = removeWhere(c.ServiceGroups, func(member *ServiceGroup) bool { return member.Name == name })
+	c.VServerBindings, _ = removeWhere(c.VServerBindings, func(binding *VServerBinding) bool {
+		if !bound(binding) {
+			return false
+		}
+		vservers[binding.VServerName] = true
+		return true
+	})
+	c.MonitorBindings, _ = removeWhere(c.MonitorBindings, func(binding *MonitorBinding) bool { return binding.ServiceName == name })
+	c.SSLServiceGroups, _ = removeWhere(c.SSLServiceGroups, func(group *SSLServiceGroup) bool { return group.Name == name })
+	c.Untranslated, _ = removeWhere(c.Untranslated, func(object *UntranslatedObject) bool { return object.ServiceGroup == name })
+
+	delete(c.groupsByName, name)
+	delete(c.membersByGroup, name)
+	delete(c.monitorsByGroup, name)
+	delete(c.sslGroupsByName, name)
+	for vserver := range vservers {
+		unindexWhere(c.bindingsByVServer, vserver, bound)
+	}
+	c.forgetGroupName(name)
+	return true
+}
+
+// RemoveMonitor removes the named monitor. Bindings to it are kept, as the
+// appliance refuses to remove a bound monitor, and are reported by Verify.
+func (c *LBConfig) RemoveMonitor(name string) bool {
+	var removed int
+	c.Monitors, removed = removeWhere(c.Monitors, func(monitor *Monitor) bool { return monitor.Name == name })
+	delete(c.monitorsByName, name)
+	return removed > 0
+}
+
+// RemoveMonitorBinding unbinds a monitor from a service group and returns
+// the number of bindings removed. Untranslated objects recorded for the
+// removed bindings are dropped with them.
+func (c *LBConfig) RemoveMonitorBinding(group, monitor string) int {
+	matches := func(binding *MonitorBinding) bool {
+		return binding.ServiceName == group && binding.MonitorName == monitor
+	}
+	dropped := make(map[Position]bool)
+	var removed int
+	c.MonitorBindings, removed = removeWhere(c.MonitorBindings, func(binding *MonitorBinding) bool {
+		if !matches(binding) {
+			return false
+		}
+		dropped[binding.Pos] = true
+		return true
+	})
+	if removed == 0 {
+		return 0
+	}
+	c.Untranslated, _ = removeWhere(c.Untranslated, func(object *UntranslatedObject) bool {
+		return object.ServiceGroup == group && dropped[object.Pos]
+	})
+	unindexWhere(c.monitorsByGroup, group, matches)
+	return removed
+}
+
+// RemoveMember unbinds a server from a service group. An empty port unbinds
+// the server on every port. It returns the number of members removed.
+func (c *LBConfig) RemoveMember(group, server, port string) int {
+	matches := func(member *ServiceGroup) bool {
+		return member.Name == group && member.ServerName == server && (port == "" || member.Port == port)
+	}
+	var removed int
+	c.ServiceGroups, removed = removeWhere(c.ServiceGroups, matches)
+	if removed > 0 {
+		unindexWhere(c.membersByGroup, group, matches)
+		c.forgetGroupName(group)
+	}
+	return removed
+}
+
+// RemoveVServerBinding unbinds a service group or, when service is empty, a
+// policy from a virtual server. Untranslated objects recorded for a removed
+// policy binding are dropped with it. It returns the number of bindings removed.
+func (c *LBConfig) RemoveVServerBinding(vserver, service, policy string) int {
+	matches := func(binding *VServerBinding) bool {
+		return binding.VServerName == vserver && binding.ServiceName == service && (policy == "" || binding.PolicyName == policy)
+	}
+	dropped := make(map[Position]bool)
+	var removed int
+	c.VServerBindings, removed = removeWhere(c.VServerBindings, func(binding *VServerBinding) bool {
+		if !matches(binding) {
+			return false
+		}
+		dropped[binding.Pos] = true
+		return true
+	})
+	if removed == 0 {
+		return 0
+	}
+	c.Untranslated, _ = removeWhere(c.Untranslated, func(object *UntranslatedObject) bool {
+		return object.VServer == vserver && dropped[object.Pos]
+	})
+	unindexWhere(c.bindingsByVServer, vserver, matches)
+	return removed
+}
+
+// RemoveCSVServer removes the named content switching virtual server with its bindings
+func (c *LBConfig) RemoveCSVServer(name string) bool {
+	var vservers []*CSVServer
+	c.CSVServers, _ = removeWhere(c.CSVServers, func(vserver *CSVServer) bool {
+		if vserver.Name != name {
+			return false
+		}
+		vservers = append(vservers, vserver)
+		return true
+	})
+	if len(vservers) == 0 {
+		return false
+	}
+	delete(c.csVServersByName, name)
+	for _, vserver := range vservers {
+		unindexWhere(c.csVServersByVIP, VIPKey(vserver.IP, vserver.Port), func(other *CSVServer) bool { return other == vserver })
+	}
+
+	c.CSBindings, _ = removeWhere(c.CSBindings, func(binding *CSBinding) bool { return binding.VServerName == name })
+	c.SSLBindings, _ = removeWhere(c.SSLBindings, func(binding *SSLBinding) bool { return binding.VServerName == name })
+	c.SSLVServers, _ = removeWhere(c.SSLVServers, func(vserver *SSLVServer) bool { return vserver.Name == name })
+	delete(c.csBindingsByName, name)
+	delete(c.sslBindingsByName, name)
+	delete(c.sslVServersByName, name)
+	return true
+}
+
+// RemoveCSPolicy removes the named content switching policy. Bindings to it
+// are kept, as the appliance refuses to remove a bound policy, and are
+// reported by Verify.
+func (c *LBConfig) RemoveCSPolicy(name string) bool {
+	var removed int
+	c.CSPolicies, removed = removeWhere(c.CSPolicies, func(policy *CSPolicy) bool { return policy.Name == name })
+	delete(c.csPoliciesByName, name)
+	return removed > 0
+}
+
+// RemoveCSAction removes the named content switching action. Policies that
+// use it are kept and reported by Verify.
+func (c *LBConfig) RemoveCSAction(name string) bool {
+	var removed int
+	c.CSActions, removed = removeWhere(c.CSActions, func(action *CSAction) bool { return action.Name == name })
+	delete(c.csActionsByName, name)
+	return removed > 0
+}
+
+// RemoveResponderPolicy removes the named responder policy. Bindings to it
+// are kept, as the appliance refuses to remove a bound policy.
+func (c *LBConfig) RemoveResponderPolicy(name string) bool {
+	var removed int
+	c.ResponderPolicies, removed = removeWhere(c.ResponderPolicies, func(policy *ResponderPolicy) bool { return policy.Name == name })
+	delete(c.respPoliciesByName, name)
+	return removed > 0
+}
+
+// RemoveResponderAction removes the named responder action. Policies that
+// use it are kept and reported by Verify.
+func (c *LBConfig) RemoveResponderAction(name string) bool {
+	var removed int
+	c.ResponderActions, removed = removeWhere(c.ResponderActions, func(action *ResponderAction) bool { return action.Name == name })
+	delete(c.respActionsByName, name)
+	return removed > 0
+}
+
+// RemoveRewritePolicy removes the named rewrite policy. Bindings to it are
+// kept, as the appliance refuses to remove a bound policy.
+func (c *LBConfig) RemoveRewritePolicy(name string) bool {
+	var removed int
+	c.RewritePolicies, removed = removeWhere(c.RewritePolicies, func(policy *RewritePolicy) bool { return policy.Name == name })
+	delete(c.rwPoliciesByName, name)
+	return removed > 0
+}
+
+// RemoveRewriteAction removes the named rewrite action. Policies that use it
+// are kept and reported by Verify.
+func (c *LBConfig) RemoveRewriteAction(name string) bool {
+	var removed int
+	c.RewriteActions, removed = removeWhere(c.RewriteActions, func(action *RewriteAction) bool { return action.Name == name })
+	delete(c.rwActionsByName, name)
+	return removed > 0
+}
+
+// RemoveCertKey removes the named certificate and key pair. Bindings to it
+// are kept, as the appliance refuses to remove a bound certificate, and are
+// reported by Verify.
+func (c *LBConfig) RemoveCertKey(name string) bool {
+	var removed int
+	c.CertKeys, removed = removeWhere(c.CertKeys, func(certKey *CertKey) bool { return certKey.Name == name })
+	delete(c.certKeysByName, name)
+	return removed > 0
+}
+
+// RemoveSSLProfile removes the named ssl profile. Virtual servers that use
+// it keep the reference, which Verify reports.
+func (c *LBConfig) RemoveSSLProfile(name string) bool {
+	var removed int
+	c.SSLProfiles, removed = removeWhere(c.SSLProfiles, func(profile *SSLProfile) bool { return profile.Name == name })
+	delete(c.sslProfilesByName, name)
+	return removed > 0
+}
+
+// RemoveCipherGroup removes the named cipher group. Bindings to it are kept
+// and reported as ciphers without a Go equivalent.
+func (c *LBConfig) RemoveCipherGroup(name string) bool {
+	var removed int
+	c.CipherGroups, removed = removeWhere(c.CipherGroups, func(group *CipherGroup) bool { return group.Name == name })
+	delete(c.cipherGroupsByName, name)
+	return removed > 0
+}
+
+// RemoveSSLBinding unbinds a certificate from a virtual server and returns
+// the number of bindings removed. Untranslated objects recorded for the
+// removed bindings are dropped with them.
+func (c *LBConfig) RemoveSSLBinding(vserver, certKey string) int {
+	matches := func(binding *SSLBinding) bool {
+		return binding.VServerName == vserver && binding.CertKeyName == certKey
+	}
+	dropped := make(map[Position]bool)
+	var removed int
+	c.SSLBindings, removed = removeWhere(c.SSLBindings, func(binding *SSLBinding) bool {
+		if !matches(binding) {
+			return false
+		}
+		dropped[binding.Pos] = true
+		return true
+	})
+	if removed == 0 {
+		return 0
+	}
+	c.Untranslated, _ = removeWhere(c.Untranslated, func(object *UntranslatedObject) bool {
+		return object.VServer == vserver && dropped[object.Pos]
+	})
+	unindexWhere(c.sslBindingsByName, vserver, matches)
+	return removed
+}
+
+// RemoveCSBinding unbinds a policy from a content switching virtual server,
+// or the default lb vserver when policy is empty. It returns the number of
+// bindings removed.
+func (c *LBConfig) RemoveCSBinding(vserver, policy string) int {
+	matches := func(binding *CSBinding) bool {
+		return binding.VServerName == vserver && binding.PolicyName == policy
+	}
+	var removed int
+	c.CSBindings, removed = removeWhere(c.CSBindings, matches)
+	if removed > 0 {
+		unindexWhere(c.csBindingsByName, vserver, matches)
+	}
+	return removed
+}
+
+// RenameServer renames a server and updates the members that reference it
+func (c *LBConfig) RenameServer(name, newName string) bool {
+	server := c.ServerByName(name)
+	if server == nil {
+		return false
+	}
+	server.Name = newName
+	for _, member := range c.ServiceGroups {
+		if member.ServerName == name {
+			member.ServerName = newName
+		}
+	}
+	renameEntry(c.serversByName, name, newName)
+	return true
+}
+
+// RenameVServer renames a virtual server and updates its bindings, the
+// virtual servers it backs up, the content switching actions and bindings
+// that target it and the untranslated objects attached to it
+func (c *LBConfig) RenameVServer(name, newName string) bool {
+	vserver := c.VServerByName(name)
+	if vserver == nil {
+		return false
+	}
+	vserver.Name = newName
+	for _, binding := range c.VServerBindings {
+		if binding.VServerName == name {
+			binding.VServerName = newName
+		}
+	}
+	for _, other := range c.VServers {
+		if other.Backup.VServer == name {
+			other.Backup.VServer = newName
+		}
+	}
+	for _, action := range c.CSActions {
+		if action.TargetLBVServer == name {
+			action.TargetLBVServer = newName
+		}
+	}
+	for _, binding := range c.CSBindings {
+		if binding.TargetLBVServer == name {
+			binding.TargetLBVServer = newName
+		}
+	}
+	for _, binding := range c.SSLBindings {
+		if binding.VServerName == name {
+			binding.VServerName = newName
+		}
+	}
+	for _, settings := range c.SSLVServers {
+		if settings.Name == name {
+			settings.Name = newName
+		}
+	}
+	for _, object := range c.Untranslated {
+		if object.VServer == name {
+			object.VServer = newName
+		}
+	}
+	renameEntry(c.vserversByName, name, newName)
+	renameEntries(c.bindingsByVServer, name, newName)
+	renameEntries(c.sslBindingsByName, name, newName)
+	renameEntry(c.sslVServersByName, name, newName)
+	return true
+}
+
+// RenameServiceGroup renames a service group and updates its members, the
+// virtual server and monitor bindings to it, its SSL settings and the
+// untranslated objects attached to it
+func (c *LBConfig) RenameServiceGroup(name, newName string) bool {
+	if !c.hasServiceGroup(name) {
+		return false
+	}
+	for _, def := range c.ServiceGroupDefs {
+		if def.Name == name {
+			def.Name = newName
+		}
+	}
+	for _, member := range c.ServiceGroups {
+		if member.Name == name {
+			member.Name = newName
+		}
+	}
+	for _, binding := range c.VServerBindings {
+		if binding.ServiceName == name {
+			binding.ServiceName = newName
+		}
+	}
+	for _, binding := range c.MonitorBindings {
+		if binding.ServiceName == name {
+			binding.ServiceName = newName
+		}
+	}
+	for _, settings := range c.SSLServiceGroups {
+		if settings.Name == name {
+			settings.Name = newName
+		}
+	}
+	for _, object := range c.Untranslated {
+		if object.ServiceGroup == name {
+			object.ServiceGroup = newName
+		}
+	}
+	renameEntry(c.groupsByName, name, newName)
+	renameEntries(c.membersByGroup, name, newName)
+	renameEntries(c.monitorsByGroup, name, newName)
+	renameEntry(c.sslGroupsByName, name, newName)
+	c.renameGroupName(name, newName)
+	return true
+}
+
+// ServerByName returns the server with the given name, or nil
+func (c *LBConfig) ServerByName(name string) *ServerInfo {
+	return c.serversByName[name]
+}
+
+// ServiceGroupDefByName returns the service group definition with the given name, or nil
+func (c *LBConfig) ServiceGroupDefByName(name string) *ServiceGroupDef {
+	return c.groupsByName[name]
+}
+
+// MembersOf returns the member bindings of the named service group in source order
+func (c *LBConfig) MembersOf(group string) []*ServiceGroup {
+	return c.membersByGroup[group]
+}
+
+// ServiceGroupNames returns every service group name, defined or only bound, in order of first appearance
+func (c *LBConfig) ServiceGroupNames() []string {
+	names := make([]string, len(c.groupOrder))
+	copy(names, c.groupOrder)
+	return names
+}
+
+// VServerByName returns the virtual server with the given name, or nil
+func (c *LBConfig) VServerByName(name string) *VServerInfo {
+	return c.vserversByName[name]
+}
+
+// VServerByVIP returns the virtual server listening on ip:port, or nil
+func (c *LBConfig) VServerByVIP(ip, port string) *VServerInfo {
+	if vservers := c.vserversByVIP[VIPKey(ip, port)]; len(vservers) > 0 {
+		return vservers[0]
+	}
+	return nil
+}
+
+// MonitorByName returns the monitor defined with the given name, or nil
+func (c *LBConfig) MonitorByName(name string) *Monitor {
+	return c.monitorsByName[name]
+}
+
+// MonitorsOf returns the monitor bindings of the named service group in source order
+func (c *LBConfig) MonitorsOf(group string) []*MonitorBinding {
+	return c.monitorsByGroup[group]
+}
+
+// BindingsOf returns the bindings of the named virtual server in source order
+func (c *LBConfig) BindingsOf(vserver string) []*VServerBinding {
+	return c.bindingsByVServer[vserver]
+}
+
+// CSVServerByName returns the content switching virtual server with the given name, or nil
+func (c *LBConfig) CSVServerByName(name string) *CSVServer {
+	return c.csVServersByName[name]
+}
+
+// CSVServerByVIP returns the content switching virtual server listening on ip:port, or nil
+func (c *LBConfig) CSVServerByVIP(ip, port string) *CSVServer {
+	if vservers := c.csVServersByVIP[VIPKey(ip, port)]; len(vservers) > 0 {
+		return vservers[0]
+	}
+	return nil
+}
+
+// CSActionByName returns the content switching action with the given name, or nil
+func (c *LBConfig) CSActionByName(name string) *CSAction {
+	return c.csActionsByName[name]
+}
+
+// CSPolicyByName returns the content switching policy with the given name, or nil
+func (c *LBConfig) CSPolicyByName(name string) *CSPolicy {
+	return c.csPoliciesByName[name]
+}
+
+// ResponderActionByName returns the responder action with the given name, or nil
+func (c *LBConfig) ResponderActionByName(name string) *ResponderAction {
+	return c.respActionsByName[name]
+}
+
+// ResponderPolicyByName returns the responder policy with the given name, or nil
+func (c *LBConfig) ResponderPolicyByName(name string) *ResponderPolicy {
+	return c.respPoliciesByName[name]
+}
+
+// RewriteActionByName returns the rewrite action with the given name, or nil
+func (c *LBConfig) RewriteActionByName(name string) *RewriteAction {
+	return c.rwActionsByName[name]
+}
+
+// RewritePolicyByName returns the rewrite policy with the given name, or nil
+func (c *LBConfig) RewritePolicyByName(name string) *RewritePolicy {
+	return c.rwPoliciesByName[name]
+}
+
+// CertKeyByName returns the certificate and key pair with the given name, or nil
+func (c *LBConfig) CertKeyByName(name string) *CertKey {
+	return c.certKeysByName[name]
+}
+
+// SSLBindingsOf returns the certificate bindings of the named lb or cs virtual server in source order
+func (c *LBConfig) SSLBindingsOf(vserver string) []*SSLBinding {
+	return c.sslBindingsByName[vserver]
+}
+
+// SSLVServerByName returns the SSL settings of the named lb or cs virtual server, or nil
+func (c *LBConfig) SSLVServerByName(name string) *SSLVServer {
+	return c.sslVServersByName[name]
+}
+
+// SSLProfileByName returns the ssl profile with the given name, or nil
+func (c *LBConfig) SSLProfileByName(name string) *SSLProfile {
+	return c.sslProfilesByName[name]
+}
+
+// CipherGroupByName returns the user-defined cipher group with the given name, or nil
+func (c *LBConfig) CipherGroupByName(name string) *CipherGroup {
+	return c.cipherGroupsByName[name]
+}
+
+// SSLServiceGroupByName returns the SSL settings of the named service group or service, or nil
+func (c *LBConfig) SSLServiceGroupByName(name string) *SSLServiceGroup {
+	return c.sslGroupsByName[name]
+}
+
+// CSBindingsOf returns the bindings of the named content switching virtual server in source order
+func (c *LBConfig) CSBindingsOf(vserver string) []*CSBinding {
+	return c.csBindingsByName[vserver]
+}
+
+// hasServiceGroup reports whether a service group is defined or bound in the model
+func (c *LBConfig) hasServiceGroup(name string) bool {
+	return c.ServiceGroupDefByName(name) != nil || len(c.MembersOf(name)) > 0
+}
+
+// MemberDisabled reports whether a service group member is administratively
+// disabled, either itself or through its server or service group
+func (c *LBConfig) MemberDisabled(member *ServiceGroup) bool {
+	if member.Disabled {
+		return true
+	}
+	if server := c.ServerByName(member.ServerName); server != nil && server.Disabled {
+		return true
+	}
+	def := c.ServiceGroupDefByName(member.Name)
+	return def != nil && def.Disabled
+}
+
+// VServerGroups returns the service groups that serve the named virtual
+// server, in binding order: the groups bound with "bind lb vserver", or when
+// none is bound, the group with the same name as the virtual server. Bindings
+// to undefined groups are left out.
+func (c *LBConfig) VServerGroups(vserver string) []string {
+	var groups []string
+	seen := make(map[string]bool)
+	for _, binding := range c.BindingsOf(vserver) {
+		name := binding.ServiceName
+		if name == "" || seen[name] || !c.hasServiceGroup(name) {
+			continue
+		}
+		seen[name] = true
+		groups = append(groups, name)
+	}
+
+	if len(groups) == 0 && c.hasServiceGroup(vserver) {
+		groups = append(groups, vserver)
+	}
+
+	return groups
+}
+
+// Link resolves the typed references between objects. It is called by every
+// parser before returning and must be called again after manual edits.
+func (c *LBConfig) Link() {
+	c.Reindex()
+
+	for _, def := range c.ServiceGroupDefs {
+		def.Members = nil
+	}
+	for _, vserver := range c.VServers {
+		vserver.Bindings = nil
+	}
+	for _, vserver := range c.CSVServers {
+		vserver.Bindings = nil
+	}
+
+	for _, member := range c.ServiceGroups {
+		member.Server = c.ServerByName(member.ServerName)
+		member.Group = c.ServiceGroupDefByName(member.Name)
+		if member.Group != nil {
+			member.Group.Members = append(member.Group.Members, member)
+		}
+	}
+
+	for _, binding := range c.VServerBindings {
+		binding.VServer = c.VServerByName(binding.VServerName)
+		binding.Group = nil
+		if binding.ServiceName != "" {
+			binding.Group = c.ServiceGroupDefByName(binding.ServiceName)
+		}
+		if binding.VServer != nil {
+			binding.VServer.Bindings = append(binding.VServer.Bindings, binding)
+		}
+	}
+
+	for _, binding := range c.CSBindings {
+		if vserver := c.CSVServerByName(binding.VServerName); vserver != nil {
+			vserver.Bindings = append(vserver.Bindings, binding)
+		}
+	}
+}
+
+// VIPKey formats an IP and port as the IP:Port key used by mappings and lookups
+func VIPKey(ip, port string) string {
+	return ip + ":" + port
+}
+
+// Addressable reports whether the virtual server listens on an address.
+// Citrix backup virtual servers are often added on 0.0.0.0 port 0 and only
+// take traffic through the virtual servers they back up.
+func (v *VServerInfo) Addressable() bool {
+	return !(v.IP == "0.0.0.0" && v.Port == "0")
+}
+
+// Addressable reports whether the content switching virtual server listens
+// on an address, see VServerInfo.Addressable
+func (v *CSVServer) Addressable() bool {
+	return !(v.IP == "0.0.0.0" && v.Port == "0")
+}
@@ -0,0 +1,204 @@
+package parser
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// buildModel builds a small model through the Add methods, without a parser
+func buildModel() *LBConfig {
+	config := NewLBConfig(ConfigTypeCitrix)
+	config.AddServer(&ServerInfo{Name: "s1", IP: "10.0.0.1"})
+	config.AddServer(&ServerInfo{Name: "s1", IP: "10.0.0.99"})
+	config.AddServer(&ServerInfo{Name: "s2", IP: "10.0.0.2", Disabled: true})
+	config.AddServiceGroupDef(&ServiceGroupDef{Name: "sg1", Protocol: "HTTP"})
+	config.AddServiceGroupDef(&ServiceGroupDef{Name: "sg2", Protocol: "HTTP"})
+	config.AddServiceGroup(&ServiceGroup{Name: "sg1", ServerName: "s1", Port: "80"})
+	config.AddServiceGroup(&ServiceGroup{Name: "sg1", ServerName: "s2", Port: "80"})
+	config.AddServiceGroup(&ServiceGroup{Name: "sg2", ServerName: "s1", Port: "8080"})
+	config.AddServiceGroup(&ServiceGroup{Name: "vs3", ServerName: "s1", Port: "9090"})
+	config.AddVServer(&VServerInfo{Name: "vs1", Protocol: "HTTP", IP: "10.9.0.1", Port: "80"})
+	config.AddVServer(&VServerInfo{Name: "vs2", Protocol: "HTTP", IP: "10.9.0.2", Port: "80"})
+	config.AddVServer(&VServerInfo{Name: "vs3", Protocol: "HTTP", IP: "10.9.0.3", Port: "80"})
+	config.AddVServerBinding(&VServerBinding{VServerName: "vs1", ServiceName: "sg2"})
+	config.AddVServerBinding(&VServerBinding{VServerName: "vs1", ServiceName: "sg1"})
+	config.AddVServerBinding(&VServerBinding{VServerName: "vs1", ServiceName: "sg2"})
+	config.AddVServerBinding(&VServerBinding{VServerName: "vs1", ServiceName: "missing"})
+	config.AddVServerBinding(&VServerBinding{VServerName: "vs2", PolicyName: "pol"})
+	config.Link()
+	return config
+}
+
+func TestLBConfigLookups(t *testing.T) {
+	config := buildModel()
+
+	if server := config.ServerByName("s1"); server == nil || server.IP != "10.0.0.1" {
+		t.Errorf("ServerByName(s1) = %+v, want the first definition on 10.0.0.1", server)
+	}
+	if vserver := config.VServerByVIP("10.9.0.2", "80"); vserver == nil || vserver.Name != "vs2" {
+		t.Errorf("VServerByVIP(10.9.0.2:80) = %+v, want vs2", vserver)
+	}
+	if got := config.ServiceGroupNames(); !slices.Equal(got, []string{"sg1", "sg2", "vs3"}) {
+		t.Errorf("ServiceGroupNames = %v, want [sg1 sg2 vs3]", got)
+	}
+
+	tests := []struct {
+		vserver string
+		want    []string
+	}{
+		{vserver: "vs1", want: []string{"sg2", "sg1"}},
+		{vserver: "vs2", want: nil},
+		{vserver: "vs3", want: []string{"vs3"}},
+	}
+	for _, tt := range tests {
+		if got := config.VServerGroups(tt.vserver); !slices.Equal(got, tt.want) {
+			t.Errorf("VServerGroups(%s) = %v, want %v", tt.vserver, got, tt.want)
+		}
+	}
+}
+
+func TestLBConfigLink(t *testing.T) {
+	config := buildModel()
+
+	members := config.ServiceGroupDefByName("sg1").Members
+	if len(members) != 2 || members[0].Server != config.ServerByName("s1") || members[0].Group.Name != "sg1" {
+		t.Errorf("sg1 members are not linked to their server and group: %+v", members)
+	}
+	if member := config.MembersOf("vs3")[0]; member.Group != nil {
+		t.Errorf("member of a group without add command has group %+v", member.Group)
+	}
+	if bindings := config.VServerByName("vs1").Bindings; len(bindings) != 4 || bindings[3].Group != nil {
+		t.Errorf("vs1 bindings = %d, want 4 with the undefined group unresolved", len(bindings))
+	}
+	if !config.MemberDisabled(members[1]) || config.MemberDisabled(members[0]) {
+		t.Error("MemberDisabled does not follow the disabled server s2")
+	}
+}
+
+func TestLBConfigEdits(t *testing.T) {
+	tests := []struct {
+		name   string
+		edit   func(config *LBConfig) bool
+		vs1    []string // VServerGroups of vs1 after the edit
+		member []string // Servers of sg1 after the edit
+	}{
+		{
+			name:   "remove server removes its members",
+			edit:   func(config *LBConfig) bool { return config.RemoveServer("s2") },
+			vs1:    []string{"sg2", "sg1"},
+			member: []string{"s1"},
+		},
+		{
+			name:   "remove service group unbinds it",
+			edit:   func(config *LBConfig) bool { return config.RemoveServiceGroup("sg2") },
+			vs1:    []string{"sg1"},
+			member: []string{"s1", "s2"},
+		},
+		{
+			name:   "rename service group follows its bindings",
+			edit:   func(config *LBConfig) bool { return config.RenameServiceGroup("sg1", "web") },
+			vs1:    []string{"sg2", "web"},
+			member: nil,
+		},
+		{
+			name:   "rename server follows its members",
+			edit:   func(config *LBConfig) bool { return config.RenameServer("s2", "db") },
+			vs1:    []string{"sg2", "sg1"},
+			member: []string{"s1", "db"},
+		},
+		{
+			name:   "unbind service group",
+			edit:   func(config *LBConfig) bool { return config.RemoveVServerBinding("vs1", "sg2", "") == 2 },
+			vs1:    []string{"sg1"},
+			member: []string{"s1", "s2"},
+		},
+		{
+			name:   "unknown names change nothing",
+			edit:   func(config *LBConfig) bool { return !config.RemoveServer("none") && !config.RenameVServer("none", "x") },
+			vs1:    []string{"sg2", "sg1"},
+			member: []string{"s1", "s2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := buildModel()
+			if !tt.edit(config) {
+				t.Fatal("edit reported no change")
+			}
+			if got := config.VServerGroups("vs1"); !slices.Equal(got, tt.vs1) {
+				t.Errorf("VServerGroups(vs1) = %v, want %v", got, tt.vs1)
+			}
+			var servers []string
+			for _, member := range config.MembersOf("sg1") {
+				servers = append(servers, member.ServerName)
+			}
+			if !slices.Equal(servers, tt.member) {
+				t.Errorf("sg1 members = %v, want %v", servers, tt.member)
+			}
+		})
+	}
+}
+
+func TestRemoveVServerDropsItsBindings(t *testing.T) {
+	config := buildModel()
+	if !config.RemoveVServer("vs1") {
+		t.Fatal("RemoveVServer(vs1) = false")
+	}
+	if config.VServerByName("vs1") != nil || len(config.BindingsOf("vs1")) != 0 {
+		t.Error("vs1 or its bindings remain")
+	}
+	if config.VServerByVIP("10.9.0.1", "80") != nil {
+		t.Error("the VIP of vs1 is still indexed")
+	}
+}
+
+func TestLBConfigEditsKeepIndexes(t *testing.T) {
+	// moveVServer changes the address of a vserver as "set lb vserver" does
+	moveVServer := func(config *LBConfig, name, ip string) {
+		vserver := config.VServerByName(name)
+		vip := VIPKey(vserver.IP, vserver.Port)
+		vserver.IP = ip
+		config.rekeyVServer(vserver, vip)
+	}
+
+	tests := []struct {
+		name string
+		edit func(config *LBConfig)
+	}{
+		{name: "remove server", edit: func(config *LBConfig) { config.RemoveServer("s1") }},
+		{name: "remove vserver", edit: func(config *LBConfig) { config.RemoveVServer("vs1") }},
+		{name: "remove service group", edit: func(config *LBConfig) { config.RemoveServiceGroup("sg2") }},
+		{name: "remove the members of an undefined group", edit: func(config *LBConfig) { config.RemoveMember("vs3", "s1", "") }},
+		{name: "unbind service group", edit: func(config *LBConfig) { config.RemoveVServerBinding("vs1", "sg2", "") }},
+		{name: "rename server", edit: func(config *LBConfig) { config.RenameServer("s2", "db") }},
+		{name: "rename vserver", edit: func(config *LBConfig) { config.RenameVServer("vs1", "web") }},
+		{name: "rename service group", edit: func(config *LBConfig) { config.RenameServiceGroup("sg1", "web") }},
+		{name: "move vserver", edit: func(config *LBConfig) { moveVServer(config, "vs1", "10.9.0.9") }},
+		{name: "move vserver onto a later one", edit: func(config *LBConfig) { moveVServer(config, "vs1", "10.9.0.2") }},
+		{name: "move vserver off a shared address", edit: func(config *LBConfig) {
+			moveVServer(config, "vs3", "10.9.0.2")
+			moveVServer(config, "vs2", "10.9.0.9")
+		}},
+	}
+
+	indexes := func(config *LBConfig) []any {
+		return []any{
+			config.serversByName, config.groupsByName, config.membersByGroup, config.vserversByName,
+			config.vserversByVIP, config.bindingsByVServer, config.sslBindingsByName, config.sslVServersByName,
+			config.groupOrder,
+		}
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := buildModel()
+			tt.edit(config)
+			got := indexes(config)
+			config.Reindex()
+			if want := indexes(config); !reflect.DeepEqual(got, want) {
+				t.Errorf("indexes after the edit = %v, want the rebuilt %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// builtinMonitors are the monitors every Citrix appliance defines, which can
+// be bound without an "add lb monitor" command
+var builtinMonitors = map[string]*Monitor{
+	"http":         {Name: "http", Type: "HTTP", HTTPRequest: "HEAD /"},
+	"http-ecv":     {Name: "http-ecv", Type: "HTTP-ECV", Send: "GET /"},
+	"https":        {Name: "https", Type: "HTTP", HTTPRequest: "HEAD /", Secure: true},
+	"https-ecv":    {Name: "https-ecv", Type: "HTTP-ECV", Send: "GET /", Secure: true},
+	"tcp":          {Name: "tcp", Type: "TCP"},
+	"tcp-default":  {Name: "tcp-default", Type: "TCP"},
+	"tcps":         {Name: "tcps", Type: "TCP", Secure: true},
+	"tcp-ecv":      {Name: "tcp-ecv", Type: "TCP-ECV"},
+	"ping":         {Name: "ping", Type: "PING"},
+	"ping-default": {Name: "ping-default", Type: "PING"},
+	"arp":          {Name: "arp", Type: "ARP"},
+	"dns":          {Name: "dns", Type: "DNS"},
+}
+
+// Citrix defaults for monitors that do not set -interval or -resptimeout.
+// They are written explicitly because Traefik's own defaults are much longer.
+const (
+	defaultMonitorInterval = "5"
+	defaultMonitorTimeout  = "2"
+)
+
+// LookupMonitor returns the monitor defined with the given name, or the
+// appliance built-in monitor of that name, or nil
+func (c *LBConfig) LookupMonitor(name string) *Monitor {
+	if monitor := c.MonitorByName(name); monitor != nil {
+		return monitor
+	}
+	return builtinMonitors[strings.ToLower(name)]
+}
+
+// translateMonitor converts a monitor to a Traefik health check. When the
+// monitor type has no Traefik equivalent it returns nil and the reason.
+// Settings that are dropped from a translated check are listed in ignored.
+func translateMonitor(monitor *Monitor) (check *TraefikHealthCheck, unsupported string, ignored []string) {
+	monitorType := strings.ToUpper(monitor.Type)
+	secure := monitor.Secure
+
+	var request string
+	switch monitorType {
+	case "HTTP", "HTTPS":
+		request = monitor.HTTPRequest
+		if request == "" {
+			request = "HEAD /"
+		}
+	case "HTTP-ECV", "HTTPS-ECV":
+		request = monitor.Send
+		if request == "" {
+			request = "GET /"
+		}
+		if monitor.Recv != "" {
+			ignored = append(ignored, fmt.Sprintf("-recv %q (response content is not checked)", monitor.Recv))
+		}
+	default:
+		return nil, fmt.Sprintf("%s monitors have no Traefik equivalent, Traefik health checks send HTTP requests", monitorType), nil
+	}
+	if strings.HasPrefix(monitorType, "HTTPS") {
+		secure = true
+	}
+
+	check = &TraefikHealthCheck{Path: "/"}
+	fields := strings.Fields(request)
+	if len(fields) > 0 && !strings.HasPrefix(fields[0], "/") {
+		if method := strings.ToUpper(fields[0]); method != "GET" {
+			check.Method = method
+		}
+		fields = fields[1:]
+	}
+	if len(fields) > 0 {
+		check.Path = fields[0]
+	}
+	if secure {
+		check.Scheme = "https"
+	}
+
+	if monitor.DestPort != "" {
+		if port, err := strconv.Atoi(monitor.DestPort); err == nil && port > 0 {
+			check.Port = port
+		} else {
+			ignored = append(ignored, fmt.Sprintf("-destPort %q", monitor.DestPort))
+		}
+	}
+
+	var problem string
+	if check.Interval, problem = monitorDuration("-interval", monitor.Interval, defaultMonitorInterval, monitor.IntervalUnits); problem != "" {
+		ignored = append(ignored, problem)
+	}
+	if check.Timeout, problem = monitorDuration("-resptimeout", monitor.RespTimeout, defaultMonitorTimeout, monitor.TimeoutUnits); problem != "" {
+		ignored = append(ignored, problem)
+	}
+
+	if codes := monitor.RespCode; codes != "" && !healthyCodes(codes) {
+		ignored = append(ignored, fmt.Sprintf("-respCode %s (Traefik treats any 2xx or 3xx status as healthy)", codes))
+	}
+
+	for _, line := range strings.FieldsFunc(monitor.CustomHeaders, func(r rune) bool { return r == '\r' || r == '\n' }) {
+		name, value, found := strings.Cut(line, ":")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		switch {
+		case !found || name == "":
+			ignored = append(ignored, fmt.Sprintf("-customHeaders line %q", line))
+		case strings.EqualFold(name, "Host"):
+			check.Hostname = value
+		default:
+			if check.Headers == nil {
+				check.Headers = make(map[string]string)
+			}
+			check.Headers[name] = value
+		}
+	}
+
+	return check, "", ignored
+}
+
+// monitorDuration converts a Citrix interval with its units (SEC, MSEC or
+// MIN) to a Traefik duration, using the default when the value is not set
+func monitorDuration(parameter, value, defaultValue, units string) (string, string) {
+	if value == "" {
+		value = defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return "", fmt.Sprintf("%s %q", parameter, value)
+	}
+
+	switch strings.ToUpper(units) {
+	case "", "SEC":
+		return fmt.Sprintf("%ds", n), ""
+	case "MSEC":
+		return fmt.Sprintf("%dms", n), ""
+	case "MIN":
+		return fmt.Sprintf("%dm", n), ""
+	default:
+		return "", fmt.Sprintf("%s %s %s", parameter, value, units)
+	}
+}
+
+// healthyCodes reports whether every response code in a -respCode list
+// ("200", "200-299", "200 302") is one Traefik treats as healthy
+func healthyCodes(codes string) bool {
+	for _, code := range strings.Fields(codes) {
+		low, high, isRange := strings.Cut(code, "-")
+		if !isRange {
+			high = low
+		}
+		from, err1 := strconv.Atoi(low)
+		to, err2 := strconv.Atoi(high)
+		if err1 != nil || err2 != nil || from < 200 || to > 399 {
+			return false
+		}
+	}
+	return true
+}
+
+// groupHealthCheck returns the health check of a service group, translated
+// from the first of its bound monitors that Traefik can express, with the
+// name of that monitor. Monitors without -secure probe servers reached over
+// TLS in plain HTTP, so their checks keep the http scheme.
+func groupHealthCheck(config *LBConfig, group string) (*TraefikHealthCheck, string) {
+	for _, binding := range config.MonitorsOf(group) {
+		monitor := config.LookupMonitor(binding.MonitorName)
+		if monitor == nil {
+			continue
+		}
+		if check, _, _ := translateMonitor(monitor); check != nil {
+			if check.Scheme == "" && backendScheme(config, group) == "https" {
+				check.Scheme = "http"
+			}
+			return check, binding.MonitorName
+		}
+	}
+	return nil, ""
+}
+
+// serviceHealthCheck returns the health check of a service built from the
+// given service groups: that of the first group with a translatable monitor
+func serviceHealthCheck(config *LBConfig, serviceNames []string) *TraefikHealthCheck {
+	for _, name := range serviceNames {
+		if check, _ := groupHealthCheck(config, name); check != nil {
+			return check
+		}
+	}
+	return nil
+}
+
+// monitorSetters returns the functions that apply "add/set lb monitor" parameters to a monitor
+func monitorSetters(monitor *Monitor) map[string]func(value string) {
+	return map[string]func(string){
+		"-httpRequest":   func(value string) { monitor.HTTPRequest = value },
+		"-send":          func(value string) { monitor.Send = value },
+		"-recv":          func(value string) { monitor.Recv = value },
+		"-respCode":      func(value string) { monitor.RespCode = value },
+		"-interval":      func(value string) { monitor.Interval = value },
+		"-units3":        func(value string) { monitor.IntervalUnits = value },
+		"-resptimeout":   func(value string) { monitor.RespTimeout = value },
+		"-units4":        func(value string) { monitor.TimeoutUnits = value },
+		"-destPort":      func(value string) { monitor.DestPort = value },
+		"-customHeaders": func(value string) { monitor.CustomHeaders = value },
+		"-secure":        func(value string) { monitor.Secure = strings.EqualFold(value, "YES") },
+	}
+}
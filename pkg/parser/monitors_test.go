@@ -0,0 +1,176 @@
+package parser
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMonitorHealthChecks(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   string
+		gap    string // Substring of the untranslated reason, empty when fully translated
+	}{
+		{
+			name:   "built-in http monitor",
+			config: "bind serviceGroup sg1 -monitorName http\n",
+			want:   "method=HEAD path=/ interval=5s timeout=2s",
+		},
+		{
+			name: "http request and timings",
+			config: `add lb monitor mon1 HTTP -httpRequest "GET /health" -interval 10 -resptimeout 3 -destPort 8081
+bind serviceGroup sg1 -monitorName mon1
+`,
+			want: "path=/health port=8081 interval=10s timeout=3s",
+		},
+		{
+			name: "interval units",
+			config: `add lb monitor mon1 HTTP -interval 500 -units3 MSEC -resptimeout 1 -units4 MIN
+bind serviceGroup sg1 -monitorName mon1
+`,
+			want: "method=HEAD path=/ interval=500ms timeout=1m",
+		},
+		{
+			name: "https ecv with host header",
+			config: `add lb monitor mon1 HTTPS-ECV -send "POST /check" -customHeaders "Host: app.example.com\r\nX-Probe: 1\r\n"
+bind serviceGroup sg1 -monitorName mon1
+`,
+			want: "scheme=https method=POST path=/check interval=5s timeout=2s hostname=app.example.com header=X-Probe: 1",
+		},
+		{
+			name: "recv content is not checked",
+			config: `add lb monitor mon1 HTTP-ECV -send "GET /status" -recv OK
+bind serviceGroup sg1 -monitorName mon1
+`,
+			want: "path=/status interval=5s timeout=2s",
+			gap:  "translated without -recv",
+		},
+		{
+			name: "response codes outside 2xx and 3xx",
+			config: `add lb monitor mon1 HTTP -respCode 200 404
+bind serviceGroup sg1 -monitorName mon1
+`,
+			want: "method=HEAD path=/ interval=5s timeout=2s",
+			gap:  "-respCode 200 404",
+		},
+		{
+			name: "set changes a monitor",
+			config: `add lb monitor mon1 HTTP -httpRequest "GET /old"
+set lb monitor mon1 -httpRequest "GET /new" -interval 30
+bind serviceGroup sg1 -monitorName mon1
+`,
+			want: "path=/new interval=30s timeout=2s",
+		},
+		{
+			name: "tcp monitor",
+			config: `add lb monitor mon1 TCP
+bind serviceGroup sg1 -monitorName mon1
+`,
+			want: "none",
+			gap:  "TCP monitors have no Traefik equivalent",
+		},
+		{
+			name:   "first translatable monitor is used",
+			config: "bind serviceGroup sg1 -monitorName ping\nbind serviceGroup sg1 -monitorName https\n",
+			want:   "scheme=https method=HEAD path=/ interval=5s timeout=2s",
+			gap:    "PING monitors have no Traefik equivalent",
+		},
+		{
+			name:   "undefined monitor",
+			config: "bind serviceGroup sg1 -monitorName missing\n",
+			want:   "none",
+			gap:    "monitor 'missing' is not defined",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := parseCitrixText(t, "ns.conf", `add server s1 10.0.0.1
+add serviceGroup sg1 HTTP
+bind serviceGroup sg1 s1 80
+add lb vserver vs1 HTTP 10.9.0.1 80
+bind lb vserver vs1 sg1
+`+tt.config)
+
+			service := GenerateTraefikConfig(config).HTTP.Services["vs1"]
+			if got := service.LoadBalancer.HealthCheck.String(); got != tt.want {
+				t.Errorf("health check = %q, want %q", got, tt.want)
+			}
+
+			var reasons []string
+			for _, object := range config.Untranslated {
+				reasons = append(reasons, object.Reason)
+			}
+			found := slices.ContainsFunc(reasons, func(reason string) bool {
+				return tt.gap != "" && strings.Contains(reason, tt.gap)
+			})
+			if tt.gap != "" && !found {
+				t.Errorf("untranslated reasons %q do not mention %q", reasons, tt.gap)
+			}
+			if tt.gap == "" && len(reasons) > 0 {
+				t.Errorf("unexpected untranslated reasons %q", reasons)
+			}
+		})
+	}
+}
+
+func TestVerifyMonitors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   []string
+	}{
+		{
+			name: "one monitor per group",
+			config: `bind serviceGroup sg1 -monitorName http
+bind serviceGroup sg2 -monitorName http
+`,
+		},
+		{
+			name: "several translatable monitors",
+			config: `bind serviceGroup sg1 -monitorName http
+bind serviceGroup sg1 -monitorName https
+bind serviceGroup sg2 -monitorName http
+`,
+			want: []string{"multiple-monitors"},
+		},
+		{
+			name: "groups of a vserver disagree",
+			config: `bind serviceGroup sg1 -monitorName http
+bind serviceGroup sg2 -monitorName https
+`,
+			want: []string{"monitor-conflict"},
+		},
+		{
+			name:   "monitor is not defined",
+			config: "bind serviceGroup sg1 -monitorName missing\n",
+			want:   []string{"undefined-monitor"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := parseCitrixText(t, "ns.conf", `add server s1 10.0.0.1
+add serviceGroup sg1 HTTP
+add serviceGroup sg2 HTTP
+bind serviceGroup sg1 s1 80
+bind serviceGroup sg2 s1 8080
+add lb vserver vs1 HTTP 10.9.0.1 80
+bind lb vserver vs1 sg1
+bind lb vserver vs1 sg2
+`+tt.config)
+
+			var got []string
+			for _, code := range diagnosticCodes(Verify(config)) {
+				if strings.Contains(code, "monitor") {
+					got = append(got, code)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("monitor diagnostics = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,128 +1,766 @@
 package parser
 
 import (
-	"bufio"
 	"fmt"
 	"io"
+	"net"
 	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
+func init() {
+	RegisterParser(citrixParser{})
+}
+
+// ConfigTypeCitrix is the type of Citrix/NetScaler configurations
+const ConfigTypeCitrix ConfigType = "citrix"
+
+// citrixParser is the registered Parser for Citrix/NetScaler ns.conf command files
+type citrixParser struct{}
+
+func (citrixParser) Name() string { return string(ConfigTypeCitrix) }
+
+func (citrixParser) Parse(reader io.Reader, opts ParseOptions) (*LBConfig, error) {
+	return ParseCitrix(reader, opts)
+}
+
+// citrixBuildHeader matches the version header that NetScaler writes at the top of ns.conf (e.g. "#NS13.1 Build 37.38")
+var citrixBuildHeader = regexp.MustCompile(`^#NS\d+(\.\d+)* Build `)
+
+// citrixSignatures are the indicators scored by citrixParser.Detect
+var citrixSignatures = []Signature{
+	{Description: "NetScaler build header", Weight: definiteWeight, Definite: true, Match: citrixBuildHeader.MatchString},
+	{Description: "'add server'", Weight: 5, Match: hasPrefix("add server ")},
+	{Description: "'add lb vserver'", Weight: 5, Match: hasPrefix("add lb vserver ")},
+	{Description: "'add cs vserver'", Weight: 5, Match: hasPrefix("add cs vserver ")},
+	{Description: "'add serviceGroup'", Weight: 5, Match: hasPrefix("add serviceGroup ")},
+	{Description: "'bind serviceGroup'", Weight: 5, Match: hasPrefix("bind serviceGroup ")},
+	{Description: "'bind lb vserver'", Weight: 5, Match: hasPrefix("bind lb vserver ")},
+	{Description: "'set' server or vserver", Weight: 3, Match: func(line string) bool {
+		return strings.HasPrefix(line, "set ") && (strings.Contains(line, " server ") || strings.Contains(line, " vserver "))
+	}},
+	// System settings usually open an ns.conf, before any load balancing commands
+	{Description: "NetScaler system command ('ns', 'system')", Weight: 2, Match: func(line string) bool {
+		for _, prefix := range []string{"set ns ", "add ns ", "enable ns ", "disable ns ", "set system ", "add system ", "bind system "} {
+			if strings.HasPrefix(line, prefix) {
+				return true
+			}
+		}
+		return false
+	}},
+}
+
+// hasPrefix returns a signature matcher for lines starting with prefix
+func hasPrefix(prefix string) func(string) bool {
+	return func(line string) bool {
+		return strings.HasPrefix(line, prefix)
+	}
+}
+
+// Detect scores the sample by matching the NetScaler build header and Citrix command prefixes
+func (citrixParser) Detect(sample []string) Detection {
+	return MatchSignatures(sample, citrixSignatures)
+}
+
 // CommandProcessor handles processing of parsed Citrix commands
-type CommandProcessor struct{}
+type CommandProcessor struct {
+	config      *LBConfig
+	pos         Position           // Position of the command being processed
+	policyKinds map[string]string  // Policy name to object type (e.g. "responder policy")
+	removed     map[string]removal // Objects removed or renamed away, by objectKey
+}
+
+// removal records how and where an object stopped existing
+type removal struct {
+	how string // "removed" or "renamed to 'x'"
+	pos Position
+}
 
 // NewCommandProcessor creates a new command processor
 func NewCommandProcessor() *CommandProcessor {
-	return &CommandProcessor{}
+	return &CommandProcessor{
+		config:      NewLBConfig(ConfigTypeCitrix),
+		policyKinds: make(map[string]string),
+		removed:     make(map[string]removal),
+	}
+}
+
+// objectKind normalizes a Citrix object type for matching, e.g. "lb vserver"
+// becomes "lbvserver"
+func objectKind(objectType string) string {
+	return strings.ToLower(strings.ReplaceAll(objectType, " ", ""))
+}
+
+// sslObjectTypes are the ssl object types whose second word the command
+// parser takes for the object name, as in "add ssl profile <name>"
+var sslObjectTypes = map[string]bool{"profile": true, "cipher": true, "service": true}
+
+// joinSSLObjectType returns an "ssl profile", "ssl cipher" or "ssl service"
+// command with the second word of its object type moved from the name back
+// into the type, and any other command unchanged
+func joinSSLObjectType(command *CitrixCommand) *CitrixCommand {
+	if objectKind(command.ObjectType) != "ssl" || !sslObjectTypes[strings.ToLower(command.Name)] || len(command.Arguments) == 0 {
+		return command
+	}
+	joined := *command
+	joined.ObjectType = command.ObjectType + " " + command.Name
+	joined.Name = command.Arguments[0]
+	joined.Arguments = command.Arguments[1:]
+	return &joined
+}
+
+// objectKey identifies a server, lb vserver or service group in the removal
+// log. Services share the service group namespace, as they do in the model,
+// and the SSL settings of a service group are looked up as the group.
+func objectKey(kind, name string) string {
+	switch kind {
+	case "service", "sslservicegroup", "sslservice":
+		kind = "servicegroup"
+	}
+	return kind + "/" + name
+}
+
+// Config returns the model built from the processed commands
+func (p *CommandProcessor) Config() *LBConfig {
+	return p.config
+}
+
+// Process applies a single parsed command, found at pos, to the model
+func (p *CommandProcessor) Process(command *CitrixCommand, pos Position) error {
+	p.pos = pos
+	command = joinSSLObjectType(command)
+	switch command.Action {
+	case "add":
+		return p.handleAddCommand(command)
+	case "bind":
+		return p.handleBindCommand(command)
+	case "set":
+		return p.handleSetCommand(command)
+	case "unset":
+		return p.handleUnsetCommand(command)
+	case "unbind":
+		return p.handleUnbindCommand(command)
+	case "rm", "remove":
+		return p.handleRemoveCommand(command)
+	case "rename":
+		return p.handleRenameCommand(command)
+	case "enable":
+		return p.handleStateCommand(command, false)
+	case "disable":
+		return p.handleStateCommand(command, true)
+	default:
+		p.recordUntranslated(command, "")
+		return nil
+	}
+}
+
+// recordUntranslated notes a command that has no Traefik translation. Commands
+// on lb vservers and service groups are linked to them so the gap report can
+// list the affected virtual servers.
+func (p *CommandProcessor) recordUntranslated(command *CitrixCommand, reason string) {
+	object := &UntranslatedObject{
+		Action:     command.Action,
+		ObjectType: command.ObjectType,
+		Name:       command.Name,
+		Reason:     reason,
+		Text:       command.Text,
+		Pos:        p.pos,
+	}
+
+	if command.Action == "add" && strings.HasSuffix(strings.ToLower(command.ObjectType), "policy") {
+		p.policyKinds[command.Name] = command.ObjectType
+	}
+
+	switch objectKind(command.ObjectType) {
+	case "lbvserver", "sslvserver":
+		object.VServer = command.Name
+	case "servicegroup", "service", "sslservicegroup", "sslservice":
+		object.ServiceGroup = command.Name
+	}
+
+	p.config.AddUntranslated(object)
+}
+
+// warn reports a problem with the command being processed
+func (p *CommandProcessor) warn(command *CitrixCommand, code, format string, args ...interface{}) {
+	p.config.AddDiagnostic(Diagnostic{
+		Position: p.pos,
+		Severity: SeverityWarning,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+		Snippet:  command.Text,
+	})
+}
+
+// reportMissing reports a command whose target object does not exist, noting
+// where it was removed or renamed if it existed before
+func (p *CommandProcessor) reportMissing(command *CitrixCommand) {
+	if r, exists := p.removed[objectKey(objectKind(command.ObjectType), command.Name)]; exists {
+		p.warn(command, "removed-object", "%s %s '%s' has no effect: it was %s at %s",
+			command.Action, command.ObjectType, command.Name, r.how, r.pos)
+		return
+	}
+	p.warn(command, "undefined-object", "%s %s '%s' has no effect: it is not defined",
+		command.Action, command.ObjectType, command.Name)
+}
+
+// checkReference reports a reference to an object that was removed or renamed
+// earlier. References to objects that never existed are left to Verify.
+func (p *CommandProcessor) checkReference(command *CitrixCommand, kind, name string) {
+	r, exists := p.removed[objectKey(kind, name)]
+	if !exists {
+		return
+	}
+	p.warn(command, "removed-reference", "%s %s '%s' references '%s', which was %s at %s",
+		command.Action, command.ObjectType, command.Name, name, r.how, r.pos)
 }
 
 // handleAddCommand processes add commands
-func (p *CommandProcessor) handleAddCommand(command *CitrixCommand, servers *[]ServerInfo, vservers *[]VServerInfo, serviceGroupDefs *[]ServiceGroupDef) error {
-	objectType := strings.ToLower(strings.ReplaceAll(command.ObjectType, " ", ""))
+func (p *CommandProcessor) handleAddCommand(command *CitrixCommand) error {
+	objectType := objectKind(command.ObjectType)
+	switch objectType {
+	case "server", "lbvserver", "servicegroup", "service", "lbmonitor", "csvserver", "csaction", "cspolicy",
+		"responderaction", "responderpolicy", "rewriteaction", "rewritepolicy", "sslcertkey", "sslprofile", "sslcipher":
+		delete(p.removed, objectKey(objectType, command.Name))
+	}
+
 	switch objectType {
 	case "server":
-		return p.handleAddServer(command, servers)
+		return p.handleAddServer(command)
 	case "lbvserver":
-		return p.handleAddLBVServer(command, vservers)
+		return p.handleAddLBVServer(command)
 	case "servicegroup":
-		return p.handleAddServiceGroup(command, serviceGroupDefs)
+		return p.handleAddServiceGroup(command)
+	case "service":
+		return p.handleAddService(command)
+	case "lbmonitor":
+		return p.handleAddMonitor(command)
+	case "csvserver":
+		return p.handleAddCSVServer(command)
+	case "csaction":
+		return p.handleAddCSAction(command)
+	case "cspolicy":
+		return p.handleAddCSPolicy(command)
+	case "responderaction":
+		return p.handleAddResponderAction(command)
+	case "responderpolicy":
+		return p.handleAddResponderPolicy(command)
+	case "rewriteaction":
+		return p.handleAddRewriteAction(command)
+	case "rewritepolicy":
+		return p.handleAddRewritePolicy(command)
+	case "sslcertkey":
+		return p.handleAddCertKey(command)
+	case "sslprofile":
+		return p.handleAddSSLProfile(command)
+	case "sslcipher":
+		return p.handleAddCipherGroup(command)
 	default:
-		// Ignore unknown object types for now
+		p.recordUntranslated(command, "")
 		return nil
 	}
 }
 
 // handleAddServer processes "add server" commands
-func (p *CommandProcessor) handleAddServer(command *CitrixCommand, servers *[]ServerInfo) error {
+func (p *CommandProcessor) handleAddServer(command *CitrixCommand) error {
 	if len(command.Arguments) < 1 {
 		return fmt.Errorf("add server command requires IP address argument")
 	}
 
 	comment := command.Parameters["-comment"]
 
-	*servers = append(*servers, ServerInfo{
-		Name:    command.Name,
-		IP:      command.Arguments[0],
-		Comment: comment,
+	p.config.AddServer(&ServerInfo{
+		Name:     command.Name,
+		IP:       command.Arguments[0],
+		Comment:  comment,
+		Disabled: isDisabled(command.Parameters),
+		Pos:      p.pos,
+		Source:   command.Text,
 	})
 
 	return nil
 }
 
 // handleAddLBVServer processes "add lb vserver" commands
-func (p *CommandProcessor) handleAddLBVServer(command *CitrixCommand, vservers *[]VServerInfo) error {
+func (p *CommandProcessor) handleAddLBVServer(command *CitrixCommand) error {
 	if len(command.Arguments) < 3 {
 		return fmt.Errorf("add lb vserver command requires protocol, IP, and port arguments")
 	}
 
-	*vservers = append(*vservers, VServerInfo{
+	vserver := &VServerInfo{
+		Name:      command.Name,
+		Protocol:  command.Arguments[0],
+		IP:        command.Arguments[1],
+		Port:      command.Arguments[2],
+		LBMethod:  defaultCitrixLBMethod,
+		LBDefault: true,
+		Disabled:  isDisabled(command.Parameters),
+		Pos:       p.pos,
+		Source:    command.Text,
+	}
+	if method := command.Parameters["-lbMethod"]; method != "" {
+		vserver.LBMethod, vserver.LBDefault = method, false
+	}
+	for _, setters := range []map[string]func(string){persistenceSetters(vserver), backupSetters(vserver)} {
+		for name, apply := range setters {
+			if value, exists := command.Parameters[name]; exists {
+				apply(value)
+			}
+		}
+	}
+
+	p.config.AddVServer(vserver)
+	return nil
+}
+
+// handleAddMonitor processes "add lb monitor" commands
+func (p *CommandProcessor) handleAddMonitor(command *CitrixCommand) error {
+	if len(command.Arguments) < 1 {
+		return fmt.Errorf("add lb monitor command requires monitor type argument")
+	}
+
+	monitor := &Monitor{
+		Name:   command.Name,
+		Type:   command.Arguments[0],
+		Pos:    p.pos,
+		Source: command.Text,
+	}
+	setters := monitorSetters(monitor)
+	for name, value := range command.Parameters {
+		if apply, exists := setters[name]; exists {
+			apply(value)
+		}
+	}
+
+	p.config.AddMonitor(monitor)
+	return nil
+}
+
+// handleAddCSVServer processes "add cs vserver" commands
+func (p *CommandProcessor) handleAddCSVServer(command *CitrixCommand) error {
+	if len(command.Arguments) < 3 {
+		return fmt.Errorf("add cs vserver command requires protocol, IP, and port arguments")
+	}
+
+	p.config.AddCSVServer(&CSVServer{
 		Name:     command.Name,
 		Protocol: command.Arguments[0],
 		IP:       command.Arguments[1],
 		Port:     command.Arguments[2],
+		Disabled: isDisabled(command.Parameters),
+		Pos:      p.pos,
+		Source:   command.Text,
+	})
+	return nil
+}
+
+// handleAddCSAction processes "add cs action" commands
+func (p *CommandProcessor) handleAddCSAction(command *CitrixCommand) error {
+	action := &CSAction{
+		Name:            command.Name,
+		TargetLBVServer: command.Parameters["-targetLBVserver"],
+		TargetExpr:      command.Parameters["-targetVserverExpr"],
+		Pos:             p.pos,
+		Source:          command.Text,
+	}
+	if action.TargetLBVServer == "" && action.TargetExpr == "" {
+		return fmt.Errorf("add cs action command requires -targetLBVserver or -targetVserverExpr")
+	}
+	p.checkReference(command, "lbvserver", action.TargetLBVServer)
+
+	p.config.AddCSAction(action)
+	return nil
+}
+
+// handleAddCSPolicy processes "add cs policy" commands, advanced ones with
+// -rule and -action and classic ones with -url or -domain
+func (p *CommandProcessor) handleAddCSPolicy(command *CitrixCommand) error {
+	policy := &CSPolicy{Name: command.Name, Pos: p.pos, Source: command.Text}
+	for name, apply := range csPolicySetters(policy) {
+		if value, exists := command.Parameters[name]; exists {
+			apply(value)
+		}
+	}
+	if policy.Rule == "" && policy.URL == "" && policy.Domain == "" {
+		return fmt.Errorf("add cs policy command requires -rule, -url or -domain")
+	}
+	if policy.Action != "" {
+		p.checkReference(command, "csaction", policy.Action)
+	}
+
+	p.config.AddCSPolicy(policy)
+	return nil
+}
+
+// handleAddResponderAction processes "add responder action" commands: the
+// action type followed by the target expression or HTML page name
+func (p *CommandProcessor) handleAddResponderAction(command *CitrixCommand) error {
+	if len(command.Arguments) < 1 {
+		return fmt.Errorf("add responder action command requires action type argument")
+	}
+
+	action := &ResponderAction{
+		Name:   command.Name,
+		Type:   command.Arguments[0],
+		Pos:    p.pos,
+		Source: command.Text,
+	}
+	if len(command.Arguments) > 1 {
+		action.Target = command.Arguments[1]
+	}
+	for name, apply := range responderActionSetters(action) {
+		if value, exists := command.Parameters[name]; exists {
+			apply(value)
+		}
+	}
+
+	p.config.AddResponderAction(action)
+	return nil
+}
+
+// handleAddResponderPolicy processes "add responder policy" commands: the
+// rule followed by the action name
+func (p *CommandProcessor) handleAddResponderPolicy(command *CitrixCommand) error {
+	if len(command.Arguments) < 2 {
+		return fmt.Errorf("add responder policy command requires rule and action arguments")
+	}
+
+	policy := &ResponderPolicy{
+		Name:   command.Name,
+		Rule:   command.Arguments[0],
+		Action: command.Arguments[1],
+		Pos:    p.pos,
+		Source: command.Text,
+	}
+	if !isBuiltinResponderAction(policy.Action) {
+		p.checkReference(command, "responderaction", policy.Action)
+	}
+
+	p.config.AddResponderPolicy(policy)
+	return nil
+}
+
+// handleAddRewriteAction processes "add rewrite action" commands: the action
+// type followed by the header name or rewritten expression and the text
+// expression
+func (p *CommandProcessor) handleAddRewriteAction(command *CitrixCommand) error {
+	if len(command.Arguments) < 2 {
+		return fmt.Errorf("add rewrite action command requires action type and target arguments")
+	}
+
+	action := &RewriteAction{
+		Name:   command.Name,
+		Type:   command.Arguments[0],
+		Target: command.Arguments[1],
+		Pos:    p.pos,
+		Source: command.Text,
+	}
+	if len(command.Arguments) > 2 {
+		action.Value = command.Arguments[2]
+	}
+	for name, apply := range rewriteActionSetters(action) {
+		if value, exists := command.Parameters[name]; exists {
+			apply(value)
+		}
+	}
+
+	p.config.AddRewriteAction(action)
+	return nil
+}
+
+// handleAddRewritePolicy processes "add rewrite policy" commands: the rule
+// followed by the action name
+func (p *CommandProcessor) handleAddRewritePolicy(command *CitrixCommand) error {
+	if len(command.Arguments) < 2 {
+		return fmt.Errorf("add rewrite policy command requires rule and action arguments")
+	}
+
+	policy := &RewritePolicy{
+		Name:   command.Name,
+		Rule:   command.Arguments[0],
+		Action: command.Arguments[1],
+		Pos:    p.pos,
+		Source: command.Text,
+	}
+	if !isBuiltinRewriteAction(policy.Action) {
+		p.checkReference(command, "rewriteaction", policy.Action)
+	}
+
+	p.config.AddRewritePolicy(policy)
+	return nil
+}
+
+// handleAddCertKey processes "add ssl certKey" commands: the certificate and
+// key files. Other parameters, such as -inform and -expiryMonitor, are
+// recorded as untranslated.
+func (p *CommandProcessor) handleAddCertKey(command *CitrixCommand) error {
+	certFile := command.Parameters["-cert"]
+	if certFile == "" {
+		return fmt.Errorf("add ssl certKey command requires -cert")
+	}
+
+	p.config.AddCertKey(&CertKey{
+		Name:     command.Name,
+		CertFile: certFile,
+		KeyFile:  command.Parameters["-key"],
+		Pos:      p.pos,
+		Source:   command.Text,
+	})
+
+	var ignored []string
+	for name := range command.Parameters {
+		if name != "-cert" && name != "-key" {
+			ignored = append(ignored, name)
+		}
+	}
+	if len(ignored) > 0 {
+		sort.Strings(ignored)
+		p.recordUntranslated(command, "not applied: "+strings.Join(ignored, ", "))
+	}
+	return nil
+}
+
+// handleAddSSLProfile processes "add ssl profile" commands
+func (p *CommandProcessor) handleAddSSLProfile(command *CitrixCommand) error {
+	profile := p.config.AddSSLProfile(&SSLProfile{
+		Name:   command.Name,
+		Pos:    p.pos,
+		Source: command.Text,
 	})
+	p.applyParameters(command, sslSettingsSetters(&profile.SSLSettings))
+	return nil
+}
 
+// handleAddCipherGroup processes "add ssl cipher <group>" commands, which
+// define an empty cipher group
+func (p *CommandProcessor) handleAddCipherGroup(command *CitrixCommand) error {
+	p.config.AddCipherGroup(&CipherGroup{
+		Name:   command.Name,
+		Pos:    p.pos,
+		Source: command.Text,
+	})
+	p.applyParameters(command, nil)
 	return nil
 }
 
 // handleAddServiceGroup processes "add serviceGroup" commands
-func (p *CommandProcessor) handleAddServiceGroup(command *CitrixCommand, serviceGroupDefs *[]ServiceGroupDef) error {
+func (p *CommandProcessor) handleAddServiceGroup(command *CitrixCommand) error {
 	comment := command.Parameters["-comment"]
 	protocol := ""
 	if len(command.Arguments) > 0 {
 		protocol = command.Arguments[0]
 	}
 
-	*serviceGroupDefs = append(*serviceGroupDefs, ServiceGroupDef{
+	p.config.AddServiceGroupDef(&ServiceGroupDef{
 		Name:     command.Name,
 		Protocol: protocol,
 		Comment:  comment,
+		Disabled: isDisabled(command.Parameters),
+		Pos:      p.pos,
+		Source:   command.Text,
+	})
+
+	return nil
+}
+
+// handleAddService processes "add service" commands. A standalone service is
+// modelled as a service group of the same name with a single member, so
+// "bind lb vserver" resolves it like any other group.
+func (p *CommandProcessor) handleAddService(command *CitrixCommand) error {
+	if len(command.Arguments) < 3 {
+		return fmt.Errorf("add service command requires server, protocol, and port arguments")
+	}
+
+	serverName := command.Arguments[0]
+	comment := command.Parameters["-comment"]
+	p.checkReference(command, "server", serverName)
+
+	// Services may name an IP instead of a server; the appliance then creates
+	// a server named after the IP
+	if p.config.ServerByName(serverName) == nil && net.ParseIP(serverName) != nil {
+		p.config.AddServer(&ServerInfo{
+			Name:   serverName,
+			IP:     serverName,
+			Pos:    p.pos,
+			Source: command.Text,
+		})
+	}
+
+	p.config.AddServiceGroupDef(&ServiceGroupDef{
+		Name:     command.Name,
+		Protocol: command.Arguments[1],
+		Comment:  comment,
+		Disabled: isDisabled(command.Parameters),
+		Metadata: map[string]string{"citrix.type": "service"},
+		Pos:      p.pos,
+		Source:   command.Text,
+	})
+	p.config.AddServiceGroup(&ServiceGroup{
+		Name:       command.Name,
+		ServerName: serverName,
+		Port:       command.Arguments[2],
+		Comment:    comment,
+		Pos:        p.pos,
+		Source:     command.Text,
 	})
 
 	return nil
 }
 
+// isService reports whether the named service group was defined by "add service"
+func (p *CommandProcessor) isService(name string) bool {
+	def := p.config.ServiceGroupDefByName(name)
+	return def != nil && def.Metadata["citrix.type"] == "service"
+}
+
+// memberOf returns the member of a service group bound to server on port, or nil
+func (p *CommandProcessor) memberOf(group, server, port string) *ServiceGroup {
+	for _, member := range p.config.MembersOf(group) {
+		if member.ServerName == server && member.Port == port {
+			return member
+		}
+	}
+	return nil
+}
+
 // handleBindCommand processes bind commands
-func (p *CommandProcessor) handleBindCommand(command *CitrixCommand, serviceGroups *[]ServiceGroup, vserverBindings *[]VServerBinding) error {
-	objectType := strings.ToLower(strings.ReplaceAll(command.ObjectType, " ", ""))
+func (p *CommandProcessor) handleBindCommand(command *CitrixCommand) error {
+	objectType := objectKind(command.ObjectType)
 	switch objectType {
 	case "servicegroup":
-		return p.handleBindServiceGroup(command, serviceGroups)
+		return p.handleBindServiceGroup(command)
 	case "lbvserver":
-		return p.handleBindLBVServer(command, vserverBindings)
+		return p.handleBindLBVServer(command)
+	case "csvserver":
+		return p.handleBindCSVServer(command)
+	case "sslvserver":
+		return p.handleBindSSLVServer(command)
+	case "sslservicegroup", "sslservice":
+		return p.handleBindSSLServiceGroup(command)
+	case "sslprofile":
+		profile := p.config.SSLProfileByName(command.Name)
+		switch {
+		case profile == nil:
+			p.reportMissing(command)
+		case command.Parameters["-cipherName"] != "":
+			p.bindCipher(command, &profile.Ciphers)
+		default:
+			p.recordUntranslated(command, "")
+		}
+		return nil
+	case "sslcipher":
+		group := p.config.CipherGroupByName(command.Name)
+		switch {
+		case group == nil:
+			p.reportMissing(command)
+		case command.Parameters["-cipherName"] != "":
+			p.bindCipher(command, &group.Ciphers)
+		default:
+			p.recordUntranslated(command, "")
+		}
+		return nil
+	case "service":
+		if command.Parameters["-monitorName"] != "" {
+			return p.handleBindMonitor(command)
+		}
+		p.recordUntranslated(command, "")
+		return nil
 	default:
-		// Ignore unknown object types for now
+		p.recordUntranslated(command, "")
+		return nil
+	}
+}
+
+// handleBindMonitor processes "bind serviceGroup|service <name> -monitorName
+// <monitor>". Monitors that Traefik cannot express, fully or in part, are
+// also recorded as untranslated so the gap report lists the affected vservers.
+func (p *CommandProcessor) handleBindMonitor(command *CitrixCommand) error {
+	monitorName := command.Parameters["-monitorName"]
+	p.checkReference(command, "servicegroup", command.Name)
+	p.checkReference(command, "lbmonitor", monitorName)
+
+	p.config.AddMonitorBinding(&MonitorBinding{
+		ServiceName: command.Name,
+		MonitorName: monitorName,
+		Pos:         p.pos,
+		Source:      command.Text,
+	})
+
+	monitor := p.config.LookupMonitor(monitorName)
+	if monitor == nil {
+		p.recordUntranslated(command, fmt.Sprintf("monitor '%s' is not defined and gets no health check", monitorName))
 		return nil
 	}
+	check, unsupported, ignored := translateMonitor(monitor)
+	switch {
+	case check == nil:
+		p.recordUntranslated(command, fmt.Sprintf("monitor '%s' is not translated: %s", monitorName, unsupported))
+	case len(ignored) > 0:
+		p.recordUntranslated(command, fmt.Sprintf("monitor '%s' is translated without %s", monitorName, strings.Join(ignored, ", ")))
+	}
+
+	return nil
 }
 
 // handleBindServiceGroup processes "bind serviceGroup" commands
-func (p *CommandProcessor) handleBindServiceGroup(command *CitrixCommand, serviceGroups *[]ServiceGroup) error {
-	// Skip monitor bindings (they don't have server/port arguments)
+func (p *CommandProcessor) handleBindServiceGroup(command *CitrixCommand) error {
+	// Monitor bindings don't have server/port arguments
 	if command.Parameters["-monitorName"] != "" {
-		return nil
+		return p.handleBindMonitor(command)
 	}
 
 	if len(command.Arguments) < 2 {
 		return fmt.Errorf("bind serviceGroup command requires server name and port arguments")
 	}
+	p.checkReference(command, "servicegroup", command.Name)
+	p.checkReference(command, "server", command.Arguments[0])
 
+	weight, err := parseWeight(command.Parameters)
+	if err != nil {
+		return err
+	}
 	comment := command.Parameters["-comment"]
 
-	*serviceGroups = append(*serviceGroups, ServiceGroup{
+	p.config.AddServiceGroup(&ServiceGroup{
 		Name:       command.Name,
 		ServerName: command.Arguments[0],
 		Port:       command.Arguments[1],
+		Weight:     weight,
+		Disabled:   isDisabled(command.Parameters),
 		Comment:    comment,
+		Pos:        p.pos,
+		Source:     command.Text,
 	})
 
 	return nil
 }
 
+// isDisabled reports whether the parameters include -state DISABLED
+func isDisabled(parameters map[string]string) bool {
+	return strings.EqualFold(parameters["-state"], "DISABLED")
+}
+
+// parseWeight returns the -weight parameter, or 0 when it is not given
+func parseWeight(parameters map[string]string) (int, error) {
+	value, exists := parameters["-weight"]
+	if !exists {
+		return 0, nil
+	}
+	weight, err := strconv.Atoi(value)
+	if err != nil || weight < 1 || weight > 100 {
+		return 0, fmt.Errorf("invalid -weight %q: expected a number from 1 to 100", value)
+	}
+	return weight, nil
+}
+
 // handleBindLBVServer processes "bind lb vserver" commands
-func (p *CommandProcessor) handleBindLBVServer(command *CitrixCommand, vserverBindings *[]VServerBinding) error {
+func (p *CommandProcessor) handleBindLBVServer(command *CitrixCommand) error {
 	var serviceName string
 
 	// Check if there are arguments and if the first one doesn't start with '-'
@@ -131,121 +769,824 @@ func (p *CommandProcessor) handleBindLBVServer(command *CitrixCommand, vserverBi
 		serviceName = command.Arguments[0]
 	}
 
+	p.checkReference(command, "lbvserver", command.Name)
+	if serviceName != "" {
+		p.checkReference(command, "servicegroup", serviceName)
+	}
+
 	// Extract policy-related parameters
 	policyName := command.Parameters["-policyName"]
 	priority := command.Parameters["-priority"]
 	gotoExpression := command.Parameters["-gotoPriorityExpression"]
 	bindType := command.Parameters["-type"]
 	comment := command.Parameters["-comment"]
+	weight, err := parseWeight(command.Parameters)
+	if err != nil {
+		return err
+	}
 
-	*vserverBindings = append(*vserverBindings, VServerBinding{
+	p.config.AddVServerBinding(&VServerBinding{
 		VServerName:    command.Name,
 		ServiceName:    serviceName,
 		PolicyName:     policyName,
 		Priority:       priority,
 		GotoExpression: gotoExpression,
 		Type:           bindType,
+		Weight:         weight,
 		Comment:        comment,
+		Pos:            p.pos,
+		Source:         command.Text,
 	})
 
-	return nil
-}
+	if policyName != "" && p.config.ResponderPolicyByName(policyName) != nil {
+		p.checkReference(command, "responderpolicy", policyName)
+	} else if policyName != "" && p.config.RewritePolicyByName(policyName) != nil {
+		p.checkReference(command, "rewritepolicy", policyName)
+	} else if policyName != "" {
+		kind := "policy"
+		if known, exists := p.policyKinds[policyName]; exists {
+			kind = known
+		}
+		var details []string
+		if bindType != "" {
+			details = append(details, bindType)
+		}
+		if priority != "" {
+			details = append(details, "priority "+priority)
+		}
+		reason := fmt.Sprintf("%s '%s' is not applied", kind, policyName)
+		if len(details) > 0 {
+			reason = fmt.Sprintf("%s '%s' (%s) is not applied", kind, policyName, strings.Join(details, ", "))
+		}
+		p.recordUntranslated(command, reason)
+	}
 
-// handleSetCommand processes set commands
-func (p *CommandProcessor) handleSetCommand(_ *CitrixCommand) error {
-	// For now, we ignore set commands as they typically modify existing objects
-	// rather than define new ones
 	return nil
 }
 
-// ParseL7Settings parses the L7 configuration file using proper Citrix command parsing
-func ParseL7Settings(filename string) ([]ServerInfo, []VServerInfo, []ServiceGroupDef, []ServiceGroup, []VServerBinding, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, nil, nil, nil, nil, err
-	}
-	defer file.Close()
-
-	var servers []ServerInfo
-	var vservers []VServerInfo
-	var serviceGroupDefs []ServiceGroupDef
-	var serviceGroups []ServiceGroup
-	var vserverBindings []VServerBinding
-
-	processor := NewCommandProcessor()
-	scanner := bufio.NewScanner(file)
-	lineNumber := 0
-
-	for scanner.Scan() {
-		lineNumber++
-		line := strings.TrimSpace(scanner.Text())
+// handleBindCSVServer processes "bind cs vserver" commands: content switching
+// policies with their priority and optional -targetLBVserver, and the
+// default lb vserver given with -lbvserver or as the only argument. Responder
+// and rewrite policies are kept with their -type and -gotoPriorityExpression;
+// other policies bound with -type are recorded as untranslated.
+func (p *CommandProcessor) handleBindCSVServer(command *CitrixCommand) error {
+	p.checkReference(command, "csvserver", command.Name)
 
-		// Skip empty lines and comments
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
+	policyName := command.Parameters["-policyName"]
+	responder := p.config.ResponderPolicyByName(policyName) != nil
+	if policyName != "" && (responder || p.config.RewritePolicyByName(policyName) != nil) {
+		if responder {
+			p.checkReference(command, "responderpolicy", policyName)
+		} else {
+			p.checkReference(command, "rewritepolicy", policyName)
 		}
-
-		// Parse the Citrix command
-		command, err := ParseCitrixCommand(line)
-		if err != nil {
-			return nil, nil, nil, nil, nil, fmt.Errorf("line %d: %v", lineNumber, err)
+		bindType := command.Parameters["-type"]
+		if bindType == "" {
+			bindType = "REQUEST"
 		}
-
-		// Skip if command is nil (empty line or comment)
-		if command == nil {
-			continue
+		p.config.AddCSBinding(&CSBinding{
+			VServerName:    command.Name,
+			PolicyName:     policyName,
+			Priority:       command.Parameters["-priority"],
+			Type:           bindType,
+			GotoExpression: command.Parameters["-gotoPriorityExpression"],
+			Pos:            p.pos,
+			Source:         command.Text,
+		})
+		return nil
+	}
+	var target string
+	if len(command.Arguments) > 0 {
+		target = command.Arguments[0]
+	}
+	if policyName == "" {
+		if lbvserver := command.Parameters["-lbvserver"]; lbvserver != "" {
+			target = lbvserver
 		}
-
-		// Process the command based on action and object type
-		switch command.Action {
-		case "add":
-			err = processor.handleAddCommand(command, &servers, &vservers, &serviceGroupDefs)
-		case "bind":
-			err = processor.handleBindCommand(command, &serviceGroups, &vserverBindings)
-		case "set":
-			err = processor.handleSetCommand(command)
-		default:
-			// Ignore unknown commands for now
-			continue
+		if target == "" {
+			p.recordUntranslated(command, "")
+			return nil
 		}
+	} else if explicit := command.Parameters["-targetLBVserver"]; explicit != "" {
+		target = explicit
+	}
 
-		if err != nil {
-			return nil, nil, nil, nil, nil, fmt.Errorf("line %d: %v", lineNumber, err)
+	isCS := p.config.CSPolicyByName(policyName) != nil
+	if policyName != "" && (command.Parameters["-type"] != "" || !isCS && p.policyKinds[policyName] != "") {
+		kind := "policy"
+		if known, exists := p.policyKinds[policyName]; exists {
+			kind = known
 		}
+		p.recordUntranslated(command, fmt.Sprintf("%s '%s' bound to cs vserver '%s' is not applied", kind, policyName, command.Name))
+		return nil
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, nil, nil, nil, nil, err
+	if policyName != "" {
+		p.checkReference(command, "cspolicy", policyName)
+	}
+	if target != "" {
+		p.checkReference(command, "lbvserver", target)
 	}
 
-	return servers, vservers, serviceGroupDefs, serviceGroups, vserverBindings, nil
+	p.config.AddCSBinding(&CSBinding{
+		VServerName:     command.Name,
+		PolicyName:      policyName,
+		Priority:        command.Parameters["-priority"],
+		TargetLBVServer: target,
+		Pos:             p.pos,
+		Source:          command.Text,
+	})
+	return nil
 }
 
-// ParseL7SettingsFromReader parses Citrix L7 settings from an io.Reader (stdin, pipe, etc.)
-func ParseL7SettingsFromReader(reader io.Reader) ([]ServerInfo, []VServerInfo, []ServiceGroupDef, []ServiceGroup, []VServerBinding, error) {
-	var servers []ServerInfo
-	var vservers []VServerInfo
-	var serviceGroupDefs []ServiceGroupDef
-	var serviceGroups []ServiceGroup
-	var vserverBindings []VServerBinding
-
+// handleBindSSLVServer processes "bind ssl vserver <vs> -certkeyName <ck>"
+// commands, with -SNICert for certificates chosen by server name and -CA for
+// client certificate authorities, and "bind ssl vserver <vs> -cipherName
+// <cipher>". Other SSL vserver bindings, such as ECC curves, are recorded as
+// untranslated.
+func (p *CommandProcessor) handleBindSSLVServer(command *CitrixCommand) error {
+	if command.Parameters["-cipherName"] != "" {
+		if ssl := p.sslVServer(command); ssl != nil {
+			p.bindCipher(command, &ssl.Ciphers)
+		}
+		return nil
+	}
+
+	certKeyName := command.Parameters["-certkeyName"]
+	if certKeyName == "" {
+		p.recordUntranslated(command, "")
+		return nil
+	}
+	p.checkReference(command, "sslcertkey", certKeyName)
+
+	_, sni := command.Parameters["-SNICert"]
+	_, ca := command.Parameters["-CA"]
+	p.config.AddSSLBinding(&SSLBinding{
+		VServerName: command.Name,
+		CertKeyName: certKeyName,
+		SNI:         sni,
+		CA:          ca,
+		Pos:         p.pos,
+		Source:      command.Text,
+	})
+
+	return nil
+}
+
+// handleBindSSLServiceGroup processes "bind ssl serviceGroup|service <name>
+// -certkeyName <ck>", with -CA for the authorities trusted to issue server
+// certificates and without it for the client certificate presented to the
+// servers. Other bindings, such as ciphers, are recorded as untranslated.
+func (p *CommandProcessor) handleBindSSLServiceGroup(command *CitrixCommand) error {
+	certKeyName := command.Parameters["-certkeyName"]
+	if certKeyName == "" {
+		p.recordUntranslated(command, "")
+		return nil
+	}
+	ssl := p.sslServiceGroup(command)
+	if ssl == nil {
+		return nil
+	}
+	p.checkReference(command, "sslcertkey", certKeyName)
+
+	if _, ca := command.Parameters["-CA"]; ca {
+		if !slices.Contains(ssl.CAs, certKeyName) {
+			ssl.CAs = append(ssl.CAs, certKeyName)
+		}
+		return nil
+	}
+	ssl.ClientCert = certKeyName
+	return nil
+}
+
+// sslServiceGroup returns the SSL settings of the service group or service a
+// command targets, adding them on first use. It reports the command and
+// returns nil when the group does not exist.
+func (p *CommandProcessor) sslServiceGroup(command *CitrixCommand) *SSLServiceGroup {
+	if !p.config.hasServiceGroup(command.Name) {
+		p.reportMissing(command)
+		return nil
+	}
+	if ssl := p.config.SSLServiceGroupByName(command.Name); ssl != nil {
+		return ssl
+	}
+	return p.config.AddSSLServiceGroup(&SSLServiceGroup{
+		Name:   command.Name,
+		Pos:    p.pos,
+		Source: command.Text,
+	})
+}
+
+// sslVServer returns the SSL settings of the lb or cs vserver a command
+// targets, adding them on first use. It reports the command and returns nil
+// when neither vserver exists.
+func (p *CommandProcessor) sslVServer(command *CitrixCommand) *SSLVServer {
+	if _, exists := sslVServer(p.config, command.Name); !exists {
+		p.reportMissing(command)
+		return nil
+	}
+	if ssl := p.config.SSLVServerByName(command.Name); ssl != nil {
+		return ssl
+	}
+	return p.config.AddSSLVServer(&SSLVServer{
+		Name:   command.Name,
+		Pos:    p.pos,
+		Source: command.Text,
+	})
+}
+
+// bindCipher appends the -cipherName of a bind command to the ciphers of an
+// ssl vserver, ssl profile or cipher group. Ciphers keep their binding order,
+// so -cipherPriority needs no translation.
+func (p *CommandProcessor) bindCipher(command *CitrixCommand, ciphers *[]SSLCipher) {
+	name := command.Parameters["-cipherName"]
+	p.checkReference(command, "sslcipher", name)
+	*ciphers = append(*ciphers, SSLCipher{Name: name, Pos: p.pos, Source: command.Text})
+	p.applyParameters(command, map[string]func(string){
+		"-cipherName":     func(string) {},
+		"-cipherPriority": func(string) {},
+	})
+}
+
+// unbindCipher removes the -cipherName of an unbind command from the ciphers
+// of an ssl vserver, ssl profile or cipher group. The built-in DEFAULT group
+// is bound implicitly, so unbinding it is not reported.
+func (p *CommandProcessor) unbindCipher(command *CitrixCommand, ciphers *[]SSLCipher) {
+	name := command.Parameters["-cipherName"]
+	var removed int
+	*ciphers, removed = removeWhere(*ciphers, func(cipher SSLCipher) bool { return cipher.Name == name })
+	if removed == 0 && !strings.EqualFold(name, defaultCipherGroup) {
+		p.warn(command, "not-bound", "cipher '%s' is not bound to %s '%s'", name, command.ObjectType, command.Name)
+	}
+}
+
+// handleSetCommand processes set commands
+func (p *CommandProcessor) handleSetCommand(command *CitrixCommand) error {
+	return p.applySettings(command, false)
+}
+
+// handleUnsetCommand processes unset commands
+func (p *CommandProcessor) handleUnsetCommand(command *CitrixCommand) error {
+	return p.applySettings(command, true)
+}
+
+// applySettings applies the parameters of a set or unset command to an
+// existing server, lb vserver, service group, lb monitor, content switching
+// vserver, policy or action, responder or rewrite policy or action, or the
+// SSL settings of a vserver, ssl profile or service group. Unset parameters
+// carry no value and reset the setting. Parameters that are not modelled,
+// and commands on other object types, are recorded as untranslated.
+func (p *CommandProcessor) applySettings(command *CitrixCommand, unset bool) error {
+	var setters map[string]func(value string)
+
+	switch objectKind(command.ObjectType) {
+	case "server":
+		server := p.config.ServerByName(command.Name)
+		if server == nil {
+			p.reportMissing(command)
+			return nil
+		}
+		setters = map[string]func(string){
+			"-comment": func(value string) { server.Comment = value },
+		}
+		if !unset {
+			setters["-IPAddress"] = func(value string) { server.IP = value }
+		}
+	case "lbvserver":
+		vserver := p.config.VServerByName(command.Name)
+		if vserver == nil {
+			p.reportMissing(command)
+			return nil
+		}
+		setters = persistenceSetters(vserver)
+		for name, apply := range backupSetters(vserver) {
+			setters[name] = apply
+		}
+		setters["-lbMethod"] = func(value string) {
+			// unset restores the appliance default
+			vserver.LBDefault = value == ""
+			if value == "" {
+				value = defaultCitrixLBMethod
+			}
+			vserver.LBMethod = value
+		}
+		if !unset {
+			setters["-IPAddress"] = func(value string) { vserver.IP = value }
+			setters["-port"] = func(value string) { vserver.Port = value }
+		}
+		// The VIP index is keyed by address
+		defer p.config.rekeyVServer(vserver, VIPKey(vserver.IP, vserver.Port))
+	case "lbmonitor":
+		monitor := p.config.MonitorByName(command.Name)
+		if monitor == nil {
+			p.reportMissing(command)
+			return nil
+		}
+		setters = monitorSetters(monitor)
+	case "csvserver":
+		vserver := p.config.CSVServerByName(command.Name)
+		if vserver == nil {
+			p.reportMissing(command)
+			return nil
+		}
+		setters = map[string]func(string){}
+		if !unset {
+			setters["-IPAddress"] = func(value string) { vserver.IP = value }
+			setters["-port"] = func(value string) { vserver.Port = value }
+		}
+		defer p.config.rekeyCSVServer(vserver, VIPKey(vserver.IP, vserver.Port))
+	case "cspolicy":
+		policy := p.config.CSPolicyByName(command.Name)
+		if policy == nil {
+			p.reportMissing(command)
+			return nil
+		}
+		setters = csPolicySetters(policy)
+	case "csaction":
+		action := p.config.CSActionByName(command.Name)
+		if action == nil {
+			p.reportMissing(command)
+			return nil
+		}
+		setters = map[string]func(string){
+			"-targetLBVserver":   func(value string) { action.TargetLBVServer = value },
+			"-targetVserverExpr": func(value string) { action.TargetExpr = value },
+		}
+	case "responderaction":
+		action := p.config.ResponderActionByName(command.Name)
+		if action == nil {
+			p.reportMissing(command)
+			return nil
+		}
+		setters = responderActionSetters(action)
+	case "responderpolicy":
+		policy := p.config.ResponderPolicyByName(command.Name)
+		if policy == nil {
+			p.reportMissing(command)
+			return nil
+		}
+		setters = map[string]func(string){
+			"-rule":   func(value string) { policy.Rule = value },
+			"-action": func(value string) { policy.Action = value },
+		}
+	case "rewriteaction":
+		action := p.config.RewriteActionByName(command.Name)
+		if action == nil {
+			p.reportMissing(command)
+			return nil
+		}
+		setters = rewriteActionSetters(action)
+	case "rewritepolicy":
+		policy := p.config.RewritePolicyByName(command.Name)
+		if policy == nil {
+			p.reportMissing(command)
+			return nil
+		}
+		setters = map[string]func(string){
+			"-rule":   func(value string) { policy.Rule = value },
+			"-action": func(value string) { policy.Action = value },
+		}
+	case "sslvserver":
+		ssl := p.sslVServer(command)
+		if ssl == nil {
+			return nil
+		}
+		setters = sslSettingsSetters(&ssl.SSLSettings)
+		setters["-sslProfile"] = func(value string) {
+			if value != "" {
+				p.checkReference(command, "sslprofile", value)
+			}
+			ssl.Profile = value
+		}
+	case "sslprofile":
+		profile := p.config.SSLProfileByName(command.Name)
+		if profile == nil {
+			p.reportMissing(command)
+			return nil
+		}
+		setters = sslSettingsSetters(&profile.SSLSettings)
+	case "sslservicegroup", "sslservice":
+		ssl := p.sslServiceGroup(command)
+		if ssl == nil {
+			return nil
+		}
+		setters = sslServiceGroupSetters(ssl)
+	case "servicegroup", "service":
+		def := p.config.ServiceGroupDefByName(command.Name)
+		if def == nil {
+			if p.config.hasServiceGroup(command.Name) {
+				p.recordUntranslated(command, "")
+			} else {
+				p.reportMissing(command)
+			}
+			return nil
+		}
+		setters = map[string]func(string){
+			"-comment": func(value string) { def.Comment = value },
+		}
+		if members := p.config.MembersOf(command.Name); !unset && p.isService(command.Name) && len(members) == 1 {
+			setters["-port"] = func(value string) { members[0].Port = value }
+		}
+		// "set serviceGroup <name> <server> <port> -weight <n>" changes a member
+		if len(command.Arguments) >= 2 {
+			weight, err := parseWeight(command.Parameters)
+			if err != nil && !unset {
+				return err
+			}
+			member := p.memberOf(command.Name, command.Arguments[0], command.Arguments[1])
+			if member == nil {
+				p.warn(command, "not-bound", "server '%s' is not bound to service group '%s' on port %s",
+					command.Arguments[0], command.Name, command.Arguments[1])
+				return nil
+			}
+			setters = map[string]func(string){
+				"-weight": func(string) { member.Weight = weight },
+			}
+		}
+	default:
+		p.recordUntranslated(command, "")
+		return nil
+	}
+
+	p.applyParameters(command, setters)
+	return nil
+}
+
+// applyParameters applies each parameter of a command with its setter, in
+// name order, and records the command as untranslated with the parameters
+// that have none
+func (p *CommandProcessor) applyParameters(command *CitrixCommand, setters map[string]func(value string)) {
+	names := make([]string, 0, len(command.Parameters))
+	for name := range command.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var ignored []string
+	for _, name := range names {
+		if apply, exists := setters[name]; exists {
+			apply(command.Parameters[name])
+		} else {
+			ignored = append(ignored, name)
+		}
+	}
+	if len(ignored) > 0 {
+		p.recordUntranslated(command, "not applied: "+strings.Join(ignored, ", "))
+	}
+}
+
+// handleUnbindCommand processes unbind commands, undoing earlier bind commands
+func (p *CommandProcessor) handleUnbindCommand(command *CitrixCommand) error {
+	switch objectKind(command.ObjectType) {
+	case "servicegroup", "service":
+		if monitorName := command.Parameters["-monitorName"]; monitorName != "" {
+			if p.config.RemoveMonitorBinding(command.Name, monitorName) == 0 {
+				p.warn(command, "not-bound", "monitor '%s' is not bound to %s '%s'", monitorName, command.ObjectType, command.Name)
+			}
+			return nil
+		}
+		if objectKind(command.ObjectType) == "service" {
+			p.recordUntranslated(command, "")
+			return nil
+		}
+		if len(command.Arguments) < 1 {
+			return fmt.Errorf("unbind serviceGroup command requires server name argument")
+		}
+		if !p.config.hasServiceGroup(command.Name) {
+			p.reportMissing(command)
+			return nil
+		}
+		server, port := command.Arguments[0], ""
+		if len(command.Arguments) > 1 {
+			port = command.Arguments[1]
+		}
+		if p.config.RemoveMember(command.Name, server, port) == 0 {
+			p.warn(command, "not-bound", "server '%s' is not bound to service group '%s'", server, command.Name)
+		}
+	case "lbvserver":
+		var serviceName string
+		if len(command.Arguments) > 0 && !strings.HasPrefix(command.Arguments[0], "-") {
+			serviceName = command.Arguments[0]
+		}
+		policyName := command.Parameters["-policyName"]
+		if serviceName == "" && policyName == "" {
+			return fmt.Errorf("unbind lb vserver command requires a service name or -policyName")
+		}
+		if p.config.VServerByName(command.Name) == nil {
+			p.reportMissing(command)
+			return nil
+		}
+		if p.config.RemoveVServerBinding(command.Name, serviceName, policyName) == 0 {
+			p.warn(command, "not-bound", "'%s' is not bound to lb vserver '%s'", serviceName+policyName, command.Name)
+		}
+	case "csvserver":
+		policyName := command.Parameters["-policyName"]
+		target := command.Parameters["-lbvserver"]
+		if policyName == "" && target == "" {
+			return fmt.Errorf("unbind cs vserver command requires -policyName or -lbvserver")
+		}
+		if p.config.CSVServerByName(command.Name) == nil {
+			p.reportMissing(command)
+			return nil
+		}
+		if policyName != "" && p.config.CSPolicyByName(policyName) == nil && p.policyKinds[policyName] != "" {
+			p.recordUntranslated(command, "")
+			return nil
+		}
+		if p.config.RemoveCSBinding(command.Name, policyName) == 0 {
+			p.warn(command, "not-bound", "'%s' is not bound to cs vserver '%s'", policyName+target, command.Name)
+		}
+	case "sslvserver":
+		if command.Parameters["-cipherName"] != "" {
+			if ssl := p.config.SSLVServerByName(command.Name); ssl != nil {
+				p.unbindCipher(command, &ssl.Ciphers)
+			} else if _, exists := sslVServer(p.config, command.Name); !exists {
+				p.reportMissing(command)
+			}
+			return nil
+		}
+		certKeyName := command.Parameters["-certkeyName"]
+		if certKeyName == "" {
+			p.recordUntranslated(command, "")
+			return nil
+		}
+		if p.config.RemoveSSLBinding(command.Name, certKeyName) == 0 {
+			p.warn(command, "not-bound", "certKey '%s' is not bound to ssl vserver '%s'", certKeyName, command.Name)
+		}
+	case "sslservicegroup", "sslservice":
+		certKeyName := command.Parameters["-certkeyName"]
+		if certKeyName == "" {
+			p.recordUntranslated(command, "")
+			return nil
+		}
+		ssl := p.config.SSLServiceGroupByName(command.Name)
+		if ssl == nil && !p.config.hasServiceGroup(command.Name) {
+			p.reportMissing(command)
+			return nil
+		}
+		var removed int
+		if ssl != nil {
+			ssl.CAs, removed = removeWhere(ssl.CAs, func(name string) bool { return name == certKeyName })
+			if ssl.ClientCert == certKeyName {
+				ssl.ClientCert = ""
+				removed++
+			}
+		}
+		if removed == 0 {
+			p.warn(command, "not-bound", "certKey '%s' is not bound to %s '%s'", certKeyName, command.ObjectType, command.Name)
+		}
+	case "sslprofile":
+		profile := p.config.SSLProfileByName(command.Name)
+		switch {
+		case command.Parameters["-cipherName"] == "":
+			p.recordUntranslated(command, "")
+		case profile == nil:
+			p.reportMissing(command)
+		default:
+			p.unbindCipher(command, &profile.Ciphers)
+		}
+	case "sslcipher":
+		group := p.config.CipherGroupByName(command.Name)
+		switch {
+		case command.Parameters["-cipherName"] == "":
+			p.recordUntranslated(command, "")
+		case group == nil:
+			p.reportMissing(command)
+		default:
+			p.unbindCipher(command, &group.Ciphers)
+		}
+	default:
+		p.recordUntranslated(command, "")
+	}
+
+	return nil
+}
+
+// handleStateCommand processes enable and disable commands on servers,
+// service groups or their members, services, lb vservers and cs vservers
+func (p *CommandProcessor) handleStateCommand(command *CitrixCommand, disabled bool) error {
+	switch objectKind(command.ObjectType) {
+	case "server":
+		server := p.config.ServerByName(command.Name)
+		if server == nil {
+			p.reportMissing(command)
+			return nil
+		}
+		server.Disabled = disabled
+	case "lbvserver":
+		vserver := p.config.VServerByName(command.Name)
+		if vserver == nil {
+			p.reportMissing(command)
+			return nil
+		}
+		vserver.Disabled = disabled
+	case "csvserver":
+		vserver := p.config.CSVServerByName(command.Name)
+		if vserver == nil {
+			p.reportMissing(command)
+			return nil
+		}
+		vserver.Disabled = disabled
+	case "servicegroup", "service":
+		if !p.config.hasServiceGroup(command.Name) {
+			p.reportMissing(command)
+			return nil
+		}
+		// "disable serviceGroup <name> <server> [<port>]" changes members only
+		if len(command.Arguments) > 0 {
+			changed := 0
+			for _, member := range p.config.MembersOf(command.Name) {
+				if member.ServerName == command.Arguments[0] && (len(command.Arguments) < 2 || member.Port == command.Arguments[1]) {
+					member.Disabled = disabled
+					changed++
+				}
+			}
+			if changed == 0 {
+				p.warn(command, "not-bound", "server '%s' is not bound to service group '%s'", command.Arguments[0], command.Name)
+			}
+			return nil
+		}
+		if def := p.config.ServiceGroupDefByName(command.Name); def != nil {
+			def.Disabled = disabled
+		} else {
+			for _, member := range p.config.MembersOf(command.Name) {
+				member.Disabled = disabled
+			}
+		}
+	default:
+		p.recordUntranslated(command, "")
+	}
+
+	return nil
+}
+
+// handleRemoveCommand processes rm commands. Removing an object also removes
+// what depends on it, as the appliance does: the members and services of a
+// server, and the bindings of a virtual server or service group.
+func (p *CommandProcessor) handleRemoveCommand(command *CitrixCommand) error {
+	kind := objectKind(command.ObjectType)
+	var removed bool
+
+	switch kind {
+	case "server":
+		// Standalone services cannot exist without their server
+		var services []string
+		for _, member := range p.config.ServiceGroups {
+			if member.ServerName == command.Name && p.isService(member.Name) {
+				services = append(services, member.Name)
+			}
+		}
+		for _, service := range services {
+			p.config.RemoveServiceGroup(service)
+			p.removed[objectKey("service", service)] = removal{how: fmt.Sprintf("removed with server '%s'", command.Name), pos: p.pos}
+		}
+		removed = p.config.RemoveServer(command.Name)
+	case "lbvserver":
+		removed = p.config.RemoveVServer(command.Name)
+	case "servicegroup", "service":
+		removed = p.config.RemoveServiceGroup(command.Name)
+	case "lbmonitor":
+		removed = p.config.RemoveMonitor(command.Name)
+	case "csvserver":
+		removed = p.config.RemoveCSVServer(command.Name)
+	case "cspolicy":
+		removed = p.config.RemoveCSPolicy(command.Name)
+	case "csaction":
+		removed = p.config.RemoveCSAction(command.Name)
+	case "responderpolicy":
+		removed = p.config.RemoveResponderPolicy(command.Name)
+	case "responderaction":
+		removed = p.config.RemoveResponderAction(command.Name)
+	case "rewritepolicy":
+		removed = p.config.RemoveRewritePolicy(command.Name)
+	case "rewriteaction":
+		removed = p.config.RemoveRewriteAction(command.Name)
+	case "sslcertkey":
+		removed = p.config.RemoveCertKey(command.Name)
+	case "sslprofile":
+		removed = p.config.RemoveSSLProfile(command.Name)
+	case "sslcipher":
+		removed = p.config.RemoveCipherGroup(command.Name)
+	default:
+		p.recordUntranslated(command, "")
+		return nil
+	}
+
+	if !removed {
+		p.reportMissing(command)
+		return nil
+	}
+	p.removed[objectKey(kind, command.Name)] = removal{how: "removed", pos: p.pos}
+	return nil
+}
+
+// handleRenameCommand processes rename commands, updating every reference to the object
+func (p *CommandProcessor) handleRenameCommand(command *CitrixCommand) error {
+	kind := objectKind(command.ObjectType)
+	var exists func(name string) bool
+	var rename func(name, newName string) bool
+
+	switch kind {
+	case "server":
+		exists = func(name string) bool { return p.config.ServerByName(name) != nil }
+		rename = p.config.RenameServer
+	case "lbvserver":
+		exists = func(name string) bool { return p.config.VServerByName(name) != nil }
+		rename = p.config.RenameVServer
+	case "servicegroup", "service":
+		exists = p.config.hasServiceGroup
+		rename = p.config.RenameServiceGroup
+	default:
+		p.recordUntranslated(command, "")
+		return nil
+	}
+
+	if len(command.Arguments) < 1 {
+		return fmt.Errorf("rename %s command requires a new name argument", command.ObjectType)
+	}
+	newName := command.Arguments[0]
+
+	if !exists(command.Name) {
+		p.reportMissing(command)
+		return nil
+	}
+	if exists(newName) {
+		p.warn(command, "rename-conflict", "cannot rename %s '%s' to '%s': the name is already in use",
+			command.ObjectType, command.Name, newName)
+		return nil
+	}
+
+	rename(command.Name, newName)
+	delete(p.removed, objectKey(kind, newName))
+	p.removed[objectKey(kind, command.Name)] = removal{how: fmt.Sprintf("renamed to '%s'", newName), pos: p.pos}
+	return nil
+}
+
+// ParseL7Settings parses the L7 configuration file using proper Citrix command parsing
+func ParseL7Settings(filename string) (*LBConfig, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseCitrix(file, ParseOptions{Filename: filename})
+}
+
+// ParseL7SettingsFromReader parses Citrix L7 settings from an io.Reader (stdin, pipe, etc.)
+func ParseL7SettingsFromReader(reader io.Reader) (*LBConfig, error) {
+	return ParseCitrix(reader, ParseOptions{})
+}
+
+// ParseCitrix parses Citrix L7 settings from a reader. The first malformed
+// command aborts parsing with a *DiagnosticError, unless opts.Lenient is set,
+// in which case the line is skipped and reported in the model's diagnostics.
+func ParseCitrix(reader io.Reader, opts ParseOptions) (*LBConfig, error) {
 	processor := NewCommandProcessor()
-	scanner := bufio.NewScanner(reader)
+	fail := func(d Diagnostic) error {
+		if !opts.Lenient {
+			return &DiagnosticError{Diagnostic: d}
+		}
+		d.Skipped = true
+		processor.config.AddDiagnostic(d)
+		return nil
+	}
+
+	scanner := newLineScanner(reader)
 	lineNumber := 0
 
 	for scanner.Scan() {
 		lineNumber++
-		line := strings.TrimSpace(scanner.Text())
+		raw := strings.TrimRight(scanner.Text(), " \t\r")
+		line := strings.TrimSpace(raw)
 
 		// Skip empty lines and comments
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
 
+		// Columns reported by the tokenizer are relative to the trimmed command
+		indent := len(raw) - len(line)
+		pos := Position{File: opts.Filename, Line: lineNumber, Column: indent + 1}
+
 		// Parse the Citrix command
 		command, err := ParseCitrixCommand(line)
 		if err != nil {
-			return nil, nil, nil, nil, nil, fmt.Errorf("line %d: %v", lineNumber, err)
+			code := "invalid-command"
+			if syntaxErr, ok := err.(*SyntaxError); ok {
+				code = "syntax"
+				pos.Column = indent + syntaxErr.Column
+			}
+			if err := fail(Diagnostic{
+				Position: pos,
+				Severity: SeverityError,
+				Code:     code,
+				Message:  err.Error(),
+				Snippet:  raw,
+			}); err != nil {
+				return nil, err
+			}
+			continue
 		}
 
 		// Skip if command is nil (empty line or comment)
@@ -253,150 +1594,475 @@ func ParseL7SettingsFromReader(reader io.Reader) ([]ServerInfo, []VServerInfo, [
 			continue
 		}
 
-		// Process the command based on action and object type
-		switch command.Action {
-		case "add":
-			err = processor.handleAddCommand(command, &servers, &vservers, &serviceGroupDefs)
-		case "bind":
-			err = processor.handleBindCommand(command, &serviceGroups, &vserverBindings)
-		case "set":
-			err = processor.handleSetCommand(command)
-		default:
-			// Ignore unknown commands for now
-			continue
-		}
-
-		if err != nil {
-			return nil, nil, nil, nil, nil, fmt.Errorf("line %d: %v", lineNumber, err)
+		if err := processor.Process(command, pos); err != nil {
+			if err := fail(Diagnostic{
+				Position: pos,
+				Severity: SeverityError,
+				Code:     "invalid-command",
+				Message:  err.Error(),
+				Snippet:  raw,
+			}); err != nil {
+				return nil, err
+			}
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, nil, nil, nil, nil, err
+		return nil, err
+	}
+
+	config := processor.Config()
+	config.Link()
+	return config, nil
+}
+
+// WeightMode selects how member weights are expressed in the generated configuration
+type WeightMode int
+
+const (
+	// WeightServices balances a weighted service over one child service per
+	// distinct weight, which every Traefik version supports
+	WeightServices WeightMode = iota
+	// WeightServers sets the weight of each server URL, for Traefik versions that support it
+	WeightServers
+)
+
+// ParseWeightMode converts "services" or "servers" to a WeightMode
+func ParseWeightMode(name string) (WeightMode, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "services":
+		return WeightServices, nil
+	case "servers":
+		return WeightServers, nil
+	default:
+		return WeightServices, fmt.Errorf("unknown weight mode %q: expected services or servers", name)
+	}
+}
+
+// DisabledMode selects what happens to administratively disabled objects
+type DisabledMode int
+
+const (
+	// DisabledDrop leaves disabled servers and virtual servers out
+	DisabledDrop DisabledMode = iota
+	// DisabledComment writes disabled servers and mappings commented out
+	DisabledComment
+)
+
+// ParseDisabledMode converts "drop" or "comment" to a DisabledMode
+func ParseDisabledMode(name string) (DisabledMode, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "drop":
+		return DisabledDrop, nil
+	case "comment":
+		return DisabledComment, nil
+	default:
+		return DisabledDrop, fmt.Errorf("unknown disabled mode %q: expected drop or comment", name)
 	}
+}
 
-	return servers, vservers, serviceGroupDefs, serviceGroups, vserverBindings, nil
+// TraefikVersion is the Traefik release the configuration is generated for.
+// The zero value stands for any version and uses no optional features.
+type TraefikVersion struct {
+	Major, Minor int
 }
 
-// GenerateTraefikConfig generates the Traefik configuration
-func GenerateTraefikConfig(servers []ServerInfo, vservers []VServerInfo, serviceGroupDefs []ServiceGroupDef, serviceGroups []ServiceGroup) TraefikConfig {
-	// Create a map of server names to server info
-	serverMap := make(map[string]ServerInfo)
-	for _, server := range servers {
-		serverMap[server.Name] = server
+// ParseTraefikVersion converts a version such as "3.4", "v3.4" or "2" to a
+// TraefikVersion; "" is the zero value
+func ParseTraefikVersion(name string) (TraefikVersion, error) {
+	version := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(name)), "v")
+	if version == "" {
+		return TraefikVersion{}, nil
 	}
 
-	// Create a map of service group definitions for comment lookup
-	serviceGroupDefMap := make(map[string]ServiceGroupDef)
-	for _, sgDef := range serviceGroupDefs {
-		serviceGroupDefMap[sgDef.Name] = sgDef
+	majorText, minorText, hasMinor := strings.Cut(version, ".")
+	if !hasMinor {
+		minorText = "0"
 	}
+	// Patch releases add no load balancer features
+	minorText, _, _ = strings.Cut(minorText, ".")
+
+	major, err1 := strconv.Atoi(majorText)
+	minor, err2 := strconv.Atoi(minorText)
+	if err1 != nil || err2 != nil || major < 1 || minor < 0 {
+		return TraefikVersion{}, fmt.Errorf("invalid Traefik version %q: expected a version such as 2.11 or 3.4", name)
+	}
+	return TraefikVersion{Major: major, Minor: minor}, nil
+}
 
-	// Group service groups by service name
-	serviceGroupMap := make(map[string][]ServiceGroup)
-	for _, sg := range serviceGroups {
-		serviceGroupMap[sg.Name] = append(serviceGroupMap[sg.Name], sg)
+// AtLeast reports whether the version is the given release or a later one
+func (v TraefikVersion) AtLeast(major, minor int) bool {
+	return v.Major > major || (v.Major == major && v.Minor >= minor)
+}
+
+// String returns the version as "major.minor", or "any" for the zero value
+func (v TraefikVersion) String() string {
+	if v == (TraefikVersion{}) {
+		return "any"
 	}
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// GenerateOptions controls how the model is translated into Traefik configuration
+type GenerateOptions struct {
+	Weights  WeightMode
+	Disabled DisabledMode
+	Traefik  TraefikVersion // Target release, enables the load balancing strategies it supports
+	CertDirs []string       // Directories holding the certKey files, in lookup order; empty keeps the appliance paths
+}
 
+// GenerateTraefikConfig generates the Traefik configuration. Each virtual
+// server gets a service named after it that load balances across the members
+// of every service group bound to it. Service groups that no virtual server
+// uses keep a service under their own name.
+func GenerateTraefikConfig(config *LBConfig) TraefikConfig {
+	return GenerateTraefikConfigWithOptions(config, GenerateOptions{})
+}
+
+// GenerateTraefikConfigWithOptions generates the Traefik configuration, see
+// GenerateTraefikConfig, expressing member weights, disabled objects and the
+// target Traefik version as selected by opts.
+func GenerateTraefikConfigWithOptions(config *LBConfig, opts GenerateOptions) TraefikConfig {
 	services := make(map[string]TraefikService)
+	used := make(map[string]bool)
 
-	// For each service group, create a Traefik service
-	for serviceName, groups := range serviceGroupMap {
-		var traefiktServers []TraefikServer
-		var serviceComment string
+	// For each virtual server, create a Traefik service from its bound service groups
+	for _, vserver := range config.VServers {
+		groups := config.VServerGroups(vserver.Name)
+		for _, group := range groups {
+			used[group] = true
+		}
+		if _, exists := services[vserver.Name]; exists || (vserver.Disabled && opts.Disabled == DisabledDrop) {
+			continue
+		}
+		weights := bindingWeights(config, vserver.Name)
+		if service, ok := buildService(config, groups, weights, opts, formatOrigin(vserver.Pos, vserver.Source), nil); ok {
+			service.LoadBalancer.Sticky, _ = translatePersistence(vserver)
+			service.LoadBalancer.Strategy = translateLBMethod(vserver.LBMethod, opts.Traefik).setting()
+			services[vserver.Name] = service
+			addPriorityFailover(services, config, vserver, groups, weights, opts)
+		}
+	}
+	addBackupFailovers(services, config)
 
-		// Check if there's a service group definition with a comment (priority)
-		if sgDef, exists := serviceGroupDefMap[serviceName]; exists && sgDef.Comment != "" {
-			serviceComment = sgDef.Comment
+	// Service groups not bound to any virtual server keep their own service
+	for _, serviceName := range config.ServiceGroupNames() {
+		if _, exists := services[serviceName]; used[serviceName] || exists {
+			continue
+		}
+		origin := ""
+		if sgDef := config.ServiceGroupDefByName(serviceName); sgDef != nil {
+			origin = formatOrigin(sgDef.Pos, sgDef.Source)
 		}
+		if service, ok := buildService(config, []string{serviceName}, nil, opts, origin, nil); ok {
+			services[serviceName] = service
+		}
+	}
 
-		for _, group := range groups {
-			if serverInfo, exists := serverMap[group.ServerName]; exists {
-				url := fmt.Sprintf("http://%s:%s", serverInfo.IP, group.Port)
-				traefiktServer := TraefikServer{URL: url}
+	applyWeights(services, opts.Weights)
+	routers, middlewares := buildRouters(config, services, opts)
+
+	return TraefikConfig{
+		HTTP: TraefikHTTP{
+			Routers:           routers,
+			Middlewares:       middlewares,
+			Services:          services,
+			ServersTransports: buildServersTransports(config, services, opts),
+		},
+		TLS: buildTLS(config, opts),
+	}
+}
+
+// buildService creates a Traefik service from the members of the given service
+// groups. Each server is weighted by its member weight times the weight its
+// group is bound with. Disabled members are dropped or marked as selected by
+// opts. The health check comes from the first group with a monitor Traefik
+// can express. Server URLs use the scheme of their group's protocol, and the
+// service references the servers transport of its first group reached over
+// TLS. A non-nil include selects the members to use. It returns false
+// when no enabled member resolves to a defined server.
+func buildService(config *LBConfig, serviceNames []string, weights map[string]int, opts GenerateOptions, origin string, include func(*ServiceGroup) bool) (TraefikService, bool) {
+	var traefiktServers []TraefikServer
+	enabled := 0
+	serviceComment := groupComment(config, serviceNames)
+	serviceOrigin := origin
+
+	for _, serviceName := range serviceNames {
+		scheme := backendScheme(config, serviceName)
+		for _, group := range config.MembersOf(serviceName) {
+			if include != nil && !include(group) {
+				continue
+			}
+			if serverInfo := group.Server; serverInfo != nil {
+				disabled := config.MemberDisabled(group)
+				if disabled && opts.Disabled == DisabledDrop {
+					continue
+				}
+				if !disabled {
+					enabled++
+				}
+				url := fmt.Sprintf("%s://%s:%s", scheme, serverInfo.IP, group.Port)
+				traefiktServer := TraefikServer{
+					URL:      url,
+					Weight:   max(group.Weight, 1) * max(weights[serviceName], 1),
+					Disabled: disabled,
+					Origin:   formatOrigin(group.Pos, group.Source),
+				}
 
 				// For server-level comments, only use server comment (not service group comment)
 				if serverInfo.Comment != "" {
 					traefiktServer.Comment = serverInfo.Comment
 				}
 
-				// For service-level comment, use add serviceGroup comment first, then bind serviceGroup comment
-				if serviceComment == "" && group.Comment != "" {
-					serviceComment = group.Comment
+				// Groups created only by bind commands are traced to their first member
+				if serviceOrigin == "" {
+					serviceOrigin = traefiktServer.Origin
 				}
 
 				traefiktServers = append(traefiktServers, traefiktServer)
 			}
 		}
+	}
+
+	if enabled == 0 {
+		return TraefikService{}, false
+	}
+
+	return TraefikService{
+		LoadBalancer: TraefikLoadBalancer{
+			Servers:          traefiktServers,
+			HealthCheck:      serviceHealthCheck(config, serviceNames),
+			ServersTransport: serviceTransport(config, serviceNames),
+		},
+		Comment: serviceComment,
+		Origin:  serviceOrigin,
+	}, true
+}
+
+// bindingWeights returns the weight of each service bound to the named
+// virtual server with "bind lb vserver -weight"
+func bindingWeights(config *LBConfig, vserver string) map[string]int {
+	weights := make(map[string]int)
+	for _, binding := range config.BindingsOf(vserver) {
+		if _, exists := weights[binding.ServiceName]; binding.ServiceName != "" && binding.Weight > 0 && !exists {
+			weights[binding.ServiceName] = binding.Weight
+		}
+	}
+	return weights
+}
+
+// applyWeights expresses the server weights set by buildService in the
+// selected form. Services whose servers all have the same weight need no
+// weights and are left as plain load balancers.
+func applyWeights(services map[string]TraefikService, mode WeightMode) {
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		service := services[name]
+		servers := service.LoadBalancer.Servers
+		uniform, first := true, 0
+		for _, server := range servers {
+			if server.Disabled {
+				continue
+			}
+			if first == 0 {
+				first = server.Weight
+			}
+			uniform = uniform && server.Weight == first
+		}
+		if uniform || mode == WeightServers {
+			if uniform {
+				for i := range servers {
+					servers[i].Weight = 0
+				}
+			}
+			continue
+		}
+
+		// One child service per distinct weight; each child's share is its
+		// weight times its enabled server count, so every server keeps its
+		// own weight. Disabled servers only join a child with enabled ones.
+		var weights []int
+		byWeight := make(map[int][]TraefikServer)
+		enabled := make(map[int]int)
+		for _, server := range servers {
+			if !server.Disabled {
+				enabled[server.Weight]++
+			}
+			if _, exists := byWeight[server.Weight]; !exists {
+				weights = append(weights, server.Weight)
+			}
+			weight := server.Weight
+			server.Weight = 0
+			byWeight[weight] = append(byWeight[weight], server)
+		}
 
-		if len(traefiktServers) > 0 {
-			services[serviceName] = TraefikService{
+		// The parent keeps the client on a child and the child on a server;
+		// children get Traefik's per-service default cookie names
+		weighted := &TraefikWeighted{Sticky: service.LoadBalancer.Sticky}
+		if service.LoadBalancer.HealthCheck != nil {
+			weighted.HealthCheck = &TraefikHealthStatus{}
+		}
+		var childSticky *TraefikSticky
+		if sticky := service.LoadBalancer.Sticky; sticky != nil && sticky.Cookie != nil {
+			cookie := *sticky.Cookie
+			cookie.Name = ""
+			childSticky = &TraefikSticky{Cookie: &cookie}
+		}
+		for _, weight := range weights {
+			if enabled[weight] == 0 {
+				continue
+			}
+			child := fmt.Sprintf("%s-weight%d", name, weight)
+			for suffix := 2; ; suffix++ {
+				if _, exists := services[child]; !exists {
+					break
+				}
+				child = fmt.Sprintf("%s-weight%d-%d", name, weight, suffix)
+			}
+			services[child] = TraefikService{
 				LoadBalancer: TraefikLoadBalancer{
-					Servers: traefiktServers,
+					Servers:          byWeight[weight],
+					Strategy:         service.LoadBalancer.Strategy,
+					HealthCheck:      service.LoadBalancer.HealthCheck,
+					Sticky:           childSticky,
+					ServersTransport: service.LoadBalancer.ServersTransport,
 				},
-				Comment: serviceComment,
+				Comment: fmt.Sprintf("weight %d members of %s", weight, name),
+				Origin:  service.Origin,
 			}
+			weighted.Services = append(weighted.Services, TraefikWeightedService{
+				Name:   child,
+				Weight: weight * enabled[weight],
+			})
 		}
-	}
 
-	return TraefikConfig{
-		HTTP: TraefikHTTP{
-			Services: services,
-		},
+		services[name] = TraefikService{
+			Weighted: weighted,
+			Comment:  service.Comment,
+			Origin:   service.Origin,
+		}
 	}
 }
 
-// GenerateMappingConfig generates the mapping configuration
-func GenerateMappingConfig(vservers []VServerInfo, serviceGroupDefs []ServiceGroupDef, serviceGroups []ServiceGroup) MappingConfig {
-	var entries []MappingEntry
-
-	// Create a map of service group definitions for comment lookup
-	serviceGroupDefMap := make(map[string]ServiceGroupDef)
-	for _, sgDef := range serviceGroupDefs {
-		serviceGroupDefMap[sgDef.Name] = sgDef
+// groupComment returns the comment describing the given service groups: the
+// first add serviceGroup comment, then the first bind serviceGroup comment
+func groupComment(config *LBConfig, serviceNames []string) string {
+	// Priority 1: Check for add serviceGroup comment
+	for _, serviceName := range serviceNames {
+		if sgDef := config.ServiceGroupDefByName(serviceName); sgDef != nil && sgDef.Comment != "" {
+			return sgDef.Comment
+		}
 	}
 
-	// Create a map to find service groups by vserver name
-	serviceGroupsByVServer := make(map[string][]ServiceGroup)
-	for _, sg := range serviceGroups {
-		serviceGroupsByVServer[sg.Name] = append(serviceGroupsByVServer[sg.Name], sg)
+	// Priority 2: Check for bind serviceGroup comment (if no add comment found)
+	for _, serviceName := range serviceNames {
+		for _, group := range config.MembersOf(serviceName) {
+			if group.Comment != "" {
+				return group.Comment
+			}
+		}
 	}
 
-	for _, vserver := range vservers {
-		key := fmt.Sprintf("%s:%s", vserver.IP, vserver.Port)
-		value := fmt.Sprintf("%s@nacoscs", vserver.Name)
+	return ""
+}
+
+// GenerateMappingConfig generates the mapping configuration. Each virtual
+// server with a generated service maps its IP:port to that service; virtual
+// servers without a bound service group are left out (see Verify). Content
+// switching virtual servers with routers map their IP:port to the entry point
+// of their routers, which is named after them like the service and routers
+// of an lb vserver.
+func GenerateMappingConfig(config *LBConfig) MappingConfig {
+	return GenerateMappingConfigWithOptions(config, GenerateOptions{})
+}
 
-		// Check if there's a service group comment for this vserver
-		comment := ""
+// GenerateMappingConfigWithOptions generates the mapping configuration, see
+// GenerateMappingConfig. Disabled virtual servers are left out, or marked to
+// be written commented out when opts selects DisabledComment. Callers that
+// also need the Traefik configuration should generate it once and pass it to
+// GenerateMappingConfigFromTraefik.
+func GenerateMappingConfigWithOptions(config *LBConfig, opts GenerateOptions) MappingConfig {
+	return GenerateMappingConfigFromTraefik(config, GenerateTraefikConfigWithOptions(config, opts), opts)
+}
 
-		// Priority 1: Check for add serviceGroup comment
-		if sgDef, exists := serviceGroupDefMap[vserver.Name]; exists && sgDef.Comment != "" {
-			comment = sgDef.Comment
+// GenerateMappingConfigFromTraefik generates the mapping configuration of
+// the Traefik configuration generated from config with the same opts, see
+// GenerateMappingConfigWithOptions
+func GenerateMappingConfigFromTraefik(config *LBConfig, traefik TraefikConfig, opts GenerateOptions) MappingConfig {
+	var entries []MappingEntry
+	services := traefik.HTTP.Services
+
+	for _, vserver := range config.VServers {
+		groups := config.VServerGroups(vserver.Name)
+		if _, exists := services[vserver.Name]; !exists || len(groups) == 0 || !vserver.Addressable() {
+			continue
+		}
+		if vserver.Disabled && opts.Disabled == DisabledDrop {
+			continue
 		}
 
-		// Priority 2: Check for bind serviceGroup comment (if no add comment found)
-		if comment == "" {
-			if groups, exists := serviceGroupsByVServer[vserver.Name]; exists {
-				// Use the first non-empty comment found
-				for _, group := range groups {
-					if group.Comment != "" {
-						comment = group.Comment
-						break
-					}
-				}
+		key := VIPKey(vserver.IP, vserver.Port)
+		value := fmt.Sprintf("%s@nacoscs", vserver.Name)
+
+		entries = append(entries, MappingEntry{
+			Key:      key,
+			Value:    value,
+			Comment:  groupComment(config, groups),
+			Disabled: vserver.Disabled,
+			Origin:   formatOrigin(vserver.Pos, vserver.Source),
+		})
+	}
+
+	routed := make(map[string]bool)
+	for _, router := range traefik.HTTP.Routers {
+		for _, entryPoint := range router.EntryPoints {
+			routed[entryPoint] = true
+		}
+	}
+	for _, vserver := range config.CSVServers {
+		if !routed[vserver.Name] || !vserver.Addressable() {
+			continue
+		}
+		var targets []string
+		for _, route := range CSRoutes(config, vserver) {
+			if _, exists := services[route.Target]; exists && route.Problem == "" && !slices.Contains(targets, route.Target) {
+				targets = append(targets, route.Target)
 			}
 		}
-
+		comment := "cs vserver " + vserver.Name
+		if len(targets) > 0 {
+			comment += " routing to " + strings.Join(targets, ", ")
+		}
 		entries = append(entries, MappingEntry{
-			Key:     key,
-			Value:   value,
-			Comment: comment,
+			Key:      VIPKey(vserver.IP, vserver.Port),
+			Value:    fmt.Sprintf("%s@nacoscs", vserver.Name),
+			Comment:  comment,
+			Disabled: vserver.Disabled,
+			Origin:   formatOrigin(vserver.Pos, vserver.Source),
 		})
 	}
 
 	return MappingConfig{Entries: entries}
 }
 
+// formatOrigin describes where an object came from as "file:line: source"
+func formatOrigin(pos Position, source string) string {
+	location := Position{File: pos.File, Line: pos.Line}.String()
+	switch {
+	case location == "":
+		return source
+	case source == "":
+		return location
+	default:
+		return location + ": " + source
+	}
+}
+
 // ReadTraefikConfig reads and parses a Traefik configuration file
 func ReadTraefikConfig(filename string) (TraefikConfig, error) {
 	var config TraefikConfig
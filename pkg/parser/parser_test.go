@@ -0,0 +1,361 @@
+package parser
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// parseCitrixText parses an inline Citrix configuration, failing the test on error
+func parseCitrixText(t *testing.T, filename, text string) *LBConfig {
+	t.Helper()
+	config, err := ParseCitrix(strings.NewReader(text), ParseOptions{Filename: filename})
+	if err != nil {
+		t.Fatalf("ParseCitrix(%s): %v", filename, err)
+	}
+	return config
+}
+
+// serverURLs lists the server URLs of a generated load balancer service
+func serverURLs(service TraefikService) []string {
+	var urls []string
+	for _, server := range service.LoadBalancer.Servers {
+		urls = append(urls, server.URL)
+	}
+	return urls
+}
+
+// diagnosticCodes lists the codes of the diagnostics, in order
+func diagnosticCodes(diagnostics Diagnostics) []string {
+	var codes []string
+	for _, d := range diagnostics {
+		codes = append(codes, d.Code)
+	}
+	return codes
+}
+
+func TestGenerateMappingConfigFromTraefik(t *testing.T) {
+	config := parseCitrixText(t, "ns.conf", `add server s1 10.0.0.1
+add serviceGroup sg1 HTTP
+bind serviceGroup sg1 s1 80
+add lb vserver vs1 HTTP 10.9.0.1 80
+bind lb vserver vs1 sg1
+add lb vserver vs2 HTTP 10.9.0.2 80 -state DISABLED
+bind lb vserver vs2 sg1
+add lb vserver unbound HTTP 10.9.0.3 80
+`)
+
+	tests := []struct {
+		name string
+		opts GenerateOptions
+		want []string
+	}{
+		{name: "disabled dropped", opts: GenerateOptions{}, want: []string{"10.9.0.1:80=vs1@nacoscs"}},
+		{name: "disabled commented", opts: GenerateOptions{Disabled: DisabledComment}, want: []string{"10.9.0.1:80=vs1@nacoscs", "#10.9.0.2:80=vs2@nacoscs"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traefik := GenerateTraefikConfigWithOptions(config, tt.opts)
+			got := mappingPairs(GenerateMappingConfigFromTraefik(config, traefik, tt.opts))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("mappings = %v, want %v", got, tt.want)
+			}
+			if again := mappingPairs(GenerateMappingConfigWithOptions(config, tt.opts)); !slices.Equal(again, got) {
+				t.Errorf("GenerateMappingConfigWithOptions = %v, want %v", again, got)
+			}
+		})
+	}
+}
+
+// mappingPairs lists mapping entries as "key=value", disabled ones prefixed with '#'
+func mappingPairs(mapping MappingConfig) []string {
+	var pairs []string
+	for _, entry := range mapping.Entries {
+		pair := entry.Key + "=" + entry.Value
+		if entry.Disabled {
+			pair = "#" + pair
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs
+}
+
+func TestGenerateDisabledObjects(t *testing.T) {
+	config := parseCitrixText(t, "ns.conf", `add server s1 10.0.0.1
+add server s2 10.0.0.2
+add server s3 10.0.0.3
+add serviceGroup sg1 HTTP
+bind serviceGroup sg1 s1 80
+bind serviceGroup sg1 s2 80 -state DISABLED
+bind serviceGroup sg1 s3 80
+disable server s3
+add lb vserver vs1 HTTP 10.9.0.1 80
+bind lb vserver vs1 sg1
+add serviceGroup sg2 HTTP
+bind serviceGroup sg2 s1 90
+disable serviceGroup sg2
+`)
+
+	tests := []struct {
+		name         string
+		opts         GenerateOptions
+		wantServers  []string
+		wantDisabled []bool
+	}{
+		{
+			name:         "drop",
+			opts:         GenerateOptions{Disabled: DisabledDrop},
+			wantServers:  []string{"http://10.0.0.1:80"},
+			wantDisabled: []bool{false},
+		},
+		{
+			name:         "comment",
+			opts:         GenerateOptions{Disabled: DisabledComment},
+			wantServers:  []string{"http://10.0.0.1:80", "http://10.0.0.2:80", "http://10.0.0.3:80"},
+			wantDisabled: []bool{false, true, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			services := GenerateTraefikConfigWithOptions(config, tt.opts).HTTP.Services
+			service := services["vs1"]
+			if got := serverURLs(service); !slices.Equal(got, tt.wantServers) {
+				t.Fatalf("vs1 servers = %v, want %v", got, tt.wantServers)
+			}
+			for i, server := range service.LoadBalancer.Servers {
+				if server.Disabled != tt.wantDisabled[i] {
+					t.Errorf("server %s disabled = %t, want %t", server.URL, server.Disabled, tt.wantDisabled[i])
+				}
+			}
+			// A service needs an enabled server in either mode
+			if _, exists := services["sg2"]; exists {
+				t.Errorf("sg2 has no enabled member and should get no service")
+			}
+		})
+	}
+}
+
+func TestGenerateServicesFromBindings(t *testing.T) {
+	config := parseCitrixText(t, "ns.conf", `add server s1 10.0.0.1
+add server s2 10.0.0.2
+add serviceGroup web_sg HTTP
+bind serviceGroup web_sg s1 80
+add serviceGroup api_sg HTTP
+bind serviceGroup api_sg s2 8080
+add serviceGroup vs2 HTTP
+bind serviceGroup vs2 s2 80
+add serviceGroup spare_sg HTTP
+bind serviceGroup spare_sg s1 9090
+add serviceGroup empty_sg HTTP
+add lb vserver vs1 HTTP 10.9.0.1 80
+bind lb vserver vs1 web_sg
+bind lb vserver vs1 api_sg
+add lb vserver vs2 HTTP 10.9.0.2 80
+add lb vserver vs3 HTTP 10.9.0.3 80
+add lb vserver vs4 HTTP 10.9.0.4 80
+bind lb vserver vs4 empty_sg
+`)
+	services := GenerateTraefikConfig(config).HTTP.Services
+
+	tests := []struct {
+		service string
+		want    []string // nil when the service is not generated
+	}{
+		{service: "vs1", want: []string{"http://10.0.0.1:80", "http://10.0.0.2:8080"}},
+		{service: "vs2", want: []string{"http://10.0.0.2:80"}},
+		{service: "spare_sg", want: []string{"http://10.0.0.1:9090"}},
+		{service: "web_sg"},
+		{service: "api_sg"},
+		{service: "vs3"},
+		{service: "vs4"},
+	}
+	for _, tt := range tests {
+		service, exists := services[tt.service]
+		if exists != (tt.want != nil) {
+			t.Errorf("service %s exists = %t, want %t", tt.service, exists, tt.want != nil)
+			continue
+		}
+		if got := serverURLs(service); exists && !slices.Equal(got, tt.want) {
+			t.Errorf("service %s servers = %v, want %v", tt.service, got, tt.want)
+		}
+	}
+
+	if got := mappingPairs(GenerateMappingConfig(config)); !slices.Equal(got, []string{"10.9.0.1:80=vs1@nacoscs", "10.9.0.2:80=vs2@nacoscs"}) {
+		t.Errorf("mappings = %v, want vs1 and vs2 only", got)
+	}
+	codes := diagnosticCodes(Verify(config))
+	for _, code := range []string{"unbound-vserver", "empty-vserver"} {
+		if !slices.Contains(codes, code) {
+			t.Errorf("Verify codes = %v, want %s", codes, code)
+		}
+	}
+}
+
+func TestStandaloneServices(t *testing.T) {
+	config := parseCitrixText(t, "ns.conf", `add server s1 10.0.0.1
+add service svc1 s1 HTTP 80
+add service svc2 10.0.0.2 HTTP 8080
+add service svc3 10.0.0.3 HTTP 80
+add lb vserver vs1 HTTP 10.9.0.1 80
+bind lb vserver vs1 svc1
+bind lb vserver vs1 svc2
+`)
+
+	if server := config.ServerByName("10.0.0.2"); server == nil || server.IP != "10.0.0.2" {
+		t.Errorf("server named after the IP of svc2 = %+v", server)
+	}
+	services := GenerateTraefikConfig(config).HTTP.Services
+	tests := []struct {
+		service string
+		want    []string
+	}{
+		{service: "vs1", want: []string{"http://10.0.0.1:80", "http://10.0.0.2:8080"}},
+		{service: "svc3", want: []string{"http://10.0.0.3:80"}},
+	}
+	for _, tt := range tests {
+		if got := serverURLs(services[tt.service]); !slices.Equal(got, tt.want) {
+			t.Errorf("service %s servers = %v, want %v", tt.service, got, tt.want)
+		}
+	}
+	if len(services) != len(tests) {
+		t.Errorf("got %d services, want %d", len(services), len(tests))
+	}
+
+	if _, err := ParseCitrix(strings.NewReader("add service svc1 s1 HTTP\n"), ParseOptions{}); err == nil {
+		t.Error("add service without a port parsed")
+	}
+}
+
+func TestGenerateWeights(t *testing.T) {
+	const members = `add server s1 10.0.0.1
+add server s2 10.0.0.2
+add server s3 10.0.0.3
+add serviceGroup sg1 HTTP
+add lb vserver vs1 HTTP 10.9.0.1 80
+`
+	tests := []struct {
+		name     string
+		config   string
+		mode     WeightMode
+		services map[string][]string
+	}{
+		{
+			name: "equal weights stay a plain load balancer",
+			config: `bind serviceGroup sg1 s1 80 -weight 4
+bind serviceGroup sg1 s2 80 -weight 4
+bind lb vserver vs1 sg1
+`,
+			services: map[string][]string{
+				"vs1": {"http://10.0.0.1:80", "http://10.0.0.2:80"},
+			},
+		},
+		{
+			name: "member weights become child services",
+			config: `bind serviceGroup sg1 s1 80 -weight 3
+bind serviceGroup sg1 s2 80
+bind serviceGroup sg1 s3 80 -weight 3
+bind lb vserver vs1 sg1
+`,
+			services: map[string][]string{
+				"vs1":         {"service vs1-weight3 (weight 6)", "service vs1-weight1 (weight 1)"},
+				"vs1-weight3": {"http://10.0.0.1:80", "http://10.0.0.3:80"},
+				"vs1-weight1": {"http://10.0.0.2:80"},
+			},
+		},
+		{
+			name: "member weights on servers",
+			config: `bind serviceGroup sg1 s1 80 -weight 3
+bind serviceGroup sg1 s2 80
+bind lb vserver vs1 sg1
+`,
+			mode: WeightServers,
+			services: map[string][]string{
+				"vs1": {"http://10.0.0.1:80 (weight 3)", "http://10.0.0.2:80 (weight 1)"},
+			},
+		},
+		{
+			name: "binding weight multiplies member weights",
+			config: `bind serviceGroup sg1 s1 80 -weight 2
+add serviceGroup sg2 HTTP
+bind serviceGroup sg2 s2 80
+bind lb vserver vs1 sg1 -weight 3
+bind lb vserver vs1 sg2
+`,
+			mode: WeightServers,
+			services: map[string][]string{
+				"vs1": {"http://10.0.0.1:80 (weight 6)", "http://10.0.0.2:80 (weight 1)"},
+			},
+		},
+		{
+			name: "set changes a member weight",
+			config: `bind serviceGroup sg1 s1 80 -weight 3
+bind serviceGroup sg1 s2 80
+bind lb vserver vs1 sg1
+set serviceGroup sg1 s1 80 -weight 1
+`,
+			services: map[string][]string{
+				"vs1": {"http://10.0.0.1:80", "http://10.0.0.2:80"},
+			},
+		},
+		{
+			name: "unbound group keeps its member weights",
+			config: `bind serviceGroup sg1 s1 80 -weight 2
+bind serviceGroup sg1 s2 80
+`,
+			mode: WeightServers,
+			services: map[string][]string{
+				"sg1": {"http://10.0.0.1:80 (weight 2)", "http://10.0.0.2:80 (weight 1)"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := parseCitrixText(t, "ns.conf", members+tt.config)
+			services := GenerateTraefikConfigWithOptions(config, GenerateOptions{Weights: tt.mode}).HTTP.Services
+			if len(services) != len(tt.services) {
+				t.Errorf("got %d services, want %d", len(services), len(tt.services))
+			}
+			for name, want := range tt.services {
+				service, exists := services[name]
+				if !exists {
+					t.Errorf("service %s not generated", name)
+					continue
+				}
+				if got := service.Backends(); !slices.Equal(got, want) {
+					t.Errorf("service %s backends = %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseInvalidWeight(t *testing.T) {
+	for _, weight := range []string{"0", "101", "heavy"} {
+		text := "add server s1 10.0.0.1\nadd serviceGroup sg1 HTTP\nbind serviceGroup sg1 s1 80 -weight " + weight + "\n"
+		if _, err := ParseCitrix(strings.NewReader(text), ParseOptions{Filename: "ns.conf"}); err == nil {
+			t.Errorf("-weight %s: expected an error", weight)
+		}
+	}
+}
+
+func TestParseWeightMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    WeightMode
+		wantErr bool
+	}{
+		{"", WeightServices, false},
+		{"services", WeightServices, false},
+		{" Servers ", WeightServers, false},
+		{"urls", WeightServices, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseWeightMode(tt.name)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseWeightMode(%q) = %v, %v", tt.name, got, err)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// defaultPersistenceTimeout is the Citrix persistence timeout, in minutes,
+// of virtual servers that do not set -timeout
+const defaultPersistenceTimeout = "2"
+
+// persistenceSetters returns the functions that apply "add/set lb vserver"
+// persistence parameters to a virtual server
+func persistenceSetters(vserver *VServerInfo) map[string]func(value string) {
+	return map[string]func(string){
+		"-persistenceType": func(value string) { vserver.Persistence.Type = value },
+		"-timeout":         func(value string) { vserver.Persistence.Timeout = value },
+		"-cookieName":      func(value string) { vserver.Persistence.CookieName = value },
+	}
+}
+
+// translatePersistence converts the persistence of a virtual server to
+// Traefik sticky sessions. Only COOKIEINSERT has a Traefik equivalent: a
+// cookie named -cookieName that lives for -timeout minutes, or for the
+// browser session when the timeout is 0. The cookie is HttpOnly, as the
+// appliance sets it, and Secure on SSL virtual servers. For other types it
+// returns nil and the reason. Virtual servers without persistence get nil.
+func translatePersistence(vserver *VServerInfo) (*TraefikSticky, string) {
+	persistence := vserver.Persistence
+	persistenceType := strings.ToUpper(persistence.Type)
+	switch persistenceType {
+	case "", "NONE":
+		return nil, ""
+	case "COOKIEINSERT":
+	default:
+		return nil, fmt.Sprintf("%s persistence has no Traefik equivalent, only COOKIEINSERT becomes sticky sessions", persistenceType)
+	}
+
+	timeout := persistence.Timeout
+	if timeout == "" {
+		timeout = defaultPersistenceTimeout
+	}
+	minutes, err := strconv.Atoi(timeout)
+	if err != nil || minutes < 0 {
+		return nil, fmt.Sprintf("COOKIEINSERT persistence has an invalid -timeout %q", persistence.Timeout)
+	}
+
+	return &TraefikSticky{Cookie: &TraefikCookie{
+		Name:     persistence.CookieName,
+		Secure:   strings.EqualFold(vserver.Protocol, "SSL"),
+		HTTPOnly: true,
+		MaxAge:   minutes * 60,
+	}}, ""
+}
@@ -0,0 +1,94 @@
+package parser
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPersistenceStickySessions(t *testing.T) {
+	tests := []struct {
+		name    string
+		vserver string
+		members string
+		sticky  string
+		gap     bool
+	}{
+		{
+			name:    "no persistence",
+			vserver: "add lb vserver vs1 HTTP 10.9.0.1 80\n",
+			sticky:  "none",
+		},
+		{
+			name:    "cookie insert with the default timeout",
+			vserver: "add lb vserver vs1 HTTP 10.9.0.1 80 -persistenceType COOKIEINSERT\n",
+			sticky:  "cookie httpOnly maxAge=120",
+		},
+		{
+			name:    "cookie name and timeout",
+			vserver: "add lb vserver vs1 HTTP 10.9.0.1 80 -persistenceType COOKIEINSERT -timeout 30 -cookieName JSESSIONID_LB\n",
+			sticky:  "cookie name=JSESSIONID_LB httpOnly maxAge=1800",
+		},
+		{
+			name:    "session cookie",
+			vserver: "add lb vserver vs1 HTTP 10.9.0.1 80 -persistenceType COOKIEINSERT -timeout 0\n",
+			sticky:  "cookie httpOnly",
+		},
+		{
+			name:    "secure cookie on ssl vservers",
+			vserver: "add lb vserver vs1 SSL 10.9.0.1 443 -persistenceType COOKIEINSERT\n",
+			sticky:  "cookie secure httpOnly maxAge=120",
+		},
+		{
+			name:    "set persistence",
+			vserver: "add lb vserver vs1 HTTP 10.9.0.1 80\nset lb vserver vs1 -persistenceType COOKIEINSERT -timeout 5\n",
+			sticky:  "cookie httpOnly maxAge=300",
+		},
+		{
+			name:    "weighted service keeps the cookie",
+			vserver: "add lb vserver vs1 HTTP 10.9.0.1 80 -persistenceType COOKIEINSERT\n",
+			members: "bind serviceGroup sg1 s2 80 -weight 2\n",
+			sticky:  "cookie httpOnly maxAge=120",
+		},
+		{
+			name:    "source ip persistence",
+			vserver: "add lb vserver vs1 HTTP 10.9.0.1 80 -persistenceType SOURCEIP\n",
+			sticky:  "none",
+			gap:     true,
+		},
+		{
+			name:    "invalid timeout",
+			vserver: "add lb vserver vs1 HTTP 10.9.0.1 80 -persistenceType COOKIEINSERT -timeout soon\n",
+			sticky:  "none",
+			gap:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := parseCitrixText(t, "ns.conf", `add server s1 10.0.0.1
+add server s2 10.0.0.2
+add serviceGroup sg1 HTTP
+bind serviceGroup sg1 s1 80
+`+tt.members+tt.vserver+"bind lb vserver vs1 sg1\n")
+
+			service := GenerateTraefikConfig(config).HTTP.Services["vs1"]
+			if got := service.StickySessions().String(); got != tt.sticky {
+				t.Errorf("sticky = %q, want %q", got, tt.sticky)
+			}
+			if tt.members != "" && service.Weighted == nil {
+				t.Errorf("expected a weighted service")
+			}
+
+			var gapTypes []string
+			for _, object := range UntranslatedObjects(config) {
+				gapTypes = append(gapTypes, object.ObjectType)
+			}
+			if got := slices.Contains(gapTypes, "lb vserver persistence"); got != tt.gap {
+				t.Errorf("persistence gap = %v, want %v (gaps %v)", got, tt.gap, gapTypes)
+			}
+			if got := slices.Contains(diagnosticCodes(Verify(config)), "unsupported-persistence"); got != tt.gap {
+				t.Errorf("unsupported-persistence warning = %v, want %v", got, tt.gap)
+			}
+		})
+	}
+}
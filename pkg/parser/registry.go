@@ -0,0 +1,87 @@
+package parser
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"sync"
+)
+
+// ErrNoParserMatched is returned when no registered parser recognizes the input
+var ErrNoParserMatched = errors.New("no parser matched the input: none of the registered formats recognized it")
+
+// ParseOptions controls how a configuration is parsed
+type ParseOptions struct {
+	Filename string     // Name reported in diagnostic positions, empty for stdin
+	Lenient  bool       // Skip malformed commands with an error diagnostic instead of aborting
+	Format   ConfigType // Parse as this format instead of detecting it, ConfigTypeUnknown to detect
+}
+
+// Parser is implemented by every vendor configuration parser
+type Parser interface {
+	// Name returns a short lowercase vendor name (e.g. "citrix"), which is
+	// also the ConfigType of the configurations it produces
+	Name() string
+	// Detect scores the trimmed leading lines of an input, a zero score when the format is not recognized
+	Detect(sample []string) Detection
+	// Parse parses a complete configuration into the vendor-neutral model
+	Parse(reader io.Reader, opts ParseOptions) (*LBConfig, error)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   []Parser
+)
+
+// RegisterParser makes a vendor parser available to format detection and auto parsing.
+// Vendor parsers call it from an init function.
+func RegisterParser(p Parser) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for i, existing := range registry {
+		if existing.Name() == p.Name() {
+			registry[i] = p
+			return
+		}
+	}
+	registry = append(registry, p)
+
+	// Keep a stable order so that ties in detection are resolved deterministically
+	sort.SliceStable(registry, func(i, j int) bool {
+		return registry[i].Name() < registry[j].Name()
+	})
+}
+
+// Parsers returns the registered parsers ordered by name
+func Parsers() []Parser {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	parsers := make([]Parser, len(registry))
+	copy(parsers, registry)
+	return parsers
+}
+
+// TypeOf returns the configuration type a parser produces
+func TypeOf(p Parser) ConfigType {
+	return ConfigType(p.Name())
+}
+
+// ParserFor returns the registered parser for a configuration type, or nil
+func ParserFor(configType ConfigType) Parser {
+	for _, p := range Parsers() {
+		if TypeOf(p) == configType {
+			return p
+		}
+	}
+	return nil
+}
+
+// String returns the vendor name of the configuration type
+func (t ConfigType) String() string {
+	if t == ConfigTypeUnknown {
+		return "unknown"
+	}
+	return string(t)
+}
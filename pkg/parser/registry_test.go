@@ -0,0 +1,96 @@
+package parser
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// acmeParser is a vendor parser registered only by the tests, to check that
+// a format needs nothing but its own registration
+type acmeParser struct{}
+
+func (acmeParser) Name() string { return "acme" }
+
+func (acmeParser) Detect(sample []string) Detection {
+	return MatchSignatures(sample, []Signature{
+		{Description: "acme header", Weight: definiteWeight, Definite: true, Match: hasPrefix("#ACME")},
+	})
+}
+
+func (acmeParser) Parse(reader io.Reader, opts ParseOptions) (*LBConfig, error) {
+	return NewLBConfig(TypeOf(acmeParser{})), nil
+}
+
+func init() {
+	RegisterParser(acmeParser{})
+}
+
+func TestRegisteredParserSuppliesItsType(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		format string
+		want   ConfigType
+	}{
+		{name: "citrix detected", input: "#NS13.1 Build 37.38\nadd server s1 10.0.0.1\n", format: "auto", want: ConfigTypeCitrix},
+		{name: "f5 detected", input: "#TMSH-VERSION: 15.1.0\nltm node /Common/n1 { }\n", format: "auto", want: ConfigTypeF5},
+		{name: "registered vendor detected", input: "#ACME 1.0\n", format: "auto", want: "acme"},
+		{name: "registered vendor forced", input: "add server s1 10.0.0.1\n", format: "acme", want: "acme"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := ParseFormat(tt.format)
+			if err != nil {
+				t.Fatalf("ParseFormat(%q): %v", tt.format, err)
+			}
+			config, err := ParseReader(strings.NewReader(tt.input), ParseOptions{Format: format})
+			if err != nil {
+				t.Fatalf("ParseReader: %v", err)
+			}
+			if config.Vendor != tt.want {
+				t.Errorf("Vendor = %q, want %q", config.Vendor, tt.want)
+			}
+			if got := config.Vendor.String(); got != string(tt.want) {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseReaderRejectsUnknownInput(t *testing.T) {
+	_, err := ParseReader(strings.NewReader("hello world\n"), ParseOptions{})
+	if !errors.Is(err, ErrNoParserMatched) {
+		t.Fatalf("err = %v, want ErrNoParserMatched", err)
+	}
+	if got := ConfigTypeUnknown.String(); got != "unknown" {
+		t.Errorf("ConfigTypeUnknown.String() = %q, want unknown", got)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    ConfigType
+		wantErr bool
+	}{
+		{name: "", want: ConfigTypeUnknown},
+		{name: "auto", want: ConfigTypeUnknown},
+		{name: " Citrix ", want: ConfigTypeCitrix},
+		{name: "f5", want: ConfigTypeF5},
+		{name: "bogus", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseFormat(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseFormat(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package parser
+
+import (
+	"slices"
+	"testing"
+)
+
+// replayBase is the configuration the change logs of the replay tests apply to
+const replayBase = `add server s1 10.0.0.1
+add server s2 10.0.0.2
+add serviceGroup sg1 HTTP
+bind serviceGroup sg1 s1 80
+bind serviceGroup sg1 s2 80
+add lb vserver vs1 HTTP 10.9.0.1 80
+bind lb vserver vs1 sg1
+`
+
+func TestReplayChanges(t *testing.T) {
+	tests := []struct {
+		name      string
+		changes   string
+		service   string
+		servers   []string
+		mappings  []string
+		wantCodes []string
+	}{
+		{
+			name:     "no changes",
+			service:  "vs1",
+			servers:  []string{"http://10.0.0.1:80", "http://10.0.0.2:80"},
+			mappings: []string{"10.9.0.1:80=vs1@nacoscs"},
+		},
+		{
+			name:     "rm server removes its members",
+			changes:  "rm server s2\n",
+			service:  "vs1",
+			servers:  []string{"http://10.0.0.1:80"},
+			mappings: []string{"10.9.0.1:80=vs1@nacoscs"},
+		},
+		{
+			name:     "unbind member",
+			changes:  "unbind serviceGroup sg1 s1 80\n",
+			service:  "vs1",
+			servers:  []string{"http://10.0.0.2:80"},
+			mappings: []string{"10.9.0.1:80=vs1@nacoscs"},
+		},
+		{
+			name:     "set server address",
+			changes:  "set server s1 -IPAddress 10.0.0.11\n",
+			service:  "vs1",
+			servers:  []string{"http://10.0.0.11:80", "http://10.0.0.2:80"},
+			mappings: []string{"10.9.0.1:80=vs1@nacoscs"},
+		},
+		{
+			name:     "set vserver address and port",
+			changes:  "set lb vserver vs1 -IPAddress 10.9.0.9 -port 8080\n",
+			service:  "vs1",
+			servers:  []string{"http://10.0.0.1:80", "http://10.0.0.2:80"},
+			mappings: []string{"10.9.0.9:8080=vs1@nacoscs"},
+		},
+		{
+			name:     "rename vserver",
+			changes:  "rename lb vserver vs1 web\n",
+			service:  "web",
+			servers:  []string{"http://10.0.0.1:80", "http://10.0.0.2:80"},
+			mappings: []string{"10.9.0.1:80=web@nacoscs"},
+		},
+		{
+			name:     "rename service group keeps its binding",
+			changes:  "rename serviceGroup sg1 web_sg\n",
+			service:  "vs1",
+			servers:  []string{"http://10.0.0.1:80", "http://10.0.0.2:80"},
+			mappings: []string{"10.9.0.1:80=vs1@nacoscs"},
+		},
+		{
+			name:     "unbind the only group",
+			changes:  "unbind lb vserver vs1 sg1\n",
+			service:  "sg1",
+			servers:  []string{"http://10.0.0.1:80", "http://10.0.0.2:80"},
+			mappings: nil,
+		},
+		{
+			name:     "rm vserver",
+			changes:  "rm lb vserver vs1\n",
+			service:  "sg1",
+			servers:  []string{"http://10.0.0.1:80", "http://10.0.0.2:80"},
+			mappings: nil,
+		},
+		{
+			name:      "change to a missing object",
+			changes:   "set server s9 -IPAddress 10.0.0.9\n",
+			service:   "vs1",
+			servers:   []string{"http://10.0.0.1:80", "http://10.0.0.2:80"},
+			mappings:  []string{"10.9.0.1:80=vs1@nacoscs"},
+			wantCodes: []string{"undefined-object"},
+		},
+		{
+			name:      "change to a removed object",
+			changes:   "rm server s2\nset server s2 -comment gone\n",
+			service:   "vs1",
+			servers:   []string{"http://10.0.0.1:80"},
+			mappings:  []string{"10.9.0.1:80=vs1@nacoscs"},
+			wantCodes: []string{"removed-object"},
+		},
+		{
+			name:      "binding a removed object",
+			changes:   "rm server s2\nbind serviceGroup sg1 s2 8080\n",
+			service:   "vs1",
+			servers:   []string{"http://10.0.0.1:80"},
+			mappings:  []string{"10.9.0.1:80=vs1@nacoscs"},
+			wantCodes: []string{"removed-reference"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := parseCitrixText(t, "ns.conf", replayBase+tt.changes)
+
+			if got := diagnosticCodes(config.Diagnostics); !slices.Equal(got, tt.wantCodes) {
+				t.Errorf("diagnostics = %v, want %v", got, tt.wantCodes)
+			}
+			if got := serverURLs(GenerateTraefikConfig(config).HTTP.Services[tt.service]); !slices.Equal(got, tt.servers) {
+				t.Errorf("service %s servers = %v, want %v", tt.service, got, tt.servers)
+			}
+			if got := mappingPairs(GenerateMappingConfig(config)); !slices.Equal(got, tt.mappings) {
+				t.Errorf("mappings = %v, want %v", got, tt.mappings)
+			}
+		})
+	}
+}
@@ -0,0 +1,434 @@
+package parser
+
+import (
+	"fmt"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// noopService is the Traefik internal service for routers whose middlewares
+// answer every request themselves
+const noopService = "noop@internal"
+
+// redirectURLRegex splits a request URL into host, port, path and query for
+// the replacement of a redirectRegex middleware
+const redirectURLRegex = `^[a-z]+://([^/:?]+)(:[0-9]+)?([^?]*)(\?.*)?$`
+
+// redirectURLParts maps the request parts a redirect target can insert to
+// the redirectURLRegex groups that hold them
+var redirectURLParts = map[string]string{
+	"HTTP.REQ.HOSTNAME":           "${1}${2}",
+	"HTTP.REQ.HOSTNAME.SERVER":    "${1}",
+	"HTTP.REQ.URL":                "${3}${4}",
+	"HTTP.REQ.URL.PATH_AND_QUERY": "${3}${4}",
+	"HTTP.REQ.URL.PATH":           "${3}",
+}
+
+// redirectURLPart returns the redirectURLRegex groups a term of a redirect
+// target inserts. Encoding functions are ignored, as the request URL is
+// already encoded.
+func redirectURLPart(term *exprNode) (string, bool) {
+	name := term.names()
+	for _, suffix := range []string{".HTTP_URL_SAFE", ".URL_RESERVED_CHARS_SAFE"} {
+		name = strings.TrimSuffix(name, suffix)
+	}
+	group, exists := redirectURLParts[name]
+	return group, exists
+}
+
+// responderActionSetters returns the functions that apply "add/set responder
+// action" parameters to an action
+func responderActionSetters(action *ResponderAction) map[string]func(value string) {
+	return map[string]func(string){
+		"-target":             func(value string) { action.Target = value },
+		"-responseStatusCode": func(value string) { action.StatusCode = value },
+	}
+}
+
+// isBuiltinResponderAction reports whether a policy action is one the
+// appliance defines itself
+func isBuiltinResponderAction(name string) bool {
+	switch strings.ToUpper(name) {
+	case "DROP", "RESET", "NOOP":
+		return true
+	}
+	return false
+}
+
+// ResponderStep is a responder policy bound to a virtual server, resolved to
+// the middleware it becomes
+type ResponderStep struct {
+	PolicyName     string
+	Priority       string // Lower priorities are evaluated first
+	GotoExpression string
+	Pos            Position // Where the policy was bound
+	Source         string
+	Policy         *ResponderPolicy
+	Middleware     *TraefikMiddleware // nil for NOOP policies and when Problem is set
+	Condition      string             // Traefik rule of the requests the middleware applies to, empty for every request
+	Problem        string             // Why the policy is not translated
+}
+
+// Position returns where the step is best fixed: the policy when it is
+// defined, the binding otherwise
+func (s ResponderStep) Position() (Position, string) {
+	if s.Policy != nil {
+		return s.Policy.Pos, s.Policy.Source
+	}
+	return s.Pos, s.Source
+}
+
+// ResponderSteps resolves the responder policies bound to the named lb
+// vserver, or cs vserver when cs is set, in the order the appliance
+// evaluates them: by ascending priority, policies without a priority in
+// binding order after them. Every translated action ends the request when
+// its policy matches, so -gotoPriorityExpression never changes the order.
+func ResponderSteps(config *LBConfig, vserver string, cs bool) []ResponderStep {
+	var steps []ResponderStep
+	if cs {
+		for _, binding := range config.CSBindingsOf(vserver) {
+			if binding.Type != "" && config.ResponderPolicyByName(binding.PolicyName) != nil {
+				steps = append(steps, ResponderStep{PolicyName: binding.PolicyName, Priority: binding.Priority,
+					GotoExpression: binding.GotoExpression, Pos: binding.Pos, Source: binding.Source})
+			}
+		}
+	} else {
+		for _, binding := range config.BindingsOf(vserver) {
+			if binding.PolicyName != "" && config.ResponderPolicyByName(binding.PolicyName) != nil {
+				steps = append(steps, ResponderStep{PolicyName: binding.PolicyName, Priority: binding.Priority,
+					GotoExpression: binding.GotoExpression, Pos: binding.Pos, Source: binding.Source})
+			}
+		}
+	}
+
+	sort.SliceStable(steps, func(i, j int) bool {
+		return priorityLess(steps[i].Priority, steps[j].Priority)
+	})
+	for i := range steps {
+		resolveResponderStep(config, &steps[i])
+	}
+	return steps
+}
+
+// priorityLess orders policy binding priorities ascending, with bindings
+// that have no numeric priority after the others
+func priorityLess(a, b string) bool {
+	x, errA := strconv.Atoi(a)
+	y, errB := strconv.Atoi(b)
+	switch {
+	case errA != nil:
+		return false
+	case errB != nil:
+		return true
+	default:
+		return x < y
+	}
+}
+
+// resolveResponderStep follows a step to its policy and action and
+// translates them to a middleware and the condition it applies on
+func resolveResponderStep(config *LBConfig, step *ResponderStep) {
+	step.Policy = config.ResponderPolicyByName(step.PolicyName)
+	if step.Policy == nil {
+		step.Problem = fmt.Sprintf("responder policy '%s' is not defined", step.PolicyName)
+		return
+	}
+
+	switch strings.ToUpper(step.Policy.Action) {
+	case "NOOP":
+		return
+	case "DROP", "RESET":
+		ranges, err := clientAllowList(step.Policy.Rule)
+		if err != nil {
+			step.Problem = err.Error()
+			return
+		}
+		step.Middleware = &TraefikMiddleware{IPAllowList: &TraefikIPAllowList{SourceRange: ranges}}
+		return
+	}
+
+	action := config.ResponderActionByName(step.Policy.Action)
+	if action == nil {
+		step.Problem = fmt.Sprintf("responder action '%s' is not defined", step.Policy.Action)
+		return
+	}
+	middleware, err := responderMiddleware(action)
+	if err != nil {
+		step.Problem = err.Error()
+		return
+	}
+	condition, err := responderCondition(step.Policy.Rule, middleware)
+	if err != nil {
+		step.Problem = err.Error()
+		return
+	}
+	step.Middleware, step.Condition = middleware, condition
+}
+
+// responderMiddleware translates a redirect or respondwith action
+func responderMiddleware(action *ResponderAction) (*TraefikMiddleware, error) {
+	switch strings.ToLower(action.Type) {
+	case "redirect":
+		return redirectMiddleware(action)
+	case "respondwith":
+		return staticResponseMiddleware(action)
+	default:
+		return nil, fmt.Errorf("responder action '%s' of type %s has no Traefik middleware equivalent", action.Name, action.Type)
+	}
+}
+
+// redirectMiddleware translates a redirect target. A target of "https://" or
+// "http://" followed by the host name and URL of the request switches the
+// scheme; other targets built from literals, the host name and the URL
+// become a redirectRegex. 301 and 308 redirects are permanent.
+func redirectMiddleware(action *ResponderAction) (*TraefikMiddleware, error) {
+	parts, err := parseConcatenation(action.Target)
+	if err != nil {
+		return nil, fmt.Errorf("responder action '%s': cannot parse target %q: %v", action.Name, action.Target, err)
+	}
+	permanent := action.StatusCode == "301" || action.StatusCode == "308"
+
+	if len(parts) == 3 && parts[0].Term == nil && parts[1].Term != nil && parts[2].Term != nil {
+		scheme, isScheme := strings.CutSuffix(strings.ToLower(parts[0].Literal), "://")
+		host, _ := redirectURLPart(parts[1].Term)
+		url, _ := redirectURLPart(parts[2].Term)
+		if isScheme && (scheme == "http" || scheme == "https") && strings.HasPrefix(host, "${1}") && url == "${3}${4}" {
+			return &TraefikMiddleware{RedirectScheme: &TraefikRedirectScheme{Scheme: scheme, Permanent: permanent}}, nil
+		}
+	}
+
+	regex := "^.*$"
+	var replacement strings.Builder
+	for _, part := range parts {
+		if part.Term == nil {
+			replacement.WriteString(strings.ReplaceAll(part.Literal, "$", "$$"))
+			continue
+		}
+		group, exists := redirectURLPart(part.Term)
+		if !exists {
+			return nil, fmt.Errorf("responder action '%s' redirects to %s, which has no Traefik redirect equivalent", action.Name, part.Term.Text)
+		}
+		regex = redirectURLRegex
+		replacement.WriteString(group)
+	}
+	return &TraefikMiddleware{RedirectRegex: &TraefikRedirectRegex{
+		Regex:       regex,
+		Replacement: replacement.String(),
+		Permanent:   permanent,
+	}}, nil
+}
+
+// staticResponseMiddleware translates a respondwith action whose target is a
+// literal: a raw HTTP response with a status line, or a body sent with
+// -responseStatusCode
+func staticResponseMiddleware(action *ResponderAction) (*TraefikMiddleware, error) {
+	parts, err := parseConcatenation(action.Target)
+	if err != nil {
+		return nil, fmt.Errorf("responder action '%s': cannot parse target %q: %v", action.Name, action.Target, err)
+	}
+	var text strings.Builder
+	for _, part := range parts {
+		if part.Term != nil {
+			return nil, fmt.Errorf("responder action '%s' responds with %s, a static response cannot include request data", action.Name, part.Term.Text)
+		}
+		text.WriteString(part.Literal)
+	}
+
+	response := &TraefikStaticResponse{Body: text.String()}
+	if head, body, isRaw := strings.Cut(response.Body, "\r\n\r\n"); isRaw && strings.HasPrefix(head, "HTTP/") {
+		lines := strings.Split(head, "\r\n")
+		fields := strings.Fields(lines[0])
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("responder action '%s' has an invalid status line %q", action.Name, lines[0])
+		}
+		if response.StatusCode, err = strconv.Atoi(fields[1]); err != nil {
+			return nil, fmt.Errorf("responder action '%s' has an invalid status line %q", action.Name, lines[0])
+		}
+		for _, line := range lines[1:] {
+			name, value, found := strings.Cut(line, ":")
+			name = textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name))
+			if !found || name == "Content-Length" || name == "Connection" {
+				continue
+			}
+			if response.Headers == nil {
+				response.Headers = make(map[string]string)
+			}
+			response.Headers[name] = strings.TrimSpace(value)
+		}
+		response.Body = body
+	} else if action.StatusCode != "" {
+		if response.StatusCode, err = strconv.Atoi(action.StatusCode); err != nil {
+			return nil, fmt.Errorf("responder action '%s' has an invalid -responseStatusCode %q", action.Name, action.StatusCode)
+		}
+	} else {
+		return nil, fmt.Errorf("responder action '%s' responds with raw data without an HTTP status line, which has no Traefik equivalent", action.Name)
+	}
+	return &TraefikMiddleware{Plugin: &TraefikPlugins{StaticResponse: response}}, nil
+}
+
+// responderCondition translates the rule of a redirect or respondwith
+// policy to the Traefik rule its router needs, empty when it applies to
+// every request. A redirect to HTTPS ignores requests that already use it,
+// so the usual CLIENT.SSL.IS_SSL.NOT rule needs no router of its own.
+func responderCondition(rule string, middleware *TraefikMiddleware) (string, error) {
+	if redirect := middleware.RedirectScheme; redirect != nil && redirect.Scheme == "https" {
+		switch strings.ToUpper(strings.Join(strings.Fields(rule), "")) {
+		case "CLIENT.SSL.IS_SSL.NOT", "!CLIENT.SSL.IS_SSL":
+			return "", nil
+		}
+	}
+
+	condition, err := translateRule(rule)
+	if err != nil {
+		return "", err
+	}
+	if strings.Trim(condition, "()") == strings.Trim(matchAllRule, "()") {
+		return "", nil
+	}
+	return condition, nil
+}
+
+// clientAllowList converts the rule of a DROP or RESET policy to the client
+// addresses Traefik should accept. The rule must drop the clients outside a
+// list of addresses: a negated CLIENT.IP.SRC.EQ or IN_SUBNET match, a
+// negated "||" of them, or an "&&" of negated matches.
+func clientAllowList(rule string) ([]string, error) {
+	node, err := parseExpression(rule)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %q: %v", rule, err)
+	}
+	unsupported := fmt.Errorf("%s does not drop the clients outside a list of addresses, which is all ipAllowList can express", rule)
+
+	var allowed func(node *exprNode) ([]string, bool)
+	allowed = func(node *exprNode) ([]string, bool) {
+		switch node.Op {
+		case "&&":
+			left, ok := allowed(node.Left)
+			if !ok {
+				return nil, false
+			}
+			right, ok := allowed(node.Right)
+			return append(left, right...), ok
+		case "!":
+			return clientAddresses(node.Left)
+		}
+		return nil, false
+	}
+	ranges, ok := allowed(node)
+	if !ok {
+		return nil, unsupported
+	}
+	return ranges, nil
+}
+
+// clientAddresses returns the addresses a CLIENT.IP.SRC match, or an "||"
+// of matches, compares the client with
+func clientAddresses(node *exprNode) ([]string, bool) {
+	if node.Op == "||" {
+		left, ok := clientAddresses(node.Left)
+		if !ok {
+			return nil, false
+		}
+		right, ok := clientAddresses(node.Right)
+		return append(left, right...), ok
+	}
+	if node.Op != "" || len(node.Terms) != 4 || len(node.Terms[3].Args) != 1 {
+		return nil, false
+	}
+	switch node.names() {
+	case "CLIENT.IP.SRC.EQ", "CLIENT.IP.SRC.IN_SUBNET":
+		return []string{node.Terms[3].Args[0]}, true
+	}
+	return nil, false
+}
+
+// addResponderSteps puts the middlewares of translated steps into
+// middlewares, named after their policy. It returns the chain of the
+// middlewares that apply to every request, and a router for each step with
+// a condition, whose chain is the unconditional middlewares before it
+// followed by its own. The routers are returned in evaluation order, with
+// the policy name they are named after.
+func addResponderSteps(steps []ResponderStep, middlewares map[string]TraefikMiddleware, opts GenerateOptions) ([]string, []string, []TraefikRouter) {
+	var chain, names []string
+	var routers []TraefikRouter
+	for _, step := range steps {
+		if step.Middleware == nil {
+			continue
+		}
+		middleware := *step.Middleware
+		if middleware.IPAllowList != nil && opts.Traefik != (TraefikVersion{}) && !opts.Traefik.AtLeast(2, 11) {
+			middleware.IPWhiteList, middleware.IPAllowList = middleware.IPAllowList, nil
+		}
+		middleware.Comment = fmt.Sprintf("responder policy %s (%s)", step.PolicyName, step.Policy.Action)
+		middleware.Origin = formatOrigin(step.Policy.Pos, step.Policy.Source)
+		name := addMiddleware(middlewares, step.PolicyName, middleware)
+
+		if step.Condition == "" {
+			chain = append(chain, name)
+			continue
+		}
+		names = append(names, step.PolicyName)
+		routers = append(routers, TraefikRouter{
+			Rule:        step.Condition,
+			Service:     noopService,
+			Middlewares: append(append([]string(nil), chain...), name),
+			Origin:      formatOrigin(step.Pos, step.Source),
+		})
+	}
+	return chain, names, routers
+}
+
+// addMiddleware adds a middleware under the given name, or the first name
+// with a numeric suffix that is free or already holds the same middleware,
+// as policies of different features may share a name. It returns the name
+// the middleware is found under.
+func addMiddleware(middlewares map[string]TraefikMiddleware, name string, middleware TraefikMiddleware) string {
+	unique := name
+	for suffix := 2; ; suffix++ {
+		existing, exists := middlewares[unique]
+		if !exists {
+			middlewares[unique] = middleware
+			return unique
+		}
+		if existing.String() == middleware.String() {
+			return unique
+		}
+		unique = fmt.Sprintf("%s-%d", name, suffix)
+	}
+}
+
+// responderGaps describes the responder policies that are not translated as
+// untranslated objects, at the policy or binding to fix
+func responderGaps(config *LBConfig) []*UntranslatedObject {
+	var gaps []*UntranslatedObject
+	add := func(steps []ResponderStep, kind, vserver string, lb bool) {
+		for _, step := range steps {
+			if step.Problem == "" {
+				continue
+			}
+			pos, source := step.Position()
+			gap := &UntranslatedObject{
+				ObjectType: "responder policy",
+				Name:       step.PolicyName,
+				Reason:     fmt.Sprintf("responder policy '%s' on %s '%s' is not applied: %s", step.PolicyName, kind, vserver, step.Problem),
+				Text:       source,
+				Pos:        pos,
+			}
+			if lb {
+				gap.VServer = vserver
+			} else {
+				gap.CSVServer = vserver
+			}
+			gaps = append(gaps, gap)
+		}
+	}
+	for _, vserver := range config.VServers {
+		add(ResponderSteps(config, vserver.Name, false), "lb vserver", vserver.Name, true)
+	}
+	for _, vserver := range config.CSVServers {
+		add(ResponderSteps(config, vserver.Name, true), "cs vserver", vserver.Name, false)
+	}
+	return gaps
+}
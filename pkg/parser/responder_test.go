@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"slices"
+	"testing"
+)
+
+// responderConfig binds responder policies to web-vs only, next to an
+// api-vs without policies
+const responderConfig = `add server s1 10.0.0.1
+add serviceGroup web_sg HTTP
+bind serviceGroup web_sg s1 80
+add serviceGroup api_sg HTTP
+bind serviceGroup api_sg s1 8080
+add lb vserver web-vs HTTP 10.1.1.10 80
+bind lb vserver web-vs web_sg
+add lb vserver api-vs HTTP 10.1.1.11 80
+bind lb vserver api-vs api_sg
+add responder action act_https redirect "\"https://\" + HTTP.REQ.HOSTNAME + HTTP.REQ.URL" -responseStatusCode 301
+add responder action act_old redirect "\"https://www.example.com/new\""
+add responder action act_page respondwithhtmlpage page1
+add responder policy allow_internal "!CLIENT.IP.SRC.IN_SUBNET(10.0.0.0/8)" DROP
+add responder policy to_https "CLIENT.SSL.IS_SSL.NOT" act_https
+add responder policy old_pol "HTTP.REQ.URL.PATH.STARTSWITH(\"/old\")" act_old
+add responder policy page_pol true act_page
+bind lb vserver web-vs -policyName to_https -priority 20 -type REQUEST
+bind lb vserver web-vs -policyName allow_internal -priority 10 -type REQUEST
+bind lb vserver web-vs -policyName old_pol -priority 30 -type REQUEST
+bind lb vserver web-vs -policyName page_pol -priority 40 -type REQUEST
+`
+
+func TestResponderSteps(t *testing.T) {
+	config := parseCitrixText(t, "ns.conf", responderConfig)
+
+	var got []string
+	for _, step := range ResponderSteps(config, "web-vs", false) {
+		result := "untranslated"
+		if step.Middleware != nil {
+			result = step.Middleware.String()
+		}
+		if step.Condition != "" {
+			result += " if " + step.Condition
+		}
+		got = append(got, step.PolicyName+": "+result)
+	}
+	want := []string{
+		"allow_internal: ipAllowList sourceRange=10.0.0.0/8",
+		"to_https: redirectScheme scheme=https permanent",
+		"old_pol: redirectRegex regex=^.*$ replacement=https://www.example.com/new if PathPrefix(`/old`)",
+		"page_pol: untranslated",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("steps =\n%q\nwant\n%q", got, want)
+	}
+	if steps := ResponderSteps(config, "api-vs", false); len(steps) != 0 {
+		t.Errorf("api-vs has %d responder steps, want none", len(steps))
+	}
+}
+
+func TestResponderRoutersAreScopedToTheirVServer(t *testing.T) {
+	config := parseCitrixText(t, "ns.conf", responderConfig)
+	traefik := GenerateTraefikConfig(config)
+
+	tests := []struct {
+		router      string
+		service     string
+		priority    int
+		middlewares []string
+	}{
+		{router: "web-vs", service: "web-vs", priority: 1, middlewares: []string{"allow_internal", "to_https"}},
+		{router: "web-vs-old_pol", service: noopService, priority: 2, middlewares: []string{"allow_internal", "to_https", "old_pol"}},
+	}
+	if len(traefik.HTTP.Routers) != len(tests) {
+		t.Errorf("got %d routers, want %d", len(traefik.HTTP.Routers), len(tests))
+	}
+	for _, tt := range tests {
+		router, exists := traefik.HTTP.Routers[tt.router]
+		if !exists {
+			t.Errorf("router %s missing", tt.router)
+			continue
+		}
+		if !slices.Equal(router.EntryPoints, []string{"web-vs"}) {
+			t.Errorf("router %s entryPoints = %v, want [web-vs]", tt.router, router.EntryPoints)
+		}
+		if router.Service != tt.service || router.Priority != tt.priority || !slices.Equal(router.Middlewares, tt.middlewares) {
+			t.Errorf("router %s = %s, want service=%s priority=%d middlewares=%v", tt.router, router, tt.service, tt.priority, tt.middlewares)
+		}
+	}
+}
+
+func TestResponderAllowListByTraefikVersion(t *testing.T) {
+	config := parseCitrixText(t, "ns.conf", responderConfig)
+
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{version: "", want: "ipAllowList sourceRange=10.0.0.0/8"},
+		{version: "2.11", want: "ipAllowList sourceRange=10.0.0.0/8"},
+		{version: "2.10", want: "ipWhiteList sourceRange=10.0.0.0/8"},
+	}
+
+	for _, tt := range tests {
+		version, err := ParseTraefikVersion(tt.version)
+		if err != nil {
+			t.Fatalf("ParseTraefikVersion(%q): %v", tt.version, err)
+		}
+		traefik := GenerateTraefikConfigWithOptions(config, GenerateOptions{Traefik: version})
+		if got := traefik.HTTP.Middlewares["allow_internal"].String(); got != tt.want {
+			t.Errorf("version %q: allow_internal = %s, want %s", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestUntranslatedResponderPolicy(t *testing.T) {
+	config := parseCitrixText(t, "ns.conf", responderConfig)
+
+	if codes := diagnosticCodes(Verify(config)); !slices.Contains(codes, "untranslated-responder-policy") {
+		t.Errorf("Verify codes = %v, want untranslated-responder-policy", codes)
+	}
+	if _, exists := GenerateTraefikConfig(config).HTTP.Middlewares["page_pol"]; exists {
+		t.Error("untranslated page_pol got a middleware")
+	}
+}
@@ -0,0 +1,387 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// traefikForwardedHeaders are the request headers Traefik sets itself, with
+// the expression a rewrite action inserting them would use
+var traefikForwardedHeaders = map[string]string{
+	"X-FORWARDED-FOR":  "CLIENT.IP.SRC",
+	"X-REAL-IP":        "CLIENT.IP.SRC",
+	"X-FORWARDED-HOST": "HTTP.REQ.HOSTNAME",
+}
+
+// rewriteActionSetters returns the functions that apply "add/set rewrite
+// action" parameters to an action
+func rewriteActionSetters(action *RewriteAction) map[string]func(value string) {
+	return map[string]func(string){
+		"-target":            func(value string) { action.Target = value },
+		"-stringBuilderExpr": func(value string) { action.Value = value },
+		"-search":            func(value string) { action.Search = value },
+	}
+}
+
+// isBuiltinRewriteAction reports whether a policy action is one the
+// appliance defines itself
+func isBuiltinRewriteAction(name string) bool {
+	switch strings.ToUpper(name) {
+	case "NOREWRITE", "RESET", "DROP":
+		return true
+	}
+	return false
+}
+
+// RewriteStep is a rewrite policy bound to a virtual server, resolved to the
+// middleware it becomes
+type RewriteStep struct {
+	PolicyName     string
+	Priority       string // Lower priorities are evaluated first
+	GotoExpression string
+	Type           string   // REQUEST or RESPONSE
+	Pos            Position // Where the policy was bound
+	Source         string
+	Policy         *RewritePolicy
+	Middleware     *TraefikMiddleware // nil for NOREWRITE policies, headers Traefik sets itself, and when Skipped or Problem is set
+	Skipped        string             // Why the appliance never evaluates the policy
+	Problem        string             // Why the policy is not translated
+}
+
+// Position returns where the step is best fixed: the policy when it is
+// defined, the binding otherwise
+func (s RewriteStep) Position() (Position, string) {
+	if s.Policy != nil {
+		return s.Policy.Pos, s.Policy.Source
+	}
+	return s.Pos, s.Source
+}
+
+// RewriteSteps resolves the rewrite policies bound to the named lb vserver,
+// or cs vserver when cs is set, in the order the appliance evaluates them:
+// request policies before response policies, each by ascending priority. A
+// translated policy applies to every request, so unless its binding's
+// -gotoPriorityExpression is NEXT or a priority, which is where evaluation
+// goes on, the policies after it are never evaluated and are marked as
+// skipped.
+func RewriteSteps(config *LBConfig, vserver string, cs bool) []RewriteStep {
+	var steps []RewriteStep
+	if cs {
+		for _, binding := range config.CSBindingsOf(vserver) {
+			if binding.Type != "" && config.RewritePolicyByName(binding.PolicyName) != nil {
+				steps = append(steps, RewriteStep{PolicyName: binding.PolicyName, Priority: binding.Priority,
+					GotoExpression: binding.GotoExpression, Type: binding.Type, Pos: binding.Pos, Source: binding.Source})
+			}
+		}
+	} else {
+		for _, binding := range config.BindingsOf(vserver) {
+			if binding.PolicyName != "" && config.RewritePolicyByName(binding.PolicyName) != nil {
+				steps = append(steps, RewriteStep{PolicyName: binding.PolicyName, Priority: binding.Priority,
+					GotoExpression: binding.GotoExpression, Type: binding.Type, Pos: binding.Pos, Source: binding.Source})
+			}
+		}
+	}
+	for i := range steps {
+		steps[i].Type = strings.ToUpper(steps[i].Type)
+		if steps[i].Type != "RESPONSE" {
+			steps[i].Type = "REQUEST"
+		}
+	}
+
+	sort.SliceStable(steps, func(i, j int) bool {
+		if steps[i].Type != steps[j].Type {
+			return steps[i].Type == "REQUEST"
+		}
+		return priorityLess(steps[i].Priority, steps[j].Priority)
+	})
+
+	var endedBy *RewriteStep
+	skipBelow := 0
+	for i := range steps {
+		step := &steps[i]
+		if endedBy != nil && endedBy.Type != step.Type {
+			endedBy, skipBelow = nil, 0
+		}
+		if endedBy != nil {
+			step.Policy = config.RewritePolicyByName(step.PolicyName)
+			step.Skipped = fmt.Sprintf("rewrite policy '%s' applies to every %s and ends evaluation",
+				endedBy.PolicyName, strings.ToLower(step.Type))
+			continue
+		}
+		if priority, err := strconv.Atoi(step.Priority); err == nil && priority < skipBelow {
+			step.Policy = config.RewritePolicyByName(step.PolicyName)
+			step.Skipped = fmt.Sprintf("evaluation goes on at priority %d", skipBelow)
+			continue
+		}
+
+		resolveRewriteStep(config, step)
+		if step.Problem != "" {
+			continue
+		}
+		switch goTo := strings.ToUpper(strings.TrimSpace(step.GotoExpression)); goTo {
+		case "", "END":
+			endedBy = step
+		case "NEXT", "USE_INVOCATION_RESULT":
+		default:
+			if priority, err := strconv.Atoi(goTo); err == nil {
+				skipBelow = priority
+			}
+		}
+	}
+	return steps
+}
+
+// resolveRewriteStep follows a step to its policy and action and translates
+// them to a middleware. Only policies whose rule selects every request or
+// response are translated; anything else needs expression evaluation.
+func resolveRewriteStep(config *LBConfig, step *RewriteStep) {
+	step.Policy = config.RewritePolicyByName(step.PolicyName)
+	if step.Policy == nil {
+		step.Problem = fmt.Sprintf("rewrite policy '%s' is not defined", step.PolicyName)
+		return
+	}
+	if !isMatchAllRule(step.Policy.Rule) {
+		step.Problem = fmt.Sprintf("rule %s selects what to rewrite, which needs expression evaluation", step.Policy.Rule)
+		return
+	}
+
+	switch strings.ToUpper(step.Policy.Action) {
+	case "NOREWRITE":
+		return
+	case "RESET", "DROP":
+		step.Problem = fmt.Sprintf("rewrite action %s closes the connection, which has no Traefik middleware equivalent", strings.ToUpper(step.Policy.Action))
+		return
+	}
+
+	action := config.RewriteActionByName(step.Policy.Action)
+	if action == nil {
+		step.Problem = fmt.Sprintf("rewrite action '%s' is not defined", step.Policy.Action)
+		return
+	}
+	middleware, err := rewriteMiddleware(action, step.Type == "RESPONSE")
+	if err != nil {
+		step.Problem = err.Error()
+		return
+	}
+	step.Middleware = middleware
+}
+
+// isMatchAllRule reports whether a policy rule selects every request or
+// response
+func isMatchAllRule(rule string) bool {
+	if strings.EqualFold(strings.TrimSpace(rule), "HTTP.RES.IS_VALID") {
+		return true
+	}
+	condition, err := translateRule(rule)
+	return err == nil && strings.Trim(condition, "()") == strings.Trim(matchAllRule, "()")
+}
+
+// rewriteMiddleware translates a rewrite action on the request, or the
+// response when response is set
+func rewriteMiddleware(action *RewriteAction, response bool) (*TraefikMiddleware, error) {
+	actionType := strings.ToLower(action.Type)
+	switch actionType {
+	case "insert_http_header", "delete_http_header":
+		return headersMiddleware(action, response)
+	}
+	if response {
+		return nil, fmt.Errorf("rewrite action '%s' of type %s rewrites the response, only response headers can be rewritten", action.Name, action.Type)
+	}
+
+	target := strings.ToUpper(strings.TrimSpace(action.Target))
+	path := target == "HTTP.REQ.URL.PATH"
+	switch {
+	case actionType == "replace" && path:
+		return replacePathMiddleware(action)
+	case actionType == "insert_before" && (path || target == "HTTP.REQ.URL"):
+		prefix, err := rewriteLiteral(action, action.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &TraefikMiddleware{AddPrefix: &TraefikAddPrefix{Prefix: prefix}}, nil
+	case actionType == "replace_all" && path:
+		regex, err := rewriteSearch(action)
+		if err != nil {
+			return nil, err
+		}
+		replacement, err := rewriteLiteral(action, action.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &TraefikMiddleware{ReplacePathRegex: &TraefikReplacePathRegex{
+			Regex:       regex,
+			Replacement: strings.ReplaceAll(replacement, "$", "$$"),
+		}}, nil
+	case actionType == "replace" || actionType == "insert_before" || actionType == "replace_all":
+		return nil, fmt.Errorf("rewrite action '%s' rewrites %s, only HTTP.REQ.URL.PATH has Traefik middleware equivalents", action.Name, action.Target)
+	default:
+		return nil, fmt.Errorf("rewrite action '%s' of type %s has no Traefik middleware equivalent", action.Name, action.Type)
+	}
+}
+
+// headersMiddleware translates an insert_http_header action with a literal
+// value, or a delete_http_header action, which sets the header to an empty
+// value Traefik removes. Headers Traefik sets itself from the same request
+// data need no middleware.
+func headersMiddleware(action *RewriteAction, response bool) (*TraefikMiddleware, error) {
+	var value string
+	if strings.EqualFold(action.Type, "insert_http_header") {
+		if expression, exists := traefikForwardedHeaders[strings.ToUpper(action.Target)]; exists && !response &&
+			strings.EqualFold(strings.TrimSpace(action.Value), expression) {
+			return nil, nil
+		}
+		var err error
+		if value, err = rewriteLiteral(action, action.Value); err != nil {
+			return nil, err
+		}
+		if value == "" {
+			return nil, fmt.Errorf("rewrite action '%s' inserts an empty header, which Traefik treats as removing it", action.Name)
+		}
+	}
+
+	headers := map[string]string{action.Target: value}
+	if response {
+		return &TraefikMiddleware{Headers: &TraefikHeaders{CustomResponseHeaders: headers}}, nil
+	}
+	return &TraefikMiddleware{Headers: &TraefikHeaders{CustomRequestHeaders: headers}}, nil
+}
+
+// replacePathMiddleware translates a replace action on the request path: a
+// literal path becomes a replacePathRegex, a literal followed by the path an
+// addPrefix, and the path after a literal a stripPrefix
+func replacePathMiddleware(action *RewriteAction) (*TraefikMiddleware, error) {
+	parts, err := parseConcatenation(action.Value)
+	if err != nil {
+		return nil, fmt.Errorf("rewrite action '%s': cannot parse %q: %v", action.Name, action.Value, err)
+	}
+
+	if len(parts) == 2 && parts[0].Term == nil && parts[1].Term != nil && parts[1].Term.names() == "HTTP.REQ.URL.PATH" {
+		return &TraefikMiddleware{AddPrefix: &TraefikAddPrefix{Prefix: parts[0].Literal}}, nil
+	}
+	if len(parts) == 1 && parts[0].Term != nil && parts[0].Term.names() == "HTTP.REQ.URL.PATH.AFTER_STR" {
+		if args := parts[0].Term.Terms[4].Args; len(args) == 1 {
+			return &TraefikMiddleware{StripPrefix: &TraefikStripPrefix{Prefixes: []string{args[0]}}}, nil
+		}
+	}
+
+	path, err := rewriteLiteral(action, action.Value)
+	if err != nil {
+		return nil, err
+	}
+	return &TraefikMiddleware{ReplacePathRegex: &TraefikReplacePathRegex{
+		Regex:       "^.*$",
+		Replacement: strings.ReplaceAll(path, "$", "$$"),
+	}}, nil
+}
+
+// rewriteLiteral returns the text of an expression made of string literals
+// only. The error names the first part that needs request data, with the
+// Traefik feature that provides it where there is one.
+func rewriteLiteral(action *RewriteAction, expression string) (string, error) {
+	parts, err := parseConcatenation(expression)
+	if err != nil {
+		return "", fmt.Errorf("rewrite action '%s': cannot parse %q: %v", action.Name, expression, err)
+	}
+	var text strings.Builder
+	for _, part := range parts {
+		if part.Term == nil {
+			text.WriteString(part.Literal)
+			continue
+		}
+		name := part.Term.names()
+		switch {
+		case strings.HasPrefix(name, "CLIENT.SSL.CLIENT_CERT"):
+			return "", fmt.Errorf("rewrite action '%s' inserts %s, which needs expression evaluation; Traefik's passTLSClientCert middleware sends the client certificate as X-Forwarded-Tls-Client-Cert instead",
+				action.Name, part.Term.Text)
+		case strings.HasPrefix(name, "CLIENT.IP.SRC"):
+			return "", fmt.Errorf("rewrite action '%s' inserts %s, which needs expression evaluation; Traefik sets X-Forwarded-For and X-Real-Ip itself",
+				action.Name, part.Term.Text)
+		}
+		return "", fmt.Errorf("rewrite action '%s' inserts %s, which needs expression evaluation", action.Name, part.Term.Text)
+	}
+	return text.String(), nil
+}
+
+// rewriteSearch converts the -search of a replace_all action, text("...") or
+// regex(re<delimiter>pattern<delimiter>), to a Go regular expression
+func rewriteSearch(action *RewriteAction) (string, error) {
+	search := strings.TrimSpace(action.Search)
+	function, argument, found := strings.Cut(search, "(")
+	argument, closed := strings.CutSuffix(argument, ")")
+	if !found || !closed {
+		return "", fmt.Errorf("rewrite action '%s' has an unsupported -search %q", action.Name, action.Search)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(function)) {
+	case "text":
+		parts, err := parseConcatenation(argument)
+		if err != nil || len(parts) != 1 || parts[0].Term != nil {
+			return "", fmt.Errorf("rewrite action '%s' has an unsupported -search %q", action.Name, action.Search)
+		}
+		return regexp.QuoteMeta(parts[0].Literal), nil
+	case "regex":
+		if len(argument) < 4 || !strings.HasPrefix(argument, "re") || argument[2] != argument[len(argument)-1] {
+			return "", fmt.Errorf("rewrite action '%s' has an unsupported -search %q", action.Name, action.Search)
+		}
+		pattern := argument[3 : len(argument)-1]
+		if _, err := regexp.Compile(pattern); err != nil {
+			return "", fmt.Errorf("rewrite action '%s' searches for %s, which Go regular expressions do not support: %v", action.Name, pattern, err)
+		}
+		return pattern, nil
+	default:
+		return "", fmt.Errorf("rewrite action '%s' searches with %s, which needs expression evaluation", action.Name, function)
+	}
+}
+
+// addRewriteSteps puts the middlewares of translated steps into middlewares,
+// named after their policy, and returns their chain in evaluation order
+func addRewriteSteps(steps []RewriteStep, middlewares map[string]TraefikMiddleware) []string {
+	var chain []string
+	for _, step := range steps {
+		if step.Middleware == nil {
+			continue
+		}
+		middleware := *step.Middleware
+		middleware.Comment = fmt.Sprintf("rewrite policy %s (%s)", step.PolicyName, step.Policy.Action)
+		middleware.Origin = formatOrigin(step.Policy.Pos, step.Policy.Source)
+		chain = append(chain, addMiddleware(middlewares, step.PolicyName, middleware))
+	}
+	return chain
+}
+
+// rewriteGaps describes the rewrite policies that are not translated as
+// untranslated objects, at the policy or binding to fix
+func rewriteGaps(config *LBConfig) []*UntranslatedObject {
+	var gaps []*UntranslatedObject
+	add := func(steps []RewriteStep, kind, vserver string, lb bool) {
+		for _, step := range steps {
+			if step.Problem == "" {
+				continue
+			}
+			pos, source := step.Position()
+			gap := &UntranslatedObject{
+				ObjectType: "rewrite policy",
+				Name:       step.PolicyName,
+				Reason:     fmt.Sprintf("rewrite policy '%s' on %s '%s' is not applied: %s", step.PolicyName, kind, vserver, step.Problem),
+				Text:       source,
+				Pos:        pos,
+			}
+			if lb {
+				gap.VServer = vserver
+			} else {
+				gap.CSVServer = vserver
+			}
+			gaps = append(gaps, gap)
+		}
+	}
+	for _, vserver := range config.VServers {
+		add(RewriteSteps(config, vserver.Name, false), "lb vserver", vserver.Name, true)
+	}
+	for _, vserver := range config.CSVServers {
+		add(RewriteSteps(config, vserver.Name, true), "cs vserver", vserver.Name, false)
+	}
+	return gaps
+}
@@ -0,0 +1,125 @@
+package parser
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// rewriteBase has an lb vserver to bind rewrite policies to
+const rewriteBase = `add server s1 10.0.0.1
+add serviceGroup web_sg HTTP
+bind serviceGroup web_sg s1 80
+add lb vserver web-vs HTTP 10.1.1.10 80
+bind lb vserver web-vs web_sg
+`
+
+func TestRewriteMiddleware(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  string
+		rule    string
+		binding string
+		want    string // Middleware, "none" when the step needs none, or "untranslated"
+	}{
+		{name: "request header", action: `insert_http_header X-Forwarded-Proto "\"https\""`, want: `headers request X-Forwarded-Proto="https"`},
+		{name: "response header", action: `insert_http_header X-Frame-Options "\"DENY\""`, rule: "HTTP.RES.IS_VALID", binding: "-type RESPONSE", want: `headers response X-Frame-Options="DENY"`},
+		{name: "delete header", action: `delete_http_header Server`, rule: "HTTP.RES.IS_VALID", binding: "-type RESPONSE", want: `headers response Server=""`},
+		{name: "header traefik sets", action: `insert_http_header X-Forwarded-For CLIENT.IP.SRC`, want: "none"},
+		{name: "header from request data", action: `insert_http_header X-Client-Cert CLIENT.SSL.CLIENT_CERT`, want: "untranslated"},
+		{name: "literal path", action: `replace HTTP.REQ.URL.PATH "\"/new\""`, want: "replacePathRegex regex=^.*$ replacement=/new"},
+		{name: "path prefix", action: `replace HTTP.REQ.URL.PATH "\"/app\" + HTTP.REQ.URL.PATH"`, want: "addPrefix prefix=/app"},
+		{name: "strip prefix", action: `replace HTTP.REQ.URL.PATH "HTTP.REQ.URL.PATH.AFTER_STR(\"/api\")"`, want: "stripPrefix prefixes=/api"},
+		{name: "insert before", action: `insert_before HTTP.REQ.URL "\"/v2\""`, want: "addPrefix prefix=/v2"},
+		{name: "replace all text", action: `replace_all HTTP.REQ.URL.PATH "\"/new\"" -search "text(\"/old\")"`, want: "replacePathRegex regex=/old replacement=/new"},
+		{name: "replace all regex", action: `replace_all HTTP.REQ.URL.PATH "\"/api/\"" -search "regex(re~^/api/v[0-9]+/~)"`, want: "replacePathRegex regex=^/api/v[0-9]+/ replacement=/api/"},
+		{name: "other target", action: `replace HTTP.REQ.HOSTNAME "\"example.com\""`, want: "untranslated"},
+		{name: "body rewrite", action: `replace_all "HTTP.RES.BODY(1000)" "\"x\"" -search "text(\"y\")"`, rule: "HTTP.RES.IS_VALID", binding: "-type RESPONSE", want: "untranslated"},
+		{name: "conditional rule", action: `insert_http_header X-A "\"1\""`, rule: `HTTP.REQ.URL.PATH.STARTSWITH("/a")`, want: "untranslated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, binding := tt.rule, tt.binding
+			if rule == "" {
+				rule = "true"
+			}
+			if binding == "" {
+				binding = "-type REQUEST"
+			}
+			config := parseCitrixText(t, "ns.conf", rewriteBase+
+				"add rewrite action act "+tt.action+"\n"+
+				"add rewrite policy pol \""+strings.ReplaceAll(rule, `"`, `\"`)+"\" act\n"+
+				"bind lb vserver web-vs -policyName pol -priority 10 "+binding+"\n")
+
+			steps := RewriteSteps(config, "web-vs", false)
+			if len(steps) != 1 {
+				t.Fatalf("got %d steps, want 1", len(steps))
+			}
+			got := "untranslated"
+			switch {
+			case steps[0].Problem != "":
+			case steps[0].Middleware == nil:
+				got = "none"
+			default:
+				got = steps[0].Middleware.String()
+			}
+			if got != tt.want {
+				t.Errorf("middleware = %s (problem %q), want %s", got, steps[0].Problem, tt.want)
+			}
+		})
+	}
+}
+
+// rewriteChainConfig binds a responder policy and rewrite policies with
+// different -gotoPriorityExpression settings to web-vs
+const rewriteChainConfig = rewriteBase + `add responder action act_https redirect "\"https://\" + HTTP.REQ.HOSTNAME + HTTP.REQ.URL"
+add responder policy to_https "CLIENT.SSL.IS_SSL.NOT" act_https
+bind lb vserver web-vs -policyName to_https -priority 10 -type REQUEST
+add rewrite action act_proto insert_http_header X-Forwarded-Proto "\"https\""
+add rewrite action act_env insert_http_header X-Env "\"prod\""
+add rewrite action act_late insert_http_header X-Late "\"1\""
+add rewrite action act_hide delete_http_header Server
+add rewrite policy add_proto true act_proto
+add rewrite policy add_env true act_env
+add rewrite policy add_late true act_late
+add rewrite policy hide_server HTTP.RES.IS_VALID act_hide
+bind lb vserver web-vs -policyName hide_server -priority 10 -type RESPONSE
+bind lb vserver web-vs -policyName add_late -priority 30 -type REQUEST
+bind lb vserver web-vs -policyName add_env -priority 20 -gotoPriorityExpression END -type REQUEST
+bind lb vserver web-vs -policyName add_proto -priority 10 -gotoPriorityExpression NEXT -type REQUEST
+`
+
+func TestRewriteStepsEvaluationOrder(t *testing.T) {
+	config := parseCitrixText(t, "ns.conf", rewriteChainConfig)
+
+	var got []string
+	for _, step := range RewriteSteps(config, "web-vs", false) {
+		result := step.Type + " " + step.PolicyName
+		if step.Skipped != "" {
+			result += " skipped"
+		}
+		got = append(got, result)
+	}
+	want := []string{"REQUEST add_proto", "REQUEST add_env", "REQUEST add_late skipped", "RESPONSE hide_server"}
+	if !slices.Equal(got, want) {
+		t.Errorf("steps = %q, want %q", got, want)
+	}
+}
+
+func TestRewriteMiddlewaresFollowResponderChain(t *testing.T) {
+	config := parseCitrixText(t, "ns.conf", rewriteChainConfig)
+	traefik := GenerateTraefikConfig(config)
+
+	router, exists := traefik.HTTP.Routers["web-vs"]
+	if !exists {
+		t.Fatal("router web-vs missing")
+	}
+	want := []string{"to_https", "add_proto", "add_env", "hide_server"}
+	if !slices.Equal(router.Middlewares, want) {
+		t.Errorf("middlewares = %v, want %v", router.Middlewares, want)
+	}
+	if _, exists := traefik.HTTP.Middlewares["add_late"]; exists {
+		t.Error("never evaluated add_late got a middleware")
+	}
+}
@@ -0,0 +1,521 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// applianceCertDir is where the appliance keeps the certificate and key
+// files of certKeys given without a directory
+const applianceCertDir = "/nsconfig/ssl"
+
+// defaultTLSStore is the Traefik certificate store routers use
+const defaultTLSStore = "default"
+
+// certificatePath resolves a certificate or key file of a certKey. Without
+// certificate directories the file keeps its path on the appliance. With
+// them, its path below /nsconfig/ssl (or its base name when it lies
+// elsewhere) is looked up in each directory in order; when no directory has
+// it, the path in the first directory is returned with found unset.
+func certificatePath(file string, dirs []string) (string, bool) {
+	relative := strings.TrimPrefix(path.Clean(file), applianceCertDir+"/")
+	if len(dirs) == 0 {
+		if path.IsAbs(relative) {
+			return relative, true
+		}
+		return path.Join(applianceCertDir, relative), true
+	}
+
+	if path.IsAbs(relative) {
+		relative = path.Base(relative)
+	}
+	for _, dir := range dirs {
+		candidate := filepath.Join(dir, filepath.FromSlash(relative))
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, true
+		}
+	}
+	return filepath.Join(dirs[0], filepath.FromSlash(relative)), false
+}
+
+// routerTLS returns the TLS setting of the routers of a virtual server, nil
+// unless its protocol terminates TLS. options maps SSL vservers to the name
+// of their TLS options.
+func routerTLS(protocol, vserverName string, options map[string]string) *TraefikRouterTLS {
+	if strings.EqualFold(protocol, "SSL") {
+		return &TraefikRouterTLS{Options: options[vserverName]}
+	}
+	return nil
+}
+
+// sslVServer reports whether the lb or cs virtual server a certificate is
+// bound to is disabled, and whether either is defined
+func sslVServer(config *LBConfig, name string) (disabled bool, exists bool) {
+	if vserver := config.VServerByName(name); vserver != nil {
+		return vserver.Disabled, true
+	}
+	if vserver := config.CSVServerByName(name); vserver != nil {
+		return vserver.Disabled, true
+	}
+	return false, false
+}
+
+// defaultCertKey returns the certKey Traefik serves to clients whose server
+// name matches no certificate: the first certKey with a key that an enabled
+// virtual server serves without -SNICert, with the vservers that do so. The
+// appliance serves such a certKey to every client of its vserver, but
+// Traefik has one default certificate for all entry points.
+func defaultCertKey(config *LBConfig) (*CertKey, []string) {
+	for _, certKey := range config.CertKeys {
+		if certKey.KeyFile == "" {
+			continue
+		}
+		var vservers []string
+		for _, binding := range config.SSLBindings {
+			if binding.CertKeyName != certKey.Name || binding.CA || binding.SNI || slices.Contains(vservers, binding.VServerName) {
+				continue
+			}
+			if disabled, exists := sslVServer(config, binding.VServerName); exists && !disabled {
+				vservers = append(vservers, binding.VServerName)
+			}
+		}
+		if len(vservers) > 0 {
+			return certKey, vservers
+		}
+	}
+	return nil, nil
+}
+
+// buildTLS creates a certificate for each certKey bound to a virtual server
+// with "bind ssl vserver", in certKey order, with its files resolved in
+// opts.CertDirs; Traefik serves each to the clients whose server name it
+// matches. The certKey of defaultCertKey also becomes the default
+// certificate of the default store, served when no server name matches.
+// Certificate authorities and certKeys without a key are left out. It also
+// creates the options of TLSOptions. Certificates and options of disabled
+// virtual servers are left out unless they are kept commented out. It
+// returns nil when there is neither.
+func buildTLS(config *LBConfig, opts GenerateOptions) *TraefikTLS {
+	var certificates []TraefikCertificate
+	for _, certKey := range config.CertKeys {
+		if certKey.KeyFile == "" {
+			continue
+		}
+
+		var vservers []string
+		enabled := false
+		for _, binding := range config.SSLBindings {
+			if binding.CertKeyName != certKey.Name || binding.CA {
+				continue
+			}
+			disabled, exists := sslVServer(config, binding.VServerName)
+			if !exists || (disabled && opts.Disabled == DisabledDrop) {
+				continue
+			}
+			if !slices.Contains(vservers, binding.VServerName) {
+				vservers = append(vservers, binding.VServerName)
+			}
+			enabled = enabled || !disabled
+		}
+		if len(vservers) == 0 {
+			continue
+		}
+
+		certFile, _ := certificatePath(certKey.CertFile, opts.CertDirs)
+		keyFile, _ := certificatePath(certKey.KeyFile, opts.CertDirs)
+		certificates = append(certificates, TraefikCertificate{
+			CertFile: certFile,
+			KeyFile:  keyFile,
+			Disabled: !enabled,
+			Comment:  fmt.Sprintf("certKey %s on %s", certKey.Name, strings.Join(vservers, ", ")),
+			Origin:   formatOrigin(certKey.Pos, certKey.Source),
+		})
+	}
+
+	var stores map[string]TraefikTLSStore
+	if certKey, vservers := defaultCertKey(config); certKey != nil {
+		certFile, _ := certificatePath(certKey.CertFile, opts.CertDirs)
+		keyFile, _ := certificatePath(certKey.KeyFile, opts.CertDirs)
+		stores = map[string]TraefikTLSStore{defaultTLSStore: {DefaultCertificate: &TraefikCertificate{
+			CertFile: certFile,
+			KeyFile:  keyFile,
+			Comment:  fmt.Sprintf("certKey %s, bound without -SNICert on %s", certKey.Name, strings.Join(vservers, ", ")),
+			Origin:   formatOrigin(certKey.Pos, certKey.Source),
+		}}}
+	}
+
+	options := make(map[string]TraefikTLSOptions)
+	for _, option := range TLSOptions(config, opts.CertDirs) {
+		enabled := false
+		for _, name := range option.VServers {
+			disabled, _ := sslVServer(config, name)
+			enabled = enabled || !disabled
+		}
+		if !enabled && opts.Disabled == DisabledDrop {
+			continue
+		}
+		traefikOption := option.Options
+		traefikOption.Disabled = !enabled
+		traefikOption.Comment = option.Kind()
+		if option.Profile {
+			traefikOption.Comment += " on " + strings.Join(option.VServers, ", ")
+		}
+		traefikOption.Origin = formatOrigin(option.Pos, option.Source)
+		options[option.Name] = traefikOption
+	}
+
+	if len(certificates) == 0 && len(options) == 0 {
+		return nil
+	}
+	tls := &TraefikTLS{Stores: stores, Certificates: certificates}
+	if len(options) > 0 {
+		tls.Options = options
+	}
+	return tls
+}
+
+// sslProtocols are the protocol switches of ssl vservers and profiles, oldest
+// first, with the Traefik version each becomes. Go does not implement SSLv3.
+var sslProtocols = []struct {
+	name    string
+	label   string
+	enabled bool // Appliance default
+	version string
+}{
+	{"ssl3", "SSLv3", false, ""},
+	{"tls1", "TLS 1.0", true, "VersionTLS10"},
+	{"tls11", "TLS 1.1", true, "VersionTLS11"},
+	{"tls12", "TLS 1.2", true, "VersionTLS12"},
+	{"tls13", "TLS 1.3", false, "VersionTLS13"},
+}
+
+// defaultCipherGroup is the built-in cipher group bound to every ssl vserver
+// and profile, for which Go's default cipher suites stand in
+const defaultCipherGroup = "DEFAULT"
+
+// goCipherSuites maps appliance cipher names to the Go cipher suites Traefik
+// accepts. TLS 1.3 ciphers map to "": Go always enables every TLS 1.3 suite.
+var goCipherSuites = map[string]string{
+	"TLS1.3-AES128-GCM-SHA256":             "",
+	"TLS1.3-AES256-GCM-SHA384":             "",
+	"TLS1.3-CHACHA20-POLY1305-SHA256":      "",
+	"TLS1.2-ECDHE-ECDSA-AES128-GCM-SHA256": "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+	"TLS1.2-ECDHE-ECDSA-AES256-GCM-SHA384": "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+	"TLS1.2-ECDHE-ECDSA-CHACHA20-POLY1305": "TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256",
+	"TLS1.2-ECDHE-ECDSA-AES128-SHA256":     "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256",
+	"TLS1.2-ECDHE-RSA-AES128-GCM-SHA256":   "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+	"TLS1.2-ECDHE-RSA-AES256-GCM-SHA384":   "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+	"TLS1.2-ECDHE-RSA-CHACHA20-POLY1305":   "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256",
+	"TLS1.2-ECDHE-RSA-AES-128-SHA256":      "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256",
+	"TLS1.2-AES128-GCM-SHA256":             "TLS_RSA_WITH_AES_128_GCM_SHA256",
+	"TLS1.2-AES256-GCM-SHA384":             "TLS_RSA_WITH_AES_256_GCM_SHA384",
+	"TLS1.2-AES-128-SHA256":                "TLS_RSA_WITH_AES_128_CBC_SHA256",
+	"TLS1-ECDHE-ECDSA-AES128-SHA":          "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA",
+	"TLS1-ECDHE-ECDSA-AES256-SHA":          "TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA",
+	"TLS1-ECDHE-RSA-AES128-SHA":            "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA",
+	"TLS1-ECDHE-RSA-AES256-SHA":            "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA",
+	"TLS1-ECDHE-RSA-DES-CBC3-SHA":          "TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA",
+	"TLS1-ECDHE-RSA-RC4-SHA":               "TLS_ECDHE_RSA_WITH_RC4_128_SHA",
+	"TLS1-ECDHE-ECDSA-RC4-SHA":             "TLS_ECDHE_ECDSA_WITH_RC4_128_SHA",
+	"TLS1-AES-128-CBC-SHA":                 "TLS_RSA_WITH_AES_128_CBC_SHA",
+	"TLS1-AES-256-CBC-SHA":                 "TLS_RSA_WITH_AES_256_CBC_SHA",
+	"SSL3-DES-CBC3-SHA":                    "TLS_RSA_WITH_3DES_EDE_CBC_SHA",
+	"SSL3-RC4-SHA":                         "TLS_RSA_WITH_RC4_128_SHA",
+}
+
+// sslSettingsSetters returns the setters of the settings shared by ssl
+// vservers and ssl profiles. Unset passes an empty value, which restores the
+// appliance default.
+func sslSettingsSetters(settings *SSLSettings) map[string]func(value string) {
+	setters := map[string]func(string){
+		// Traefik always picks the certificate matching the requested server name
+		"-sniEnable":        func(string) {},
+		"-SNIHTTPHostMatch": func(value string) { settings.SNIHostMatch = value },
+		"-clientAuth":       func(value string) { settings.ClientAuth = strings.EqualFold(value, "ENABLED") },
+		"-clientCert":       func(value string) { settings.ClientCert = value },
+	}
+	for _, protocol := range sslProtocols {
+		setters["-"+protocol.name] = func(value string) {
+			if value == "" {
+				delete(settings.Protocols, protocol.name)
+				return
+			}
+			if settings.Protocols == nil {
+				settings.Protocols = make(map[string]bool)
+			}
+			settings.Protocols[protocol.name] = strings.EqualFold(value, "ENABLED")
+		}
+	}
+	return setters
+}
+
+// isDefault reports whether nothing was set or bound
+func (s SSLSettings) isDefault() bool {
+	return len(s.Protocols) == 0 && s.SNIHostMatch == "" && !s.ClientAuth && s.ClientCert == "" && len(s.Ciphers) == 0
+}
+
+// TLSOption is the named Traefik TLS option an ssl profile, or the own
+// settings of an SSL vserver without a profile, becomes
+type TLSOption struct {
+	Name     string
+	Profile  bool     // Whether the option comes from an ssl profile
+	VServers []string // SSL vservers whose routers reference the option
+	Options  TraefikTLSOptions
+	Problems []TLSProblem // Settings the option cannot express
+	Pos      Position
+	Source   string
+}
+
+// Kind describes where the option comes from, e.g. "ssl profile strict"
+func (o TLSOption) Kind() string {
+	if o.Profile {
+		return "ssl profile " + o.Name
+	}
+	return "ssl vserver " + o.VServers[0]
+}
+
+// TLSProblem is an SSL setting that Traefik TLS options or servers
+// transports cannot express
+type TLSProblem struct {
+	Code    string // unsupported-cipher, untranslated-ssl-setting or untranslated-backend-ssl
+	Message string
+	Pos     Position
+	Source  string
+}
+
+// TLSOptions returns the TLS options of the SSL vservers that set protocols,
+// client authentication or ciphers, or use an ssl profile, in the order the
+// vservers were first configured. Vservers sharing a profile share its
+// option. Option names are unique: an option named after a vserver that is
+// also the name of a profile gets a numeric suffix. CA certKey files are
+// resolved in certDirs.
+func TLSOptions(config *LBConfig, certDirs []string) []TLSOption {
+	var options []TLSOption
+	byProfile := make(map[string]int)
+	names := make(map[string]bool)
+	for _, ssl := range config.SSLVServers {
+		if _, exists := sslVServer(config, ssl.Name); !exists {
+			continue
+		}
+		if profile := config.SSLProfileByName(ssl.Profile); profile != nil {
+			if i, seen := byProfile[profile.Name]; seen {
+				options[i].VServers = append(options[i].VServers, ssl.Name)
+				continue
+			}
+			byProfile[profile.Name] = len(options)
+			options = append(options, TLSOption{Name: profile.Name, Profile: true, VServers: []string{ssl.Name}, Pos: profile.Pos, Source: profile.Source})
+			continue
+		}
+		if !ssl.SSLSettings.isDefault() {
+			options = append(options, TLSOption{Name: ssl.Name, VServers: []string{ssl.Name}, Pos: ssl.Pos, Source: ssl.Source})
+		}
+	}
+
+	// Profile names are kept, vserver options make way for them
+	for _, option := range options {
+		if option.Profile {
+			names[option.Name] = true
+		}
+	}
+	for i := range options {
+		option := &options[i]
+		var settings SSLSettings
+		if option.Profile {
+			settings = config.SSLProfileByName(option.Name).SSLSettings
+		} else {
+			settings = config.SSLVServerByName(option.Name).SSLSettings
+			option.Name = uniqueName(names, option.Name)
+			names[option.Name] = true
+		}
+		option.Options, option.Problems = resolveTLSOptions(config, settings, option, certDirs)
+	}
+	return options
+}
+
+// tlsGaps lists the SSL settings that the TLS options cannot express, with
+// unsupported ciphers apart. Those of the own settings of an lb or cs vserver
+// are attached to it.
+func tlsGaps(config *LBConfig) []*UntranslatedObject {
+	var gaps []*UntranslatedObject
+	for _, option := range TLSOptions(config, nil) {
+		for _, problem := range option.Problems {
+			gap := &UntranslatedObject{
+				ObjectType: "ssl vserver",
+				Name:       option.VServers[0],
+				Reason:     fmt.Sprintf("TLS options '%s' lose a setting: %s", option.Name, problem.Message),
+				Text:       problem.Source,
+				Pos:        problem.Pos,
+			}
+			switch {
+			case option.Profile:
+				gap.ObjectType, gap.Name = "ssl profile", option.Name
+			case config.VServerByName(option.VServers[0]) != nil:
+				gap.VServer = option.VServers[0]
+			case config.CSVServerByName(option.VServers[0]) != nil:
+				gap.CSVServer = option.VServers[0]
+			}
+			if problem.Code == "unsupported-cipher" {
+				gap.ObjectType = "ssl cipher"
+			}
+			gaps = append(gaps, gap)
+		}
+	}
+	return gaps
+}
+
+// tlsOptionNames maps each SSL vserver with TLS options to the option name
+func tlsOptionNames(config *LBConfig) map[string]string {
+	names := make(map[string]string)
+	for _, option := range TLSOptions(config, nil) {
+		for _, vserver := range option.VServers {
+			names[vserver] = option.Name
+		}
+	}
+	return names
+}
+
+// resolveTLSOptions translates the settings of an option. Protocols become
+// the range from minVersion to maxVersion, ciphers and the ciphers of bound
+// cipher groups become cipherSuites, -SNIHTTPHostMatch STRICT becomes
+// sniStrict and -clientAuth ENABLED becomes clientAuth with the CA certKeys
+// bound to the option's vservers. Settings Traefik cannot express are
+// returned as problems.
+func resolveTLSOptions(config *LBConfig, settings SSLSettings, option *TLSOption, certDirs []string) (TraefikTLSOptions, []TLSProblem) {
+	var options TraefikTLSOptions
+	var problems []TLSProblem
+	problem := func(pos Position, source, code, format string, args ...interface{}) {
+		problems = append(problems, TLSProblem{Code: code, Message: fmt.Sprintf(format, args...), Pos: pos, Source: source})
+	}
+
+	// Traefik allows every version between minVersion and maxVersion
+	first, last := -1, -1
+	for i, protocol := range sslProtocols {
+		enabled, set := settings.Protocols[protocol.name]
+		if !set {
+			enabled = protocol.enabled
+		}
+		switch {
+		case !enabled:
+		case protocol.version == "":
+			problem(option.Pos, option.Source, "untranslated-ssl-setting", "%s is enabled, which Go does not support", protocol.label)
+		default:
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 {
+		problem(option.Pos, option.Source, "untranslated-ssl-setting", "no TLS version is enabled; Traefik keeps its default versions")
+	} else {
+		for _, protocol := range sslProtocols[first:last] {
+			if enabled, set := settings.Protocols[protocol.name]; set && !enabled {
+				problem(option.Pos, option.Source, "untranslated-ssl-setting", "%s is disabled, but Traefik allows every version from %s to %s",
+					protocol.label, sslProtocols[first].label, sslProtocols[last].label)
+			}
+		}
+		options.MinVersion = sslProtocols[first].version
+		if last < len(sslProtocols)-1 {
+			options.MaxVersion = sslProtocols[last].version
+		}
+	}
+
+	// Cipher groups expand to their ciphers, in binding order
+	var defaultGroup *SSLCipher
+	seen := make(map[string]bool)
+	addCipher := func(cipher SSLCipher) {
+		suite, known := goCipherSuites[strings.ToUpper(cipher.Name)]
+		switch {
+		case strings.EqualFold(cipher.Name, defaultCipherGroup):
+			defaultGroup = &cipher
+		case !known:
+			problem(cipher.Pos, cipher.Source, "unsupported-cipher", "cipher %s has no Go TLS equivalent", cipher.Name)
+		case suite != "" && !seen[suite]:
+			seen[suite] = true
+			options.CipherSuites = append(options.CipherSuites, suite)
+		}
+	}
+	for _, cipher := range settings.Ciphers {
+		if group := config.CipherGroupByName(cipher.Name); group != nil {
+			for _, member := range group.Ciphers {
+				addCipher(member)
+			}
+			continue
+		}
+		addCipher(cipher)
+	}
+	if defaultGroup != nil && len(options.CipherSuites) > 0 {
+		problem(defaultGroup.Pos, defaultGroup.Source, "unsupported-cipher",
+			"cipher group %s is bound with other ciphers; only those are kept", defaultCipherGroup)
+	}
+
+	options.SNIStrict = strings.EqualFold(settings.SNIHostMatch, "STRICT")
+
+	if settings.ClientAuth {
+		auth := &TraefikClientAuth{ClientAuthType: "VerifyClientCertIfGiven"}
+		if strings.EqualFold(settings.ClientCert, "Mandatory") {
+			auth.ClientAuthType = "RequireAndVerifyClientCert"
+		}
+		for _, vserver := range option.VServers {
+			for _, binding := range config.SSLBindingsOf(vserver) {
+				certKey := config.CertKeyByName(binding.CertKeyName)
+				if !binding.CA || certKey == nil {
+					continue
+				}
+				file, _ := certificatePath(certKey.CertFile, certDirs)
+				if !slices.Contains(auth.CAFiles, file) {
+					auth.CAFiles = append(auth.CAFiles, file)
+				}
+			}
+		}
+		if len(auth.CAFiles) == 0 {
+			problem(option.Pos, option.Source, "untranslated-ssl-setting",
+				"client authentication has no CA certKey bound; Traefik accepts client certificates issued by any system root")
+		}
+		options.ClientAuth = auth
+	}
+
+	return options, problems
+}
+
+// CheckCertificateFiles reports the files of bound certKeys that are in none
+// of the given certificate directories. It reports nothing without
+// directories.
+func CheckCertificateFiles(config *LBConfig, dirs []string) Diagnostics {
+	if len(dirs) == 0 {
+		return nil
+	}
+
+	var diagnostics Diagnostics
+	for _, certKey := range config.CertKeys {
+		bound := false
+		for _, binding := range config.SSLBindings {
+			bound = bound || binding.CertKeyName == certKey.Name
+		}
+		for _, ssl := range config.SSLServiceGroups {
+			bound = bound || ssl.ClientCert == certKey.Name || slices.Contains(ssl.CAs, certKey.Name)
+		}
+		if !bound {
+			continue
+		}
+		for _, file := range []string{certKey.CertFile, certKey.KeyFile} {
+			if file == "" {
+				continue
+			}
+			if _, found := certificatePath(file, dirs); !found {
+				diagnostics = append(diagnostics, Diagnostic{
+					Position: certKey.Pos,
+					Severity: SeverityWarning,
+					Code:     "missing-certificate",
+					Message: fmt.Sprintf("certKey '%s' file %s is in none of the certificate directories (%s)",
+						certKey.Name, file, strings.Join(dirs, ", ")),
+				})
+			}
+		}
+	}
+	return diagnostics
+}
@@ -0,0 +1,262 @@
+package parser
+
+import (
+	"bytes"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// sslBase has two SSL lb vservers to bind certKeys to
+const sslBase = `add server s1 10.0.0.1
+add serviceGroup sg1 HTTP
+bind serviceGroup sg1 s1 80
+add lb vserver web_ssl SSL 192.168.1.10 443
+bind lb vserver web_ssl sg1
+add lb vserver api_ssl SSL 192.168.1.11 443
+bind lb vserver api_ssl sg1
+add ssl certKey site_ck -cert site.crt -key site.key
+add ssl certKey api_ck -cert sub/api.crt -key sub/api.key
+add ssl certKey other_ck -cert other.crt -key other.key
+add ssl certKey ca_ck -cert ca.crt
+`
+
+func TestBuildTLSCertificates(t *testing.T) {
+	tests := []struct {
+		name         string
+		bindings     string
+		opts         GenerateOptions
+		wantComments []string
+		wantDefault  string
+	}{
+		{
+			name: "sni and default certificates",
+			bindings: "bind ssl vserver web_ssl -certkeyName site_ck\n" +
+				"bind ssl vserver web_ssl -certkeyName api_ck -SNICert\n" +
+				"bind ssl vserver web_ssl -certkeyName ca_ck -CA\n",
+			wantComments: []string{"certKey site_ck on web_ssl", "certKey api_ck on web_ssl"},
+			wantDefault:  "certFile=/nsconfig/ssl/site.crt keyFile=/nsconfig/ssl/site.key",
+		},
+		{
+			name: "vserver bound twice is listed once",
+			bindings: "bind ssl vserver web_ssl -certkeyName site_ck\n" +
+				"bind ssl vserver web_ssl -certkeyName site_ck -SNICert\n" +
+				"bind ssl vserver api_ssl -certkeyName site_ck\n",
+			wantComments: []string{"certKey site_ck on web_ssl, api_ssl"},
+			wantDefault:  "certFile=/nsconfig/ssl/site.crt keyFile=/nsconfig/ssl/site.key",
+		},
+		{
+			name:         "sni only has no default certificate",
+			bindings:     "bind ssl vserver web_ssl -certkeyName api_ck -SNICert\n",
+			wantComments: []string{"certKey api_ck on web_ssl"},
+		},
+		{
+			name: "default certificate of an enabled vserver",
+			bindings: "bind ssl vserver web_ssl -certkeyName site_ck\n" +
+				"bind ssl vserver api_ssl -certkeyName other_ck\n" +
+				"disable lb vserver web_ssl\n",
+			opts:         GenerateOptions{Disabled: DisabledComment},
+			wantComments: []string{"certKey site_ck on web_ssl", "certKey other_ck on api_ssl"},
+			wantDefault:  "certFile=/nsconfig/ssl/other.crt keyFile=/nsconfig/ssl/other.key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tls := GenerateTraefikConfigWithOptions(parseCitrixText(t, "ns.conf", sslBase+tt.bindings), tt.opts).TLS
+			if tls == nil {
+				t.Fatal("no tls section")
+			}
+			var comments []string
+			for _, certificate := range tls.Certificates {
+				comments = append(comments, certificate.Comment)
+			}
+			if !slices.Equal(comments, tt.wantComments) {
+				t.Errorf("certificates = %q, want %q", comments, tt.wantComments)
+			}
+			got := ""
+			if certificate := tls.DefaultCertificate(); certificate != nil {
+				got = certificate.String()
+			}
+			if got != tt.wantDefault {
+				t.Errorf("default certificate = %q, want %q", got, tt.wantDefault)
+			}
+		})
+	}
+}
+
+func TestWriteTLSDefaultCertificate(t *testing.T) {
+	config := parseCitrixText(t, "ns.conf", sslBase+"bind ssl vserver web_ssl -certkeyName site_ck\n")
+
+	var out bytes.Buffer
+	if err := WriteTraefikConfigWithComments(&out, GenerateTraefikConfig(config)); err != nil {
+		t.Fatalf("WriteTraefikConfigWithComments: %v", err)
+	}
+	want := `tls:
+  stores:
+    default:
+      # certKey site_ck, bound without -SNICert on web_ssl
+      defaultCertificate:
+        certFile: /nsconfig/ssl/site.crt
+        keyFile: /nsconfig/ssl/site.key
+  certificates:
+`
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("output does not contain\n%s\ngot\n%s", want, out.String())
+	}
+}
+
+func TestDefaultCertificateConflict(t *testing.T) {
+	tests := []struct {
+		name     string
+		bindings string
+		want     bool
+	}{
+		{name: "one default certificate", bindings: "bind ssl vserver web_ssl -certkeyName site_ck\nbind ssl vserver api_ssl -certkeyName site_ck\n"},
+		{name: "other certificate with sni", bindings: "bind ssl vserver web_ssl -certkeyName site_ck\nbind ssl vserver api_ssl -certkeyName other_ck -SNICert\n"},
+		{name: "two default certificates", bindings: "bind ssl vserver web_ssl -certkeyName site_ck\nbind ssl vserver api_ssl -certkeyName other_ck\n", want: true},
+		{name: "disabled vserver", bindings: "bind ssl vserver web_ssl -certkeyName site_ck\nbind ssl vserver api_ssl -certkeyName other_ck\ndisable lb vserver api_ssl\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codes := diagnosticCodes(Verify(parseCitrixText(t, "ns.conf", sslBase+tt.bindings)))
+			if got := slices.Contains(codes, "default-certificate-conflict"); got != tt.want {
+				t.Errorf("default-certificate-conflict = %v, want %v (codes %v)", got, tt.want, codes)
+			}
+		})
+	}
+}
+
+func TestTLSOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		settings  string
+		options   map[string]string
+		routers   map[string]string // Router name to its TLS options, "" for Traefik's default
+		wantCodes []string
+	}{
+		{
+			name:    "no settings",
+			options: map[string]string{},
+			routers: map[string]string{"web_ssl": "", "api_ssl": ""},
+		},
+		{
+			name: "shared profile",
+			settings: `add ssl profile strict_prof -tls1 DISABLED -tls11 DISABLED -SNIHTTPHostMatch STRICT
+set ssl vserver web_ssl -sslProfile strict_prof
+set ssl vserver api_ssl -sslProfile strict_prof
+`,
+			options: map[string]string{"strict_prof": "minVersion=VersionTLS12 maxVersion=VersionTLS12 sniStrict"},
+			routers: map[string]string{"web_ssl": "strict_prof", "api_ssl": "strict_prof"},
+		},
+		{
+			name:     "own settings",
+			settings: "set ssl vserver web_ssl -tls1 DISABLED -tls13 ENABLED\n",
+			options:  map[string]string{"web_ssl": "minVersion=VersionTLS11"},
+			routers:  map[string]string{"web_ssl": "web_ssl", "api_ssl": ""},
+		},
+		{
+			name: "ciphers and cipher groups in binding order",
+			settings: `add ssl cipher modern
+bind ssl cipher modern -cipherName TLS1.2-ECDHE-RSA-AES256-GCM-SHA384
+bind ssl cipher modern -cipherName TLS1.3-AES128-GCM-SHA256
+bind ssl vserver web_ssl -cipherName TLS1.2-ECDHE-RSA-AES128-GCM-SHA256
+bind ssl vserver web_ssl -cipherName modern
+`,
+			options: map[string]string{"web_ssl": "minVersion=VersionTLS10 maxVersion=VersionTLS12 " +
+				"cipherSuites=TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"},
+			routers: map[string]string{"web_ssl": "web_ssl", "api_ssl": ""},
+		},
+		{
+			name: "unsupported cipher",
+			settings: `bind ssl vserver web_ssl -cipherName TLS1-DHE-RSA-AES-128-CBC-SHA
+bind ssl vserver web_ssl -cipherName TLS1.2-AES128-GCM-SHA256
+`,
+			options:   map[string]string{"web_ssl": "minVersion=VersionTLS10 maxVersion=VersionTLS12 cipherSuites=TLS_RSA_WITH_AES_128_GCM_SHA256"},
+			routers:   map[string]string{"web_ssl": "web_ssl", "api_ssl": ""},
+			wantCodes: []string{"unsupported-cipher"},
+		},
+		{
+			name: "mandatory client certificates",
+			settings: `set ssl vserver web_ssl -clientAuth ENABLED -clientCert Mandatory
+bind ssl vserver web_ssl -certkeyName ca_ck -CA
+`,
+			options: map[string]string{"web_ssl": "minVersion=VersionTLS10 maxVersion=VersionTLS12 " +
+				"clientAuth=RequireAndVerifyClientCert caFiles=/nsconfig/ssl/ca.crt"},
+			routers: map[string]string{"web_ssl": "web_ssl", "api_ssl": ""},
+		},
+		{
+			name:      "client authentication without a CA",
+			settings:  "set ssl vserver web_ssl -clientAuth ENABLED\n",
+			options:   map[string]string{"web_ssl": "minVersion=VersionTLS10 maxVersion=VersionTLS12 clientAuth=VerifyClientCertIfGiven"},
+			routers:   map[string]string{"web_ssl": "web_ssl", "api_ssl": ""},
+			wantCodes: []string{"untranslated-ssl-setting"},
+		},
+		{
+			name:      "version gap",
+			settings:  "set ssl vserver web_ssl -tls11 DISABLED\n",
+			options:   map[string]string{"web_ssl": "minVersion=VersionTLS10 maxVersion=VersionTLS12"},
+			routers:   map[string]string{"web_ssl": "web_ssl", "api_ssl": ""},
+			wantCodes: []string{"untranslated-ssl-setting"},
+		},
+		{
+			name: "vserver option makes way for a profile of the same name",
+			settings: `add ssl profile web_ssl -SNIHTTPHostMatch STRICT
+set ssl vserver api_ssl -sslProfile web_ssl
+set ssl vserver web_ssl -tls1 DISABLED
+`,
+			options: map[string]string{
+				"web_ssl":   "minVersion=VersionTLS10 maxVersion=VersionTLS12 sniStrict",
+				"web_ssl-2": "minVersion=VersionTLS11 maxVersion=VersionTLS12",
+			},
+			routers: map[string]string{"web_ssl": "web_ssl-2", "api_ssl": "web_ssl"},
+		},
+		{
+			name:      "undefined profile",
+			settings:  "set ssl vserver web_ssl -sslProfile missing_prof\n",
+			options:   map[string]string{},
+			routers:   map[string]string{"web_ssl": "", "api_ssl": ""},
+			wantCodes: []string{"undefined-ssl-profile"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := parseCitrixText(t, "ns.conf", sslBase+tt.settings)
+			generated := GenerateTraefikConfig(config)
+
+			got := make(map[string]string)
+			if generated.TLS != nil {
+				for name, options := range generated.TLS.Options {
+					got[name] = options.String()
+				}
+			}
+			if !maps.Equal(got, tt.options) {
+				t.Errorf("options = %v, want %v", got, tt.options)
+			}
+
+			for name, want := range tt.routers {
+				router, exists := generated.HTTP.Routers[name]
+				if !exists || router.TLS == nil {
+					t.Errorf("router %s missing or without tls", name)
+					continue
+				}
+				if router.TLS.Options != want {
+					t.Errorf("router %s tls options = %q, want %q", name, router.TLS.Options, want)
+				}
+			}
+
+			var codes []string
+			for _, code := range diagnosticCodes(Verify(config)) {
+				if strings.Contains(code, "ssl") || strings.Contains(code, "cipher") {
+					codes = append(codes, code)
+				}
+			}
+			if !slices.Equal(codes, tt.wantCodes) {
+				t.Errorf("diagnostics = %v, want %v", codes, tt.wantCodes)
+			}
+		})
+	}
+}
@@ -113,7 +113,7 @@ func NewTokenizer(input string) *Tokenizer {
 		input:  input,
 		pos:    0,
 		line:   1,
-		column: 1,
+		column: 0,
 	}
 	t.readChar()
 	return t
@@ -129,7 +129,7 @@ func (t *Tokenizer) readChar() {
 	t.pos++
 	if t.current == '\n' {
 		t.line++
-		t.column = 1
+		t.column = 0
 	} else {
 		t.column++
 	}
@@ -142,57 +142,70 @@ func (t *Tokenizer) skipWhitespace() {
 	}
 }
 
+// stringEscapes maps escape letters in quoted strings to the control characters they stand for
+var stringEscapes = map[rune]byte{'r': '\r', 'n': '\n', 't': '\t'}
+
 // readString reads a quoted string
 func (t *Tokenizer) readString() string {
 	var result strings.Builder
 	quote := t.current
 	t.readChar() // skip opening quote
 
+	// Unescaped runs are copied as slices of the input. \r, \n and \t (as in
+	// monitor -customHeaders) become control characters; any other escaped
+	// character is kept as is.
+	start := t.pos - 1
 	for t.current != quote && t.current != 0 {
 		if t.current == '\\' {
+			result.WriteString(t.input[start : t.pos-1])
 			t.readChar()
-			if t.current != 0 {
-				result.WriteRune(t.current)
+			start = t.pos - 1
+			if control, exists := stringEscapes[t.current]; exists {
+				result.WriteByte(control)
+				t.readChar()
+				start = t.pos - 1
+			} else if t.current != 0 {
 				t.readChar()
 			}
 		} else {
-			result.WriteRune(t.current)
 			t.readChar()
 		}
 	}
+	value := t.input[start:min(t.pos-1, len(t.input))]
+	if result.Len() > 0 {
+		result.WriteString(value)
+		value = result.String()
+	}
 
 	if t.current == quote {
 		t.readChar() // skip closing quote
 	}
 
-	return result.String()
+	return value
 }
 
 // readIdentifier reads an identifier or keyword
 func (t *Tokenizer) readIdentifier() string {
-	var result strings.Builder
+	start := t.pos - 1
 
 	for unicode.IsLetter(t.current) || unicode.IsDigit(t.current) ||
-		t.current == '_' || t.current == '-' || t.current == ':' || t.current == '.' {
-		result.WriteRune(t.current)
+		t.current == '_' || t.current == '-' || t.current == ':' || t.current == '.' || t.current == '/' {
 		t.readChar()
 	}
 
-	return result.String()
+	return t.input[start : t.pos-1]
 }
 
 // readParameter reads a parameter starting with -
 func (t *Tokenizer) readParameter() string {
-	var result strings.Builder
-	result.WriteRune(t.current) // include the -
+	start := t.pos - 1 // include the -
 	t.readChar()
 
 	for unicode.IsLetter(t.current) || unicode.IsDigit(t.current) || t.current == '_' {
-		result.WriteRune(t.current)
 		t.readChar()
 	}
 
-	return result.String()
+	return t.input[start : t.pos-1]
 }
 
 // isIPAddress checks if a string looks like an IP address
@@ -393,7 +406,8 @@ func (t *Tokenizer) NextToken() Token {
 		token.Value = "."
 		t.readChar()
 	default:
-		if unicode.IsLetter(t.current) || unicode.IsDigit(t.current) || t.current == '_' {
+		// A leading slash starts an unquoted file path, such as a certKey file
+		if unicode.IsLetter(t.current) || unicode.IsDigit(t.current) || t.current == '_' || t.current == '/' {
 			value := t.readIdentifier()
 			token.Type = t.getKeywordType(value)
 			token.Value = value
@@ -410,7 +424,7 @@ func (t *Tokenizer) NextToken() Token {
 // TokenizeCommand tokenizes a complete command line
 func TokenizeCommand(command string) []Token {
 	tokenizer := NewTokenizer(command)
-	var tokens []Token
+	tokens := make([]Token, 0, 16)
 
 	for {
 		token := tokenizer.NextToken()
@@ -0,0 +1,195 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// backendSchemes maps service group protocols to the scheme of their server
+// URLs. Servers of other protocols are reached over plain HTTP.
+var backendSchemes = map[string]string{
+	"SSL":     "https",
+	"SSL_TCP": "https",
+	"HTTP2":   "h2c",
+}
+
+// backendScheme returns the scheme of the server URLs of a service group
+func backendScheme(config *LBConfig, group string) string {
+	if def := config.ServiceGroupDefByName(group); def != nil {
+		if scheme, exists := backendSchemes[strings.ToUpper(def.Protocol)]; exists {
+			return scheme
+		}
+	}
+	return "http"
+}
+
+// serviceTransport returns the name of the servers transport of a service
+// built from the given service groups: that of the first group whose servers
+// are reached over TLS, or "" when there is none
+func serviceTransport(config *LBConfig, serviceNames []string) string {
+	for _, name := range serviceNames {
+		if backendScheme(config, name) == "https" {
+			return name
+		}
+	}
+	return ""
+}
+
+// ServersTransport is the Traefik servers transport the SSL settings of a
+// service group with an SSL protocol become. It is named after the group.
+type ServersTransport struct {
+	Name      string
+	Service   bool // Whether the group is a standalone Citrix service
+	Transport TraefikServersTransport
+	Problems  []TLSProblem // Settings the transport cannot express
+	Pos       Position
+	Source    string
+}
+
+// Kind describes where the transport comes from, e.g. "ssl serviceGroup sg1"
+func (t ServersTransport) Kind() string {
+	if t.Service {
+		return "ssl service " + t.Name
+	}
+	return "ssl serviceGroup " + t.Name
+}
+
+// ServersTransports returns the servers transports of the service groups
+// whose servers are reached over TLS, in service group order. CertKey files
+// are resolved in certDirs.
+func ServersTransports(config *LBConfig, certDirs []string) []ServersTransport {
+	var transports []ServersTransport
+	for _, name := range config.ServiceGroupNames() {
+		if backendScheme(config, name) == "https" {
+			transports = append(transports, resolveServersTransport(config, name, certDirs))
+		}
+	}
+	return transports
+}
+
+// resolveServersTransport translates the SSL settings of a service group.
+// The appliance accepts any server certificate unless -serverAuth is
+// enabled, so without it the transport skips verification. With it, the CA
+// certKeys become rootCAs and -commonName becomes serverName, which Traefik
+// also sends with SNI. A certKey bound without -CA becomes the client
+// certificate. Settings Traefik cannot express are returned as problems.
+func resolveServersTransport(config *LBConfig, group string, certDirs []string) ServersTransport {
+	transport := ServersTransport{Name: group}
+	if def := config.ServiceGroupDefByName(group); def != nil {
+		transport.Service = def.Metadata["citrix.type"] == "service"
+		transport.Pos, transport.Source = def.Pos, def.Source
+	}
+	var settings SSLServiceGroup
+	if ssl := config.SSLServiceGroupByName(group); ssl != nil {
+		settings = *ssl
+		transport.Pos, transport.Source = ssl.Pos, ssl.Source
+	}
+	problem := func(format string, args ...interface{}) {
+		transport.Problems = append(transport.Problems, TLSProblem{
+			Code:    "untranslated-backend-ssl",
+			Message: fmt.Sprintf(format, args...),
+			Pos:     transport.Pos,
+			Source:  transport.Source,
+		})
+	}
+
+	result := &transport.Transport
+	if settings.SNI {
+		result.ServerName = settings.ServerName
+	}
+	if !settings.ServerAuth {
+		result.InsecureSkipVerify = true
+	} else {
+		switch {
+		case settings.CommonName != "" && result.ServerName != "" && !strings.EqualFold(result.ServerName, settings.CommonName):
+			problem("Traefik sends the name it verifies; -serverName %s is replaced by -commonName %s", result.ServerName, settings.CommonName)
+			result.ServerName = settings.CommonName
+		case settings.CommonName != "":
+			result.ServerName = settings.CommonName
+		case result.ServerName != "":
+			problem("server certificates are verified against -serverName %s; the appliance only checks who issued them", result.ServerName)
+		default:
+			problem("server certificates are verified against the server addresses; the appliance only checks who issued them")
+		}
+		for _, name := range settings.CAs {
+			if certKey := config.CertKeyByName(name); certKey != nil {
+				file, _ := certificatePath(certKey.CertFile, certDirs)
+				if !slices.Contains(result.RootCAs, file) {
+					result.RootCAs = append(result.RootCAs, file)
+				}
+			}
+		}
+		if len(result.RootCAs) == 0 {
+			problem("server authentication has no CA certKey bound; Traefik trusts server certificates issued by any system root")
+		}
+	}
+	if certKey := config.CertKeyByName(settings.ClientCert); certKey != nil && certKey.KeyFile != "" {
+		certFile, _ := certificatePath(certKey.CertFile, certDirs)
+		keyFile, _ := certificatePath(certKey.KeyFile, certDirs)
+		result.Certificates = append(result.Certificates, TraefikCertificate{CertFile: certFile, KeyFile: keyFile})
+	}
+
+	return transport
+}
+
+// buildServersTransports creates the servers transports that the given
+// services reference
+func buildServersTransports(config *LBConfig, services map[string]TraefikService, opts GenerateOptions) map[string]TraefikServersTransport {
+	referenced := make(map[string]bool)
+	for _, service := range services {
+		if name := service.LoadBalancer.ServersTransport; name != "" {
+			referenced[name] = true
+		}
+	}
+	if len(referenced) == 0 {
+		return nil
+	}
+
+	transports := make(map[string]TraefikServersTransport)
+	for _, transport := range ServersTransports(config, opts.CertDirs) {
+		if !referenced[transport.Name] {
+			continue
+		}
+		result := transport.Transport
+		result.Comment = transport.Kind()
+		result.Origin = formatOrigin(transport.Pos, transport.Source)
+		transports[transport.Name] = result
+	}
+	return transports
+}
+
+// transportGaps lists the SSL settings of service groups that their servers
+// transports cannot express, attached to the service groups
+func transportGaps(config *LBConfig) []*UntranslatedObject {
+	var gaps []*UntranslatedObject
+	for _, transport := range ServersTransports(config, nil) {
+		for _, problem := range transport.Problems {
+			objectType := "ssl serviceGroup"
+			if transport.Service {
+				objectType = "ssl service"
+			}
+			gaps = append(gaps, &UntranslatedObject{
+				ObjectType:   objectType,
+				Name:         transport.Name,
+				ServiceGroup: transport.Name,
+				Reason:       fmt.Sprintf("servers transport '%s' loses a setting: %s", transport.Name, problem.Message),
+				Text:         problem.Source,
+				Pos:          problem.Pos,
+			})
+		}
+	}
+	return gaps
+}
+
+// sslServiceGroupSetters returns the setters of "set ssl serviceGroup" and
+// "set ssl service". Unset passes an empty value, which restores the
+// appliance default.
+func sslServiceGroupSetters(settings *SSLServiceGroup) map[string]func(value string) {
+	return map[string]func(string){
+		"-serverAuth": func(value string) { settings.ServerAuth = strings.EqualFold(value, "ENABLED") },
+		"-commonName": func(value string) { settings.CommonName = value },
+		"-SNIEnable":  func(value string) { settings.SNI = strings.EqualFold(value, "ENABLED") },
+		"-serverName": func(value string) { settings.ServerName = value },
+	}
+}
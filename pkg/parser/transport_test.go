@@ -0,0 +1,252 @@
+package parser
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestServersTransports(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     string
+		servers    []string
+		transport  string            // serversTransport of the vs1 service
+		transports map[string]string // Generated transports
+		wantCodes  []string
+	}{
+		{
+			name: "plain http",
+			config: `add serviceGroup sg1 HTTP
+bind serviceGroup sg1 s1 80
+bind lb vserver vs1 sg1
+`,
+			servers:    []string{"http://10.0.0.1:80"},
+			transports: map[string]string{},
+		},
+		{
+			name: "http2 servers",
+			config: `add serviceGroup sg1 HTTP2
+bind serviceGroup sg1 s1 8080
+bind lb vserver vs1 sg1
+`,
+			servers:    []string{"h2c://10.0.0.1:8080"},
+			transports: map[string]string{},
+		},
+		{
+			name: "ssl without server authentication",
+			config: `add serviceGroup sg1 SSL
+bind serviceGroup sg1 s1 443
+bind lb vserver vs1 sg1
+`,
+			servers:    []string{"https://10.0.0.1:443"},
+			transport:  "sg1",
+			transports: map[string]string{"sg1": "insecureSkipVerify"},
+		},
+		{
+			name: "server authentication with a CA and client certificate",
+			config: `add serviceGroup sg1 SSL
+bind serviceGroup sg1 s1 8443
+bind lb vserver vs1 sg1
+set ssl serviceGroup sg1 -serverAuth ENABLED -commonName api.internal
+bind ssl serviceGroup sg1 -certkeyName ca_ck -CA
+bind ssl serviceGroup sg1 -certkeyName client_ck
+`,
+			servers:    []string{"https://10.0.0.1:8443"},
+			transport:  "sg1",
+			transports: map[string]string{"sg1": "serverName=api.internal rootCAs=/nsconfig/ssl/ca.crt certificate=/nsconfig/ssl/client.crt"},
+		},
+		{
+			name: "sni name differs from the common name",
+			config: `add serviceGroup sg1 SSL
+bind serviceGroup sg1 s1 443
+bind lb vserver vs1 sg1
+set ssl serviceGroup sg1 -serverAuth ENABLED -commonName api.internal -SNIEnable ENABLED -serverName api.example.com
+bind ssl serviceGroup sg1 -certkeyName ca_ck -CA
+`,
+			servers:    []string{"https://10.0.0.1:443"},
+			transport:  "sg1",
+			transports: map[string]string{"sg1": "serverName=api.internal rootCAs=/nsconfig/ssl/ca.crt"},
+			wantCodes:  []string{"untranslated-backend-ssl"},
+		},
+		{
+			name: "server authentication without a CA",
+			config: `add serviceGroup sg1 SSL
+bind serviceGroup sg1 s1 443
+bind lb vserver vs1 sg1
+set ssl serviceGroup sg1 -serverAuth ENABLED -commonName api.internal
+`,
+			servers:    []string{"https://10.0.0.1:443"},
+			transport:  "sg1",
+			transports: map[string]string{"sg1": "serverName=api.internal"},
+			wantCodes:  []string{"untranslated-backend-ssl"},
+		},
+		{
+			name: "standalone ssl service",
+			config: `add service svc1 s1 SSL 443
+bind lb vserver vs1 svc1
+set ssl service svc1 -serverAuth ENABLED -commonName api.internal
+bind ssl service svc1 -certkeyName ca_ck -CA
+`,
+			servers:    []string{"https://10.0.0.1:443"},
+			transport:  "svc1",
+			transports: map[string]string{"svc1": "serverName=api.internal rootCAs=/nsconfig/ssl/ca.crt"},
+		},
+		{
+			name: "groups with different ssl settings",
+			config: `add serviceGroup sg1 SSL
+add serviceGroup sg2 SSL
+bind serviceGroup sg1 s1 443
+bind serviceGroup sg2 s2 443
+bind lb vserver vs1 sg1
+bind lb vserver vs1 sg2
+set ssl serviceGroup sg2 -serverAuth ENABLED -commonName api.internal
+bind ssl serviceGroup sg2 -certkeyName ca_ck -CA
+`,
+			servers:    []string{"https://10.0.0.1:443", "https://10.0.0.2:443"},
+			transport:  "sg1",
+			transports: map[string]string{"sg1": "insecureSkipVerify"},
+			wantCodes:  []string{"servers-transport-conflict"},
+		},
+		{
+			name: "ssl settings on a plain http group",
+			config: `add serviceGroup sg1 HTTP
+bind serviceGroup sg1 s1 80
+bind lb vserver vs1 sg1
+set ssl serviceGroup sg1 -serverAuth ENABLED
+`,
+			servers:    []string{"http://10.0.0.1:80"},
+			transports: map[string]string{},
+			wantCodes:  []string{"unused-ssl-settings"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := parseCitrixText(t, "ns.conf", `add server s1 10.0.0.1
+add server s2 10.0.0.2
+add ssl certKey ca_ck -cert ca.crt
+add ssl certKey client_ck -cert client.crt -key client.key
+add lb vserver vs1 HTTP 10.9.0.1 80
+`+tt.config)
+			generated := GenerateTraefikConfig(config)
+
+			service := generated.HTTP.Services["vs1"]
+			if got := serverURLs(service); !slices.Equal(got, tt.servers) {
+				t.Errorf("servers = %v, want %v", got, tt.servers)
+			}
+			if got := service.LoadBalancer.ServersTransport; got != tt.transport {
+				t.Errorf("serversTransport = %q, want %q", got, tt.transport)
+			}
+
+			got := make(map[string]string)
+			for name, transport := range generated.HTTP.ServersTransports {
+				got[name] = transport.String()
+			}
+			if !maps.Equal(got, tt.transports) {
+				t.Errorf("transports = %v, want %v", got, tt.transports)
+			}
+
+			var codes []string
+			for _, code := range diagnosticCodes(Verify(config)) {
+				if strings.Contains(code, "ssl") || strings.Contains(code, "transport") {
+					codes = append(codes, code)
+				}
+			}
+			if !slices.Equal(codes, tt.wantCodes) {
+				t.Errorf("diagnostics = %v, want %v", codes, tt.wantCodes)
+			}
+		})
+	}
+}
+
+func TestSSLGroupMonitorScheme(t *testing.T) {
+	config := parseCitrixText(t, "ns.conf", `add server s1 10.0.0.1
+add serviceGroup sg1 SSL
+bind serviceGroup sg1 s1 443
+bind serviceGroup sg1 -monitorName http
+add serviceGroup sg2 SSL
+bind serviceGroup sg2 s1 8443
+bind serviceGroup sg2 -monitorName https
+`)
+	services := GenerateTraefikConfig(config).HTTP.Services
+	if got := services["sg1"].LoadBalancer.HealthCheck.Scheme; got != "http" {
+		t.Errorf("http monitor scheme = %q, want http", got)
+	}
+	if got := services["sg2"].LoadBalancer.HealthCheck.Scheme; got != "https" {
+		t.Errorf("https monitor scheme = %q, want https", got)
+	}
+}
+
+func TestF5ServerSSLTransport(t *testing.T) {
+	config, err := ParseF5(strings.NewReader(`ltm profile server-ssl /Common/base_ssl {
+    defaults-from /Common/serverssl
+    peer-cert-mode require
+    ca-file /Common/ca.crt
+}
+ltm profile server-ssl /Common/api_ssl {
+    defaults-from /Common/base_ssl
+    authenticate-name api.internal
+}
+ltm pool /Common/api_pool {
+    members {
+        /Common/10.0.0.1:8443 {
+            address 10.0.0.1
+        }
+    }
+}
+ltm pool /Common/web_pool {
+    members {
+        /Common/10.0.0.2:443 {
+            address 10.0.0.2
+        }
+    }
+}
+ltm virtual /Common/api_vs {
+    destination /Common/10.9.0.1:443
+    pool /Common/api_pool
+    profiles {
+        /Common/api_ssl {
+            context serverside
+        }
+        /Common/http { }
+    }
+}
+ltm virtual /Common/web_vs {
+    destination /Common/10.9.0.2:443
+    pool /Common/web_pool
+    profiles {
+        /Common/serverssl {
+            context serverside
+        }
+    }
+}
+`), ParseOptions{Filename: "bigip.conf"})
+	if err != nil {
+		t.Fatalf("ParseF5: %v", err)
+	}
+
+	generated := GenerateTraefikConfig(config)
+	for name, want := range map[string]string{"api_vs": "https://10.0.0.1:8443", "web_vs": "https://10.0.0.2:443"} {
+		service := generated.HTTP.Services[name]
+		if got := serverURLs(service); !slices.Equal(got, []string{want}) {
+			t.Errorf("service %s servers = %v, want %s", name, got, want)
+		}
+		if service.LoadBalancer.ServersTransport != name {
+			t.Errorf("service %s serversTransport = %q", name, service.LoadBalancer.ServersTransport)
+		}
+	}
+
+	got := make(map[string]string)
+	for name, transport := range generated.HTTP.ServersTransports {
+		got[name] = transport.String()
+	}
+	want := map[string]string{
+		"api_vs": "serverName=api.internal rootCAs=/Common/ca.crt",
+		"web_vs": "insecureSkipVerify",
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("transports = %v, want %v", got, want)
+	}
+}
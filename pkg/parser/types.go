@@ -1,18 +1,51 @@
 package parser
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
 // ServerInfo represents a server with its IP address
 type ServerInfo struct {
-	Name    string
-	IP      string
-	Comment string
+	Name     string
+	IP       string
+	Comment  string
+	Disabled bool              // Administratively disabled (-state DISABLED or "disable server")
+	Metadata map[string]string // Vendor-specific metadata (e.g. F5 full path)
+	Pos      Position          // Where the object was defined
+	Source   string            // Original command line or F5 object path
 }
 
 // VServerInfo represents a virtual server configuration
 type VServerInfo struct {
-	Name     string
-	Protocol string
-	IP       string
-	Port     string
+	Name        string
+	Protocol    string
+	IP          string
+	Port        string
+	LBMethod    string            // Citrix -lbMethod or F5 pool load-balancing-mode, the vendor default when not set
+	LBDefault   bool              // LBMethod is the vendor default because the configuration does not set one
+	Backup      Backup            // Virtual server that takes over when this one is down
+	Disabled    bool              // Administratively disabled, gets no mapping
+	Persistence Persistence       // Session persistence (-persistenceType and its settings)
+	Metadata    map[string]string // Vendor-specific metadata (e.g. F5 full path)
+	Pos         Position          // Where the object was defined
+	Source      string            // Original command line or F5 object path
+
+	Bindings []*VServerBinding // Resolved by LBConfig.Link
+}
+
+// Backup is the backup virtual server of a virtual server
+type Backup struct {
+	VServer            string // -backupVServer, empty when there is none
+	PersistenceTimeout string // -backupPersistenceTimeout in minutes, how long clients stay on the backup
+}
+
+// Persistence is the session persistence of a virtual server
+type Persistence struct {
+	Type       string // -persistenceType: COOKIEINSERT, SOURCEIP, SSLSESSION, RULE, ...; empty or NONE when off
+	Timeout    string // -timeout in minutes, the Citrix default of 2 when empty
+	CookieName string // -cookieName of COOKIEINSERT persistence
 }
 
 // ServiceGroup represents a service group binding
@@ -20,14 +53,29 @@ type ServiceGroup struct {
 	Name       string
 	ServerName string
 	Port       string
+	Weight     int  // Load balancing weight (1-100), 0 when not set
+	Priority   int  // F5 priority-group, higher groups take traffic first; 0 when not set
+	Disabled   bool // This member is administratively disabled, see LBConfig.MemberDisabled
 	Comment    string
+	Pos        Position // Where the member was bound
+	Source     string   // Original command line or F5 object path
+
+	Server *ServerInfo      // Resolved by LBConfig.Link, nil if the server is undefined
+	Group  *ServiceGroupDef // Resolved by LBConfig.Link, nil if the group has no add command
 }
 
 // ServiceGroupDef represents a service group definition from add command
 type ServiceGroupDef struct {
-	Name     string
-	Protocol string
-	Comment  string
+	Name      string
+	Protocol  string
+	Comment   string
+	Disabled  bool              // Every member is administratively disabled
+	MinActive int               // F5 min-active-members, member priorities are used when above 0
+	Metadata  map[string]string // Vendor-specific metadata (e.g. F5 pool path)
+	Pos       Position          // Where the group was defined
+	Source    string            // Original command line or F5 object path
+
+	Members []*ServiceGroup // Resolved by LBConfig.Link
 }
 
 // VServerBinding represents a bind lb vserver command that binds a service to a vserver
@@ -38,41 +86,708 @@ type VServerBinding struct {
 	Priority       string
 	GotoExpression string
 	Type           string
+	Weight         int // Weight of a bound service (1-100), 0 when not set
 	Comment        string
+	Pos            Position // Where the binding was made
+	Source         string   // Original command line or F5 object path
+
+	VServer *VServerInfo     // Resolved by LBConfig.Link
+	Group   *ServiceGroupDef // Resolved by LBConfig.Link, nil for policy-only bindings
+}
+
+// Monitor represents a load balancing health monitor (Citrix "add lb monitor").
+// Settings are kept as written; see translateMonitor for the Traefik form.
+type Monitor struct {
+	Name          string
+	Type          string // HTTP, HTTP-ECV, HTTPS, TCP, PING, ...
+	HTTPRequest   string // -httpRequest of HTTP monitors, e.g. "HEAD /"
+	Send          string // -send of ECV monitors, e.g. "GET /health"
+	Recv          string // -recv of ECV monitors, the expected response content
+	RespCode      string // -respCode, e.g. "200" or "200-299"
+	Interval      string // -interval, in IntervalUnits
+	IntervalUnits string // -units3: SEC (default), MSEC or MIN
+	RespTimeout   string // -resptimeout, in TimeoutUnits
+	TimeoutUnits  string // -units4: SEC (default), MSEC or MIN
+	DestPort      string // -destPort, the member port when empty
+	CustomHeaders string // -customHeaders, CRLF separated "Name: value" lines
+	Secure        bool   // -secure YES, probe over TLS
+	Pos           Position
+	Source        string
+}
+
+// MonitorBinding binds a monitor to a service group or standalone service
+type MonitorBinding struct {
+	ServiceName string
+	MonitorName string
+	Pos         Position
+	Source      string
+}
+
+// CSVServer represents a content switching virtual server ("add cs vserver"),
+// which picks an lb vserver for each request with its bound policies
+type CSVServer struct {
+	Name     string
+	Protocol string
+	IP       string
+	Port     string
+	Disabled bool // Administratively disabled, gets no routers
+	Pos      Position
+	Source   string
+
+	Bindings []*CSBinding // Resolved by LBConfig.Link
+}
+
+// CSAction represents a content switching action ("add cs action")
+type CSAction struct {
+	Name            string
+	TargetLBVServer string // -targetLBVserver
+	TargetExpr      string // -targetVserverExpr, picks the lb vserver at run time and is not translated
+	Pos             Position
+	Source          string
+}
+
+// CSPolicy represents a content switching policy ("add cs policy")
+type CSPolicy struct {
+	Name   string
+	Rule   string // -rule, an AppExpert expression
+	URL    string // -url of classic policies, e.g. "/api/*"
+	Domain string // -domain of classic policies
+	Action string // -action, empty for policies bound with -targetLBVserver
+	Pos    Position
+	Source string
+}
+
+// CSBinding binds a content switching policy to a content switching virtual
+// server. A binding without a policy is the default lb vserver (-lbvserver),
+// which takes the requests no policy matches. Responder and rewrite policies
+// are bound the same way, with a Type.
+type CSBinding struct {
+	VServerName     string
+	PolicyName      string
+	Priority        string // Lower priorities are evaluated first
+	TargetLBVServer string // -targetLBVserver of the binding, or the default lb vserver
+	Type            string // -type of a responder or rewrite policy binding (REQUEST, RESPONSE), empty for content switching policies
+	GotoExpression  string // -gotoPriorityExpression of a responder or rewrite policy binding
+	Pos             Position
+	Source          string
+}
+
+// ResponderAction represents a Citrix "add responder action": what a
+// responder policy does with the requests its rule matches
+type ResponderAction struct {
+	Name       string
+	Type       string // redirect, respondwith, respondwithhtmlpage, ...
+	Target     string // AppExpert expression of the redirect URL or response, or the HTML page name
+	StatusCode string // -responseStatusCode
+	Pos        Position
+	Source     string
+}
+
+// ResponderPolicy represents a Citrix "add responder policy"
+type ResponderPolicy struct {
+	Name   string
+	Rule   string // AppExpert expression selecting the requests
+	Action string // Responder action name, or the built-in DROP, RESET or NOOP
+	Pos    Position
+	Source string
+}
+
+// RewriteAction represents a Citrix "add rewrite action": how a rewrite
+// policy changes the requests or responses its rule matches
+type RewriteAction struct {
+	Name   string
+	Type   string // insert_http_header, delete_http_header, replace, replace_all, insert_before, ...
+	Target string // Header name, or the expression of the part that is rewritten
+	Value  string // AppExpert expression of the inserted or replacing text
+	Search string // -search of replace_all actions, e.g. text("/old") or regex(re~^/old~)
+	Pos    Position
+	Source string
+}
+
+// RewritePolicy represents a Citrix "add rewrite policy"
+type RewritePolicy struct {
+	Name   string
+	Rule   string // AppExpert expression selecting the requests or responses
+	Action string // Rewrite action name, or the built-in NOREWRITE, RESET or DROP
+	Pos    Position
+	Source string
+}
+
+// CertKey represents a Citrix "add ssl certKey": a certificate and its
+// private key, stored on the appliance
+type CertKey struct {
+	Name     string
+	CertFile string // -cert, relative to /nsconfig/ssl unless absolute
+	KeyFile  string // -key, empty for certificate authorities
+	Pos      Position
+	Source   string
+}
+
+// SSLBinding represents a Citrix "bind ssl vserver <vs> -certkeyName <ck>"
+type SSLBinding struct {
+	VServerName string // lb or cs vserver
+	CertKeyName string
+	SNI         bool // -SNICert: served to clients that ask for one of its host names
+	CA          bool // -CA: trusted to issue client certificates
+	Pos         Position
+	Source      string
+}
+
+// SSLSettings are the protocol and client authentication settings of an ssl
+// vserver or ssl profile, with the ciphers bound to it. Only the protocols
+// that were set are recorded; the others keep the appliance default.
+type SSLSettings struct {
+	Protocols    map[string]bool // -ssl3, -tls1, -tls11, -tls12, -tls13 keyed without the dash
+	SNIHostMatch string          // -SNIHTTPHostMatch: STRICT, LOOSE or NONE
+	ClientAuth   bool            // -clientAuth ENABLED
+	ClientCert   string          // -clientCert: Mandatory or Optional
+	Ciphers      []SSLCipher     // Bound with -cipherName, in binding order
+}
+
+// SSLCipher is a cipher or cipher group bound with -cipherName
+type SSLCipher struct {
+	Name   string
+	Pos    Position
+	Source string
+}
+
+// SSLVServer holds the "set ssl vserver" settings and cipher bindings of an
+// lb or cs vserver
+type SSLVServer struct {
+	Name    string
+	Profile string // -sslProfile, whose settings replace those of the vserver
+	SSLSettings
+	Pos    Position // First command that set or bound something
+	Source string
+}
+
+// SSLProfile represents a Citrix "add ssl profile"
+type SSLProfile struct {
+	Name string
+	SSLSettings
+	Pos    Position
+	Source string
+}
+
+// CipherGroup represents a Citrix "add ssl cipher" user-defined cipher group
+type CipherGroup struct {
+	Name    string
+	Ciphers []SSLCipher // Bound with "bind ssl cipher <group> -cipherName", in binding order
+	Pos     Position
+	Source  string
+}
+
+// SSLServiceGroup holds the "set ssl serviceGroup" settings and certificate
+// bindings of a service group or service whose servers are reached over TLS
+type SSLServiceGroup struct {
+	Name       string
+	ServerAuth bool     // -serverAuth ENABLED: server certificates are verified
+	CommonName string   // -commonName the server certificates must be issued to
+	SNI        bool     // -SNIEnable ENABLED: ServerName is sent to the servers
+	ServerName string   // -serverName
+	CAs        []string // CertKeys bound with -CA, trusted to issue server certificates
+	ClientCert string   // CertKey bound without -CA, presented to the servers
+	Pos        Position // First command that set or bound something
+	Source     string
+}
+
+// UntranslatedObject records a command or vendor object that was seen in the
+// input but is not translated into the generated configuration
+type UntranslatedObject struct {
+	Action       string // Citrix verb (add, bind, set, ...), empty for F5 objects
+	ObjectType   string // e.g. "responder policy", "ltm monitor http"
+	Name         string
+	VServer      string // Set when the object is attached directly to a virtual server
+	CSVServer    string // Set when the object is attached directly to a content switching virtual server
+	ServiceGroup string // Set when the object is attached to a service group
+	Reason       string // Why the object matters, shown for affected virtual servers
+	Text         string // Original command line or F5 block header
+	Pos          Position
 }
 
 // TraefikService represents a Traefik service configuration
 type TraefikService struct {
-	LoadBalancer TraefikLoadBalancer `yaml:"loadBalancer"`
-	Comment      string              `yaml:"-"` // Service-level comment (not serialized)
+	LoadBalancer TraefikLoadBalancer `yaml:"loadBalancer,omitempty"`
+	Weighted     *TraefikWeighted    `yaml:"weighted,omitempty"` // Weighted round robin over other services, instead of LoadBalancer
+	Failover     *TraefikFailover    `yaml:"failover,omitempty"` // Primary service with a fallback, instead of LoadBalancer
+	Comment      string              `yaml:"-"`                  // Service-level comment (not serialized)
+	Origin       string              `yaml:"-"`                  // Source file, line and command the service came from
+}
+
+// Backends describes what the service balances across: each server URL, or
+// each child service of a weighted service, followed by its weight if set,
+// or the primary and fallback services of a failover service.
+// Disabled servers are left out, as they are commented out when written.
+func (s TraefikService) Backends() []string {
+	var backends []string
+	if s.Failover != nil {
+		backends = append(backends, "service "+s.Failover.Service, "fallback "+s.Failover.Fallback)
+	}
+	if s.Weighted != nil {
+		for _, child := range s.Weighted.Services {
+			backends = append(backends, fmt.Sprintf("service %s (weight %d)", child.Name, child.Weight))
+		}
+	}
+	for _, server := range s.LoadBalancer.Servers {
+		if server.Disabled {
+			continue
+		}
+		if server.Weight > 0 {
+			backends = append(backends, fmt.Sprintf("%s (weight %d)", server.URL, server.Weight))
+		} else {
+			backends = append(backends, server.URL)
+		}
+	}
+	return backends
+}
+
+// StickySessions returns the sticky sessions of the service: those of the
+// weighted service, or of the load balancer
+func (s TraefikService) StickySessions() *TraefikSticky {
+	if s.Weighted != nil {
+		return s.Weighted.Sticky
+	}
+	return s.LoadBalancer.Sticky
+}
+
+// TraefikWeighted represents a weighted round robin service
+type TraefikWeighted struct {
+	Services    []TraefikWeightedService `yaml:"services"`
+	Sticky      *TraefikSticky           `yaml:"sticky,omitempty"`      // Keeps a client on the same child service
+	HealthCheck *TraefikHealthStatus     `yaml:"healthCheck,omitempty"` // Skips child services that are down
+}
+
+// TraefikFailover represents a failover service: requests go to the primary
+// service until its health check fails, then to the fallback
+type TraefikFailover struct {
+	Service     string               `yaml:"service"`
+	Fallback    string               `yaml:"fallback"`
+	HealthCheck *TraefikHealthStatus `yaml:"healthCheck,omitempty"` // Reports the failover as down to a parent failover
+}
+
+// TraefikHealthStatus is the empty healthCheck of a weighted or failover
+// service, which makes it report the health of the services it contains
+type TraefikHealthStatus struct{}
+
+// TraefikWeightedService is a child service of a weighted service
+type TraefikWeightedService struct {
+	Name   string `yaml:"name"`
+	Weight int    `yaml:"weight"`
 }
 
 // TraefikLoadBalancer represents the load balancer configuration
 type TraefikLoadBalancer struct {
-	Servers []TraefikServer `yaml:"servers"`
+	Servers          []TraefikServer     `yaml:"servers"`
+	Strategy         string              `yaml:"strategy,omitempty"` // wrr (the default), p2c or hrw
+	HealthCheck      *TraefikHealthCheck `yaml:"healthCheck,omitempty"`
+	Sticky           *TraefikSticky      `yaml:"sticky,omitempty"`
+	ServersTransport string              `yaml:"serversTransport,omitempty"` // Name of the transport to the servers, Traefik's default when empty
+}
+
+// TraefikSticky represents sticky sessions of a load balancer or weighted service
+type TraefikSticky struct {
+	Cookie *TraefikCookie `yaml:"cookie,omitempty"`
+}
+
+// TraefikCookie represents the cookie that keeps a client on its server
+type TraefikCookie struct {
+	Name     string `yaml:"name,omitempty"`
+	Secure   bool   `yaml:"secure,omitempty"`
+	HTTPOnly bool   `yaml:"httpOnly,omitempty"`
+	MaxAge   int    `yaml:"maxAge,omitempty"` // Seconds, a session cookie when 0
+}
+
+// String describes the sticky sessions on one line, "none" when they are nil
+func (s *TraefikSticky) String() string {
+	if s == nil || s.Cookie == nil {
+		return "none"
+	}
+
+	parts := []string{"cookie"}
+	if s.Cookie.Name != "" {
+		parts = append(parts, "name="+s.Cookie.Name)
+	}
+	if s.Cookie.Secure {
+		parts = append(parts, "secure")
+	}
+	if s.Cookie.HTTPOnly {
+		parts = append(parts, "httpOnly")
+	}
+	if s.Cookie.MaxAge > 0 {
+		parts = append(parts, fmt.Sprintf("maxAge=%d", s.Cookie.MaxAge))
+	}
+	return strings.Join(parts, " ")
+}
+
+// TraefikHealthCheck represents the active health check of a load balancer
+type TraefikHealthCheck struct {
+	Scheme   string            `yaml:"scheme,omitempty"`
+	Path     string            `yaml:"path"`
+	Method   string            `yaml:"method,omitempty"`
+	Port     int               `yaml:"port,omitempty"`
+	Interval string            `yaml:"interval,omitempty"`
+	Timeout  string            `yaml:"timeout,omitempty"`
+	Hostname string            `yaml:"hostname,omitempty"`
+	Headers  map[string]string `yaml:"headers,omitempty"`
+}
+
+// String describes the health check on one line, "none" when it is nil
+func (h *TraefikHealthCheck) String() string {
+	if h == nil {
+		return "none"
+	}
+
+	var parts []string
+	add := func(name, value string) {
+		if value != "" {
+			parts = append(parts, name+"="+value)
+		}
+	}
+	add("scheme", h.Scheme)
+	add("method", h.Method)
+	add("path", h.Path)
+	if h.Port > 0 {
+		add("port", fmt.Sprint(h.Port))
+	}
+	add("interval", h.Interval)
+	add("timeout", h.Timeout)
+	add("hostname", h.Hostname)
+
+	names := make([]string, 0, len(h.Headers))
+	for name := range h.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		add("header", name+": "+h.Headers[name])
+	}
+
+	return strings.Join(parts, " ")
 }
 
 // TraefikServer represents a server in the load balancer
 type TraefikServer struct {
-	URL     string `yaml:"url"`
-	Comment string `yaml:"-"` // Don't include in YAML output
+	URL      string `yaml:"url"`
+	Weight   int    `yaml:"weight,omitempty"`
+	Disabled bool   `yaml:"-"` // Written commented out
+	Comment  string `yaml:"-"` // Don't include in YAML output
+	Origin   string `yaml:"-"` // Source file, line and command the server came from
 }
 
 // TraefikConfig represents the complete Traefik configuration
 type TraefikConfig struct {
 	HTTP TraefikHTTP `yaml:"http"`
+	TLS  *TraefikTLS `yaml:"tls,omitempty"`
+}
+
+// TraefikTLS represents the TLS section of Traefik config
+type TraefikTLS struct {
+	Stores       map[string]TraefikTLSStore   `yaml:"stores,omitempty"`
+	Certificates []TraefikCertificate         `yaml:"certificates,omitempty"`
+	Options      map[string]TraefikTLSOptions `yaml:"options,omitempty"`
+}
+
+// TraefikTLSStore holds the certificate Traefik serves to the clients whose
+// server name matches none of the certificates
+type TraefikTLSStore struct {
+	DefaultCertificate *TraefikCertificate `yaml:"defaultCertificate,omitempty"`
+}
+
+// TraefikCertificate is a certificate Traefik serves to the clients whose
+// server name it matches
+type TraefikCertificate struct {
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+	Disabled bool   `yaml:"-"` // Written commented out
+	Comment  string `yaml:"-"` // Certificate-level comment (not serialized)
+	Origin   string `yaml:"-"` // Source file, line and command the certificate came from
+}
+
+// String describes the certificate on one line
+func (c TraefikCertificate) String() string {
+	return fmt.Sprintf("certFile=%s keyFile=%s", c.CertFile, c.KeyFile)
+}
+
+// TraefikTLSOptions are the protocol versions, cipher suites and client
+// authentication of the routers that reference them
+type TraefikTLSOptions struct {
+	MinVersion   string             `yaml:"minVersion,omitempty"`
+	MaxVersion   string             `yaml:"maxVersion,omitempty"`
+	CipherSuites []string           `yaml:"cipherSuites,omitempty"`
+	SNIStrict    bool               `yaml:"sniStrict,omitempty"`
+	ClientAuth   *TraefikClientAuth `yaml:"clientAuth,omitempty"`
+	Disabled     bool               `yaml:"-"` // Written commented out
+	Comment      string             `yaml:"-"` // Option-level comment (not serialized)
+	Origin       string             `yaml:"-"` // Source file, line and command the option came from
+}
+
+// TraefikClientAuth makes routers ask for client certificates issued by caFiles
+type TraefikClientAuth struct {
+	CAFiles        []string `yaml:"caFiles,omitempty"`
+	ClientAuthType string   `yaml:"clientAuthType"` // VerifyClientCertIfGiven or RequireAndVerifyClientCert
+}
+
+// String describes the options on one line
+func (o TraefikTLSOptions) String() string {
+	var parts []string
+	if o.MinVersion != "" {
+		parts = append(parts, "minVersion="+o.MinVersion)
+	}
+	if o.MaxVersion != "" {
+		parts = append(parts, "maxVersion="+o.MaxVersion)
+	}
+	if len(o.CipherSuites) > 0 {
+		parts = append(parts, "cipherSuites="+strings.Join(o.CipherSuites, ","))
+	}
+	if o.SNIStrict {
+		parts = append(parts, "sniStrict")
+	}
+	if o.ClientAuth != nil {
+		parts = append(parts, "clientAuth="+o.ClientAuth.ClientAuthType)
+		if len(o.ClientAuth.CAFiles) > 0 {
+			parts = append(parts, "caFiles="+strings.Join(o.ClientAuth.CAFiles, ","))
+		}
+	}
+	if len(parts) == 0 {
+		return "defaults"
+	}
+	return strings.Join(parts, " ")
+}
+
+// DefaultCertificate returns the default certificate of the default store, or nil
+func (t *TraefikTLS) DefaultCertificate() *TraefikCertificate {
+	if t == nil {
+		return nil
+	}
+	return t.Stores[defaultTLSStore].DefaultCertificate
+}
+
+// CertificatesByFile returns the certificates keyed by their certFile
+func (t *TraefikTLS) CertificatesByFile() map[string]TraefikCertificate {
+	certificates := make(map[string]TraefikCertificate)
+	if t == nil {
+		return certificates
+	}
+	for _, certificate := range t.Certificates {
+		certificates[certificate.CertFile] = certificate
+	}
+	return certificates
 }
 
 // TraefikHTTP represents the HTTP section of Traefik config
 type TraefikHTTP struct {
-	Services map[string]TraefikService `yaml:"services"`
+	Routers           map[string]TraefikRouter           `yaml:"routers,omitempty"`
+	Middlewares       map[string]TraefikMiddleware       `yaml:"middlewares,omitempty"`
+	Services          map[string]TraefikService          `yaml:"services"`
+	ServersTransports map[string]TraefikServersTransport `yaml:"serversTransports,omitempty"`
+}
+
+// TraefikServersTransport sets how services connect to servers with https URLs
+type TraefikServersTransport struct {
+	ServerName         string               `yaml:"serverName,omitempty"` // Sent with SNI and verified against the server certificates
+	InsecureSkipVerify bool                 `yaml:"insecureSkipVerify,omitempty"`
+	RootCAs            []string             `yaml:"rootCAs,omitempty"`      // Trusted to issue server certificates, instead of the system roots
+	Certificates       []TraefikCertificate `yaml:"certificates,omitempty"` // Client certificates presented to the servers
+	Comment            string               `yaml:"-"`                      // Transport-level comment (not serialized)
+	Origin             string               `yaml:"-"`                      // Source file, line and command the transport came from
+}
+
+// String describes the transport on one line, "defaults" when nothing is set
+func (t TraefikServersTransport) String() string {
+	var parts []string
+	if t.ServerName != "" {
+		parts = append(parts, "serverName="+t.ServerName)
+	}
+	if t.InsecureSkipVerify {
+		parts = append(parts, "insecureSkipVerify")
+	}
+	if len(t.RootCAs) > 0 {
+		parts = append(parts, "rootCAs="+strings.Join(t.RootCAs, ","))
+	}
+	for _, certificate := range t.Certificates {
+		parts = append(parts, "certificate="+certificate.CertFile)
+	}
+	if len(parts) == 0 {
+		return "defaults"
+	}
+	return strings.Join(parts, " ")
+}
+
+// TraefikRouter represents a router that sends the requests matching its rule to a service
+type TraefikRouter struct {
+	EntryPoints []string          `yaml:"entryPoints,omitempty"` // Entry points the router listens on, named after its vserver
+	Rule        string            `yaml:"rule"`
+	Service     string            `yaml:"service"`
+	Middlewares []string          `yaml:"middlewares,omitempty"` // Applied in order before the service
+	Priority    int               `yaml:"priority,omitempty"`    // Higher priorities are evaluated first
+	TLS         *TraefikRouterTLS `yaml:"tls,omitempty"`         // Set for routers of SSL vservers, which terminate TLS
+	Disabled    bool              `yaml:"-"`                     // Written commented out
+	Comment     string            `yaml:"-"`                     // Router-level comment (not serialized)
+	Origin      string            `yaml:"-"`                     // Source file, line and command the router came from
+}
+
+// String describes the router on one line
+func (r TraefikRouter) String() string {
+	description := fmt.Sprintf("rule=%s service=%s priority=%d", r.Rule, r.Service, r.Priority)
+	if len(r.EntryPoints) > 0 {
+		description += " entryPoints=" + strings.Join(r.EntryPoints, ",")
+	}
+	if len(r.Middlewares) > 0 {
+		description += " middlewares=" + strings.Join(r.Middlewares, ",")
+	}
+	if r.TLS != nil {
+		description += " tls"
+		if r.TLS.Options != "" {
+			description += "=" + r.TLS.Options
+		}
+	}
+	return description
+}
+
+// TraefikRouterTLS makes a router accept TLS connections only, terminating
+// them with the certificate that matches the requested server name
+type TraefikRouterTLS struct {
+	Options string `yaml:"options,omitempty"` // Name of the TLS options, Traefik's default when empty
+}
+
+// TraefikMiddleware represents a middleware; exactly one of its fields is set
+type TraefikMiddleware struct {
+	RedirectScheme   *TraefikRedirectScheme   `yaml:"redirectScheme,omitempty"`
+	RedirectRegex    *TraefikRedirectRegex    `yaml:"redirectRegex,omitempty"`
+	IPAllowList      *TraefikIPAllowList      `yaml:"ipAllowList,omitempty"`
+	IPWhiteList      *TraefikIPAllowList      `yaml:"ipWhiteList,omitempty"` // ipAllowList before Traefik 2.11
+	Plugin           *TraefikPlugins          `yaml:"plugin,omitempty"`
+	Headers          *TraefikHeaders          `yaml:"headers,omitempty"`
+	ReplacePathRegex *TraefikReplacePathRegex `yaml:"replacePathRegex,omitempty"`
+	AddPrefix        *TraefikAddPrefix        `yaml:"addPrefix,omitempty"`
+	StripPrefix      *TraefikStripPrefix      `yaml:"stripPrefix,omitempty"`
+	Comment          string                   `yaml:"-"` // Middleware-level comment (not serialized)
+	Origin           string                   `yaml:"-"` // Source file, line and command the middleware came from
+}
+
+// TraefikHeaders sets request headers before the service and response
+// headers after it; an empty value removes the header
+type TraefikHeaders struct {
+	CustomRequestHeaders  map[string]string `yaml:"customRequestHeaders,omitempty"`
+	CustomResponseHeaders map[string]string `yaml:"customResponseHeaders,omitempty"`
+}
+
+// TraefikReplacePathRegex rewrites the request path matching Regex to Replacement
+type TraefikReplacePathRegex struct {
+	Regex       string `yaml:"regex"`
+	Replacement string `yaml:"replacement"`
+}
+
+// TraefikAddPrefix prepends a prefix to the request path
+type TraefikAddPrefix struct {
+	Prefix string `yaml:"prefix"`
+}
+
+// TraefikStripPrefix removes the first matching prefix from the request path
+type TraefikStripPrefix struct {
+	Prefixes []string `yaml:"prefixes"`
+}
+
+// TraefikRedirectScheme redirects requests to another scheme, keeping host and path
+type TraefikRedirectScheme struct {
+	Scheme    string `yaml:"scheme"`
+	Port      string `yaml:"port,omitempty"`
+	Permanent bool   `yaml:"permanent,omitempty"`
+}
+
+// TraefikRedirectRegex redirects requests whose URL matches Regex to Replacement
+type TraefikRedirectRegex struct {
+	Regex       string `yaml:"regex"`
+	Replacement string `yaml:"replacement"`
+	Permanent   bool   `yaml:"permanent,omitempty"`
+}
+
+// TraefikIPAllowList accepts only requests from the listed client addresses
+type TraefikIPAllowList struct {
+	SourceRange []string `yaml:"sourceRange"`
+}
+
+// TraefikPlugins holds the configuration of middleware plugins
+type TraefikPlugins struct {
+	StaticResponse *TraefikStaticResponse `yaml:"staticresponse,omitempty"`
+}
+
+// TraefikStaticResponse configures the staticresponse plugin, which answers
+// requests itself instead of passing them to the service
+type TraefikStaticResponse struct {
+	StatusCode int               `yaml:"statusCode"`
+	Headers    map[string]string `yaml:"headers,omitempty"`
+	Body       string            `yaml:"body,omitempty"`
+}
+
+// String describes the middleware on one line
+func (m TraefikMiddleware) String() string {
+	switch {
+	case m.RedirectScheme != nil:
+		description := "redirectScheme scheme=" + m.RedirectScheme.Scheme
+		if m.RedirectScheme.Port != "" {
+			description += " port=" + m.RedirectScheme.Port
+		}
+		if m.RedirectScheme.Permanent {
+			description += " permanent"
+		}
+		return description
+	case m.RedirectRegex != nil:
+		description := fmt.Sprintf("redirectRegex regex=%s replacement=%s", m.RedirectRegex.Regex, m.RedirectRegex.Replacement)
+		if m.RedirectRegex.Permanent {
+			description += " permanent"
+		}
+		return description
+	case m.IPAllowList != nil:
+		return "ipAllowList sourceRange=" + strings.Join(m.IPAllowList.SourceRange, ",")
+	case m.IPWhiteList != nil:
+		return "ipWhiteList sourceRange=" + strings.Join(m.IPWhiteList.SourceRange, ",")
+	case m.Plugin != nil && m.Plugin.StaticResponse != nil:
+		response := m.Plugin.StaticResponse
+		description := fmt.Sprintf("staticresponse statusCode=%d", response.StatusCode)
+		headers := make([]string, 0, len(response.Headers))
+		for name, value := range response.Headers {
+			headers = append(headers, name+": "+value)
+		}
+		sort.Strings(headers)
+		for _, header := range headers {
+			description += fmt.Sprintf(" header=%q", header)
+		}
+		return description + fmt.Sprintf(" body=%q", response.Body)
+	case m.Headers != nil:
+		description := "headers"
+		for _, set := range []struct {
+			kind    string
+			headers map[string]string
+		}{{"request", m.Headers.CustomRequestHeaders}, {"response", m.Headers.CustomResponseHeaders}} {
+			names := make([]string, 0, len(set.headers))
+			for name := range set.headers {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				description += fmt.Sprintf(" %s %s=%q", set.kind, name, set.headers[name])
+			}
+		}
+		return description
+	case m.ReplacePathRegex != nil:
+		return fmt.Sprintf("replacePathRegex regex=%s replacement=%s", m.ReplacePathRegex.Regex, m.ReplacePathRegex.Replacement)
+	case m.AddPrefix != nil:
+		return "addPrefix prefix=" + m.AddPrefix.Prefix
+	case m.StripPrefix != nil:
+		return "stripPrefix prefixes=" + strings.Join(m.StripPrefix.Prefixes, ",")
+	default:
+		return "none"
+	}
 }
 
 // MappingEntry represents a mapping entry with optional comment
 type MappingEntry struct {
-	Key     string
-	Value   string
-	Comment string
+	Key      string
+	Value    string
+	Comment  string
+	Disabled bool   // The virtual server is disabled; written commented out
+	Origin   string // Source file, line and command the entry came from
 }
 
 // MappingConfig represents the mapping configuration
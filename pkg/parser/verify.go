@@ -0,0 +1,416 @@
+package parser
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// reportFunc records a diagnostic of a Verify check
+type reportFunc func(pos Position, severity Severity, code, format string, args ...interface{})
+
+// Verify performs consistency checks on a parsed configuration and returns
+// the problems found. Errors mean the generated configuration would be wrong,
+// warnings point at objects that are defined but unused.
+func Verify(config *LBConfig) Diagnostics {
+	var diagnostics Diagnostics
+	report := func(pos Position, severity Severity, code, format string, args ...interface{}) {
+		diagnostics = append(diagnostics, Diagnostic{
+			Position: pos,
+			Severity: severity,
+			Code:     code,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	verifyReferences(config, report)
+	verifyMappings(config, report)
+	verifyServices(config, report)
+	verifyRouters(config, report)
+	verifyTLS(config, report)
+	verifyTransports(config, report)
+
+	return diagnostics
+}
+
+// verifyReferences checks that names are unique and references resolve
+func verifyReferences(config *LBConfig, report reportFunc) {
+	// Check if all referenced servers exist
+	for _, sg := range config.ServiceGroups {
+		if sg.Server == nil {
+			report(sg.Pos, SeverityError, "undefined-server",
+				"service group '%s' references non-existent server '%s'", sg.Name, sg.ServerName)
+		}
+	}
+
+	// Check if all service groups have at least one server binding
+	for _, sgDef := range config.ServiceGroupDefs {
+		if len(config.MembersOf(sgDef.Name)) == 0 {
+			report(sgDef.Pos, SeverityWarning, "empty-service-group",
+				"service group '%s' is defined but has no server bindings", sgDef.Name)
+		}
+	}
+
+	// Check for duplicate server names
+	seenServers := make(map[string]bool)
+	for _, server := range config.Servers {
+		if seenServers[server.Name] {
+			report(server.Pos, SeverityError, "duplicate-server", "duplicate server name '%s'", server.Name)
+		}
+		seenServers[server.Name] = true
+	}
+
+	// Check for duplicate vserver names
+	seenVServers := make(map[string]bool)
+	for _, vserver := range config.VServers {
+		if seenVServers[vserver.Name] {
+			report(vserver.Pos, SeverityError, "duplicate-vserver", "duplicate vserver name '%s'", vserver.Name)
+		}
+		seenVServers[vserver.Name] = true
+	}
+
+	// Check vserver bindings
+	for _, binding := range config.VServerBindings {
+		if binding.VServer == nil {
+			report(binding.Pos, SeverityError, "undefined-vserver",
+				"vserver binding references non-existent vserver '%s'", binding.VServerName)
+		}
+		// Only check service group if there's actually a service name (not policy-only bindings)
+		if binding.ServiceName != "" && len(config.MembersOf(binding.ServiceName)) == 0 {
+			report(binding.Pos, SeverityWarning, "unbound-service",
+				"vserver binding '%s' references service '%s' that has no group definition",
+				binding.VServerName, binding.ServiceName)
+		}
+	}
+}
+
+// verifyMappings reports the virtual servers that get no service and no mapping
+func verifyMappings(config *LBConfig, report reportFunc) {
+	// Virtual servers that are disabled or without an enabled member get no service and no mapping
+	for _, vserver := range config.VServers {
+		groups := config.VServerGroups(vserver.Name)
+		members, enabled := 0, 0
+		for _, group := range groups {
+			for _, member := range config.MembersOf(group) {
+				members++
+				if !config.MemberDisabled(member) {
+					enabled++
+				}
+			}
+		}
+		switch {
+		case vserver.Disabled:
+			report(vserver.Pos, SeverityInfo, "disabled-vserver",
+				"vserver '%s' on %s is disabled and gets no mapping", vserver.Name, VIPKey(vserver.IP, vserver.Port))
+		case len(groups) == 0:
+			report(vserver.Pos, SeverityWarning, "unbound-vserver",
+				"vserver '%s' on %s has no service group bound and gets no mapping", vserver.Name, VIPKey(vserver.IP, vserver.Port))
+		case members == 0:
+			report(vserver.Pos, SeverityWarning, "empty-vserver",
+				"vserver '%s' on %s has no service group members and gets no mapping", vserver.Name, VIPKey(vserver.IP, vserver.Port))
+		case enabled == 0:
+			report(vserver.Pos, SeverityWarning, "disabled-members",
+				"vserver '%s' on %s has only disabled members and gets no mapping", vserver.Name, VIPKey(vserver.IP, vserver.Port))
+		}
+	}
+}
+
+// verifyServices reports the persistence, failover and health checks that
+// Traefik services lose
+func verifyServices(config *LBConfig, report reportFunc) {
+	// Persistence other than COOKIEINSERT is lost
+	for _, vserver := range config.VServers {
+		if _, unsupported := translatePersistence(vserver); unsupported != "" && !vserver.Disabled {
+			report(vserver.Pos, SeverityWarning, "unsupported-persistence",
+				"vserver '%s' on %s loses its persistence: %s", vserver.Name, VIPKey(vserver.IP, vserver.Port), unsupported)
+		}
+	}
+
+	// Failover needs an existing, acyclic backup and a health check on the primary
+	for _, vserver := range config.VServers {
+		if vserver.Disabled {
+			continue
+		}
+		groups := config.VServerGroups(vserver.Name)
+		tiers := priorityTiers(config, groups)
+		backup := vserver.Backup.VServer
+		if backup == "" && tiers == nil {
+			continue
+		}
+
+		if backup != "" {
+			if config.VServerByName(backup) == nil {
+				report(vserver.Pos, SeverityWarning, "undefined-backup",
+					"vserver '%s' has non-existent backup vserver '%s'", vserver.Name, backup)
+				continue
+			}
+			if chain, cycle := backupChain(config, vserver); cycle {
+				report(vserver.Pos, SeverityWarning, "backup-cycle",
+					"vserver '%s' has a backup chain that loops (%s -> %s) and gets no failover",
+					vserver.Name, vserver.Name, strings.Join(chain, " -> "))
+				continue
+			}
+		}
+		if serviceHealthCheck(config, groups) == nil {
+			fallback := fmt.Sprintf("backup vserver '%s'", backup)
+			if backup == "" {
+				lower := make([]string, 0, len(tiers)-1)
+				for _, priority := range tiers[1:] {
+					lower = append(lower, fmt.Sprint(priority))
+				}
+				fallback = "priority groups " + strings.Join(lower, ", ")
+			}
+			report(vserver.Pos, SeverityWarning, "failover-without-health-check",
+				"vserver '%s' never fails over to %s: none of its service groups has a monitor with a Traefik health check",
+				vserver.Name, fallback)
+		}
+	}
+
+	// Check monitor bindings: every monitor must exist, and only one health check per group is translated
+	for _, sgDef := range config.ServiceGroupDefs {
+		translated := 0
+		for _, binding := range config.MonitorsOf(sgDef.Name) {
+			monitor := config.LookupMonitor(binding.MonitorName)
+			if monitor == nil {
+				report(binding.Pos, SeverityWarning, "undefined-monitor",
+					"service group '%s' is bound to non-existent monitor '%s'", sgDef.Name, binding.MonitorName)
+				continue
+			}
+			if check, _, _ := translateMonitor(monitor); check != nil {
+				translated++
+			}
+		}
+		if translated > 1 {
+			_, used := groupHealthCheck(config, sgDef.Name)
+			report(sgDef.Pos, SeverityWarning, "multiple-monitors",
+				"service group '%s' has %d monitors with a Traefik health check, only '%s' is used", sgDef.Name, translated, used)
+		}
+	}
+
+	// A Traefik service has a single health check, so the groups of a vserver must agree on it
+	for _, vserver := range config.VServers {
+		groups := config.VServerGroups(vserver.Name)
+		if len(groups) < 2 {
+			continue
+		}
+		first, _ := groupHealthCheck(config, groups[0])
+		for _, group := range groups[1:] {
+			if check, _ := groupHealthCheck(config, group); !reflect.DeepEqual(first, check) {
+				report(vserver.Pos, SeverityWarning, "monitor-conflict",
+					"vserver '%s' binds service groups '%s' and '%s' with different health checks, the first one found is used",
+					vserver.Name, groups[0], group)
+				break
+			}
+		}
+	}
+}
+
+// verifyRouters reports the content switching routes and the responder and
+// rewrite policies that get no router or middleware
+func verifyRouters(config *LBConfig, report reportFunc) {
+	// Content switching routes need a defined policy and lb vserver and a rule Traefik can express
+	for _, vserver := range config.CSVServers {
+		if vserver.Disabled {
+			report(vserver.Pos, SeverityInfo, "disabled-cs-vserver",
+				"cs vserver '%s' on %s is disabled and gets no routers", vserver.Name, VIPKey(vserver.IP, vserver.Port))
+			continue
+		}
+		if lb := config.VServerByName(vserver.Name); lb != nil {
+			report(vserver.Pos, SeverityWarning, "entry-point-conflict",
+				"cs vserver '%s' on %s has the name of the lb vserver on %s, so their routers share the entry point '%s'",
+				vserver.Name, VIPKey(vserver.IP, vserver.Port), VIPKey(lb.IP, lb.Port), vserver.Name)
+		}
+		routes := CSRoutes(config, vserver)
+		if len(routes) == 0 {
+			report(vserver.Pos, SeverityWarning, "unbound-cs-vserver",
+				"cs vserver '%s' on %s has no policy or default lb vserver bound and gets no routers", vserver.Name, VIPKey(vserver.IP, vserver.Port))
+		}
+		for _, route := range routes {
+			if route.Problem == "" {
+				continue
+			}
+			pos, _ := route.Pos()
+			report(pos, SeverityWarning, "untranslated-cs-route",
+				"cs vserver '%s' gets no router for %s: %s", vserver.Name, csRouteName(route), route.Problem)
+		}
+	}
+
+	// Responder policies need a defined policy and action that Traefik middlewares can express
+	reportSteps := func(steps []ResponderStep, kind, vserver string) {
+		for _, step := range steps {
+			if step.Problem == "" {
+				continue
+			}
+			pos, _ := step.Position()
+			report(pos, SeverityWarning, "untranslated-responder-policy",
+				"%s '%s' loses responder policy '%s': %s", kind, vserver, step.PolicyName, step.Problem)
+		}
+	}
+	for _, vserver := range config.VServers {
+		if !vserver.Disabled {
+			reportSteps(ResponderSteps(config, vserver.Name, false), "lb vserver", vserver.Name)
+		}
+	}
+	for _, vserver := range config.CSVServers {
+		if !vserver.Disabled {
+			reportSteps(ResponderSteps(config, vserver.Name, true), "cs vserver", vserver.Name)
+		}
+	}
+
+	// Rewrite policies need a rule that selects everything and an action Traefik middlewares can express
+	reportRewrites := func(steps []RewriteStep, kind, vserver string) {
+		for _, step := range steps {
+			if step.Problem == "" {
+				continue
+			}
+			pos, _ := step.Position()
+			report(pos, SeverityWarning, "untranslated-rewrite-policy",
+				"%s '%s' loses rewrite policy '%s': %s", kind, vserver, step.PolicyName, step.Problem)
+		}
+	}
+	for _, vserver := range config.VServers {
+		if !vserver.Disabled {
+			reportRewrites(RewriteSteps(config, vserver.Name, false), "lb vserver", vserver.Name)
+		}
+	}
+	for _, vserver := range config.CSVServers {
+		if !vserver.Disabled {
+			reportRewrites(RewriteSteps(config, vserver.Name, true), "cs vserver", vserver.Name)
+		}
+	}
+}
+
+// verifyTLS checks the certificate bindings and TLS options of SSL vservers
+func verifyTLS(config *LBConfig, report reportFunc) {
+	// Certificate bindings need a defined vserver and certKey, and served certificates need a key
+	for _, binding := range config.SSLBindings {
+		if _, exists := sslVServer(config, binding.VServerName); !exists {
+			report(binding.Pos, SeverityWarning, "undefined-vserver",
+				"certificate binding references non-existent vserver '%s'", binding.VServerName)
+		}
+		certKey := config.CertKeyByName(binding.CertKeyName)
+		switch {
+		case certKey == nil:
+			report(binding.Pos, SeverityWarning, "undefined-certkey",
+				"ssl vserver '%s' binds non-existent certKey '%s'", binding.VServerName, binding.CertKeyName)
+		case certKey.KeyFile == "" && !binding.CA:
+			report(binding.Pos, SeverityWarning, "certkey-without-key",
+				"ssl vserver '%s' serves certKey '%s', which has no -key and gets no certificate", binding.VServerName, binding.CertKeyName)
+		}
+	}
+
+	// SSL vservers need a server certificate, or Traefik serves its default one
+	hasServerCertificate := func(vserver string) bool {
+		for _, binding := range config.SSLBindingsOf(vserver) {
+			if !binding.CA && !binding.SNI {
+				return true
+			}
+		}
+		return false
+	}
+	for _, vserver := range config.VServers {
+		if strings.EqualFold(vserver.Protocol, "SSL") && !vserver.Disabled && vserver.Addressable() && !hasServerCertificate(vserver.Name) {
+			report(vserver.Pos, SeverityWarning, "missing-server-certificate",
+				"vserver '%s' on %s has no server certificate bound; Traefik serves its default certificate", vserver.Name, VIPKey(vserver.IP, vserver.Port))
+		}
+	}
+	for _, vserver := range config.CSVServers {
+		if strings.EqualFold(vserver.Protocol, "SSL") && !vserver.Disabled && !hasServerCertificate(vserver.Name) {
+			report(vserver.Pos, SeverityWarning, "missing-server-certificate",
+				"cs vserver '%s' on %s has no server certificate bound; Traefik serves its default certificate", vserver.Name, VIPKey(vserver.IP, vserver.Port))
+		}
+	}
+
+	// Traefik has one default certificate, served when no server name matches
+	if defaultKey, _ := defaultCertKey(config); defaultKey != nil {
+		for _, binding := range config.SSLBindings {
+			certKey := config.CertKeyByName(binding.CertKeyName)
+			if binding.CA || binding.SNI || certKey == nil || certKey.KeyFile == "" || certKey == defaultKey {
+				continue
+			}
+			if disabled, exists := sslVServer(config, binding.VServerName); exists && !disabled {
+				report(binding.Pos, SeverityWarning, "default-certificate-conflict",
+					"ssl vserver '%s' serves certKey '%s' without -SNICert, but Traefik has one default certificate, certKey '%s'; clients whose server name matches neither get that one",
+					binding.VServerName, binding.CertKeyName, defaultKey.Name)
+			}
+		}
+	}
+
+	// SSL vservers need a defined ssl profile, and their TLS options lose the settings Traefik cannot express
+	for _, ssl := range config.SSLVServers {
+		if ssl.Profile != "" && config.SSLProfileByName(ssl.Profile) == nil {
+			report(ssl.Pos, SeverityWarning, "undefined-ssl-profile",
+				"ssl vserver '%s' uses non-existent ssl profile '%s'; its own settings apply", ssl.Name, ssl.Profile)
+		}
+	}
+	for _, option := range TLSOptions(config, nil) {
+		enabled := false
+		for _, name := range option.VServers {
+			disabled, _ := sslVServer(config, name)
+			enabled = enabled || !disabled
+		}
+		if !enabled {
+			continue
+		}
+		for _, problem := range option.Problems {
+			report(problem.Pos, SeverityWarning, problem.Code, "%s: %s", option.Kind(), problem.Message)
+		}
+	}
+}
+
+// verifyTransports checks the SSL settings of service groups reached over TLS
+func verifyTransports(config *LBConfig, report reportFunc) {
+	// SSL service groups need defined certKeys and an SSL protocol, and their
+	// servers transports lose the settings Traefik cannot express
+	for _, ssl := range config.SSLServiceGroups {
+		certKeys := ssl.CAs
+		if ssl.ClientCert != "" {
+			certKeys = append(slices.Clip(certKeys), ssl.ClientCert)
+		}
+		for _, name := range certKeys {
+			if config.CertKeyByName(name) == nil {
+				report(ssl.Pos, SeverityWarning, "undefined-certkey",
+					"ssl serviceGroup '%s' binds non-existent certKey '%s'", ssl.Name, name)
+			}
+		}
+		if certKey := config.CertKeyByName(ssl.ClientCert); certKey != nil && certKey.KeyFile == "" {
+			report(ssl.Pos, SeverityWarning, "certkey-without-key",
+				"ssl serviceGroup '%s' presents certKey '%s', which has no -key and gets no client certificate", ssl.Name, ssl.ClientCert)
+		}
+		if scheme := backendScheme(config, ssl.Name); scheme != "https" {
+			report(ssl.Pos, SeverityWarning, "unused-ssl-settings",
+				"ssl serviceGroup '%s' has SSL settings, but its servers are reached over %s", ssl.Name, scheme)
+		}
+	}
+	for _, transport := range ServersTransports(config, nil) {
+		if def := config.ServiceGroupDefByName(transport.Name); def != nil && def.Disabled {
+			continue
+		}
+		for _, problem := range transport.Problems {
+			report(problem.Pos, SeverityWarning, problem.Code, "%s: %s", transport.Kind(), problem.Message)
+		}
+	}
+
+	// A Traefik service has a single servers transport, so the groups of a
+	// vserver reached over TLS must agree on their SSL settings
+	for _, vserver := range config.VServers {
+		first, firstTransport := "", ""
+		for _, group := range config.VServerGroups(vserver.Name) {
+			if backendScheme(config, group) != "https" {
+				continue
+			}
+			transport := resolveServersTransport(config, group, nil).Transport.String()
+			if first == "" {
+				first, firstTransport = group, transport
+				continue
+			}
+			if transport != firstTransport {
+				report(vserver.Pos, SeverityWarning, "servers-transport-conflict",
+					"vserver '%s' binds service groups '%s' and '%s' with different SSL settings, the transport of '%s' is used",
+					vserver.Name, first, group, first)
+				break
+			}
+		}
+	}
+}
@@ -4,12 +4,32 @@ import (
 	"fmt"
 	"io"
 	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
+// WriteOptions controls optional annotations in the generated YAML
+type WriteOptions struct {
+	Provenance bool // Annotate services, server URLs and mappings with their source file, line and command
+}
+
 // WriteTraefikConfigWithComments writes the Traefik config to the writer with YAML comments
 func WriteTraefikConfigWithComments(w io.Writer, config TraefikConfig) error {
+	return WriteTraefikConfigWithOptions(w, config, WriteOptions{})
+}
+
+// WriteTraefikConfigWithOptions writes the Traefik config to the writer with YAML comments and optional annotations
+func WriteTraefikConfigWithOptions(w io.Writer, config TraefikConfig, opts WriteOptions) error {
 	// Write the beginning of the YAML
 	fmt.Fprintf(w, "http:\n")
+	if len(config.HTTP.Routers) > 0 {
+		writeRouters(w, config.HTTP.Routers, opts)
+	}
+	if len(config.HTTP.Middlewares) > 0 {
+		writeMiddlewares(w, config.HTTP.Middlewares, opts)
+	}
 	fmt.Fprintf(w, "  services:\n")
 
 	// Get service names and sort them
@@ -26,9 +46,40 @@ func WriteTraefikConfigWithComments(w io.Writer, config TraefikConfig) error {
 		if service.Comment != "" {
 			fmt.Fprintf(w, "    # %s\n", service.Comment)
 		}
+		if opts.Provenance && service.Origin != "" {
+			fmt.Fprintf(w, "    # source: %s\n", service.Origin)
+		}
 
 		fmt.Fprintf(w, "    %s:\n", serviceName)
+		if service.Weighted != nil {
+			fmt.Fprintf(w, "      weighted:\n")
+			fmt.Fprintf(w, "        services:\n")
+			for _, child := range service.Weighted.Services {
+				fmt.Fprintf(w, "          - name: %s\n", child.Name)
+				fmt.Fprintf(w, "            weight: %d\n", child.Weight)
+			}
+			if sticky := service.Weighted.Sticky; sticky != nil {
+				writeSticky(w, sticky)
+			}
+			if service.Weighted.HealthCheck != nil {
+				fmt.Fprintf(w, "        healthCheck: {}\n")
+			}
+			continue
+		}
+		if failover := service.Failover; failover != nil {
+			fmt.Fprintf(w, "      failover:\n")
+			fmt.Fprintf(w, "        service: %s\n", failover.Service)
+			fmt.Fprintf(w, "        fallback: %s\n", failover.Fallback)
+			if failover.HealthCheck != nil {
+				fmt.Fprintf(w, "        healthCheck: {}\n")
+			}
+			continue
+		}
+
 		fmt.Fprintf(w, "      loadBalancer:\n")
+		if service.LoadBalancer.Strategy != "" {
+			fmt.Fprintf(w, "        strategy: %s\n", service.LoadBalancer.Strategy)
+		}
 		fmt.Fprintf(w, "        servers:\n")
 
 		// Sort servers by URL
@@ -41,18 +92,49 @@ func WriteTraefikConfigWithComments(w io.Writer, config TraefikConfig) error {
 		for _, server := range servers {
 			if server.Comment != "" {
 				fmt.Fprintf(w, "          # %s\n", server.Comment)
-				fmt.Fprintf(w, "          - url: %s\n", server.URL)
-			} else {
-				fmt.Fprintf(w, "          - url: %s\n", server.URL)
 			}
+			if opts.Provenance && server.Origin != "" {
+				fmt.Fprintf(w, "          # source: %s\n", server.Origin)
+			}
+			// Disabled servers are kept commented out so they can be re-enabled by hand
+			prefix := ""
+			if server.Disabled {
+				fmt.Fprintf(w, "          # disabled\n")
+				prefix = "# "
+			}
+			fmt.Fprintf(w, "          %s- url: %s\n", prefix, server.URL)
+			if server.Weight > 0 {
+				fmt.Fprintf(w, "          %s  weight: %d\n", prefix, server.Weight)
+			}
+		}
+
+		if check := service.LoadBalancer.HealthCheck; check != nil {
+			writeHealthCheck(w, check)
+		}
+		if sticky := service.LoadBalancer.Sticky; sticky != nil {
+			writeSticky(w, sticky)
 		}
+		if transport := service.LoadBalancer.ServersTransport; transport != "" {
+			fmt.Fprintf(w, "        serversTransport: %s\n", yamlScalar(transport))
+		}
+	}
+	if len(config.HTTP.ServersTransports) > 0 {
+		writeServersTransports(w, config.HTTP.ServersTransports, opts)
 	}
 
+	if config.TLS != nil {
+		writeTLS(w, *config.TLS, opts)
+	}
 	return nil
 }
 
 // WriteMappingConfigWithComments writes the mapping config to the writer with YAML comments
 func WriteMappingConfigWithComments(w io.Writer, config MappingConfig) error {
+	return WriteMappingConfigWithOptions(w, config, WriteOptions{})
+}
+
+// WriteMappingConfigWithOptions writes the mapping config to the writer with YAML comments and optional annotations
+func WriteMappingConfigWithOptions(w io.Writer, config MappingConfig, opts WriteOptions) error {
 	// Sort entries by key
 	entries := make([]MappingEntry, len(config.Entries))
 	copy(entries, config.Entries)
@@ -63,10 +145,397 @@ func WriteMappingConfigWithComments(w io.Writer, config MappingConfig) error {
 	for _, entry := range entries {
 		if entry.Comment != "" {
 			fmt.Fprintf(w, "# %s\n", entry.Comment)
-			fmt.Fprintf(w, "\"%s\": \"%s\"\n", entry.Key, entry.Value)
-		} else {
-			fmt.Fprintf(w, "\"%s\": \"%s\"\n", entry.Key, entry.Value)
 		}
+		if opts.Provenance && entry.Origin != "" {
+			fmt.Fprintf(w, "# source: %s\n", entry.Origin)
+		}
+		if entry.Disabled {
+			fmt.Fprintf(w, "# disabled\n# \"%s\": \"%s\"\n", entry.Key, entry.Value)
+			continue
+		}
+		fmt.Fprintf(w, "\"%s\": \"%s\"\n", entry.Key, entry.Value)
 	}
 	return nil
 }
+
+// writeRouters writes the routers section in name order. Routers of disabled
+// virtual servers are kept commented out.
+func writeRouters(w io.Writer, routers map[string]TraefikRouter, opts WriteOptions) {
+	names := make([]string, 0, len(routers))
+	for name := range routers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "  routers:\n")
+	for _, name := range names {
+		router := routers[name]
+		if router.Comment != "" {
+			fmt.Fprintf(w, "    # %s\n", router.Comment)
+		}
+		if opts.Provenance && router.Origin != "" {
+			fmt.Fprintf(w, "    # source: %s\n", router.Origin)
+		}
+		prefix := ""
+		if router.Disabled {
+			fmt.Fprintf(w, "    # disabled\n")
+			prefix = "# "
+		}
+		fmt.Fprintf(w, "    %s%s:\n", prefix, yamlScalar(name))
+		if len(router.EntryPoints) > 0 {
+			fmt.Fprintf(w, "    %s  entryPoints:\n", prefix)
+			for _, entryPoint := range router.EntryPoints {
+				fmt.Fprintf(w, "    %s    - %s\n", prefix, yamlScalar(entryPoint))
+			}
+		}
+		fmt.Fprintf(w, "    %s  rule: %s\n", prefix, yamlScalar(router.Rule))
+		fmt.Fprintf(w, "    %s  service: %s\n", prefix, yamlScalar(router.Service))
+		if len(router.Middlewares) > 0 {
+			fmt.Fprintf(w, "    %s  middlewares:\n", prefix)
+			for _, middleware := range router.Middlewares {
+				fmt.Fprintf(w, "    %s    - %s\n", prefix, yamlScalar(middleware))
+			}
+		}
+		if router.Priority > 0 {
+			fmt.Fprintf(w, "    %s  priority: %d\n", prefix, router.Priority)
+		}
+		switch {
+		case router.TLS != nil && router.TLS.Options != "":
+			fmt.Fprintf(w, "    %s  tls:\n", prefix)
+			fmt.Fprintf(w, "    %s    options: %s\n", prefix, yamlScalar(router.TLS.Options))
+		case router.TLS != nil:
+			fmt.Fprintf(w, "    %s  tls: {}\n", prefix)
+		}
+	}
+}
+
+// writeServersTransports writes the serversTransports section in name order
+func writeServersTransports(w io.Writer, transports map[string]TraefikServersTransport, opts WriteOptions) {
+	names := make([]string, 0, len(transports))
+	for name := range transports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "  serversTransports:\n")
+	for _, name := range names {
+		transport := transports[name]
+		if transport.Comment != "" {
+			fmt.Fprintf(w, "    # %s\n", transport.Comment)
+		}
+		if opts.Provenance && transport.Origin != "" {
+			fmt.Fprintf(w, "    # source: %s\n", transport.Origin)
+		}
+		if transport.String() == "defaults" {
+			fmt.Fprintf(w, "    %s: {}\n", yamlScalar(name))
+			continue
+		}
+		fmt.Fprintf(w, "    %s:\n", yamlScalar(name))
+		if transport.ServerName != "" {
+			fmt.Fprintf(w, "      serverName: %s\n", yamlScalar(transport.ServerName))
+		}
+		if transport.InsecureSkipVerify {
+			fmt.Fprintf(w, "      insecureSkipVerify: true\n")
+		}
+		if len(transport.RootCAs) > 0 {
+			fmt.Fprintf(w, "      rootCAs:\n")
+			for _, file := range transport.RootCAs {
+				fmt.Fprintf(w, "        - %s\n", yamlScalar(file))
+			}
+		}
+		if len(transport.Certificates) > 0 {
+			fmt.Fprintf(w, "      certificates:\n")
+			for _, certificate := range transport.Certificates {
+				fmt.Fprintf(w, "        - certFile: %s\n", yamlScalar(certificate.CertFile))
+				fmt.Fprintf(w, "          keyFile: %s\n", yamlScalar(certificate.KeyFile))
+			}
+		}
+	}
+}
+
+// writeTLS writes the stores, certificates and options of the tls section
+func writeTLS(w io.Writer, tls TraefikTLS, opts WriteOptions) {
+	fmt.Fprintf(w, "tls:\n")
+	if len(tls.Stores) > 0 {
+		writeTLSStores(w, tls.Stores, opts)
+	}
+	if len(tls.Certificates) > 0 {
+		writeCertificates(w, tls.Certificates, opts)
+	}
+	if len(tls.Options) > 0 {
+		writeTLSOptions(w, tls.Options, opts)
+	}
+}
+
+// writeTLSStores writes the stores of the tls section in name order
+func writeTLSStores(w io.Writer, stores map[string]TraefikTLSStore, opts WriteOptions) {
+	names := make([]string, 0, len(stores))
+	for name := range stores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "  stores:\n")
+	for _, name := range names {
+		fmt.Fprintf(w, "    %s:\n", yamlScalar(name))
+		certificate := stores[name].DefaultCertificate
+		if certificate == nil {
+			continue
+		}
+		if certificate.Comment != "" {
+			fmt.Fprintf(w, "      # %s\n", certificate.Comment)
+		}
+		if opts.Provenance && certificate.Origin != "" {
+			fmt.Fprintf(w, "      # source: %s\n", certificate.Origin)
+		}
+		fmt.Fprintf(w, "      defaultCertificate:\n")
+		fmt.Fprintf(w, "        certFile: %s\n", yamlScalar(certificate.CertFile))
+		fmt.Fprintf(w, "        keyFile: %s\n", yamlScalar(certificate.KeyFile))
+	}
+}
+
+// writeCertificates writes the certificates of the tls section in order.
+// Certificates used only by disabled virtual servers are kept commented out.
+func writeCertificates(w io.Writer, certificates []TraefikCertificate, opts WriteOptions) {
+	fmt.Fprintf(w, "  certificates:\n")
+	for _, certificate := range certificates {
+		if certificate.Comment != "" {
+			fmt.Fprintf(w, "    # %s\n", certificate.Comment)
+		}
+		if opts.Provenance && certificate.Origin != "" {
+			fmt.Fprintf(w, "    # source: %s\n", certificate.Origin)
+		}
+		prefix := ""
+		if certificate.Disabled {
+			fmt.Fprintf(w, "    # disabled\n")
+			prefix = "# "
+		}
+		fmt.Fprintf(w, "    %s- certFile: %s\n", prefix, yamlScalar(certificate.CertFile))
+		fmt.Fprintf(w, "    %s  keyFile: %s\n", prefix, yamlScalar(certificate.KeyFile))
+	}
+}
+
+// writeTLSOptions writes the options of the tls section in name order.
+// Options used only by disabled virtual servers are kept commented out.
+func writeTLSOptions(w io.Writer, options map[string]TraefikTLSOptions, opts WriteOptions) {
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "  options:\n")
+	for _, name := range names {
+		option := options[name]
+		if option.Comment != "" {
+			fmt.Fprintf(w, "    # %s\n", option.Comment)
+		}
+		if opts.Provenance && option.Origin != "" {
+			fmt.Fprintf(w, "    # source: %s\n", option.Origin)
+		}
+		prefix := ""
+		if option.Disabled {
+			fmt.Fprintf(w, "    # disabled\n")
+			prefix = "# "
+		}
+		if option.String() == "defaults" {
+			// An empty mapping keeps the option defined for the routers that reference it
+			fmt.Fprintf(w, "    %s%s: {}\n", prefix, yamlScalar(name))
+			continue
+		}
+		fmt.Fprintf(w, "    %s%s:\n", prefix, yamlScalar(name))
+		if option.MinVersion != "" {
+			fmt.Fprintf(w, "    %s  minVersion: %s\n", prefix, yamlScalar(option.MinVersion))
+		}
+		if option.MaxVersion != "" {
+			fmt.Fprintf(w, "    %s  maxVersion: %s\n", prefix, yamlScalar(option.MaxVersion))
+		}
+		if len(option.CipherSuites) > 0 {
+			fmt.Fprintf(w, "    %s  cipherSuites:\n", prefix)
+			for _, suite := range option.CipherSuites {
+				fmt.Fprintf(w, "    %s    - %s\n", prefix, yamlScalar(suite))
+			}
+		}
+		if option.SNIStrict {
+			fmt.Fprintf(w, "    %s  sniStrict: true\n", prefix)
+		}
+		if auth := option.ClientAuth; auth != nil {
+			fmt.Fprintf(w, "    %s  clientAuth:\n", prefix)
+			if len(auth.CAFiles) > 0 {
+				fmt.Fprintf(w, "    %s    caFiles:\n", prefix)
+				for _, file := range auth.CAFiles {
+					fmt.Fprintf(w, "    %s      - %s\n", prefix, yamlScalar(file))
+				}
+			}
+			fmt.Fprintf(w, "    %s    clientAuthType: %s\n", prefix, yamlScalar(auth.ClientAuthType))
+		}
+	}
+}
+
+// writeMiddlewares writes the middlewares section in name order
+func writeMiddlewares(w io.Writer, middlewares map[string]TraefikMiddleware, opts WriteOptions) {
+	names := make([]string, 0, len(middlewares))
+	for name := range middlewares {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "  middlewares:\n")
+	for _, name := range names {
+		middleware := middlewares[name]
+		if middleware.Comment != "" {
+			fmt.Fprintf(w, "    # %s\n", middleware.Comment)
+		}
+		if opts.Provenance && middleware.Origin != "" {
+			fmt.Fprintf(w, "    # source: %s\n", middleware.Origin)
+		}
+		fmt.Fprintf(w, "    %s:\n", yamlScalar(name))
+
+		switch {
+		case middleware.RedirectScheme != nil:
+			redirect := middleware.RedirectScheme
+			fmt.Fprintf(w, "      redirectScheme:\n")
+			fmt.Fprintf(w, "        scheme: %s\n", yamlScalar(redirect.Scheme))
+			if redirect.Port != "" {
+				fmt.Fprintf(w, "        port: %s\n", yamlScalar(redirect.Port))
+			}
+			if redirect.Permanent {
+				fmt.Fprintf(w, "        permanent: true\n")
+			}
+		case middleware.RedirectRegex != nil:
+			redirect := middleware.RedirectRegex
+			fmt.Fprintf(w, "      redirectRegex:\n")
+			fmt.Fprintf(w, "        regex: %s\n", yamlScalar(redirect.Regex))
+			fmt.Fprintf(w, "        replacement: %s\n", yamlScalar(redirect.Replacement))
+			if redirect.Permanent {
+				fmt.Fprintf(w, "        permanent: true\n")
+			}
+		case middleware.IPAllowList != nil:
+			writeSourceRange(w, "ipAllowList", middleware.IPAllowList)
+		case middleware.IPWhiteList != nil:
+			writeSourceRange(w, "ipWhiteList", middleware.IPWhiteList)
+		case middleware.Plugin != nil && middleware.Plugin.StaticResponse != nil:
+			response := middleware.Plugin.StaticResponse
+			fmt.Fprintf(w, "      plugin:\n")
+			fmt.Fprintf(w, "        staticresponse:\n")
+			fmt.Fprintf(w, "          statusCode: %d\n", response.StatusCode)
+			writeHeaderMap(w, "          ", "headers", response.Headers)
+			if response.Body != "" {
+				fmt.Fprintf(w, "          body: %s\n", yamlScalar(response.Body))
+			}
+		case middleware.Headers != nil:
+			fmt.Fprintf(w, "      headers:\n")
+			writeHeaderMap(w, "        ", "customRequestHeaders", middleware.Headers.CustomRequestHeaders)
+			writeHeaderMap(w, "        ", "customResponseHeaders", middleware.Headers.CustomResponseHeaders)
+		case middleware.ReplacePathRegex != nil:
+			fmt.Fprintf(w, "      replacePathRegex:\n")
+			fmt.Fprintf(w, "        regex: %s\n", yamlScalar(middleware.ReplacePathRegex.Regex))
+			fmt.Fprintf(w, "        replacement: %s\n", yamlScalar(middleware.ReplacePathRegex.Replacement))
+		case middleware.AddPrefix != nil:
+			fmt.Fprintf(w, "      addPrefix:\n")
+			fmt.Fprintf(w, "        prefix: %s\n", yamlScalar(middleware.AddPrefix.Prefix))
+		case middleware.StripPrefix != nil:
+			fmt.Fprintf(w, "      stripPrefix:\n")
+			fmt.Fprintf(w, "        prefixes:\n")
+			for _, prefix := range middleware.StripPrefix.Prefixes {
+				fmt.Fprintf(w, "          - %s\n", yamlScalar(prefix))
+			}
+		}
+	}
+}
+
+// writeHeaderMap writes a map of header names to values in name order, at
+// the given indentation. Empty values stay quoted, as Traefik removes the
+// headers they are set for.
+func writeHeaderMap(w io.Writer, indent, key string, headers map[string]string) {
+	if len(headers) == 0 {
+		return
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "%s%s:\n", indent, key)
+	for _, name := range names {
+		fmt.Fprintf(w, "%s  %s: %s\n", indent, yamlScalar(name), yamlScalar(headers[name]))
+	}
+}
+
+// writeSourceRange writes an ipAllowList or ipWhiteList middleware
+func writeSourceRange(w io.Writer, kind string, list *TraefikIPAllowList) {
+	fmt.Fprintf(w, "      %s:\n", kind)
+	fmt.Fprintf(w, "        sourceRange:\n")
+	for _, source := range list.SourceRange {
+		fmt.Fprintf(w, "          - %s\n", yamlScalar(source))
+	}
+}
+
+// writeHealthCheck writes the healthCheck block of a load balancer
+func writeHealthCheck(w io.Writer, check *TraefikHealthCheck) {
+	fmt.Fprintf(w, "        healthCheck:\n")
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(w, "          %s: %s\n", name, yamlScalar(value))
+		}
+	}
+	field("scheme", check.Scheme)
+	field("path", check.Path)
+	field("method", check.Method)
+	if check.Port > 0 {
+		fmt.Fprintf(w, "          port: %d\n", check.Port)
+	}
+	field("interval", check.Interval)
+	field("timeout", check.Timeout)
+	field("hostname", check.Hostname)
+
+	if len(check.Headers) > 0 {
+		names := make([]string, 0, len(check.Headers))
+		for name := range check.Headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fmt.Fprintf(w, "          headers:\n")
+		for _, name := range names {
+			fmt.Fprintf(w, "            %s: %s\n", yamlScalar(name), yamlScalar(check.Headers[name]))
+		}
+	}
+}
+
+// writeSticky writes the sticky block of a load balancer or weighted service
+func writeSticky(w io.Writer, sticky *TraefikSticky) {
+	fmt.Fprintf(w, "        sticky:\n")
+	if sticky.Cookie == nil {
+		return
+	}
+	cookie := sticky.Cookie
+	fmt.Fprintf(w, "          cookie:\n")
+	if cookie.Name != "" {
+		fmt.Fprintf(w, "            name: %s\n", yamlScalar(cookie.Name))
+	}
+	if cookie.Secure {
+		fmt.Fprintf(w, "            secure: true\n")
+	}
+	if cookie.HTTPOnly {
+		fmt.Fprintf(w, "            httpOnly: true\n")
+	}
+	if cookie.MaxAge > 0 {
+		fmt.Fprintf(w, "            maxAge: %d\n", cookie.MaxAge)
+	}
+}
+
+// yamlScalar formats a string as a YAML scalar, quoting it when needed.
+// Multi-line values are double-quoted, as block scalars would need the
+// indentation of the line they are written on.
+func yamlScalar(value string) string {
+	if strings.ContainsAny(value, "\r\n") {
+		return strconv.Quote(value)
+	}
+	out, err := yaml.Marshal(value)
+	if err != nil {
+		return strconv.Quote(value)
+	}
+	return strings.TrimSuffix(string(out), "\n")
+}
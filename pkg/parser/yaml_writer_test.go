@@ -0,0 +1,99 @@
+package parser
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// provenanceConfig is converted with and without provenance annotations
+const provenanceConfig = `add server s1 10.0.0.1
+add serviceGroup sg1 HTTP
+bind serviceGroup sg1 s1 80
+add lb vserver vs1 HTTP 10.9.0.1 80
+bind lb vserver vs1 sg1
+`
+
+func TestWriteProvenance(t *testing.T) {
+	config := parseCitrixText(t, "ns.conf", provenanceConfig)
+	traefik := GenerateTraefikConfig(config)
+	mapping := GenerateMappingConfigFromTraefik(config, traefik, GenerateOptions{})
+
+	tests := []struct {
+		name       string
+		provenance bool
+		services   []string
+		mappings   []string
+	}{
+		{
+			name:       "annotated",
+			provenance: true,
+			services: []string{
+				"    # source: ns.conf:4: add lb vserver vs1 HTTP 10.9.0.1 80\n    vs1:\n",
+				"          # source: ns.conf:3: bind serviceGroup sg1 s1 80\n          - url: http://10.0.0.1:80\n",
+			},
+			mappings: []string{"# source: ns.conf:4: add lb vserver vs1 HTTP 10.9.0.1 80\n\"10.9.0.1:80\": \"vs1@nacoscs\"\n"},
+		},
+		{name: "plain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := WriteOptions{Provenance: tt.provenance}
+			var services, mappings bytes.Buffer
+			if err := WriteTraefikConfigWithOptions(&services, traefik, opts); err != nil {
+				t.Fatalf("WriteTraefikConfigWithOptions: %v", err)
+			}
+			if err := WriteMappingConfigWithOptions(&mappings, mapping, opts); err != nil {
+				t.Fatalf("WriteMappingConfigWithOptions: %v", err)
+			}
+
+			for _, want := range tt.services {
+				if !strings.Contains(services.String(), want) {
+					t.Errorf("services do not contain %q:\n%s", want, services.String())
+				}
+			}
+			for _, want := range tt.mappings {
+				if !strings.Contains(mappings.String(), want) {
+					t.Errorf("mappings do not contain %q:\n%s", want, mappings.String())
+				}
+			}
+			if !tt.provenance && strings.Contains(services.String()+mappings.String(), "# source:") {
+				t.Errorf("plain output has source annotations:\n%s%s", services.String(), mappings.String())
+			}
+
+			// Annotations are comments, so the files read back the same
+			var parsed TraefikConfig
+			if err := yaml.Unmarshal(services.Bytes(), &parsed); err != nil {
+				t.Fatalf("services are not valid YAML: %v", err)
+			}
+			if got := serverURLs(parsed.HTTP.Services["vs1"]); len(got) != 1 || got[0] != "http://10.0.0.1:80" {
+				t.Errorf("vs1 servers = %v, want [http://10.0.0.1:80]", got)
+			}
+		})
+	}
+}
+
+func TestProvenanceOfMergedInputs(t *testing.T) {
+	merged := MergeConfigs([]Source{
+		{Name: "a.conf", Config: parseCitrixText(t, "a.conf", provenanceConfig)},
+		{Name: "b.conf", Config: parseCitrixText(t, "b.conf", "add server s2 10.0.0.2\nbind serviceGroup sg2 s2 80\n")},
+	})
+
+	services := GenerateTraefikConfig(merged).HTTP.Services
+	tests := []struct {
+		service string
+		want    string
+	}{
+		{service: "vs1", want: "a.conf:3: bind serviceGroup sg1 s1 80"},
+		{service: "sg2", want: "b.conf:2: bind serviceGroup sg2 s2 80"},
+	}
+	for _, tt := range tests {
+		servers := services[tt.service].LoadBalancer.Servers
+		if len(servers) != 1 || servers[0].Origin != tt.want {
+			t.Errorf("%s servers = %+v, want one from %q", tt.service, servers, tt.want)
+		}
+	}
+}
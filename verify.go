@@ -0,0 +1,584 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fabricates/traefik7/pkg/parser"
+)
+
+// verify performs basic verification checks on the parsed configuration,
+// including that bound certificates are in the certificate directories. It
+// prints every diagnostic and returns those that were not skipped in lenient mode.
+func verify(config *parser.LBConfig, certDirs []string) parser.Diagnostics {
+	// Parser diagnostics come first so problems are listed in pipeline order.
+	// Lines skipped in lenient mode are reported but do not fail verification.
+	diagnostics := append(append(parser.Diagnostics{}, config.Diagnostics...), parser.Verify(config)...)
+	diagnostics = append(diagnostics, parser.CheckCertificateFiles(config, certDirs)...)
+	parser.WriteDiagnostics(os.Stdout, diagnostics)
+
+	// Report summary
+	fmt.Printf("Found %d servers, %d vservers, %d service group definitions, %d service group bindings, %d vserver bindings\n",
+		len(config.Servers), len(config.VServers), len(config.ServiceGroupDefs), len(config.ServiceGroups), len(config.VServerBindings))
+	parser.WriteSkipSummary(os.Stdout, config.Diagnostics)
+
+	return diagnostics.Unskipped()
+}
+
+// runVerify implements the verify subcommand
+func runVerify(args []string) int {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	setUsage(flags, "verify -m <mapping_folder> [flags] [input ...]",
+		"Checks the load balancer configurations for errors, then compares them with the\n"+
+			"traefik-services.yaml and mapping.yaml previously generated into the mapping folder.")
+
+	var input inputFlags
+	input.register(flags)
+	mappingFolder := flags.String("m", "", "Mapping folder containing traefik-services.yaml and mapping.yaml (required)")
+	var generate generateFlags
+	generate.register(flags)
+	flags.Parse(args)
+
+	opts, err := generate.options()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 2
+	}
+
+	return verifyCommand(flags, &input, *mappingFolder, opts)
+}
+
+// verifyCommand runs enhanced verification and returns the exit code. The
+// generation options must match those the mappings were generated with.
+func verifyCommand(flags *flag.FlagSet, input *inputFlags, mappingFolder string, opts parser.GenerateOptions) int {
+	// Check if mapping folder is provided
+	if mappingFolder == "" {
+		fmt.Println("Error: Mapping folder (-m) is required for verification mode")
+		fmt.Println()
+		flags.Usage()
+		return 2
+	}
+
+	if !verifyWithMappings(flags, input, mappingFolder, opts) {
+		fmt.Println("Enhanced verification failed")
+		return 1
+	}
+	fmt.Println("Enhanced verification passed")
+	return 0
+}
+
+// verifyWithMappings performs enhanced verification by comparing L7 load balancer commands with generated mappings
+// Supports both file input and stdin input and works with both Citrix and F5 configurations
+func verifyWithMappings(flags *flag.FlagSet, input *inputFlags, mappingFolder string, opts parser.GenerateOptions) bool {
+	fmt.Printf("Enhanced verification: comparing L7 load balancer commands from %s with mappings in '%s'\n", input.describe(flags.Args()), mappingFolder)
+
+	// Parse the L7 load balancer settings (auto-detects Citrix or F5 format per input)
+	config, err := input.load(flags.Args())
+	if err != nil {
+		loadError(flags, err)
+		return false
+	}
+
+	// Perform basic verification first
+	if verify(config, opts.CertDirs).HasErrors() {
+		fmt.Println("Basic verification failed, skipping mapping verification")
+		return false
+	}
+
+	// Read the generated mapping files
+	traefikPath := filepath.Join(mappingFolder, "traefik-services.yaml")
+	mappingPath := filepath.Join(mappingFolder, "mapping.yaml")
+
+	// Check if mapping files exist
+	if _, err := os.Stat(traefikPath); os.IsNotExist(err) {
+		fmt.Printf("Error: Traefik services file not found: %s\n", traefikPath)
+		return false
+	}
+	if _, err := os.Stat(mappingPath); os.IsNotExist(err) {
+		fmt.Printf("Error: Mapping file not found: %s\n", mappingPath)
+		return false
+	}
+
+	// Generate expected configurations to compare
+	expectedTraefikConfig := parser.GenerateTraefikConfigWithOptions(config, opts)
+	expectedMappingConfig := parser.GenerateMappingConfigFromTraefik(config, expectedTraefikConfig, opts)
+
+	success := true
+
+	// Verify Traefik services mapping
+	fmt.Println("\n=== Verifying Traefik Services ===")
+	actualTraefikConfig, err := parser.ReadTraefikConfig(traefikPath)
+	if err != nil {
+		fmt.Printf("Error reading Traefik config: %v\n", err)
+		success = false
+	} else {
+		success = verifyTraefikServices(expectedTraefikConfig, actualTraefikConfig) && success
+	}
+
+	// Verify the transports to servers reached over TLS
+	if len(expectedTraefikConfig.HTTP.ServersTransports) > 0 || len(actualTraefikConfig.HTTP.ServersTransports) > 0 {
+		fmt.Println("\n=== Verifying Traefik Servers Transports ===")
+		success = verifyTraefikServersTransports(expectedTraefikConfig, actualTraefikConfig) && success
+	}
+
+	// Verify content switching and responder routers
+	if len(expectedTraefikConfig.HTTP.Routers) > 0 || len(actualTraefikConfig.HTTP.Routers) > 0 {
+		fmt.Println("\n=== Verifying Traefik Routers ===")
+		success = verifyTraefikRouters(expectedTraefikConfig, actualTraefikConfig) && success
+	}
+
+	// Verify responder and rewrite middlewares
+	if len(expectedTraefikConfig.HTTP.Middlewares) > 0 || len(actualTraefikConfig.HTTP.Middlewares) > 0 {
+		fmt.Println("\n=== Verifying Traefik Middlewares ===")
+		success = verifyTraefikMiddlewares(expectedTraefikConfig, actualTraefikConfig) && success
+	}
+
+	// Verify TLS certificates and options
+	if expectedTraefikConfig.TLS != nil || actualTraefikConfig.TLS != nil {
+		fmt.Println("\n=== Verifying Traefik TLS ===")
+		success = verifyTraefikCertificates(expectedTraefikConfig, actualTraefikConfig) && success
+		success = verifyTraefikTLSOptions(expectedTraefikConfig, actualTraefikConfig) && success
+	}
+
+	// Verify IP:Port mappings
+	fmt.Println("\n=== Verifying IP:Port Mappings ===")
+	actualMappingConfig, err := parser.ReadMappingConfig(mappingPath)
+	if err != nil {
+		fmt.Printf("Error reading mapping config: %v\n", err)
+		success = false
+	} else {
+		success = verifyMappings(expectedMappingConfig, actualMappingConfig) && success
+	}
+
+	// Verify that all L7 services have corresponding Traefik services
+	fmt.Println("\n=== Verifying Service Coverage ===")
+	success = verifyServiceCoverage(config, expectedTraefikConfig) && success
+
+	// Verify that all virtual servers have corresponding mappings
+	fmt.Println("\n=== Verifying Virtual Server Coverage ===")
+	success = verifyVServerCoverage(config, expectedMappingConfig) && success
+
+	// Load balancing methods without an exact equivalent are reported for review, not failed
+	fmt.Println("\n=== Load Balancing Method Changes ===")
+	parser.WriteMethodChanges(os.Stdout, parser.LBMethodChanges(config, opts.Traefik), opts.Traefik)
+
+	if success {
+		fmt.Println("\n✅ Enhanced verification passed - all L7 load balancer commands correctly mapped!")
+	} else {
+		fmt.Println("\n❌ Enhanced verification failed - discrepancies found!")
+	}
+
+	return success
+}
+
+// verifyTraefikServices compares expected and actual Traefik service configurations
+func verifyTraefikServices(expected, actual parser.TraefikConfig) bool {
+	success := true
+
+	// Check if all expected services are present
+	for serviceName, expectedService := range expected.HTTP.Services {
+		actualService, exists := actual.HTTP.Services[serviceName]
+		if !exists {
+			fmt.Printf("❌ Missing Traefik service: %s\n", serviceName)
+			success = false
+			continue
+		}
+
+		// Check if server counts match
+		expectedBackends := expectedService.Backends()
+		actualBackends := actualService.Backends()
+		expectedCount := len(expectedBackends)
+		actualCount := len(actualBackends)
+		if expectedCount != actualCount {
+			fmt.Printf("❌ Service '%s': expected %d servers, found %d\n", serviceName, expectedCount, actualCount)
+			success = false
+		}
+
+		// Check if all expected server URLs, with their weights, are present
+		expectedURLs := make(map[string]bool)
+		for _, backend := range expectedBackends {
+			expectedURLs[backend] = true
+		}
+
+		for _, backend := range actualBackends {
+			if !expectedURLs[backend] {
+				fmt.Printf("❌ Service '%s': unexpected server URL: %s\n", serviceName, backend)
+				success = false
+			} else {
+				delete(expectedURLs, backend)
+			}
+		}
+
+		// Check for missing URLs
+		for missingURL := range expectedURLs {
+			fmt.Printf("❌ Service '%s': missing server URL: %s\n", serviceName, missingURL)
+			success = false
+		}
+
+		expectedCheck := expectedService.LoadBalancer.HealthCheck.String()
+		actualCheck := actualService.LoadBalancer.HealthCheck.String()
+		if expectedCheck != actualCheck {
+			fmt.Printf("❌ Service '%s': expected health check %s, found %s\n", serviceName, expectedCheck, actualCheck)
+			success = false
+		}
+
+		expectedStrategy := expectedService.LoadBalancer.Strategy
+		actualStrategy := actualService.LoadBalancer.Strategy
+		if expectedStrategy != actualStrategy {
+			fmt.Printf("❌ Service '%s': expected strategy %q, found %q\n", serviceName, expectedStrategy, actualStrategy)
+			success = false
+		}
+
+		expectedSticky := expectedService.StickySessions().String()
+		actualSticky := actualService.StickySessions().String()
+		if expectedSticky != actualSticky {
+			fmt.Printf("❌ Service '%s': expected sticky sessions %s, found %s\n", serviceName, expectedSticky, actualSticky)
+			success = false
+		}
+
+		expectedTransport := expectedService.LoadBalancer.ServersTransport
+		actualTransport := actualService.LoadBalancer.ServersTransport
+		if expectedTransport != actualTransport {
+			fmt.Printf("❌ Service '%s': expected servers transport %q, found %q\n", serviceName, expectedTransport, actualTransport)
+			success = false
+		}
+
+		if expectedCount == actualCount && len(expectedURLs) == 0 && expectedStrategy == actualStrategy &&
+			expectedCheck == actualCheck && expectedSticky == actualSticky && expectedTransport == actualTransport {
+			fmt.Printf("✅ Service '%s': %d servers correctly mapped\n", serviceName, expectedCount)
+		}
+	}
+
+	// Check for unexpected services
+	for serviceName := range actual.HTTP.Services {
+		if _, exists := expected.HTTP.Services[serviceName]; !exists {
+			fmt.Printf("⚠️  Unexpected Traefik service found: %s\n", serviceName)
+		}
+	}
+
+	return success
+}
+
+// verifyTraefikRouters compares expected and actual Traefik routers. Routers
+// of disabled virtual servers are absent or commented out.
+func verifyTraefikRouters(expected, actual parser.TraefikConfig) bool {
+	success := true
+
+	for _, name := range unionKeys(expected.HTTP.Routers, actual.HTTP.Routers) {
+		expectedRouter, inExpected := expected.HTTP.Routers[name]
+		actualRouter, inActual := actual.HTTP.Routers[name]
+		switch {
+		case inExpected && expectedRouter.Disabled:
+			continue
+		case !inActual:
+			fmt.Printf("❌ Missing Traefik router: %s\n", name)
+			success = false
+		case !inExpected:
+			fmt.Printf("⚠️  Unexpected Traefik router found: %s\n", name)
+		case expectedRouter.String() != actualRouter.String():
+			fmt.Printf("❌ Router '%s': expected %s, found %s\n", name, expectedRouter, actualRouter)
+			success = false
+		default:
+			fmt.Printf("✅ Router '%s': %s\n", name, expectedRouter.Rule)
+		}
+	}
+
+	return success
+}
+
+// verifyTraefikMiddlewares compares expected and actual Traefik middlewares
+func verifyTraefikMiddlewares(expected, actual parser.TraefikConfig) bool {
+	success := true
+
+	for _, name := range unionKeys(expected.HTTP.Middlewares, actual.HTTP.Middlewares) {
+		expectedMiddleware, inExpected := expected.HTTP.Middlewares[name]
+		actualMiddleware, inActual := actual.HTTP.Middlewares[name]
+		switch {
+		case !inActual:
+			fmt.Printf("❌ Missing Traefik middleware: %s\n", name)
+			success = false
+		case !inExpected:
+			fmt.Printf("⚠️  Unexpected Traefik middleware found: %s\n", name)
+		case expectedMiddleware.String() != actualMiddleware.String():
+			fmt.Printf("❌ Middleware '%s': expected %s, found %s\n", name, expectedMiddleware, actualMiddleware)
+			success = false
+		default:
+			fmt.Printf("✅ Middleware '%s': %s\n", name, expectedMiddleware)
+		}
+	}
+
+	return success
+}
+
+// verifyTraefikCertificates compares expected and actual TLS certificates
+// and the default certificate. Certificates of disabled virtual servers are
+// absent or commented out.
+func verifyTraefikCertificates(expected, actual parser.TraefikConfig) bool {
+	success := true
+
+	expectedDefault, actualDefault := expected.TLS.DefaultCertificate(), actual.TLS.DefaultCertificate()
+	switch {
+	case expectedDefault == nil && actualDefault == nil:
+	case actualDefault == nil:
+		fmt.Printf("❌ Missing default TLS certificate: %s\n", expectedDefault.CertFile)
+		success = false
+	case expectedDefault == nil:
+		fmt.Printf("⚠️  Unexpected default TLS certificate found: %s\n", actualDefault.CertFile)
+	case expectedDefault.String() != actualDefault.String():
+		fmt.Printf("❌ Default certificate: expected %s, found %s\n", expectedDefault, actualDefault)
+		success = false
+	default:
+		fmt.Printf("✅ Default certificate: %s\n", expectedDefault.CertFile)
+	}
+
+	expectedCertificates := expected.TLS.CertificatesByFile()
+	actualCertificates := actual.TLS.CertificatesByFile()
+	for _, file := range unionKeys(expectedCertificates, actualCertificates) {
+		expectedCertificate, inExpected := expectedCertificates[file]
+		actualCertificate, inActual := actualCertificates[file]
+		switch {
+		case inExpected && expectedCertificate.Disabled:
+			continue
+		case !inActual:
+			fmt.Printf("❌ Missing TLS certificate: %s\n", file)
+			success = false
+		case !inExpected:
+			fmt.Printf("⚠️  Unexpected TLS certificate found: %s\n", file)
+		case expectedCertificate.String() != actualCertificate.String():
+			fmt.Printf("❌ Certificate '%s': expected %s, found %s\n", file, expectedCertificate, actualCertificate)
+			success = false
+		default:
+			fmt.Printf("✅ Certificate '%s': %s\n", file, expectedCertificate.KeyFile)
+		}
+	}
+
+	return success
+}
+
+// verifyTraefikTLSOptions compares expected and actual TLS options. Options
+// of disabled virtual servers are absent or commented out.
+func verifyTraefikTLSOptions(expected, actual parser.TraefikConfig) bool {
+	success := true
+
+	var expectedOptions, actualOptions map[string]parser.TraefikTLSOptions
+	if expected.TLS != nil {
+		expectedOptions = expected.TLS.Options
+	}
+	if actual.TLS != nil {
+		actualOptions = actual.TLS.Options
+	}
+	for _, name := range unionKeys(expectedOptions, actualOptions) {
+		expectedOption, inExpected := expectedOptions[name]
+		actualOption, inActual := actualOptions[name]
+		switch {
+		case inExpected && expectedOption.Disabled:
+			continue
+		case !inActual:
+			fmt.Printf("❌ Missing TLS options: %s\n", name)
+			success = false
+		case !inExpected:
+			fmt.Printf("⚠️  Unexpected TLS options found: %s\n", name)
+		case expectedOption.String() != actualOption.String():
+			fmt.Printf("❌ TLS options '%s': expected %s, found %s\n", name, expectedOption, actualOption)
+			success = false
+		default:
+			fmt.Printf("✅ TLS options '%s': %s\n", name, expectedOption)
+		}
+	}
+
+	return success
+}
+
+// verifyTraefikServersTransports compares expected and actual servers transports
+func verifyTraefikServersTransports(expected, actual parser.TraefikConfig) bool {
+	success := true
+
+	expectedTransports := expected.HTTP.ServersTransports
+	actualTransports := actual.HTTP.ServersTransports
+	for _, name := range unionKeys(expectedTransports, actualTransports) {
+		expectedTransport, inExpected := expectedTransports[name]
+		actualTransport, inActual := actualTransports[name]
+		switch {
+		case !inActual:
+			fmt.Printf("❌ Missing servers transport: %s\n", name)
+			success = false
+		case !inExpected:
+			fmt.Printf("⚠️  Unexpected servers transport found: %s\n", name)
+		case expectedTransport.String() != actualTransport.String():
+			fmt.Printf("❌ Servers transport '%s': expected %s, found %s\n", name, expectedTransport, actualTransport)
+			success = false
+		default:
+			fmt.Printf("✅ Servers transport '%s': %s\n", name, expectedTransport)
+		}
+	}
+
+	return success
+}
+
+// verifyMappings compares expected and actual mapping configurations
+func verifyMappings(expected, actual parser.MappingConfig) bool {
+	success := true
+
+	// Mappings of disabled virtual servers are absent or commented out
+	expectedMappings := make(map[string]string)
+	for _, entry := range expected.Entries {
+		if !entry.Disabled {
+			expectedMappings[entry.Key] = entry.Value
+		}
+	}
+
+	actualMappings := make(map[string]string)
+	for _, entry := range actual.Entries {
+		actualMappings[entry.Key] = entry.Value
+	}
+
+	// Check if all expected mappings are present
+	for key, expectedValue := range expectedMappings {
+		actualValue, exists := actualMappings[key]
+		if !exists {
+			fmt.Printf("❌ Missing mapping: %s -> %s\n", key, expectedValue)
+			success = false
+		} else if actualValue != expectedValue {
+			fmt.Printf("❌ Incorrect mapping: %s -> expected '%s', found '%s'\n", key, expectedValue, actualValue)
+			success = false
+		} else {
+			fmt.Printf("✅ Mapping verified: %s -> %s\n", key, expectedValue)
+		}
+	}
+
+	// Check for unexpected mappings
+	for key, value := range actualMappings {
+		if _, exists := expectedMappings[key]; !exists {
+			fmt.Printf("⚠️  Unexpected mapping found: %s -> %s\n", key, value)
+		}
+	}
+
+	return success
+}
+
+// verifyServiceCoverage ensures all L7 service groups have corresponding Traefik services
+func verifyServiceCoverage(config *parser.LBConfig, traefikConfig parser.TraefikConfig) bool {
+	success := true
+
+	// A service group is served by the service of each virtual server it is bound to,
+	// or by a service under its own name when no virtual server uses it
+	servedBy := make(map[string][]string)
+	boundToDisabled := make(map[string]bool)
+	for _, vserver := range config.VServers {
+		for _, group := range config.VServerGroups(vserver.Name) {
+			if vserver.Disabled {
+				boundToDisabled[group] = true
+				continue
+			}
+			servedBy[group] = append(servedBy[group], vserver.Name)
+		}
+	}
+
+	// Check if each bound service group has a corresponding Traefik service
+	for _, serviceName := range config.ServiceGroupNames() {
+		if enabledMembers(config, serviceName) == 0 {
+			if len(config.MembersOf(serviceName)) > 0 {
+				fmt.Printf("⚠️  Service group '%s' has no enabled members and is not mapped\n", serviceName)
+			}
+			continue
+		}
+
+		services := servedBy[serviceName]
+		if len(services) == 0 && boundToDisabled[serviceName] {
+			fmt.Printf("⚠️  Service group '%s' is only bound to disabled virtual servers and is not mapped\n", serviceName)
+			continue
+		}
+		if len(services) == 0 {
+			services = []string{serviceName}
+		}
+		for _, service := range services {
+			if _, exists := traefikConfig.HTTP.Services[service]; !exists {
+				fmt.Printf("❌ Service group '%s' not found in Traefik service '%s'\n", serviceName, service)
+				success = false
+			} else {
+				fmt.Printf("✅ Service group '%s' mapped to Traefik service '%s'\n", serviceName, service)
+			}
+		}
+	}
+
+	return success
+}
+
+// verifyVServerCoverage ensures all L7 virtual servers have corresponding mappings
+func verifyVServerCoverage(config *parser.LBConfig, mappingConfig parser.MappingConfig) bool {
+	success := true
+
+	// Create a map of existing mappings by virtual server name
+	mappingsByVServer := make(map[string]bool)
+	for _, entry := range mappingConfig.Entries {
+		if entry.Disabled {
+			continue
+		}
+		// Extract virtual server name from the mapping value (remove @nacoscs suffix)
+		vserverName := entry.Value
+		if idx := strings.Index(vserverName, "@"); idx != -1 {
+			vserverName = vserverName[:idx]
+		}
+		mappingsByVServer[vserverName] = true
+	}
+
+	// Check if each virtual server has a corresponding mapping
+	for _, vserver := range config.VServers {
+		// Virtual servers without enabled service group members are reported by basic verification
+		members, enabled := 0, 0
+		for _, group := range config.VServerGroups(vserver.Name) {
+			members += len(config.MembersOf(group))
+			enabled += enabledMembers(config, group)
+		}
+		switch {
+		case vserver.Disabled:
+			fmt.Printf("⚠️  Virtual server '%s' (%s:%s) is disabled and is not mapped\n", vserver.Name, vserver.IP, vserver.Port)
+			continue
+		case members == 0:
+			fmt.Printf("⚠️  Virtual server '%s' (%s:%s) has no service group members and is not mapped\n", vserver.Name, vserver.IP, vserver.Port)
+			continue
+		case enabled == 0:
+			fmt.Printf("⚠️  Virtual server '%s' (%s:%s) has no enabled members and is not mapped\n", vserver.Name, vserver.IP, vserver.Port)
+			continue
+		case !vserver.Addressable():
+			fmt.Printf("⚠️  Virtual server '%s' (%s:%s) is not addressable and is not mapped\n", vserver.Name, vserver.IP, vserver.Port)
+			continue
+		}
+		if !mappingsByVServer[vserver.Name] {
+			fmt.Printf("❌ Virtual server '%s' (%s:%s) not found in mappings\n", vserver.Name, vserver.IP, vserver.Port)
+			success = false
+		} else {
+			fmt.Printf("✅ Virtual server '%s' (%s:%s) mapped correctly\n", vserver.Name, vserver.IP, vserver.Port)
+		}
+	}
+
+	// Content switching virtual servers are mapped to the entry point of their routers
+	for _, vserver := range config.CSVServers {
+		switch {
+		case vserver.Disabled:
+			fmt.Printf("⚠️  Content switching virtual server '%s' (%s:%s) is disabled and is not mapped\n", vserver.Name, vserver.IP, vserver.Port)
+		case !vserver.Addressable():
+			fmt.Printf("⚠️  Content switching virtual server '%s' (%s:%s) is not addressable and is not mapped\n", vserver.Name, vserver.IP, vserver.Port)
+		case !mappingsByVServer[vserver.Name]:
+			fmt.Printf("⚠️  Content switching virtual server '%s' (%s:%s) has no routers and is not mapped\n", vserver.Name, vserver.IP, vserver.Port)
+		default:
+			fmt.Printf("✅ Content switching virtual server '%s' (%s:%s) mapped correctly\n", vserver.Name, vserver.IP, vserver.Port)
+		}
+	}
+
+	return success
+}
+
+// enabledMembers counts the members of a service group that are not disabled and resolve to a defined server
+func enabledMembers(config *parser.LBConfig, group string) int {
+	count := 0
+	for _, member := range config.MembersOf(group) {
+		if member.Server != nil && !config.MemberDisabled(member) {
+			count++
+		}
+	}
+	return count
+}